@@ -0,0 +1,294 @@
+// Package analysis reviews games with an engine.  Every move is scored by
+// its centipawn loss and drop in winning chances, classified as an
+// inaccuracy, mistake or blunder and annotated in the game's PGN together
+// with the engine's best line.
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// MateScore is the centipawn score of a mate in zero.  A mate in n moves is
+// MateScore-n.
+const MateScore = 100000
+
+// maxCP caps scores when computing centipawn loss and winning chances so
+// the loss of a won position isn't dominated by the size of the advantage.
+const maxCP = 1000
+
+// Class is the classification of a move.
+type Class int
+
+const (
+	// Good indicates that the move didn't lose significant winning chances.
+	Good Class = iota
+	// Inaccuracy indicates a small drop in winning chances.
+	Inaccuracy
+	// Mistake indicates a drop in winning chances.
+	Mistake
+	// Blunder indicates a large drop in winning chances.
+	Blunder
+)
+
+// String implements the fmt.Stringer interface.
+func (c Class) String() string {
+	switch c {
+	case Inaccuracy:
+		return "Inaccuracy"
+	case Mistake:
+		return "Mistake"
+	case Blunder:
+		return "Blunder"
+	}
+	return "Good"
+}
+
+// NAG returns the numeric annotation glyph of the class: 6 (?!), 2 (?) or 4
+// (??) and 0 for good moves.
+func (c Class) NAG() int {
+	switch c {
+	case Inaccuracy:
+		return 6
+	case Mistake:
+		return 2
+	case Blunder:
+		return 4
+	}
+	return 0
+}
+
+// Thresholds are the minimum drops in winning chances, in percentage
+// points, of each class.
+type Thresholds struct {
+	Inaccuracy float64
+	Mistake    float64
+	Blunder    float64
+}
+
+// DefaultThresholds are the thresholds used by lichess.org.
+var DefaultThresholds = Thresholds{Inaccuracy: 5, Mistake: 10, Blunder: 15}
+
+func (t Thresholds) class(drop float64) Class {
+	switch {
+	case drop >= t.Blunder:
+		return Blunder
+	case drop >= t.Mistake:
+		return Mistake
+	case drop >= t.Inaccuracy:
+		return Inaccuracy
+	}
+	return Good
+}
+
+// Ply is the analysis of one move.  Scores are from the mover's point of
+// view.
+type Ply struct {
+	Color chess.Color
+	Move  *chess.Move
+	// Best is the engine's best move and PV its line.
+	Best *chess.Move
+	PV   []*chess.Move
+	// Before and After are the centipawn scores of the positions before and
+	// after the move.  Mates are scored as MateScore minus the moves to mate.
+	Before int
+	After  int
+	// Loss is the centipawn loss of the move.
+	Loss int
+	// WinBefore and WinAfter are the winning chances in percent.
+	WinBefore float64
+	WinAfter  float64
+	// Accuracy is the accuracy of the move from 0 to 100.
+	Accuracy float64
+	Class    Class
+}
+
+// Report is the analysis of a game.
+type Report struct {
+	// Game is a copy of the game annotated with evaluations, classification
+	// glyphs and the best lines of inaccuracies, mistakes and blunders.
+	Game  *chess.Game
+	Plies []Ply
+}
+
+// Accuracy returns the mean accuracy of the color's moves from 0 to 100.
+func (r *Report) Accuracy(c chess.Color) float64 {
+	sum, n := 0.0, 0
+	for _, p := range r.Plies {
+		if p.Color == c {
+			sum += p.Accuracy
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// AverageCentipawnLoss returns the mean centipawn loss of the color's
+// moves.
+func (r *Report) AverageCentipawnLoss(c chess.Color) float64 {
+	sum, n := 0, 0
+	for _, p := range r.Plies {
+		if p.Color == c {
+			sum += p.Loss
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(sum) / float64(n)
+}
+
+// Count returns the number of the color's moves of the class.
+func (r *Report) Count(c chess.Color, class Class) int {
+	n := 0
+	for _, p := range r.Plies {
+		if p.Color == c && p.Class == class {
+			n++
+		}
+	}
+	return n
+}
+
+// Analyzer analyzes games with an Evaluator.
+type Analyzer struct {
+	eval       Evaluator
+	thresholds Thresholds
+	onPly      func(i int, p Ply)
+}
+
+// WithThresholds is an option for the New function to change the
+// thresholds of the classes.
+func WithThresholds(t Thresholds) func(a *Analyzer) {
+	return func(a *Analyzer) {
+		a.thresholds = t
+	}
+}
+
+// OnPly is an option for the New function to call f with the index and
+// analysis of every move as soon as it is analyzed.
+func OnPly(f func(i int, p Ply)) func(a *Analyzer) {
+	return func(a *Analyzer) {
+		a.onPly = f
+	}
+}
+
+// New returns an Analyzer evaluating positions with the Evaluator.
+func New(eval Evaluator, opts ...func(a *Analyzer)) *Analyzer {
+	a := &Analyzer{eval: eval, thresholds: DefaultThresholds}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Analyze evaluates every position of the game and returns the report.  The
+// game isn't modified.
+func (a *Analyzer) Analyze(ctx context.Context, g *chess.Game) (*Report, error) {
+	positions := g.Positions()
+	moves := g.Moves()
+	r := &Report{}
+	var prev uci.Info
+	prevScore := 0
+	for i, pos := range positions {
+		score, info, err := a.evaluate(ctx, pos)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			p := a.ply(positions[i-1], moves[i-1], prev, prevScore, score)
+			r.Plies = append(r.Plies, p)
+			if a.onPly != nil {
+				a.onPly(i-1, p)
+			}
+		}
+		prev, prevScore = info, score
+	}
+	annotated, err := annotate(g, r.Plies)
+	if err != nil {
+		return nil, err
+	}
+	r.Game = annotated
+	return r, nil
+}
+
+// evaluate returns the centipawn score and info of the position.
+func (a *Analyzer) evaluate(ctx context.Context, pos *chess.Position) (int, uci.Info, error) {
+	switch pos.Status() {
+	case chess.Checkmate:
+		return -MateScore, uci.Info{}, nil
+	case chess.Stalemate:
+		return 0, uci.Info{}, nil
+	}
+	if len(pos.ValidMoves()) == 0 {
+		// variants end games without checkmate
+		return 0, uci.Info{}, nil
+	}
+	info, err := a.eval.Evaluate(ctx, pos)
+	if err != nil {
+		return 0, uci.Info{}, fmt.Errorf("analysis: evaluating %s: %w", pos, err)
+	}
+	return centipawns(info.Score), info, nil
+}
+
+// ply returns the analysis of the move from pre.  The info and score are
+// of pre and after is the score of the next position from its side to move.
+func (a *Analyzer) ply(pre *chess.Position, m *chess.Move, info uci.Info, before, after int) Ply {
+	p := Ply{
+		Color:  pre.Turn(),
+		Move:   m,
+		PV:     info.PV,
+		Before: before,
+		After:  -after,
+	}
+	if len(info.PV) > 0 {
+		p.Best = info.PV[0]
+	}
+	p.Loss = clamp(p.Before) - clamp(p.After)
+	if p.Loss < 0 {
+		p.Loss = 0
+	}
+	p.WinBefore = winChances(p.Before)
+	p.WinAfter = winChances(p.After)
+	drop := p.WinBefore - p.WinAfter
+	p.Accuracy = math.Max(0, math.Min(100, 103.1668*math.Exp(-0.04354*math.Max(0, drop))-3.1669))
+	if p.Best == nil || p.Best.S1() != m.S1() || p.Best.S2() != m.S2() || p.Best.Promo() != m.Promo() {
+		p.Class = a.thresholds.class(drop)
+	}
+	return p
+}
+
+// centipawns returns the score in centipawns with mates scored as
+// MateScore minus the moves to mate.
+func centipawns(s uci.Score) int {
+	switch {
+	case s.Mate > 0:
+		return MateScore - s.Mate
+	case s.Mate < 0:
+		return -MateScore - s.Mate
+	}
+	return s.CP
+}
+
+func clamp(cp int) int {
+	switch {
+	case cp > maxCP:
+		return maxCP
+	case cp < -maxCP:
+		return -maxCP
+	}
+	return cp
+}
+
+// winChances returns the winning chances in percent of the centipawn score
+// with the model used by lichess.org.
+func winChances(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(clamp(cp))))-1)
+}
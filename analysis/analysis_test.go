@@ -0,0 +1,142 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/analysis"
+	"github.com/notnil/chess/internal/enginetest"
+	"github.com/notnil/chess/uci"
+)
+
+// TestMain makes the test binary act as a UCI engine so UCIEvaluator can
+// be tested.
+func TestMain(m *testing.M) {
+	enginetest.Main(m, map[string]enginetest.Engine{
+		"server": enginetest.Server(firstMoveSearcher{}),
+	})
+}
+
+type firstMoveSearcher struct{}
+
+func (firstMoveSearcher) Search(ctx context.Context, pos *chess.Position, cmd uci.CmdGo, send func(uci.Info)) (*chess.Move, *chess.Move) {
+	m := pos.ValidMoves()[0]
+	send(uci.Info{Depth: 1, Score: uci.Score{CP: 10}, PV: []*chess.Move{m}})
+	return m, nil
+}
+
+// scholarsMate is a game in which black blunders with 3...Nf6.
+func scholarsMate(t *testing.T) *chess.Game {
+	g := chess.NewGame()
+	for _, s := range []string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+// scriptedEvaluator returns the scores of the positions of scholarsMate in
+// order with the best moves.
+func scriptedEvaluator(t *testing.T, g *chess.Game) analysis.Evaluator {
+	scores := []uci.Score{{CP: 30}, {CP: -30}, {CP: 30}, {CP: 0}, {CP: 0}, {CP: -10}, {Mate: 1}}
+	best := []string{"e2e4", "e7e5", "d2d4", "b8c6", "f1c4", "g7g6", "h5f7"}
+	evals := map[string]uci.Info{}
+	for i, pos := range g.Positions()[:len(scores)] {
+		m, err := chess.UCINotation{}.Decode(pos, best[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		evals[pos.String()] = uci.Info{Depth: 10, Score: scores[i], PV: []*chess.Move{m}}
+	}
+	return analysis.EvaluatorFunc(func(ctx context.Context, pos *chess.Position) (uci.Info, error) {
+		info, ok := evals[pos.String()]
+		if !ok {
+			return info, errors.New("unexpected position")
+		}
+		return info, nil
+	})
+}
+
+func TestAnalyze(t *testing.T) {
+	g := scholarsMate(t)
+	g.AddTagPair("White", "A")
+	plies := 0
+	a := analysis.New(scriptedEvaluator(t, g), analysis.OnPly(func(i int, p analysis.Ply) {
+		plies++
+	}))
+	r, err := a.Analyze(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plies != 7 || len(r.Plies) != 7 {
+		t.Fatalf("expected 7 plies but got %d and %d", plies, len(r.Plies))
+	}
+	nf6 := r.Plies[5]
+	if nf6.Class != analysis.Blunder || nf6.Loss != 990 || nf6.Best.String() != "g7g6" {
+		t.Fatalf("expected Nf6 to be a blunder but got %+v", nf6)
+	}
+	qh5 := r.Plies[2]
+	if qh5.Class != analysis.Good || qh5.Loss != 30 {
+		t.Fatalf("expected Qh5 to be good but got %+v", qh5)
+	}
+	if mate := r.Plies[6]; mate.Loss != 0 || mate.After != analysis.MateScore || mate.Accuracy < 99.99 {
+		t.Fatalf("expected mate to be best but got %+v", mate)
+	}
+	if r.Count(chess.Black, analysis.Blunder) != 1 || r.Count(chess.White, analysis.Blunder) != 0 {
+		t.Fatal("expected one blunder by black")
+	}
+	if acpl := r.AverageCentipawnLoss(chess.White); acpl != 7.5 {
+		t.Fatalf("expected white average centipawn loss 7.5 but got %f", acpl)
+	}
+	if acc := r.Accuracy(chess.Black); acc >= r.Accuracy(chess.White) || acc <= 0 || math.IsNaN(acc) {
+		t.Fatalf("expected black to be less accurate but got %f and %f", acc, r.Accuracy(chess.White))
+	}
+
+	pgn := strings.Join(strings.Fields(r.Game.String()), " ")
+	for _, s := range []string{
+		`[White "A"]`,
+		"1. e4 { [%eval 0.30] } e5 { [%eval 0.30] }",
+		"3. Bc4 { [%eval 0.10] } Nf6 $4 { [%eval #1] } { Blunder. g6 was best. } ( 3... g6 ) 4. Qxf7# 1-0",
+	} {
+		if !strings.Contains(pgn, s) {
+			t.Fatalf("expected %s in annotated pgn %s", s, pgn)
+		}
+	}
+	if len(g.Comments()[0]) != 0 {
+		t.Fatal("expected game to be unchanged")
+	}
+}
+
+func TestAnalyzeError(t *testing.T) {
+	g := scholarsMate(t)
+	a := analysis.New(analysis.EvaluatorFunc(func(ctx context.Context, pos *chess.Position) (uci.Info, error) {
+		return uci.Info{}, errors.New("engine exited")
+	}))
+	if _, err := a.Analyze(context.Background(), g); err == nil || !strings.HasPrefix(err.Error(), "analysis: ") {
+		t.Fatalf("expected evaluation error but got %v", err)
+	}
+}
+
+func TestUCIEvaluator(t *testing.T) {
+	eng, err := uci.New(enginetest.Path("server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	a := analysis.New(analysis.UCIEvaluator(eng, uci.CmdGo{Depth: 1}))
+	r, err := a.Analyze(context.Background(), scholarsMate(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Plies) != 7 || r.Plies[0].Before != 10 || r.Plies[0].After != -10 {
+		t.Fatalf("expected engine scores but got %+v", r.Plies[0])
+	}
+}
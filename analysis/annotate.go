@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/notnil/chess"
+)
+
+// annotate returns a copy of the game with an eval comment after every move
+// in the [%eval] format of lichess.org and ChessBase and, for inaccuracies,
+// mistakes and blunders, a glyph, a comment naming the best move and the
+// best line as a variation.
+func annotate(g *chess.Game, plies []Ply) (*chess.Game, error) {
+	start := g.Positions()[0]
+	fen := start.String()
+	if _, ok := start.Variant().(chess.Chess960); ok {
+		fen = start.XFENString()
+	}
+	opt, err := chess.VariantFEN(start.Variant(), fen)
+	if err != nil {
+		return nil, err
+	}
+	a := chess.NewGame(opt, chess.TagPairs(g.TagPairs()))
+	comments, nags, variations := g.Comments(), g.NAGs(), g.Variations()
+	for i, m := range g.Moves() {
+		pos := a.Position()
+		if err := a.Move(m); err != nil {
+			return nil, err
+		}
+		for _, c := range comments[i] {
+			if err := a.AddComment(c); err != nil {
+				return nil, err
+			}
+		}
+		for _, nag := range nags[i] {
+			if err := a.AddNAG(nag); err != nil {
+				return nil, err
+			}
+		}
+		for _, v := range variations[i] {
+			if err := a.AddVariation(v); err != nil {
+				return nil, err
+			}
+		}
+		p := plies[i]
+		if a.Position().Status() != chess.Checkmate {
+			if err := a.AddComment("[%eval " + evalString(p.After, p.Color) + "]"); err != nil {
+				return nil, err
+			}
+		}
+		if p.Class == Good {
+			continue
+		}
+		if err := a.AddNAG(p.Class.NAG()); err != nil {
+			return nil, err
+		}
+		if p.Best == nil {
+			continue
+		}
+		if err := a.AddVariation(p.PV); err != nil {
+			return nil, err
+		}
+		// the variation holds the best move with the tags needed for SAN
+		vs := a.Variations()[i]
+		best := vs[len(vs)-1][0]
+		if err := a.AddComment(fmt.Sprintf("%s. %s was best.", p.Class, chess.AlgebraicNotation{}.Encode(pos, best))); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case a.Outcome() != chess.NoOutcome:
+	case g.Outcome() == chess.Draw:
+		if err := a.Draw(chess.DrawOffer); err != nil {
+			return nil, err
+		}
+	case g.Outcome() == chess.WhiteWon:
+		a.Resign(chess.Black)
+	case g.Outcome() == chess.BlackWon:
+		a.Resign(chess.White)
+	}
+	return a, nil
+}
+
+// evalString returns the score of the color from white's point of view in
+// pawns such as 0.25 or mates such as #-3.
+func evalString(cp int, c chess.Color) string {
+	if c == chess.Black {
+		cp = -cp
+	}
+	switch {
+	case cp > MateScore-1000:
+		return "#" + strconv.Itoa(MateScore-cp)
+	case cp < -MateScore+1000:
+		return "#-" + strconv.Itoa(MateScore+cp)
+	}
+	return strconv.FormatFloat(float64(cp)/100, 'f', 2, 64)
+}
@@ -0,0 +1,36 @@
+package analysis
+
+import (
+	"context"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// Evaluator evaluates positions for analysis.  The score of the returned
+// info is from the point of view of the side to move and its PV is the best
+// line, starting with the best move.  Evaluate is never called for
+// positions without legal moves.
+type Evaluator interface {
+	Evaluate(ctx context.Context, pos *chess.Position) (uci.Info, error)
+}
+
+// EvaluatorFunc is an adapter to use functions as Evaluators.
+type EvaluatorFunc func(ctx context.Context, pos *chess.Position) (uci.Info, error)
+
+// Evaluate implements the Evaluator interface.
+func (f EvaluatorFunc) Evaluate(ctx context.Context, pos *chess.Position) (uci.Info, error) {
+	return f(ctx, pos)
+}
+
+// UCIEvaluator returns an Evaluator searching every position with the
+// engine and the go command, such as uci.CmdGo{Depth: 18}.  CmdUCI should be
+// run before the analysis.
+func UCIEvaluator(eng *uci.Engine, cmd uci.CmdGo) Evaluator {
+	return EvaluatorFunc(func(ctx context.Context, pos *chess.Position) (uci.Info, error) {
+		if err := eng.RunContext(ctx, uci.CmdPosition{Position: pos}, cmd); err != nil {
+			return uci.Info{}, err
+		}
+		return eng.SearchResults().Info, nil
+	})
+}
@@ -0,0 +1,102 @@
+package chess
+
+import "strings"
+
+// Antichess (or losing chess) is a variant in which a player wins by
+// losing all their pieces or being stalemated.  Capturing is compulsory,
+// the king is an ordinary piece which can be captured, there is no check
+// or castling and pawns can also promote to a king.
+type Antichess struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Antichess) Name() string {
+	return "Antichess"
+}
+
+// StartingPosition implements the Variant interface.
+func (Antichess) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(strings.Replace(startFEN, "KQkq", "-", 1), Antichess{})
+	return pos
+}
+
+// Moves implements the Variant interface and only returns captures if
+// a capture is available.
+func (Antichess) Moves(pos *Position) []*Move {
+	moves := standardMoves(pos, false, addAntichessTags)
+	captures := []*Move{}
+	for _, m := range moves {
+		if m.HasTag(Capture) || m.HasTag(EnPassant) {
+			captures = append(captures, m)
+		}
+		if m.promo == Queen {
+			moves = append(moves, &Move{s1: m.s1, s2: m.s2, promo: King, tags: m.tags})
+			if m.HasTag(Capture) {
+				captures = append(captures, moves[len(moves)-1])
+			}
+		}
+	}
+	if len(captures) > 0 {
+		return captures
+	}
+	return moves
+}
+
+// Status implements the Variant interface.  The player to move wins if
+// they have no legal moves.  Possible methods are AllPiecesLost,
+// Stalemate and NoMethod.
+func (Antichess) Status(pos *Position) (Outcome, Method) {
+	if len(pos.ValidMoves()) > 0 {
+		return NoOutcome, NoMethod
+	}
+	outcome := WhiteWon
+	if pos.turn == Black {
+		outcome = BlackWon
+	}
+	pieces := pos.board.whiteSqs
+	if pos.turn == Black {
+		pieces = pos.board.blackSqs
+	}
+	if pieces == 0 {
+		return outcome, AllPiecesLost
+	}
+	return outcome, Stalemate
+}
+
+// InsufficientMaterial implements the Variant interface and returns true
+// if each player has a single bishop and the bishops are on squares of
+// opposite colors.
+func (Antichess) InsufficientMaterial(pos *Position) bool {
+	b := pos.board
+	if b.whiteSqs != b.bbWhiteBishop || b.blackSqs != b.bbBlackBishop {
+		return false
+	}
+	if !b.bbWhiteBishop.single() || !b.bbBlackBishop.single() {
+		return false
+	}
+	var colors []Color
+	for sq, p := range b.SquareMap() {
+		if p.Type() == Bishop {
+			colors = append(colors, sq.color())
+		}
+	}
+	return colors[0] != colors[1]
+}
+
+func (Antichess) validate(pos *Position) error {
+	if (pos.board.bbWhitePawn|pos.board.bbBlackPawn)&(bbRank1|bbRank8) != 0 {
+		return errPawnsOnBackRank
+	}
+	return nil
+}
+
+// addAntichessTags adds the capture tags of the move.  There is no check
+// in antichess so every move is legal.
+func addAntichessTags(m *Move, pos *Position) {
+	if pos.board.isOccupied(m.s2) {
+		m.addTag(Capture)
+	} else if m.s2 == pos.enPassantSquare && pos.board.Piece(m.s1).Type() == Pawn {
+		m.addTag(EnPassant)
+	}
+}
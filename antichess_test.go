@@ -0,0 +1,97 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAntichessPerft(t *testing.T) {
+	pos := Antichess{}.StartingPosition()
+	for depth, expected := range []int{1, 20, 400, 8067, 153299} {
+		if n := perft(pos, depth); n != expected {
+			t.Fatalf("expected perft(%d) to be %d but got %d", depth, expected, n)
+		}
+	}
+}
+
+func TestAntichessForcedCapture(t *testing.T) {
+	g := NewGame(UseVariant(Antichess{}))
+	for _, m := range []string{"e3", "b5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moves := g.ValidMoves()
+	if len(moves) != 1 || moves[0].String() != "f1b5" {
+		t.Fatalf("expected capture Bxb5 to be forced but got %v", moves)
+	}
+	if err := g.MoveStr("Bxb5"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAntichessKingPromotion(t *testing.T) {
+	opt, err := VariantFEN(Antichess{}, "8/P7/8/8/8/8/8/7k w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt, UseNotation(UCINotation{}))
+	if len(g.ValidMoves()) != 5 {
+		t.Fatalf("expected 5 promotions but got %d", len(g.ValidMoves()))
+	}
+	if err := g.MoveStr("a7a8k"); err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Position().Board().Piece(A8); p != WhiteKing {
+		t.Fatalf("expected white king on a8 but got %s", p)
+	}
+	if s := (AlgebraicNotation{}).Encode(g.Positions()[0], g.Moves()[0]); s != "a8=K" {
+		t.Fatalf("expected king promotion a8=K but got %s", s)
+	}
+}
+
+func TestAntichessStatus(t *testing.T) {
+	opt, err := VariantFEN(Antichess{}, "8/8/8/8/8/8/1p6/2R5 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if err := g.MoveStr("bxc1=K"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != AllPiecesLost {
+		t.Fatalf("expected white to win by losing all pieces but got %s %s", g.Outcome(), g.Method())
+	}
+	opt, err = VariantFEN(Antichess{}, "8/8/8/8/8/p7/P7/7k b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(opt)
+	if err := g.MoveStr("Kg1"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != Stalemate {
+		t.Fatalf("expected stalemated white to win but got %s %s", g.Outcome(), g.Method())
+	}
+	opt, err = VariantFEN(Antichess{}, "8/8/8/8/8/8/8/b6B w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := NewGame(opt); !g.Position().Variant().InsufficientMaterial(g.Position()) {
+		t.Fatal("expected opposite colored bishops to be insufficient material")
+	}
+}
+
+func TestAntichessPGN(t *testing.T) {
+	pgn := `[Variant "Losing Chess"]
+
+1. e3 b5 2. Bxb5 *`
+	opt, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if g.Variant().Name() != "Antichess" || len(g.Moves()) != 3 {
+		t.Fatalf("expected antichess game with 3 moves but got %s with %d", g.Variant().Name(), len(g.Moves()))
+	}
+}
@@ -0,0 +1,183 @@
+package chess
+
+import (
+	"errors"
+	"strings"
+)
+
+// Atomic is standard chess in which captures cause an explosion that
+// removes the capturing piece and every piece other than pawns on the
+// squares surrounding the capture.  A player wins by exploding the
+// opponent's king or by checkmate.  Kings can't capture and can't be in
+// check while they are adjacent.
+type Atomic struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Atomic) Name() string {
+	return "Atomic"
+}
+
+// StartingPosition implements the Variant interface.
+func (Atomic) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(startFEN, Atomic{})
+	return pos
+}
+
+// Moves implements the Variant interface.
+func (Atomic) Moves(pos *Position) []*Move {
+	if pos.board.bbForPiece(NewPiece(King, pos.turn)) == 0 {
+		return []*Move{}
+	}
+	moves := standardMoves(pos, false, addAtomicTags)
+	for _, m := range castleMoves(pos) {
+		m.tags &= ^(Capture | Check | inCheck)
+		addAtomicTags(m, pos)
+		if !m.HasTag(inCheck) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// Status implements the Variant interface.  Possible methods are
+// KingExploded, Checkmate, Stalemate and NoMethod.
+func (Atomic) Status(pos *Position) (Outcome, Method) {
+	if pos.board.bbForPiece(NewPiece(King, pos.turn)) == 0 {
+		if pos.turn == White {
+			return BlackWon, KingExploded
+		}
+		return WhiteWon, KingExploded
+	}
+	if len(pos.ValidMoves()) > 0 {
+		return NoOutcome, NoMethod
+	}
+	if !kingsAdjacent(pos.board) && isInCheck(pos) {
+		if pos.turn == White {
+			return BlackWon, Checkmate
+		}
+		return WhiteWon, Checkmate
+	}
+	return Draw, Stalemate
+}
+
+// InsufficientMaterial implements the Variant interface and returns true
+// if both players have a bare king or one player has a bare king and the
+// other a king and a single minor piece.
+func (Atomic) InsufficientMaterial(pos *Position) bool {
+	b := pos.board
+	white := b.whiteSqs & ^b.bbWhiteKing
+	black := b.blackSqs & ^b.bbBlackKing
+	minors := b.bbWhiteBishop | b.bbWhiteKnight | b.bbBlackBishop | b.bbBlackKnight
+	switch {
+	case white == 0 && black == 0:
+		return true
+	case white == 0:
+		return black.single() && black&minors != 0
+	case black == 0:
+		return white.single() && white&minors != 0
+	}
+	return false
+}
+
+func (Atomic) validate(pos *Position) error {
+	if (pos.board.bbWhitePawn|pos.board.bbBlackPawn)&(bbRank1|bbRank8) != 0 {
+		return errPawnsOnBackRank
+	}
+	if !pos.board.bbWhiteKing.single() || !pos.board.bbBlackKing.single() {
+		return errors.New("both black and white should have one king each")
+	}
+	cp := pos.copy()
+	cp.turn = cp.turn.Other()
+	if !kingsAdjacent(pos.board) && isInCheck(cp) {
+		return errors.New("king can be captured in next move")
+	}
+	return nil
+}
+
+func (Atomic) afterMove(pos *Position, m *Move, next *Position) {
+	if !m.HasTag(Capture) && !m.HasTag(EnPassant) {
+		return
+	}
+	explode(next.board, m.s2)
+	// castling is lost if the king or rook exploded
+	cr := next.castleRights
+	for _, c := range []Color{White, Black} {
+		rank := Rank1
+		if c == Black {
+			rank = Rank8
+		}
+		kingSide, queenSide := NewSquare(FileH, rank), NewSquare(FileA, rank)
+		if cr.nineSixtyMode {
+			if cr.hSideRookStartingFile[c] != "" {
+				kingSide = strToSquareMap[strings.ToLower(cr.hSideRookStartingFile[c])+rank.String()]
+			}
+			if cr.aSideRookStartingFile[c] != "" {
+				queenSide = strToSquareMap[strings.ToLower(cr.aSideRookStartingFile[c])+rank.String()]
+			}
+		}
+		rooks := next.board.bbForPiece(NewPiece(Rook, c))
+		noKing := next.board.bbForPiece(NewPiece(King, c)) == 0
+		if noKing || !rooks.Occupied(kingSide) {
+			cr.setCastle(c, KingSide, false)
+		}
+		if noKing || !rooks.Occupied(queenSide) {
+			cr.setCastle(c, QueenSide, false)
+		}
+	}
+}
+
+// explode removes the piece on the square and all pieces other than pawns
+// on the surrounding squares.
+func explode(b *Board, sq Square) {
+	blast := bbKingMoves[sq] & ^(b.bbWhitePawn|b.bbBlackPawn) | bbForSquare(sq)
+	for _, p := range allPieces {
+		b.setBBForPiece(p, b.bbForPiece(p) & ^blast)
+	}
+	b.calcConvienceBBs(nil)
+}
+
+func kingsAdjacent(b *Board) bool {
+	if b.whiteKingSq == NoSquare || b.blackKingSq == NoSquare {
+		return false
+	}
+	return bbKingMoves[b.whiteKingSq]&b.bbBlackKing != 0
+}
+
+// addAtomicTags adds the move's tags following the atomic rules: a move
+// is illegal if it explodes the player's own king or leaves it in check
+// unless it explodes the opponent's king.
+func addAtomicTags(m *Move, pos *Position) {
+	castle := m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle)
+	if !castle && pos.board.isOccupied(m.s2) {
+		m.addTag(Capture)
+	} else if m.s2 == pos.enPassantSquare && pos.board.Piece(m.s1).Type() == Pawn {
+		m.addTag(EnPassant)
+	}
+	capture := m.HasTag(Capture) || m.HasTag(EnPassant)
+	if capture && pos.board.Piece(m.s1).Type() == King {
+		m.addTag(inCheck)
+		return
+	}
+	cp := pos.copy()
+	cp.board.update(m)
+	if capture {
+		explode(cp.board, m.s2)
+	}
+	if cp.board.bbForPiece(NewPiece(King, pos.turn)) == 0 {
+		m.addTag(inCheck)
+		return
+	}
+	if cp.board.bbForPiece(NewPiece(King, pos.turn.Other())) == 0 || kingsAdjacent(cp.board) {
+		return
+	}
+	if isInCheck(cp) {
+		m.addTag(inCheck)
+		return
+	}
+	cp.turn = cp.turn.Other()
+	if isInCheck(cp) {
+		m.addTag(Check)
+	}
+}
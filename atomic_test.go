@@ -0,0 +1,88 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAtomicPerft(t *testing.T) {
+	pos := Atomic{}.StartingPosition()
+	for depth, expected := range []int{1, 20, 400, 8902, 197326} {
+		if n := perft(pos, depth); n != expected {
+			t.Fatalf("expected perft(%d) to be %d but got %d", depth, expected, n)
+		}
+	}
+}
+
+func TestAtomicExplosion(t *testing.T) {
+	opt, err := VariantFEN(Atomic{}, "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if err := g.MoveStr("Rxa8"); err != nil {
+		t.Fatal(err)
+	}
+	pos := g.Position()
+	if pos.Board().Piece(A8) != NoPiece {
+		t.Fatal("expected the capturing rook to explode")
+	}
+	if cr := pos.castleRights.String(); cr != "Kk" {
+		t.Fatalf("expected castle rights Kk but got %s", cr)
+	}
+	opt, err = VariantFEN(Atomic{}, "4k3/4p3/8/8/8/8/8/4RK2 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(opt)
+	if err := g.MoveStr("Rxe7"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != KingExploded {
+		t.Fatalf("expected white to win by exploding the king but got %s %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestAtomicKings(t *testing.T) {
+	opt, err := VariantFEN(Atomic{}, "4k3/8/8/8/8/8/3p4/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewGame(opt).MoveStr("Kxd2"); err == nil {
+		t.Fatal("expected king captures to be illegal")
+	}
+	// adjacent kings can't give check
+	opt, err = VariantFEN(Atomic{}, "4r3/8/8/8/8/8/4k3/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if err := g.MoveStr("Kd1"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != NoOutcome {
+		t.Fatalf("expected game in progress but got %s", g.Outcome())
+	}
+}
+
+func TestAtomicPGN(t *testing.T) {
+	g := NewGame(UseVariant(Atomic{}))
+	for _, m := range []string{"Nf3", "a6", "Ng5", "a5", "Nxf7"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.Outcome() != WhiteWon || g.Method() != KingExploded {
+		t.Fatalf("expected white to win by exploding the king but got %s %s", g.Outcome(), g.Method())
+	}
+	if !strings.Contains(g.String(), `[Variant "Atomic"]`) {
+		t.Fatal("expected variant tag in pgn")
+	}
+	opt, err := PGN(strings.NewReader(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2 := NewGame(opt); g2.Variant().Name() != "Atomic" || g2.Position().Hash() != g.Position().Hash() {
+		t.Fatal("expected atomic game to round trip through pgn")
+	}
+}
@@ -0,0 +1,74 @@
+package chess
+
+// Attacks returns the squares attacked by the piece on the square,
+// including squares of pieces of its own color that it defends.  Pawns
+// attack the squares diagonally in front of them.  No squares are returned
+// for an empty square.  Squares are ordered A1, B1 ... H8.
+func (b *Board) Attacks(sq Square) []Square {
+	return b.attacksBB(sq).squares()
+}
+
+// Attackers returns the squares of the pieces of the color attacking the
+// square.  The square doesn't need to be occupied.
+func (b *Board) Attackers(sq Square, c Color) []Square {
+	return b.attackersBB(sq, c).squares()
+}
+
+// IsAttacked returns true if a piece of the color attacks the square.
+func (b *Board) IsAttacked(sq Square, by Color) bool {
+	return b.attackersBB(sq, by) != 0
+}
+
+func (b *Board) attacksBB(sq Square) bitboard {
+	p := b.Piece(sq)
+	occ := ^b.emptySqs
+	switch p.Type() {
+	case King:
+		return bbKingMoves[sq]
+	case Queen:
+		return diaAttack(occ, sq) | hvAttack(occ, sq)
+	case Rook:
+		return hvAttack(occ, sq)
+	case Bishop:
+		return diaAttack(occ, sq)
+	case Knight:
+		return bbKnightMoves[sq]
+	case Pawn:
+		return pawnAttacks(bbForSquare(sq), p.Color())
+	}
+	return 0
+}
+
+func (b *Board) attackersBB(sq Square, c Color) bitboard {
+	occ := ^b.emptySqs
+	dia, hv := diaAttack(occ, sq), hvAttack(occ, sq)
+	queens := b.bbForPiece(NewPiece(Queen, c))
+	bb := (dia | hv) & queens
+	bb |= hv & b.bbForPiece(NewPiece(Rook, c))
+	bb |= dia & b.bbForPiece(NewPiece(Bishop, c))
+	bb |= bbKnightMoves[sq] & b.bbForPiece(NewPiece(Knight, c))
+	bb |= bbKingMoves[sq] & b.bbForPiece(NewPiece(King, c))
+	// pawns of the color attack the square from where a pawn of the other
+	// color on the square would attack
+	bb |= pawnAttacks(bbForSquare(sq), c.Other()) & b.bbForPiece(NewPiece(Pawn, c))
+	return bb
+}
+
+// pawnAttacks returns the squares attacked by pawns of the color.
+func pawnAttacks(pawns bitboard, c Color) bitboard {
+	if c == White {
+		return ((pawns & ^bbFileH & ^bbRank8) >> 9) | ((pawns & ^bbFileA & ^bbRank8) >> 7)
+	}
+	return ((pawns & ^bbFileH & ^bbRank1) << 7) | ((pawns & ^bbFileA & ^bbRank1) << 9)
+}
+
+// squares returns the occupied squares in order from A1, B1 to H8.
+func (b bitboard) squares() []Square {
+	sqs := []Square{}
+	for sq := 0; sq < numOfSquaresInBoard; sq++ {
+		if b&bbSquares[sq] != 0 {
+			sqs = append(sqs, Square(sq))
+		}
+	}
+	return sqs
+}
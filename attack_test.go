@@ -0,0 +1,41 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttacks(t *testing.T) {
+	fen, err := FEN("4k3/8/2n5/1B6/4P3/8/8/R3K3 w - - 0 1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewGame(fen).Position().Board()
+	tests := []struct {
+		sq      Square
+		attacks []Square
+	}{
+		{B5, []Square{F1, E2, D3, A4, C4, A6, C6}},
+		{E4, []Square{D5, F5}},
+		{C6, []Square{B4, D4, A5, E5, A7, E7, B8, D8}},
+		{A1, []Square{B1, C1, D1, E1, A2, A3, A4, A5, A6, A7, A8}},
+		{D4, []Square{}},
+	}
+	for _, test := range tests {
+		if attacks := b.Attacks(test.sq); !reflect.DeepEqual(attacks, test.attacks) {
+			t.Fatalf("expected %s to attack %v but got %v", test.sq, test.attacks, attacks)
+		}
+	}
+	if attackers := b.Attackers(C6, White); !reflect.DeepEqual(attackers, []Square{B5}) {
+		t.Fatalf("expected b5 to attack c6 but got %v", attackers)
+	}
+	if attackers := b.Attackers(E4, Black); !reflect.DeepEqual(attackers, []Square{}) {
+		t.Fatalf("expected no attackers of e4 but got %v", attackers)
+	}
+	if attackers := b.Attackers(D5, White); !reflect.DeepEqual(attackers, []Square{E4}) {
+		t.Fatalf("expected e4 to attack d5 but got %v", attackers)
+	}
+	if !b.IsAttacked(D4, Black) || b.IsAttacked(D4, White) || b.IsAttacked(E8, White) {
+		t.Fatal("unexpected attacked squares")
+	}
+}
@@ -54,3 +54,8 @@ func (b bitboard) Draw() string {
 	}
 	return s
 }
+
+// single returns true if exactly one square is occupied.
+func (b bitboard) single() bool {
+	return b != 0 && b&(b-1) == 0
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -177,6 +178,9 @@ func (b *Board) UnmarshalText(text []byte) error {
 	if err != nil {
 		return err
 	}
+	if (cp.bbWhitePawn|cp.bbBlackPawn)&(bbRank1|bbRank8) != 0 {
+		return fmt.Errorf("chess: fen illegal board %s , pawns cannot be on first or last rank", text)
+	}
 	*b = *cp
 	return nil
 }
@@ -218,6 +222,12 @@ func (b *Board) UnmarshalBinary(data []byte) error {
 }
 
 func (b *Board) update(m *Move) {
+	if m.drop != NoPiece {
+		b.setBBForPiece(m.drop, b.bbForPiece(m.drop)|bbForSquare(m.s2))
+		b.calcConvienceBBs(m)
+		return
+	}
+
 	p1 := b.Piece(m.s1)
 	s1BB := bbForSquare(m.s1)
 	s2BB := bbForSquare(m.s2)
@@ -320,6 +330,8 @@ func (b *Board) calcConvienceBBs(m *Move) {
 				b.blackKingSq = sqr
 			}
 		}
+	} else if m.drop != NoPiece {
+		// kings are never dropped
 	} else if m.s1 == b.whiteKingSq {
 		b.whiteKingSq = m.s2
 	} else if m.s1 == b.blackKingSq {
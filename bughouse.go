@@ -0,0 +1,143 @@
+package chess
+
+import "errors"
+
+// Bughouse is the variant played on each board of a BughouseGame.  It
+// follows the Crazyhouse rules except that captured pieces are passed to
+// the partner on the other board instead of the capturing player's pocket.
+type Bughouse struct {
+	Crazyhouse
+}
+
+// Name implements the Variant interface.
+func (Bughouse) Name() string {
+	return "Bughouse"
+}
+
+// StartingPosition implements the Variant interface.
+func (Bughouse) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(startFEN, Bughouse{})
+	return pos
+}
+
+func (Bughouse) afterMove(pos *Position, m *Move, next *Position) {
+	updatePockets(pos, m, next, false)
+}
+
+// A BughouseBoard identifies one of the two boards of a BughouseGame.
+type BughouseBoard int
+
+const (
+	// BoardA is the board on which the first team plays white.
+	BoardA BughouseBoard = iota
+	// BoardB is the board on which the first team plays black.
+	BoardB
+)
+
+// Other returns the other board.
+func (b BughouseBoard) Other() BughouseBoard {
+	return 1 - b
+}
+
+// BughouseGame is a pair of Bughouse games played by two teams of two
+// players.  The first team plays white on BoardA and black on BoardB.
+// Pieces captured on one board are added to the pocket of the capturing
+// player's partner.  The game ends as soon as one of the boards has an
+// outcome.  Checkmate is determined with the pieces currently in hand.
+type BughouseGame struct {
+	boards  [2]*Game
+	outcome Outcome
+	method  Method
+}
+
+// NewBughouseGame returns a bughouse game with both boards in the starting
+// position.  The options are applied to both boards.
+func NewBughouseGame(options ...func(*Game)) *BughouseGame {
+	bg := &BughouseGame{outcome: NoOutcome}
+	for i := range bg.boards {
+		opts := append([]func(*Game){UseVariant(Bughouse{})}, options...)
+		bg.boards[i] = NewGame(opts...)
+	}
+	return bg
+}
+
+// Board returns the game played on the board.
+func (bg *BughouseGame) Board(b BughouseBoard) *Game {
+	return bg.boards[b]
+}
+
+// Move plays the move on the board and passes any captured piece to the
+// partner on the other board.  An error is returned if the move is invalid
+// or the game is over.
+func (bg *BughouseGame) Move(b BughouseBoard, m *Move) error {
+	if bg.outcome != NoOutcome {
+		return errors.New("chess: bughouse game is over")
+	}
+	g := bg.boards[b]
+	pos := g.Position()
+	if err := g.Move(m); err != nil {
+		return err
+	}
+	m = g.Moves()[len(g.Moves())-1]
+	if m.HasTag(Capture) || m.HasTag(EnPassant) {
+		pt := Pawn
+		if !m.HasTag(EnPassant) && !pos.IsPromoted(m.s2) {
+			pt = pos.board.Piece(m.s2).Type()
+		}
+		// the partner plays the color of the captured piece
+		other := bg.boards[b.Other()]
+		other.pos = other.pos.withPocket(NewPiece(pt, pos.Turn().Other()), 1)
+		other.positions[len(other.positions)-1] = other.pos
+	}
+	bg.updateOutcome(b)
+	return nil
+}
+
+// MoveStr decodes the given string in the board's notation and calls the
+// Move method.
+func (bg *BughouseGame) MoveStr(b BughouseBoard, s string) error {
+	g := bg.boards[b]
+	m, err := g.notation.Decode(g.pos, s)
+	if err != nil {
+		return err
+	}
+	return bg.Move(b, m)
+}
+
+// Resign resigns the game for the player of the color on the board.
+func (bg *BughouseGame) Resign(b BughouseBoard, c Color) {
+	if bg.outcome != NoOutcome {
+		return
+	}
+	bg.boards[b].Resign(c)
+	bg.updateOutcome(b)
+}
+
+// Outcome returns the outcome of the game.  WhiteWon indicates that the
+// first team (white on BoardA and black on BoardB) won the game.
+func (bg *BughouseGame) Outcome() Outcome {
+	return bg.outcome
+}
+
+// Method returns the method by which the game ended on the deciding board.
+func (bg *BughouseGame) Method() Method {
+	return bg.method
+}
+
+func (bg *BughouseGame) updateOutcome(b BughouseBoard) {
+	g := bg.boards[b]
+	outcome := g.Outcome()
+	if outcome == NoOutcome {
+		return
+	}
+	if b == BoardB {
+		switch outcome {
+		case WhiteWon:
+			outcome = BlackWon
+		case BlackWon:
+			outcome = WhiteWon
+		}
+	}
+	bg.outcome = outcome
+	bg.method = g.Method()
+}
@@ -0,0 +1,198 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// chess960Knights lists the knight placements on the five squares left
+// after placing the bishops and the queen, indexed by Scharnagl number.
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960Position returns the Chess960 starting position with the given
+// Scharnagl number which ranges from 0 to 959.  The standard starting
+// position is number 518.  An error is returned if the number is out of
+// range.
+func Chess960Position(id int) (*Position, error) {
+	return chess960Position(id, id, Chess960{})
+}
+
+// DoubleChess960Position returns the Double Chess960 starting position in
+// which white and black are set up independently with the given Scharnagl
+// numbers.  An error is returned if either number is out of range.
+func DoubleChess960Position(white, black int) (*Position, error) {
+	return chess960Position(white, black, DoubleChess960{})
+}
+
+// chess960Position decodes the starting position of the Scharnagl numbers
+// with the rules of the variant.
+func chess960Position(white, black int, v Variant) (*Position, error) {
+	w, err := chess960Rank(white)
+	if err != nil {
+		return nil, err
+	}
+	b, err := chess960Rank(black)
+	if err != nil {
+		return nil, err
+	}
+	castle := strings.ToUpper(rookFiles(w)) + rookFiles(b)
+	fen := fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w %s - 0 1", strings.ToLower(b), w, castle)
+	return decodeVariantFEN(fen, v)
+}
+
+// Chess960ID returns the Scharnagl number of the Chess960 starting
+// position.  An error is returned if the position isn't a Chess960
+// starting position or if white and black are set up differently.
+func Chess960ID(pos *Position) (int, error) {
+	white, black, err := DoubleChess960ID(pos)
+	if err != nil {
+		return 0, err
+	}
+	if white != black {
+		return 0, errors.New("chess: white and black have different chess960 setups")
+	}
+	return white, nil
+}
+
+// DoubleChess960ID returns the Scharnagl numbers of the white and black
+// setups of the Chess960 or Double Chess960 starting position.  An error
+// is returned if the position isn't a starting position.
+func DoubleChess960ID(pos *Position) (white, black int, err error) {
+	if pos == nil {
+		return 0, 0, errors.New("chess: can not find chess960 id of position = nil")
+	}
+	notStart := fmt.Errorf("chess: %s is not a chess960 starting position", pos)
+	ranks := strings.Split(strings.Fields(pos.String())[0], "/")
+	if len(ranks) != 8 || ranks[1] != "pppppppp" || ranks[6] != "PPPPPPPP" {
+		return 0, 0, notStart
+	}
+	for _, r := range ranks[2:6] {
+		if r != "8" {
+			return 0, 0, notStart
+		}
+	}
+	castle := strings.ToUpper(rookFiles(ranks[7])) + rookFiles(ranks[0])
+	if !pos.castleRights.nineSixtyMode {
+		castle = "KQkq"
+	}
+	if pos.turn != White || pos.castleRights.String() != castle {
+		return 0, 0, notStart
+	}
+	if white, err = chess960Number(ranks[7]); err != nil {
+		return 0, 0, notStart
+	}
+	if black, err = chess960Number(strings.ToUpper(ranks[0])); err != nil {
+		return 0, 0, notStart
+	}
+	return white, black, nil
+}
+
+// Chess960Start returns a function that sets the game to the Chess960
+// starting position with the given Scharnagl number.  The returned
+// function is designed to be used in the NewGame constructor.
+func Chess960Start(id int) (func(*Game), error) {
+	pos, err := Chess960Position(id)
+	if err != nil {
+		return nil, err
+	}
+	return startPosition(pos), nil
+}
+
+// DoubleChess960Start returns a function that sets the game to the Double
+// Chess960 starting position with the given Scharnagl numbers.  The
+// returned function is designed to be used in the NewGame constructor.
+func DoubleChess960Start(white, black int) (func(*Game), error) {
+	pos, err := DoubleChess960Position(white, black)
+	if err != nil {
+		return nil, err
+	}
+	return startPosition(pos), nil
+}
+
+func startPosition(pos *Position) func(*Game) {
+	return func(g *Game) {
+		cp := pos.copy()
+		cp.inCheck = isInCheck(cp)
+		g.pos = cp
+		g.positions = []*Position{cp}
+		g.updatePosition()
+	}
+}
+
+// chess960Rank returns the white back rank of the Scharnagl number such
+// as RNBQKBNR for number 518.
+func chess960Rank(id int) (string, error) {
+	if id < 0 || id > 959 {
+		return "", fmt.Errorf("chess: invalid chess960 id %d , should be 0 to 959", id)
+	}
+	rank := make([]byte, 8)
+	rank[2*(id%4)+1] = 'B'
+	id /= 4
+	rank[2*(id%4)] = 'B'
+	id /= 4
+	empty := func() []int {
+		sqs := []int{}
+		for i, c := range rank {
+			if c == 0 {
+				sqs = append(sqs, i)
+			}
+		}
+		return sqs
+	}
+	rank[empty()[id%6]] = 'Q'
+	id /= 6
+	sqs := empty()
+	rank[sqs[chess960Knights[id][0]]] = 'N'
+	rank[sqs[chess960Knights[id][1]]] = 'N'
+	sqs = empty()
+	rank[sqs[0]], rank[sqs[1]], rank[sqs[2]] = 'R', 'K', 'R'
+	return string(rank), nil
+}
+
+// chess960Number is the inverse of chess960Rank.
+func chess960Number(rank string) (int, error) {
+	if len(rank) != 8 || strings.Count(rank, "B") != 2 || strings.Count(rank, "N") != 2 || strings.Count(rank, "Q") != 1 {
+		return 0, fmt.Errorf("chess: invalid chess960 rank %s", rank)
+	}
+	light, dark, knights := -1, -1, -1
+	for i, c := range rank {
+		if c == 'B' && i%2 == 1 {
+			light = i / 2
+		} else if c == 'B' {
+			dark = i / 2
+		}
+	}
+	if light < 0 || dark < 0 {
+		return 0, fmt.Errorf("chess: invalid chess960 rank %s , bishops on same color", rank)
+	}
+	rest := strings.Replace(rank, "B", "", -1)
+	queen := strings.Index(rest, "Q")
+	rest = strings.Replace(rest, "Q", "", -1)
+	first := strings.Index(rest, "N")
+	second := strings.LastIndex(rest, "N")
+	for i, k := range chess960Knights {
+		if k[0] == first && k[1] == second {
+			knights = i
+		}
+	}
+	if knights < 0 || strings.Replace(rest, "N", "", -1) != "RKR" {
+		return 0, fmt.Errorf("chess: invalid chess960 rank %s , king should be between rooks", rank)
+	}
+	return light + 4*dark + 16*queen + 96*knights, nil
+}
+
+// rookFiles returns the files of the rooks of the back rank in Shredder-FEN
+// order (king side rook first).
+func rookFiles(rank string) string {
+	rank = strings.ToUpper(rank)
+	a := strings.Index(rank, "R")
+	h := strings.LastIndex(rank, "R")
+	if a < 0 {
+		return ""
+	}
+	return File(h).String() + File(a).String()
+}
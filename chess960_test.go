@@ -0,0 +1,113 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChess960Position(t *testing.T) {
+	tests := []struct {
+		id  int
+		fen string
+	}{
+		{0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1"},
+		{518, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"},
+		{959, "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w CAca - 0 1"},
+	}
+	for _, test := range tests {
+		pos, err := Chess960Position(test.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos.String() != test.fen {
+			t.Fatalf("expected position %d to be %s but got %s", test.id, test.fen, pos)
+		}
+	}
+	for id := 0; id < 960; id++ {
+		pos, err := Chess960Position(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := Chess960ID(pos); err != nil || n != id {
+			t.Fatalf("expected id %d but got %d %v", id, n, err)
+		}
+	}
+	for _, id := range []int{-1, 960} {
+		if _, err := Chess960Position(id); err == nil {
+			t.Fatalf("expected error for id %d", id)
+		}
+	}
+	if n, err := Chess960ID(StartingPosition()); err != nil || n != 518 {
+		t.Fatalf("expected standard starting position to be 518 but got %d %v", n, err)
+	}
+	if _, err := Chess960ID(NewGame(UseVariant(Horde{})).Position()); err == nil {
+		t.Fatal("expected error for a position which isn't a chess960 starting position")
+	}
+}
+
+func TestDoubleChess960(t *testing.T) {
+	opt, err := DoubleChess960Start(518, 342)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if fen := g.FEN(); fen != "nrbkqbrn/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAgb - 0 1" {
+		t.Fatalf("unexpected fen %s", fen)
+	}
+	if g.Variant() != (DoubleChess960{}) {
+		t.Fatalf("expected variant Double Chess960 but got %s", g.Variant().Name())
+	}
+	if _, err := FEN(g.FEN(), true); err == nil {
+		t.Fatal("expected error decoding a double chess960 fen as chess960")
+	}
+	if _, err := VariantFEN(DoubleChess960{}, g.FEN()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Chess960ID(g.Position()); err == nil {
+		t.Fatal("expected error for different white and black setups")
+	}
+	if w, b, err := DoubleChess960ID(g.Position()); err != nil || w != 518 || b != 342 {
+		t.Fatalf("expected ids 518 and 342 but got %d %d %v", w, b, err)
+	}
+	for _, m := range []string{"e4", "b6", "Nf3", "Bb7", "Bc4"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := g.Position().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := &Position{}
+	if err := pos.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if pos.String() != g.FEN() {
+		t.Fatalf("expected binary round trip to give %s but got %s", g.FEN(), pos)
+	}
+	for _, m := range []string{"O-O-O", "O-O"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fen := g.FEN(); !strings.HasPrefix(fen, "n1krqbrn/pbpppppp/1p6/8/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 b - -") {
+		t.Fatalf("unexpected fen after castling %s", fen)
+	}
+}
+
+func TestChess960FENFiles(t *testing.T) {
+	fens := []string{
+		// kings on different files
+		"rkrnbbqn/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		// rooks on different files
+		"1r1k2r1/pppppppp/8/8/8/8/PPPPPPPP/R2K3R w HAgb - 0 1",
+	}
+	for _, fen := range fens {
+		if _, err := FEN(fen, true); err == nil {
+			t.Fatalf("expected error decoding chess960 fen %s", fen)
+		}
+		if _, err := VariantFEN(DoubleChess960{}, fen); err != nil {
+			t.Fatalf("expected double chess960 fen %s to decode but got %v", fen, err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Crazyhouse is standard chess in which captured pieces change sides and
+// go to the capturing player's pocket.  Instead of moving a piece on the
+// board, a player can drop a piece from their pocket on an empty square.
+// Captured promoted pieces are demoted to pawns.  Positions are written
+// with the pockets appended to the board in brackets and promoted pieces
+// marked with a tilde:
+//
+//	r1bqkb1r/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQKB1R[Pp] w KQkq - 0 4
+type Crazyhouse struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Crazyhouse) Name() string {
+	return "Crazyhouse"
+}
+
+// StartingPosition implements the Variant interface.
+func (Crazyhouse) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(startFEN, Crazyhouse{})
+	return pos
+}
+
+// Moves implements the Variant interface and includes drops.
+func (Crazyhouse) Moves(pos *Position) []*Move {
+	return append(engine{}.CalcMoves(pos, false), dropMoves(pos)...)
+}
+
+// InsufficientMaterial implements the Variant interface and always
+// returns false since captured pieces remain in play.
+func (Crazyhouse) InsufficientMaterial(pos *Position) bool {
+	return false
+}
+
+func (Crazyhouse) drops() bool {
+	return true
+}
+
+func (Crazyhouse) afterMove(pos *Position, m *Move, next *Position) {
+	updatePockets(pos, m, next, true)
+}
+
+// pockets holds the number of pieces in hand indexed by piece.
+type pockets [13]int8
+
+func (pk *pockets) equal(o *pockets) bool {
+	if pk == nil || o == nil {
+		return pk == o
+	}
+	return *pk == *o
+}
+
+// String returns the pieces in hand in FEN order (white's pieces first).
+func (pk *pockets) String() string {
+	s := ""
+	for _, c := range []Color{White, Black} {
+		for _, pt := range []PieceType{Queen, Rook, Bishop, Knight, Pawn} {
+			p := NewPiece(pt, c)
+			s += strings.Repeat(p.getFENChar(), int(pk[p]))
+		}
+	}
+	return s
+}
+
+// Pocket returns the number of pieces of each type in hand for the color.
+// It returns nil if the position's variant doesn't have pockets.
+func (pos *Position) Pocket(c Color) map[PieceType]int {
+	if pos.pockets == nil {
+		return nil
+	}
+	m := map[PieceType]int{}
+	for _, pt := range []PieceType{Queen, Rook, Bishop, Knight, Pawn} {
+		if n := pos.pockets[NewPiece(pt, c)]; n > 0 {
+			m[pt] = int(n)
+		}
+	}
+	return m
+}
+
+// IsPromoted returns true if the piece on the square was promoted from a
+// pawn.  Promoted pieces are only tracked in variants with pockets.
+func (pos *Position) IsPromoted(sq Square) bool {
+	return pos.promoted.Occupied(sq)
+}
+
+// withPocket returns a copy of the position with n pieces added to
+// (or removed from if negative) the pocket.
+func (pos *Position) withPocket(p Piece, n int) *Position {
+	cp := pos.copy()
+	pk := pockets{}
+	if pos.pockets != nil {
+		pk = *pos.pockets
+	}
+	pk[p] += int8(n)
+	cp.pockets = &pk
+	return cp
+}
+
+func dropMoves(pos *Position) []*Move {
+	moves := []*Move{}
+	if pos.pockets == nil {
+		return moves
+	}
+	for _, pt := range []PieceType{Queen, Rook, Bishop, Knight, Pawn} {
+		p := NewPiece(pt, pos.turn)
+		if pos.pockets[p] <= 0 {
+			continue
+		}
+		for sq := 0; sq < numOfSquaresInBoard; sq++ {
+			s2 := Square(sq)
+			if !pos.board.emptySqs.Occupied(s2) {
+				continue
+			}
+			if pt == Pawn && (s2.Rank() == Rank1 || s2.Rank() == Rank8) {
+				continue
+			}
+			m := &Move{s1: NoSquare, s2: s2, drop: p}
+			addTags(m, pos)
+			// filter out drops that don't resolve a check
+			if !m.HasTag(inCheck) {
+				moves = append(moves, m)
+			}
+		}
+	}
+	return moves
+}
+
+// updatePockets sets the pockets and promoted pieces of next.  The
+// captured piece is added to the capturing player's pocket if capture is
+// true.
+func updatePockets(pos *Position, m *Move, next *Position, capture bool) {
+	pk := pockets{}
+	if pos.pockets != nil {
+		pk = *pos.pockets
+	}
+	promoted := pos.promoted
+	if m.drop != NoPiece {
+		pk[m.drop]--
+	} else {
+		if capture && (m.HasTag(Capture) || m.HasTag(EnPassant)) {
+			pt := Pawn
+			if !m.HasTag(EnPassant) && !promoted.Occupied(m.s2) {
+				pt = pos.board.Piece(m.s2).Type()
+			}
+			pk[NewPiece(pt, pos.turn)]++
+		}
+		s1BB, s2BB := bbForSquare(m.s1), bbForSquare(m.s2)
+		wasPromoted := promoted&s1BB != 0
+		promoted &= ^(s1BB | s2BB)
+		if wasPromoted || m.promo != NoPieceType {
+			promoted |= s2BB
+		}
+	}
+	next.pockets = &pk
+	next.promoted = promoted
+}
+
+// decodePocketFEN removes the pockets and promoted piece markers from the
+// board section of the FEN.  Pockets can be written in brackets after the
+// board or as a ninth rank.
+func decodePocketFEN(fen string) (string, *pockets, bitboard, error) {
+	parts := strings.SplitN(strings.TrimSpace(fen), " ", 2)
+	if len(parts) != 2 {
+		return "", nil, 0, fmt.Errorf("chess: fen invalid notation %s must have 6 sections", fen)
+	}
+	board, pocketStr := parts[0], ""
+	if i := strings.Index(board, "["); i >= 0 {
+		if !strings.HasSuffix(board, "]") {
+			return "", nil, 0, fmt.Errorf("chess: fen invalid pocket %s", board[i:])
+		}
+		board, pocketStr = board[:i], board[i+1:len(board)-1]
+	} else if strings.Count(board, "/") == 8 {
+		i := strings.LastIndex(board, "/")
+		board, pocketStr = board[:i], board[i+1:]
+	}
+	pk := &pockets{}
+	for _, r := range pocketStr {
+		p := fenPieceMap[string(r)]
+		if p == NoPiece || p.Type() == King {
+			return "", nil, 0, fmt.Errorf("chess: fen invalid pocket %s", pocketStr)
+		}
+		pk[p]++
+	}
+	var promoted bitboard
+	ranks := strings.Split(board, "/")
+	for i, rankStr := range ranks {
+		file := 0
+		for _, r := range rankStr {
+			switch {
+			case r == '~':
+				if file == 0 {
+					return "", nil, 0, fmt.Errorf("chess: fen invalid rank %s", rankStr)
+				}
+				promoted |= bbForSquare(NewSquare(File(file-1), Rank(7-i)))
+			case r >= '1' && r <= '8':
+				n, _ := strconv.Atoi(string(r))
+				file += n
+			default:
+				file++
+			}
+		}
+	}
+	board = strings.Replace(board, "~", "", -1)
+	return board + " " + parts[1], pk, promoted, nil
+}
+
+// boardFEN returns the board section of the position's FEN including the
+// pockets and promoted pieces.
+func (pos *Position) boardFEN() string {
+	if pos.pockets == nil {
+		return pos.board.String()
+	}
+	fen := ""
+	for r := 7; r >= 0; r-- {
+		empty := 0
+		for f := 0; f < numOfSquaresInRow; f++ {
+			sq := NewSquare(File(f), Rank(r))
+			p := pos.board.Piece(sq)
+			if p == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fen += strconv.Itoa(empty)
+				empty = 0
+			}
+			fen += p.getFENChar()
+			if pos.promoted.Occupied(sq) {
+				fen += "~"
+			}
+		}
+		if empty > 0 {
+			fen += strconv.Itoa(empty)
+		}
+		if r != 0 {
+			fen += "/"
+		}
+	}
+	return fen + "[" + pos.pockets.String() + "]"
+}
@@ -0,0 +1,210 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func perft(pos *Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	moves := pos.ValidMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+	n := 0
+	for _, m := range moves {
+		n += perft(pos.Update(m), depth-1)
+	}
+	return n
+}
+
+func TestCrazyhousePerft(t *testing.T) {
+	pos := Crazyhouse{}.StartingPosition()
+	for depth, expected := range []int{1, 20, 400, 8902, 197281} {
+		if n := perft(pos, depth); n != expected {
+			t.Fatalf("expected perft(%d) to be %d but got %d", depth, expected, n)
+		}
+	}
+}
+
+func TestCrazyhouseFEN(t *testing.T) {
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		"r1bqkb1r/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQKB1R[Pp] w KQkq - 0 4",
+		"rQ~bqkbnr/p1pppppp/8/8/8/8/1PPPPPPP/RNBQKBNR[NPp] b KQk - 0 5",
+	} {
+		opt, err := VariantFEN(Crazyhouse{}, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := NewGame(opt).Position().String(); s != fen {
+			t.Fatalf("expected fen %s but got %s", fen, s)
+		}
+		text, err := NewGame(opt).Position().MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opt, err = VariantFEN(Crazyhouse{}, string(text)); err != nil {
+			t.Fatal(err)
+		}
+		if pos := NewGame(opt).Position(); pos.String() != fen || pos.Variant() != (Crazyhouse{}) {
+			t.Fatalf("expected crazyhouse fen %s but got %s", fen, pos)
+		}
+	}
+	opt, err := VariantFEN(Crazyhouse{}, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR/Qn w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := NewGame(opt).Position()
+	if pos.Pocket(White)[Queen] != 1 || pos.Pocket(Black)[Knight] != 1 {
+		t.Fatalf("expected pockets from ninth rank but got %s", pos)
+	}
+	if _, err := VariantFEN(Crazyhouse{}, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[K] w KQkq - 0 1"); err == nil {
+		t.Fatal("expected error for a king in the pocket")
+	}
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	g := NewGame(UseVariant(Crazyhouse{}))
+	for _, m := range []string{"e4", "d5", "exd5", "Qxd5", "Nc3", "Qa5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pos := g.Position()
+	if pos.Pocket(White)[Pawn] != 1 || pos.Pocket(Black)[Pawn] != 1 {
+		t.Fatalf("expected a pawn in each pocket but got %s", pos)
+	}
+	if err := g.MoveStr("P@d5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("@e4"); err != nil {
+		t.Fatal(err)
+	}
+	if pos := g.Position(); len(pos.Pocket(White)) != 0 || len(pos.Pocket(Black)) != 0 {
+		t.Fatalf("expected empty pockets but got %s", pos)
+	}
+	m := g.Moves()[len(g.Moves())-1]
+	if m.Drop() != Pawn || m.S1() != NoSquare || m.S2() != E4 {
+		t.Fatalf("expected pawn drop on e4 but got %s", m)
+	}
+	if s := (UCINotation{}).Encode(nil, m); s != "P@e4" {
+		t.Fatalf("expected P@e4 but got %s", s)
+	}
+	pgn := g.String()
+	if !strings.Contains(pgn, `[Variant "Crazyhouse"]`) || !strings.Contains(pgn, "P@d5") {
+		t.Fatalf("expected crazyhouse pgn but got %s", pgn)
+	}
+	opt, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := NewGame(opt).Position().String(); s != g.Position().String() {
+		t.Fatalf("expected %s after decoding pgn but got %s", g.Position(), s)
+	}
+}
+
+func TestCrazyhouseCheckmateBlockedByDrop(t *testing.T) {
+	// back rank mate that can be blocked by dropping the knight
+	opt, err := VariantFEN(Crazyhouse{}, "6k1/5ppp/8/8/8/8/8/K2R4[n] w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if err := g.MoveStr("Rd8+"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != NoOutcome {
+		t.Fatalf("expected no outcome but got %s by %s", g.Outcome(), g.Method())
+	}
+	if err := g.MoveStr("N@f8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Rxf8+"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Kxf8"); err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Position().Pocket(Black); p[Rook] != 1 {
+		t.Fatalf("expected rook in black's pocket but got %v", p)
+	}
+	if g.Outcome() != NoOutcome {
+		t.Fatal("expected no insufficient material draw in crazyhouse")
+	}
+}
+
+func TestCrazyhousePromotedCapture(t *testing.T) {
+	opt, err := VariantFEN(Crazyhouse{}, "4k3/1Q~6/8/8/8/8/7r/4K3[] b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if err := g.MoveStr("Rh8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Qb5+"); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Position().IsPromoted(B5) {
+		t.Fatal("expected promoted queen on b5")
+	}
+	if err := g.MoveStr("Kd8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Kd2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Rh5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Kc3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Rxb5"); err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Position().Pocket(Black); p[Pawn] != 1 || p[Queen] != 0 {
+		t.Fatalf("expected promoted queen to be demoted to a pawn but got %v", p)
+	}
+}
+
+func TestBughouse(t *testing.T) {
+	bg := NewBughouseGame()
+	for _, m := range []struct {
+		board BughouseBoard
+		move  string
+	}{
+		{BoardA, "e4"}, {BoardA, "d5"}, {BoardA, "exd5"},
+		{BoardB, "e4"}, {BoardB, "d5"},
+	} {
+		if err := bg.MoveStr(m.board, m.move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// white captured a black pawn on board A which goes to black on board B
+	if p := bg.Board(BoardA).Position().Pocket(White); len(p) != 0 {
+		t.Fatalf("expected capturing player's pocket to be empty but got %v", p)
+	}
+	if p := bg.Board(BoardB).Position().Pocket(Black); p[Pawn] != 1 {
+		t.Fatalf("expected pawn in partner's pocket but got %v", p)
+	}
+	if err := bg.MoveStr(BoardB, "Nf3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bg.MoveStr(BoardB, "P@e4"); err == nil {
+		t.Fatal("expected drop on occupied square to be illegal")
+	}
+	if err := bg.MoveStr(BoardB, "P@e6"); err != nil {
+		t.Fatal(err)
+	}
+	bg.Resign(BoardB, White)
+	if bg.Outcome() != WhiteWon || bg.Method() != Resignation {
+		t.Fatalf("expected first team to win by resignation but got %s %s", bg.Outcome(), bg.Method())
+	}
+	if err := bg.MoveStr(BoardA, "Qxd5"); err == nil {
+		t.Fatal("expected game over error")
+	}
+}
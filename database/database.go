@@ -0,0 +1,293 @@
+// Package database implements a local chess game database with
+// position, material and tag pair indexes.
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	dataMagic  = "CGDB\x02"
+	indexMagic = "CGDX\x01"
+)
+
+// DB is a game database stored in a single local file.  Games are appended
+// to the data file and indexed by tag pairs, Zobrist position hashes and
+// material signatures.  The position and material indexes are saved next
+// to the data file (with the ".idx" suffix) when the DB is closed and are
+// rebuilt from the games if missing or out of date.  DB is safe for
+// concurrent use.
+type DB struct {
+	mu       sync.RWMutex
+	path     string
+	f        *os.File
+	size     int64
+	offsets  []int64
+	outcomes []chess.Outcome
+	tags     map[string]map[string][]int
+	dirty    bool
+	idx      *index
+}
+
+// Open opens the database at the given path, creating the file if it doesn't
+// exist.
+func Open(path string) (*DB, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{
+		path: path,
+		f:    f,
+		tags: map[string]map[string][]int{},
+		idx:  newIndex(),
+	}
+	if err := db.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close saves the position index if it changed and closes the data file.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.saveIndex(); err != nil {
+		db.f.Close()
+		return err
+	}
+	return db.f.Close()
+}
+
+// Len returns the number of games in the database.
+func (db *DB) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.offsets)
+}
+
+// Add appends the game to the database and returns its id.  Ids are
+// assigned sequentially starting at zero.
+func (db *DB) Add(g *chess.Game) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.add(g)
+}
+
+// Import adds all the games read by the scanner to the database and
+// returns the number of games added.  Entries without tag pairs or moves
+// are skipped.
+func (db *DB) Import(s *chess.Scanner) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	count := 0
+	for s.Scan() {
+		g := s.Next()
+		if g == nil || (len(g.Moves()) == 0 && len(g.TagPairs()) == 0) {
+			continue
+		}
+		if _, err := db.add(g); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := s.Err(); err != nil && err != io.EOF {
+		return count, err
+	}
+	return count, nil
+}
+
+// Game returns the game with the given id.
+func (db *DB) Game(id int) (*chess.Game, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	r, err := db.record(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.game()
+}
+
+func (db *DB) add(g *chess.Game) (int, error) {
+	r := newRecord(g)
+	b, err := r.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	buf := new(bytes.Buffer)
+	writeUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+	if _, err := db.f.WriteAt(buf.Bytes(), db.size); err != nil {
+		return 0, err
+	}
+	id := len(db.offsets)
+	db.offsets = append(db.offsets, db.size)
+	db.size += int64(buf.Len())
+	db.addTags(id, r)
+	db.idx.addGame(id, g.Positions(), g.Moves())
+	db.dirty = true
+	return id, nil
+}
+
+func (db *DB) record(id int) (*record, error) {
+	if id < 0 || id >= len(db.offsets) {
+		return nil, fmt.Errorf("database: game id %d out of range", id)
+	}
+	end := db.size
+	if id+1 < len(db.offsets) {
+		end = db.offsets[id+1]
+	}
+	sr := io.NewSectionReader(db.f, db.offsets[id], end-db.offsets[id])
+	b, err := readRecord(bufio.NewReader(sr))
+	if err != nil {
+		return nil, err
+	}
+	r := &record{}
+	if err := r.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (db *DB) addTags(id int, r *record) {
+	db.outcomes = append(db.outcomes, r.outcome)
+	for _, tp := range r.tagPairs {
+		k, v := normalize(tp.Key), normalize(tp.Value)
+		if db.tags[k] == nil {
+			db.tags[k] = map[string][]int{}
+		}
+		ids := db.tags[k][v]
+		if len(ids) > 0 && ids[len(ids)-1] == id {
+			continue
+		}
+		db.tags[k][v] = append(ids, id)
+	}
+}
+
+// load reads the data file, indexes the tag pairs of every game and loads
+// the saved index or rebuilds it if needed.
+func (db *DB) load() error {
+	info, err := db.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		if _, err := db.f.WriteAt([]byte(dataMagic), 0); err != nil {
+			return err
+		}
+		db.size = int64(len(dataMagic))
+		return nil
+	}
+	br := bufio.NewReader(io.NewSectionReader(db.f, 0, info.Size()))
+	magic := make([]byte, len(dataMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != dataMagic {
+		return errors.New("database: invalid data file " + db.path)
+	}
+	db.size = int64(len(dataMagic))
+	records := []*record{}
+	for db.size < info.Size() {
+		b, err := readRecord(br)
+		if err != nil {
+			return fmt.Errorf("database: corrupt record at offset %d: %w", db.size, err)
+		}
+		r := &record{}
+		if err := r.UnmarshalBinary(b); err != nil {
+			return fmt.Errorf("database: corrupt record at offset %d: %w", db.size, err)
+		}
+		id := len(db.offsets)
+		db.offsets = append(db.offsets, db.size)
+		db.size += int64(uvarintLen(uint64(len(b))) + len(b))
+		db.addTags(id, r)
+		records = append(records, r)
+	}
+	if err := db.loadIndex(); err == nil {
+		return nil
+	}
+	// rebuild the index from the games
+	db.idx = newIndex()
+	for id, r := range records {
+		if err := db.idx.addRecord(id, r); err != nil {
+			return err
+		}
+	}
+	db.dirty = true
+	return nil
+}
+
+func (db *DB) indexPath() string {
+	return db.path + ".idx"
+}
+
+func (db *DB) loadIndex() error {
+	f, err := os.Open(db.indexPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != indexMagic {
+		return errors.New("database: invalid index file")
+	}
+	var header struct {
+		Games uint32
+		Size  int64
+	}
+	if err := binary.Read(br, binary.BigEndian, &header); err != nil {
+		return err
+	}
+	if int(header.Games) != len(db.offsets) || header.Size != db.size {
+		return errors.New("database: index file is out of date")
+	}
+	idx := newIndex()
+	if err := idx.read(br); err != nil {
+		return err
+	}
+	db.idx = idx
+	return nil
+}
+
+func (db *DB) saveIndex() error {
+	if !db.dirty {
+		return nil
+	}
+	f, err := os.Create(db.indexPath())
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	bw.WriteString(indexMagic)
+	header := struct {
+		Games uint32
+		Size  int64
+	}{uint32(len(db.offsets)), db.size}
+	if err := binary.Write(bw, binary.BigEndian, header); err != nil {
+		f.Close()
+		return err
+	}
+	if err := db.idx.write(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	db.dirty = false
+	return f.Close()
+}
+
+func uvarintLen(v uint64) int {
+	b := make([]byte, binary.MaxVarintLen64)
+	return binary.PutUvarint(b, v)
+}
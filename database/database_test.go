@@ -0,0 +1,292 @@
+package database_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/database"
+)
+
+func tempDB(t *testing.T) (*database.DB, string) {
+	dir, err := ioutil.TempDir("", "chessdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "games.db")
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, path
+}
+
+func importFixtures(t *testing.T, db *database.DB) []*chess.Game {
+	games := []*chess.Game{}
+	for _, fname := range []string{"../fixtures/pgns/0006.pgn", "../fixtures/pgns/0007.pgn"} {
+		f, err := os.Open(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := chess.NewScanner(f)
+		for scanner.Scan() {
+			if g := scanner.Next(); len(g.Moves()) > 0 {
+				games = append(games, g)
+			}
+		}
+		f.Close()
+		f, err = os.Open(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Import(chess.NewScanner(f)); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	return games
+}
+
+func mustGame(t *testing.T, moves ...string) *chess.Game {
+	g := chess.NewGame()
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestImport(t *testing.T) {
+	db, _ := tempDB(t)
+	defer db.Close()
+	games := importFixtures(t, db)
+	if db.Len() != len(games) {
+		t.Fatalf("expected %d games but got %d", len(games), db.Len())
+	}
+	for id, expected := range games {
+		g, err := db.Game(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Position().String() != expected.Position().String() {
+			t.Fatalf("game %d expected final position %s but got %s", id, expected.Position(), g.Position())
+		}
+		if g.Outcome() != expected.Outcome() {
+			t.Fatalf("game %d expected outcome %s but got %s", id, expected.Outcome(), g.Outcome())
+		}
+		if len(g.TagPairs()) != len(expected.TagPairs()) {
+			t.Fatalf("game %d expected %d tag pairs but got %d", id, len(expected.TagPairs()), len(g.TagPairs()))
+		}
+	}
+	ids := db.SearchTag("white", "giri,a")
+	if len(ids) != 1 || games[ids[0]].GetTagPair("White").Value != "Giri,A" {
+		t.Fatalf("expected to find Giri's game but got %v", ids)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db, _ := tempDB(t)
+	defer db.Close()
+	games := importFixtures(t, db)
+	ids := db.Search(database.Query{Event: "FIDE World Cup 2021", Outcome: chess.BlackWon})
+	for _, id := range ids {
+		g := games[id]
+		if g.GetTagPair("Event").Value != "FIDE World Cup 2021" || g.Outcome() != chess.BlackWon {
+			t.Fatalf("game %d doesn't match query", id)
+		}
+	}
+	if len(ids) == 0 {
+		t.Fatal("expected games matching the query")
+	}
+	ids = db.Search(database.Query{Player: "Vidit,S"})
+	if len(ids) != 1 {
+		t.Fatalf("expected one game with Vidit but got %d", len(ids))
+	}
+	ids = db.Search(database.Query{From: "2013.01.01", To: "2013.12.31"})
+	for _, id := range ids {
+		if games[id].GetTagPair("UTCDate") == nil {
+			t.Fatalf("game %d expected to be played in 2013", id)
+		}
+	}
+	if len(ids) == 0 {
+		t.Fatal("expected games played in 2013")
+	}
+}
+
+func TestSearchPosition(t *testing.T) {
+	db, _ := tempDB(t)
+	defer db.Close()
+	g1 := mustGame(t, "d4", "Nf6", "c4", "e6", "Nc3")
+	g1.Resign(chess.Black)
+	g2 := mustGame(t, "c4", "e6", "Nc3", "Nf6", "d4", "Bb4")
+	g2.Resign(chess.White)
+	g3 := mustGame(t, "e4", "e5")
+	for _, g := range []*chess.Game{g1, g2, g3} {
+		if _, err := db.Add(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hits := db.SearchPosition(g1.Position())
+	if len(hits) != 2 || hits[0].Ply != 5 || hits[1].Ply != 5 {
+		t.Fatalf("expected transposition in two games but got %v", hits)
+	}
+	stats := db.MoveStats(chess.StartingPosition())
+	if len(stats) != 3 {
+		t.Fatalf("expected stats for 3 moves but got %d", len(stats))
+	}
+	stats = db.MoveStats(g1.Position())
+	if len(stats) != 1 || stats[0].Move.String() != "f8b4" || stats[0].BlackWins != 1 {
+		t.Fatalf("expected Bb4 with one black win but got %+v", stats)
+	}
+	ids, err := db.SearchMaterial("kqrrbbnnppppppppvkqrrbbnnpppppppp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("expected all games to have full material but got %v", ids)
+	}
+	if _, err := db.SearchMaterial("KRPKR"); err == nil {
+		t.Fatal("expected invalid material signature error")
+	}
+}
+
+func TestSearchPositionVariants(t *testing.T) {
+	db, _ := tempDB(t)
+	defer db.Close()
+	g1 := mustGame(t, "e4", "e5")
+	g1.Resign(chess.Black)
+	g2 := chess.NewGame(chess.UseVariant(chess.Atomic{}))
+	for _, m := range []string{"e4", "e5"} {
+		if err := g2.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g2.Resign(chess.White)
+	for _, g := range []*chess.Game{g1, g2} {
+		if _, err := db.Add(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, g := range []*chess.Game{g1, g2} {
+		hits := db.SearchPosition(g.Position())
+		if len(hits) != 1 || hits[0].Game != i {
+			t.Fatalf("expected only the %s game but got %v", g.Variant().Name(), hits)
+		}
+	}
+	stats := db.MoveStats(chess.StartingPosition())
+	if len(stats) != 1 || stats[0].WhiteWins != 1 || stats[0].BlackWins != 0 {
+		t.Fatalf("expected e4 with the standard game's win only but got %+v", stats)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	db, path := tempDB(t)
+	g := mustGame(t, "e4", "c5", "Nf3")
+	if _, err := db.Add(g); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".idx"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		db, err := database.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.Len() != 1 {
+			t.Fatalf("expected 1 game but got %d", db.Len())
+		}
+		if hits := db.SearchPosition(g.Position()); len(hits) != 1 || hits[0].Ply != 3 {
+			t.Fatalf("expected position at ply 3 but got %v", hits)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// rebuild the index on the second open
+		if err := os.Remove(path + ".idx"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	db, _ := tempDB(t)
+	defer db.Close()
+	pgn, err := chess.PGN(strings.NewReader("1. e4 e5 $2 { weak } ( 1... c5 2. Nf3 ) ( 1... e6 ) 2. Nf3 $1 *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := db.Add(chess.NewGame(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := db.Game(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nags := g.NAGs(); len(nags[1]) != 1 || nags[1][0] != 2 || len(nags[2]) != 1 || nags[2][0] != 1 {
+		t.Fatalf("expected nags $2 and $1 but got %v", nags)
+	}
+	if comments := g.Comments(); len(comments[1]) != 1 || comments[1][0] != "weak" {
+		t.Fatalf("expected comment weak but got %v", comments)
+	}
+	vs := g.Variations()[1]
+	if len(vs) != 2 || len(vs[0]) != 2 || vs[0][1].String() != "g1f3" || vs[1][0].String() != "e7e6" {
+		t.Fatalf("expected variations 1... c5 2. Nf3 and 1... e6 but got %v", vs)
+	}
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	db, path := tempDB(t)
+	games := []*chess.Game{}
+	// both knight and bishop drops to d3 are valid in the first position
+	for _, moves := range [][]string{{"N@d3", "N@c4", "B@b5"}, {"B@d3", "N@c4", "N@e5"}} {
+		opt, err := chess.VariantFEN(chess.Crazyhouse{}, "4k3/8/8/8/8/8/8/4K3[NBn] w - - 0 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := chess.NewGame(opt)
+		for _, m := range moves {
+			if err := g.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := db.Add(g); err != nil {
+			t.Fatal(err)
+		}
+		games = append(games, g)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for id, g := range games {
+		cp, err := db.Game(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp.Variant() != (chess.Crazyhouse{}) || len(cp.Moves()) != 3 {
+			t.Fatalf("expected a crazyhouse game with 3 moves but got %s with %v", cp.Variant().Name(), cp.Moves())
+		}
+		for i, m := range g.Moves() {
+			if cp.Moves()[i].String() != m.String() {
+				t.Fatalf("expected move %s but got %s", m, cp.Moves()[i])
+			}
+		}
+		if cp.Position().String() != g.Position().String() {
+			t.Fatalf("expected position %s but got %s", g.Position(), cp.Position())
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/movecode"
+)
+
+// entry is the first occurrence of a position in a game along with
+// the move played from it (noMove if the game ended there).
+type entry struct {
+	Game uint32
+	Ply  uint16
+	Next uint16
+}
+
+type index struct {
+	positions map[uint64][]entry
+	material  map[string][]int
+}
+
+func newIndex() *index {
+	return &index{
+		positions: map[uint64][]entry{},
+		material:  map[string][]int{},
+	}
+}
+
+func (idx *index) addGame(id int, positions []*chess.Position, moves []*chess.Move) {
+	seen := map[uint64]bool{}
+	sigs := map[string]bool{}
+	for ply, pos := range positions {
+		next := noMove
+		if ply < len(moves) {
+			next = movecode.Encode(moves[ply])
+		}
+		key := pos.ZobristHash()
+		if !seen[key] {
+			seen[key] = true
+			idx.positions[key] = append(idx.positions[key], entry{Game: uint32(id), Ply: uint16(ply), Next: next})
+		}
+		sig := MaterialSignature(pos.Board())
+		if !sigs[sig] {
+			sigs[sig] = true
+			idx.material[sig] = append(idx.material[sig], id)
+		}
+	}
+}
+
+func (idx *index) addRecord(id int, r *record) error {
+	pos, err := r.startingPosition()
+	if err != nil {
+		return err
+	}
+	positions := []*chess.Position{pos}
+	moves := []*chess.Move{}
+	for _, code := range r.moves {
+		m, err := findMove(pos, code)
+		if err != nil {
+			return err
+		}
+		pos = pos.Update(m)
+		moves = append(moves, m)
+		positions = append(positions, pos)
+	}
+	idx.addGame(id, positions, moves)
+	return nil
+}
+
+func (idx *index) write(w io.Writer) error {
+	keys := make([]uint64, 0, len(idx.positions))
+	for k := range idx.positions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	if err := binary.Write(w, binary.BigEndian, uint32(len(keys))); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		entries := idx.positions[k]
+		if err := binary.Write(w, binary.BigEndian, k); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.BigEndian, uint32(len(entries))); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.BigEndian, entries); err != nil {
+			return err
+		}
+	}
+	sigs := make([]string, 0, len(idx.material))
+	for sig := range idx.material {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	if err := binary.Write(w, binary.BigEndian, uint32(len(sigs))); err != nil {
+		return err
+	}
+	for _, sig := range sigs {
+		ids := idx.material[sig]
+		if err := binary.Write(w, binary.BigEndian, uint8(len(sig))); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sig); err != nil {
+			return err
+		}
+		games := make([]uint32, len(ids))
+		for i, id := range ids {
+			games[i] = uint32(id)
+		}
+		if err := binary.Write(w, binary.BigEndian, uint32(len(games))); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.BigEndian, games); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *index) read(r *bufio.Reader) error {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		var k uint64
+		var count uint32
+		if err := binary.Read(r, binary.BigEndian, &k); err != nil {
+			return err
+		}
+		if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+			return err
+		}
+		entries := make([]entry, count)
+		if err := binary.Read(r, binary.BigEndian, entries); err != nil {
+			return err
+		}
+		idx.positions[k] = entries
+	}
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		var l uint8
+		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+			return err
+		}
+		sig := make([]byte, l)
+		if _, err := io.ReadFull(r, sig); err != nil {
+			return err
+		}
+		var count uint32
+		if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+			return err
+		}
+		games := make([]uint32, count)
+		if err := binary.Read(r, binary.BigEndian, games); err != nil {
+			return err
+		}
+		ids := make([]int, count)
+		for i, g := range games {
+			ids[i] = int(g)
+		}
+		idx.material[string(sig)] = ids
+	}
+	return nil
+}
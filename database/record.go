@@ -0,0 +1,327 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/movecode"
+)
+
+// record is the stored form of a game.  Moves are kept as 16 bit codes
+// (origin, destination and promotion, or the dropped piece and its
+// square) instead of notation text which keeps records small and
+// independent of move generation order.
+type record struct {
+	outcome    chess.Outcome
+	tagPairs   []*chess.TagPair
+	moves      []uint16
+	comments   [][]string
+	nags       [][]int
+	variations [][][]uint16
+}
+
+const noMove uint16 = 0xFFFF
+
+var outcomes = []chess.Outcome{chess.NoOutcome, chess.WhiteWon, chess.BlackWon, chess.Draw}
+
+// findMove returns the valid move in the position matching the code.
+func findMove(pos *chess.Position, code uint16) (*chess.Move, error) {
+	if m := movecode.Find(pos, code); m != nil {
+		return m, nil
+	}
+	return nil, fmt.Errorf("database: move %s is invalid in position %s", movecode.String(code), pos)
+}
+
+func newRecord(g *chess.Game) *record {
+	r := &record{
+		outcome:  g.Outcome(),
+		tagPairs: g.TagPairs(),
+		comments: g.Comments(),
+		nags:     g.NAGs(),
+	}
+	if v := g.Variant(); v.Name() != (chess.Standard{}).Name() && tagValue(r.tagPairs, "Variant") == "" {
+		r.tagPairs = append(r.tagPairs, &chess.TagPair{Key: "Variant", Value: v.Name()})
+	}
+	start := g.Positions()[0]
+	if start.String() != chess.StartingPosition().String() && tagValue(r.tagPairs, "FEN") == "" {
+		r.tagPairs = append(r.tagPairs,
+			&chess.TagPair{Key: "SetUp", Value: "1"},
+			&chess.TagPair{Key: "FEN", Value: start.String()},
+		)
+	}
+	for _, m := range g.Moves() {
+		r.moves = append(r.moves, movecode.Encode(m))
+	}
+	for _, vs := range g.Variations() {
+		codes := [][]uint16{}
+		for _, v := range vs {
+			variation := []uint16{}
+			for _, m := range v {
+				variation = append(variation, movecode.Encode(m))
+			}
+			codes = append(codes, variation)
+		}
+		r.variations = append(r.variations, codes)
+	}
+	return r
+}
+
+func (r *record) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	outcome := byte(0)
+	for i, o := range outcomes {
+		if o == r.outcome {
+			outcome = byte(i)
+		}
+	}
+	buf.WriteByte(outcome)
+	writeUvarint(buf, uint64(len(r.tagPairs)))
+	for _, tp := range r.tagPairs {
+		writeString(buf, tp.Key)
+		writeString(buf, tp.Value)
+	}
+	writeUvarint(buf, uint64(len(r.moves)))
+	for i, m := range r.moves {
+		if err := binary.Write(buf, binary.BigEndian, m); err != nil {
+			return nil, err
+		}
+		var comments []string
+		if i < len(r.comments) {
+			comments = r.comments[i]
+		}
+		writeUvarint(buf, uint64(len(comments)))
+		for _, c := range comments {
+			writeString(buf, c)
+		}
+		var nags []int
+		if i < len(r.nags) {
+			nags = r.nags[i]
+		}
+		writeUvarint(buf, uint64(len(nags)))
+		for _, nag := range nags {
+			writeUvarint(buf, uint64(nag))
+		}
+		var variations [][]uint16
+		if i < len(r.variations) {
+			variations = r.variations[i]
+		}
+		writeUvarint(buf, uint64(len(variations)))
+		for _, v := range variations {
+			writeUvarint(buf, uint64(len(v)))
+			if err := binary.Write(buf, binary.BigEndian, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *record) UnmarshalBinary(data []byte) error {
+	buf := bytes.NewReader(data)
+	outcome, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	if int(outcome) >= len(outcomes) {
+		return errors.New("database: invalid outcome in record")
+	}
+	r.outcome = outcomes[outcome]
+	n, err := binary.ReadUvarint(buf)
+	if err != nil {
+		return err
+	}
+	r.tagPairs = make([]*chess.TagPair, 0, n)
+	for i := uint64(0); i < n; i++ {
+		k, err := readString(buf)
+		if err != nil {
+			return err
+		}
+		v, err := readString(buf)
+		if err != nil {
+			return err
+		}
+		r.tagPairs = append(r.tagPairs, &chess.TagPair{Key: k, Value: v})
+	}
+	n, err = binary.ReadUvarint(buf)
+	if err != nil {
+		return err
+	}
+	r.moves = make([]uint16, n)
+	r.comments = make([][]string, n)
+	r.nags = make([][]int, n)
+	r.variations = make([][][]uint16, n)
+	for i := range r.moves {
+		if err := binary.Read(buf, binary.BigEndian, &r.moves[i]); err != nil {
+			return err
+		}
+		c, err := binary.ReadUvarint(buf)
+		if err != nil {
+			return err
+		}
+		r.comments[i] = []string{}
+		for j := uint64(0); j < c; j++ {
+			s, err := readString(buf)
+			if err != nil {
+				return err
+			}
+			r.comments[i] = append(r.comments[i], s)
+		}
+		c, err = binary.ReadUvarint(buf)
+		if err != nil {
+			return err
+		}
+		r.nags[i] = []int{}
+		for j := uint64(0); j < c; j++ {
+			nag, err := binary.ReadUvarint(buf)
+			if err != nil {
+				return err
+			}
+			r.nags[i] = append(r.nags[i], int(nag))
+		}
+		c, err = binary.ReadUvarint(buf)
+		if err != nil {
+			return err
+		}
+		r.variations[i] = [][]uint16{}
+		for j := uint64(0); j < c; j++ {
+			l, err := binary.ReadUvarint(buf)
+			if err != nil {
+				return err
+			}
+			if l > uint64(buf.Len()) {
+				return io.ErrUnexpectedEOF
+			}
+			v := make([]uint16, l)
+			if err := binary.Read(buf, binary.BigEndian, v); err != nil {
+				return err
+			}
+			r.variations[i] = append(r.variations[i], v)
+		}
+	}
+	return nil
+}
+
+// startingPosition returns the position the recorded game starts from.
+func (r *record) startingPosition() (*chess.Position, error) {
+	v := variant(r.tagPairs)
+	fen := tagValue(r.tagPairs, "FEN")
+	if fen == "" {
+		return chess.NewGame(chess.UseVariant(v)).Position(), nil
+	}
+	opt, err := chess.VariantFEN(v, fen)
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(opt).Position(), nil
+}
+
+// game rebuilds the game by decoding its PGN representation so
+// that the outcome, method, comments, glyphs and variations are restored.
+func (r *record) game() (*chess.Game, error) {
+	pos, err := r.startingPosition()
+	if err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	for _, tp := range r.tagPairs {
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", tp.Key, tp.Value)
+	}
+	sb.WriteString("\n")
+	for i, code := range r.moves {
+		if i%2 == 0 {
+			fmt.Fprintf(&sb, "%d. ", i/2+1)
+		}
+		m, err := findMove(pos, code)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(chess.UCINotation{}.Encode(pos, m) + " ")
+		for _, nag := range r.nags[i] {
+			fmt.Fprintf(&sb, "$%d ", nag)
+		}
+		for _, c := range r.comments[i] {
+			sb.WriteString("{ " + c + " } ")
+		}
+		for _, v := range r.variations[i] {
+			sb.WriteString("( ")
+			vpos := pos
+			for _, code := range v {
+				m, err := findMove(vpos, code)
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteString(chess.UCINotation{}.Encode(vpos, m) + " ")
+				vpos = vpos.Update(m)
+			}
+			sb.WriteString(") ")
+		}
+		pos = pos.Update(m)
+	}
+	sb.WriteString(r.outcome.String())
+	g := chess.NewGame()
+	if err := g.UnmarshalText([]byte(sb.String())); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func tagValue(tagPairs []*chess.TagPair, key string) string {
+	for _, tp := range tagPairs {
+		if strings.EqualFold(tp.Key, key) {
+			return tp.Value
+		}
+	}
+	return ""
+}
+
+func variant(tagPairs []*chess.TagPair) chess.Variant {
+	if v, ok := chess.VariantFromName(tagValue(tagPairs, "Variant")); ok {
+		return v
+	}
+	return chess.Standard{}
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, v)
+	buf.Write(b[:n])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readRecord reads a length prefixed record from the reader.
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,272 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// A Hit is a game in which a searched position occurred.  Ply is the
+// number of half moves played before the position was first reached.
+type Hit struct {
+	Game int
+	Ply  int
+}
+
+// SearchPosition returns the games that reach the position, independent
+// of the move order used to get there.
+func (db *DB) SearchPosition(pos *chess.Position) []Hit {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	entries := db.idx.positions[pos.ZobristHash()]
+	hits := make([]Hit, len(entries))
+	for i, e := range entries {
+		hits[i] = Hit{Game: int(e.Game), Ply: int(e.Ply)}
+	}
+	return hits
+}
+
+// SearchMaterial returns the ids of games that reach a position with the
+// given material signature such as "KRPvKR".  The signature lists white's
+// pieces, a "v" and black's pieces.  The order of the pieces on each side
+// doesn't matter.
+func (db *DB) SearchMaterial(signature string) ([]int, error) {
+	sig, err := normalizeSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return append([]int(nil), db.idx.material[sig]...), nil
+}
+
+// SearchTag returns the ids of games with a tag pair matching the key and
+// value.  Both are compared case insensitively.
+func (db *DB) SearchTag(key, value string) []int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return append([]int(nil), db.tags[normalize(key)][normalize(value)]...)
+}
+
+// Query holds the criteria of a Search.  Empty fields are ignored.  Names are
+// compared case insensitively.  Dates use the PGN format (e.g. 2013.01.31)
+// and are inclusive, unknown parts of a game's date ("??") compare as zero.
+type Query struct {
+	// White matches the White tag.
+	White string
+	// Black matches the Black tag.
+	Black string
+	// Player matches either the White or Black tag.
+	Player string
+	// Event matches the Event tag.
+	Event string
+	// Site matches the Site tag.
+	Site string
+	// From is the earliest game date.
+	From string
+	// To is the latest game date.
+	To string
+	// Outcome matches the game's outcome.
+	Outcome chess.Outcome
+}
+
+// Search returns the ids of the games matching all the criteria of the query
+// in ascending order.
+func (db *DB) Search(q Query) []int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	var sets [][]int
+	for _, kv := range [][2]string{{"white", q.White}, {"black", q.Black}, {"event", q.Event}, {"site", q.Site}} {
+		if kv[1] != "" {
+			sets = append(sets, db.tags[kv[0]][normalize(kv[1])])
+		}
+	}
+	if q.Player != "" {
+		p := normalize(q.Player)
+		sets = append(sets, union(db.tags["white"][p], db.tags["black"][p]))
+	}
+	var ids []int
+	if len(sets) == 0 {
+		ids = make([]int, len(db.offsets))
+		for i := range ids {
+			ids[i] = i
+		}
+	} else {
+		ids = sets[0]
+		for _, s := range sets[1:] {
+			ids = intersect(ids, s)
+		}
+	}
+	if q.From == "" && q.To == "" && q.Outcome == "" {
+		return append([]int(nil), ids...)
+	}
+	dates := map[int]string{}
+	if q.From != "" || q.To != "" {
+		for _, key := range []string{"utcdate", "date"} {
+			for d, games := range db.tags[key] {
+				for _, id := range games {
+					if _, ok := dates[id]; !ok {
+						dates[id] = strings.Replace(d, "?", "0", -1)
+					}
+				}
+			}
+		}
+	}
+	results := []int{}
+	for _, id := range ids {
+		if q.Outcome != "" && db.outcomes[id] != q.Outcome {
+			continue
+		}
+		if q.From != "" || q.To != "" {
+			d, ok := dates[id]
+			if !ok || (q.From != "" && d < q.From) || (q.To != "" && d > q.To) {
+				continue
+			}
+		}
+		results = append(results, id)
+	}
+	return results
+}
+
+// MoveStats holds the aggregated results of the games in which a move
+// was played from a position.
+type MoveStats struct {
+	Move      *chess.Move
+	Games     int
+	WhiteWins int
+	Draws     int
+	BlackWins int
+}
+
+// MoveStats returns statistics for every move played from the position
+// ordered by the number of games, most played first.
+func (db *DB) MoveStats(pos *chess.Position) []*MoveStats {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	byMove := map[uint16]*MoveStats{}
+	for _, e := range db.idx.positions[pos.ZobristHash()] {
+		if e.Next == noMove {
+			continue
+		}
+		s, ok := byMove[e.Next]
+		if !ok {
+			m, err := findMove(pos, e.Next)
+			if err != nil {
+				continue
+			}
+			s = &MoveStats{Move: m}
+			byMove[e.Next] = s
+		}
+		s.Games++
+		switch db.outcomes[e.Game] {
+		case chess.WhiteWon:
+			s.WhiteWins++
+		case chess.BlackWon:
+			s.BlackWins++
+		case chess.Draw:
+			s.Draws++
+		}
+	}
+	stats := make([]*MoveStats, 0, len(byMove))
+	for _, s := range byMove {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Games != stats[j].Games {
+			return stats[i].Games > stats[j].Games
+		}
+		return stats[i].Move.String() < stats[j].Move.String()
+	})
+	return stats
+}
+
+// MaterialSignature returns the material signature of the board such as
+// "KRPvKR".  Pieces are listed in the order king, queen, rook, bishop,
+// knight and pawn with white's pieces first.
+func MaterialSignature(b *chess.Board) string {
+	counts := map[chess.Piece]int{}
+	for _, p := range b.SquareMap() {
+		counts[p]++
+	}
+	return signature(func(c chess.Color, pt chess.PieceType) int {
+		return counts[chess.NewPiece(pt, c)]
+	})
+}
+
+func signature(count func(chess.Color, chess.PieceType) int) string {
+	var sb strings.Builder
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		if c == chess.Black {
+			sb.WriteString("v")
+		}
+		for _, pt := range chess.PieceTypes() {
+			sb.WriteString(strings.Repeat(strings.ToUpper(pt.String()), count(c, pt)))
+		}
+	}
+	return sb.String()
+}
+
+func normalizeSignature(s string) (string, error) {
+	sides := strings.Split(strings.ToUpper(strings.TrimSpace(s)), "V")
+	if len(sides) != 2 {
+		return "", errors.New("database: invalid material signature " + s)
+	}
+	counts := map[chess.Color]map[chess.PieceType]int{chess.White: {}, chess.Black: {}}
+	for i, c := range []chess.Color{chess.White, chess.Black} {
+		for _, r := range sides[i] {
+			pt := pieceTypeFromRune(r)
+			if pt == chess.NoPieceType {
+				return "", errors.New("database: invalid material signature " + s)
+			}
+			counts[c][pt]++
+		}
+	}
+	return signature(func(c chess.Color, pt chess.PieceType) int {
+		return counts[c][pt]
+	}), nil
+}
+
+func pieceTypeFromRune(r rune) chess.PieceType {
+	for _, pt := range chess.PieceTypes() {
+		if strings.ToUpper(pt.String()) == string(r) {
+			return pt
+		}
+	}
+	return chess.NoPieceType
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func union(a, b []int) []int {
+	m := map[int]bool{}
+	for _, id := range a {
+		m[id] = true
+	}
+	for _, id := range b {
+		m[id] = true
+	}
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func intersect(a, b []int) []int {
+	m := map[int]bool{}
+	for _, id := range b {
+		m[id] = true
+	}
+	ids := []int{}
+	for _, id := range a {
+		if m[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
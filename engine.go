@@ -6,17 +6,23 @@ type engine struct{}
 
 func (engine) CalcMoves(pos *Position, first bool) []*Move {
 	// generate possible moves
-	moves := standardMoves(pos, first)
+	moves := standardMoves(pos, first, addTags)
 	// return moves including castles
 	return append(moves, castleMoves(pos)...)
 }
 
 func (engine) Status(pos *Position) Method {
 	hasMove := false
-	if pos.validMoves != nil {
-		hasMove = len(pos.validMoves) > 0
-	} else {
-		hasMove = len(engine{}.CalcMoves(pos, true)) > 0
+	switch pos.rules().(type) {
+	case Standard, Chess960:
+		if pos.validMoves != nil {
+			hasMove = len(pos.validMoves) > 0
+		} else {
+			hasMove = len(engine{}.CalcMoves(pos, true)) > 0
+		}
+	default:
+		// custom variants may restrict the moves of the standard rules
+		hasMove = len(pos.ValidMoves()) > 0
 	}
 	if !pos.inCheck && !hasMove {
 		return Stalemate
@@ -30,7 +36,10 @@ var (
 	promoPieceTypes = []PieceType{Queen, Rook, Bishop, Knight}
 )
 
-func standardMoves(pos *Position, first bool) []*Move {
+// standardMoves generates the moves of the pieces on the board.  The tag
+// function adds the move's tags and marks moves leaving the king in check
+// with the inCheck tag which filters them out.
+func standardMoves(pos *Position, first bool, tag func(*Move, *Position)) []*Move {
 	// compute allowed destination bitboard
 	bbAllowed := ^pos.board.whiteSqs
 	if pos.Turn() == Black {
@@ -64,7 +73,7 @@ func standardMoves(pos *Position, first bool) []*Move {
 				if (p == WhitePawn && Square(s2).Rank() == Rank8) || (p == BlackPawn && Square(s2).Rank() == Rank1) {
 					for _, pt := range promoPieceTypes {
 						m := &Move{s1: Square(s1), s2: Square(s2), promo: pt}
-						addTags(m, pos)
+						tag(m, pos)
 						// filter out moves that put king into check
 						if !m.HasTag(inCheck) {
 							moves = append(moves, m)
@@ -75,7 +84,7 @@ func standardMoves(pos *Position, first bool) []*Move {
 					}
 				} else {
 					m := &Move{s1: Square(s1), s2: Square(s2)}
-					addTags(m, pos)
+					tag(m, pos)
 					// filter out moves that put king into check
 					if !m.HasTag(inCheck) {
 						moves = append(moves, m)
@@ -91,10 +100,10 @@ func standardMoves(pos *Position, first bool) []*Move {
 }
 
 func addTags(m *Move, pos *Position) {
-	p := pos.board.Piece(m.s1)
-	if pos.board.isOccupied(m.s2) {
+	// chess960 castling moves the king onto its own rook
+	if pos.board.isOccupied(m.s2) && !m.HasTag(NineSixtyCastle) {
 		m.addTag(Capture)
-	} else if m.s2 == pos.enPassantSquare && p.Type() == Pawn {
+	} else if m.drop == NoPiece && m.s2 == pos.enPassantSquare && pos.board.Piece(m.s1).Type() == Pawn {
 		m.addTag(EnPassant)
 	}
 	// determine if in check after move (makes move invalid)
@@ -124,60 +133,8 @@ func isInCheck(pos *Position) bool {
 
 func squaresAreAttacked(pos *Position, sqs ...Square) bool {
 	otherColor := pos.Turn().Other()
-	occ := ^pos.board.emptySqs
 	for _, sq := range sqs {
-		// hot path check to see if attack vector is possible
-		s2BB := pos.board.blackSqs
-		if pos.Turn() == Black {
-			s2BB = pos.board.whiteSqs
-		}
-		if ((diaAttack(occ, sq)|hvAttack(occ, sq))&s2BB)|(bbKnightMoves[sq]&s2BB) == 0 {
-			continue
-		}
-		// check queen attack vector
-		queenBB := pos.board.bbForPiece(NewPiece(Queen, otherColor))
-		bb := (diaAttack(occ, sq) | hvAttack(occ, sq)) & queenBB
-		if bb != 0 {
-			return true
-		}
-		// check rook attack vector
-		rookBB := pos.board.bbForPiece(NewPiece(Rook, otherColor))
-		bb = hvAttack(occ, sq) & rookBB
-		if bb != 0 {
-			return true
-		}
-		// check bishop attack vector
-		bishopBB := pos.board.bbForPiece(NewPiece(Bishop, otherColor))
-		bb = diaAttack(occ, sq) & bishopBB
-		if bb != 0 {
-			return true
-		}
-		// check knight attack vector
-		knightBB := pos.board.bbForPiece(NewPiece(Knight, otherColor))
-		bb = bbKnightMoves[sq] & knightBB
-		if bb != 0 {
-			return true
-		}
-		// check pawn attack vector
-		if pos.Turn() == White {
-			capRight := (pos.board.bbBlackPawn & ^bbFileH & ^bbRank1) << 7
-			capLeft := (pos.board.bbBlackPawn & ^bbFileA & ^bbRank1) << 9
-			bb = (capRight | capLeft) & bbForSquare(sq)
-			if bb != 0 {
-				return true
-			}
-		} else {
-			capRight := (pos.board.bbWhitePawn & ^bbFileH & ^bbRank8) >> 9
-			capLeft := (pos.board.bbWhitePawn & ^bbFileA & ^bbRank8) >> 7
-			bb = (capRight | capLeft) & bbForSquare(sq)
-			if bb != 0 {
-				return true
-			}
-		}
-		// check king attack vector
-		kingBB := pos.board.bbForPiece(NewPiece(King, otherColor))
-		bb = bbKingMoves[sq] & kingBB
-		if bb != 0 {
+		if pos.board.attackersBB(sq, otherColor) != 0 {
 			return true
 		}
 	}
@@ -218,8 +175,8 @@ func castleMoves(pos *Position) []*Move {
 	var krFile, qrFile string
 	if pos.castleRights.nineSixtyMode {
 		// one or both of these could be "", but in that case they are not used in below code
-		krFile = strings.ToLower(pos.castleRights.hSideRookStartingFile)
-		qrFile = strings.ToLower(pos.castleRights.aSideRookStartingFile)
+		krFile = strings.ToLower(pos.castleRights.hSideRookStartingFile[pos.turn])
+		qrFile = strings.ToLower(pos.castleRights.aSideRookStartingFile[pos.turn])
 	} else {
 		krFile = "h"
 		qrFile = "a"
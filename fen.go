@@ -7,12 +7,53 @@ import (
 	"unicode"
 )
 
+// decodeVariantFEN decodes FEN notation into a position played under the
+// variant's rules.
+func decodeVariantFEN(fen string, v Variant) (*Position, error) {
+	var pk *pockets
+	var promoted bitboard
+	var checks *checkCount
+	if v.checkLimit() > 0 {
+		var err error
+		if fen, checks, err = decodeChecksFEN(fen, v.checkLimit()); err != nil {
+			return nil, err
+		}
+	}
+	if v.drops() {
+		var err error
+		if fen, pk, promoted, err = decodePocketFEN(fen); err != nil {
+			return nil, err
+		}
+	}
+	pos, err := parseFEN(fen, v.nineSixty())
+	if err != nil {
+		return nil, err
+	}
+	pos.variant = v
+	pos.pockets = pk
+	pos.promoted = promoted
+	pos.checks = checks
+	if err := v.validate(pos); err != nil {
+		return nil, fmt.Errorf("chess: fen illegal %s , %s", strings.TrimSpace(fen), err)
+	}
+	return pos, nil
+}
+
 // Decodes FEN notation into a GameState.  An error is returned
 // if there is a parsing error or if the FEN is illegal. FEN
 // notation format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
 // for 960 mode it can decode both shredder-fen and x-fen wuthout knowing
 // which one it is
 func decodeFEN(fen string, isNineSixty bool) (*Position, error) {
+	if isNineSixty {
+		return decodeVariantFEN(fen, Chess960{})
+	}
+	return decodeVariantFEN(fen, Standard{})
+}
+
+// parseFEN decodes the six FEN sections without checking the position
+// is legal.
+func parseFEN(fen string, isNineSixty bool) (*Position, error) {
 	fen = strings.TrimSpace(fen)
 	parts := strings.Split(fen, " ")
 	if len(parts) != 6 {
@@ -42,32 +83,17 @@ func decodeFEN(fen string, isNineSixty bool) (*Position, error) {
 	if err != nil || moveCount < 1 {
 		return nil, fmt.Errorf("chess: fen invalid move count %s", parts[5])
 	}
-
-	pos := &Position{
+	return &Position{
 		board:           b,
 		turn:            turn,
 		castleRights:    rights,
 		enPassantSquare: sq,
 		halfMoveClock:   halfMoveClock,
 		moveCount:       moveCount,
-	}
-
-	// Make sure the player in next turn cannot capture opponent's king.
-	cp := pos.copy()
-	cp.turn = cp.turn.Other()
-	if isInCheck(cp) {
-		return nil, fmt.Errorf("chess: fen illegal %s , king can be captured in next move", fen)
-	}
-
-	return pos, nil
+	}, nil
 }
 
-// generates board from fen format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR
 func fenBoard(boardStr string) (*Board, error) {
-	// make sure boardStr contains only one K and one k.
-	if strings.Count(boardStr, "K") != 1 || strings.Count(boardStr, "k") != 1 {
-		return nil, fmt.Errorf("chess: fen illegal board %s , both black and white should have one king each", boardStr)
-	}
 	rankStrs := strings.Split(boardStr, "/")
 	if len(rankStrs) != 8 {
 		return nil, fmt.Errorf("chess: fen invalid board %s", boardStr)
@@ -75,9 +101,6 @@ func fenBoard(boardStr string) (*Board, error) {
 	m := map[Square]Piece{}
 	for i, rankStr := range rankStrs {
 		rank := Rank(7 - i)
-		if (rank == Rank1 || rank == Rank8) && (strings.ContainsRune(rankStr, 'p') || strings.ContainsRune(rankStr, 'P')) {
-			return nil, fmt.Errorf("chess: fen illegal board %s , pawns cannot be on first or last rank", boardStr)
-		}
 		fileMap, err := fenFormRank(rankStr)
 		if err != nil {
 			return nil, err
@@ -115,13 +138,11 @@ func fenFormRank(rankStr string) (map[File]Piece, error) {
 
 func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*CastleRights, error) {
 	cr := &CastleRights{
-		nineSixtyMode:         isNineSixty,
-		aSideRookStartingFile: "",
-		hSideRookStartingFile: "",
-		whiteKingSideCastle:   false,
-		whiteQueenSideCastle:  false,
-		blackKingSideCastle:   false,
-		blackQueenSideCastle:  false,
+		nineSixtyMode:        isNineSixty,
+		whiteKingSideCastle:  false,
+		whiteQueenSideCastle: false,
+		blackKingSideCastle:  false,
+		blackQueenSideCastle: false,
 	}
 	if castleStr == "-" {
 		return cr, nil
@@ -162,7 +183,6 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 		}
 		return cr, nil
 	} else { // 960 mode, handles both Shredder-FEN and X-FEN
-		kingStartingFile := ""
 		for _, c := range castleStr {
 			if unicode.IsUpper(c) { // white castle
 				if board.whiteKingSq.Rank() != Rank1 {
@@ -171,20 +191,13 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 				if board.whiteKingSq.File() == FileA || board.whiteKingSq.File() == FileH {
 					return cr, fmt.Errorf("chess: fen illegal castle rights %s , white king cant be on file A or H for castle in 960", castleStr)
 				}
-				if kingStartingFile != "" && kingStartingFile != board.whiteKingSq.File().String() {
-					return cr, fmt.Errorf("chess: fen illegal castle rights %s , white and black kings must be on same file for both to have castle rights", castleStr)
-				}
-				kingStartingFile = board.whiteKingSq.File().String()
 				if c == 'K' {
 					if cr.whiteKingSideCastle {
 						return cr, fmt.Errorf("chess: fen invalid castle rights %s , white king side castle info provided more than once", castleStr)
 					}
 					for sq := H1; sq > board.whiteKingSq; sq-- {
 						if board.Piece(sq) == WhiteRook {
-							if cr.hSideRookStartingFile != "" && cr.hSideRookStartingFile != sq.File().String() {
-								return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting king side file missmatch", castleStr)
-							}
-							cr.hSideRookStartingFile = sq.File().String()
+							cr.hSideRookStartingFile[White] = sq.File().String()
 							cr.whiteKingSideCastle = true
 							break
 						}
@@ -198,10 +211,7 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 					}
 					for sq := A1; sq < board.whiteKingSq; sq++ {
 						if board.Piece(sq) == WhiteRook {
-							if cr.aSideRookStartingFile != "" && cr.aSideRookStartingFile != sq.File().String() {
-								return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting queen side file missmatch", castleStr)
-							}
-							cr.aSideRookStartingFile = sq.File().String()
+							cr.aSideRookStartingFile[White] = sq.File().String()
 							cr.whiteQueenSideCastle = true
 							break
 						}
@@ -217,10 +227,7 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 						if board.Piece(NewSquare(runeToFileMap[c], Rank1)) != WhiteRook {
 							return cr, fmt.Errorf("chess: fen invalid castle rights %s , no white kingside rook found", castleStr)
 						}
-						if cr.hSideRookStartingFile != "" && cr.hSideRookStartingFile != runeToFileMap[c].String() {
-							return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting king side file missmatch", castleStr)
-						}
-						cr.hSideRookStartingFile = runeToFileMap[c].String()
+						cr.hSideRookStartingFile[White] = runeToFileMap[c].String()
 						cr.whiteKingSideCastle = true
 					} else if runeToFileMap[c] < board.whiteKingSq.File() { // queen side
 						if cr.whiteQueenSideCastle {
@@ -229,10 +236,7 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 						if board.Piece(NewSquare(runeToFileMap[c], Rank1)) != WhiteRook {
 							return cr, fmt.Errorf("chess: fen invalid castle rights %s , no white queenside rook found", castleStr)
 						}
-						if cr.aSideRookStartingFile != "" && cr.aSideRookStartingFile != runeToFileMap[c].String() {
-							return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting queen side file missmatch", castleStr)
-						}
-						cr.aSideRookStartingFile = runeToFileMap[c].String()
+						cr.aSideRookStartingFile[White] = runeToFileMap[c].String()
 						cr.whiteQueenSideCastle = true
 					} else {
 						return cr, fmt.Errorf("chess: fen illegal castle rights %s , rook can't be on king", castleStr)
@@ -247,20 +251,13 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 				if board.blackKingSq.File() == FileA || board.blackKingSq.File() == FileH {
 					return cr, fmt.Errorf("chess: fen illegal castle rights %s , black king cant be on file A or H for castle in 960", castleStr)
 				}
-				if kingStartingFile != "" && kingStartingFile != board.blackKingSq.File().String() {
-					return cr, fmt.Errorf("chess: fen illegal castle rights %s , white and black kings must be on same file for both to have castle rights", castleStr)
-				}
-				kingStartingFile = board.blackKingSq.File().String()
 				if c == 'k' {
 					if cr.blackKingSideCastle {
 						return cr, fmt.Errorf("chess: fen invalid castle rights %s , black king side castle info provided more than once", castleStr)
 					}
 					for sq := H8; sq > board.blackKingSq; sq-- {
 						if board.Piece(sq) == BlackRook {
-							if cr.hSideRookStartingFile != "" && cr.hSideRookStartingFile != sq.File().String() {
-								return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting king side file missmatch", castleStr)
-							}
-							cr.hSideRookStartingFile = sq.File().String()
+							cr.hSideRookStartingFile[Black] = sq.File().String()
 							cr.blackKingSideCastle = true
 							break
 						}
@@ -274,10 +271,7 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 					}
 					for sq := A8; sq < board.blackKingSq; sq++ {
 						if board.Piece(sq) == BlackRook {
-							if cr.aSideRookStartingFile != "" && cr.aSideRookStartingFile != sq.File().String() {
-								return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting queen side file missmatch", castleStr)
-							}
-							cr.aSideRookStartingFile = sq.File().String()
+							cr.aSideRookStartingFile[Black] = sq.File().String()
 							cr.blackQueenSideCastle = true
 							break
 						}
@@ -293,10 +287,7 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 						if board.Piece(NewSquare(runeToFileMap[c], Rank8)) != BlackRook {
 							return cr, fmt.Errorf("chess: fen invalid castle rights %s , no black kingside rook found", castleStr)
 						}
-						if cr.hSideRookStartingFile != "" && cr.hSideRookStartingFile != runeToFileMap[c].String() {
-							return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting king side file missmatch", castleStr)
-						}
-						cr.hSideRookStartingFile = runeToFileMap[c].String()
+						cr.hSideRookStartingFile[Black] = runeToFileMap[c].String()
 						cr.blackKingSideCastle = true
 					} else if runeToFileMap[c] < board.blackKingSq.File() { // queen side
 						if cr.blackQueenSideCastle {
@@ -305,10 +296,7 @@ func formCastleRights(castleStr string, isNineSixty bool, board *Board) (*Castle
 						if board.Piece(NewSquare(runeToFileMap[c], Rank8)) != BlackRook {
 							return cr, fmt.Errorf("chess: fen invalid castle rights %s , no black queenside rook found", castleStr)
 						}
-						if cr.aSideRookStartingFile != "" && cr.aSideRookStartingFile != runeToFileMap[c].String() {
-							return cr, fmt.Errorf("chess: fen invalid castle rights %s , rook starting queen side file missmatch", castleStr)
-						}
-						cr.aSideRookStartingFile = runeToFileMap[c].String()
+						cr.aSideRookStartingFile[Black] = runeToFileMap[c].String()
 						cr.blackQueenSideCastle = true
 					} else {
 						return cr, fmt.Errorf("chess: fen illegal castle rights %s , rook can't be on king", castleStr)
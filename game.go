@@ -55,6 +55,24 @@ const (
 	// InsufficientMaterial indicates that the game was automatically drawn
 	// because there was insufficient material for checkmate.
 	InsufficientMaterial
+	// ThirdCheck indicates that the game was won by giving a third
+	// check in Three-check.
+	ThirdCheck
+	// KingInCenter indicates that the game was won by moving the king
+	// to one of the four central squares in King of the Hill.
+	KingInCenter
+	// KingReachedGoal indicates that the game was decided by a king
+	// reaching the eighth rank in Racing Kings.
+	KingReachedGoal
+	// KingExploded indicates that the game was won by exploding the
+	// opponent's king in Atomic.
+	KingExploded
+	// AllPiecesLost indicates that the game was won by losing all
+	// pieces in Antichess.
+	AllPiecesLost
+	// AllPiecesCaptured indicates that the game was won by capturing all
+	// of white's pieces in Horde.
+	AllPiecesCaptured
 )
 
 // TagPair represents metadata in a key value pairing used in the PGN format.
@@ -69,6 +87,8 @@ type Game struct {
 	tagPairs             []*TagPair
 	moves                []*Move
 	comments             [][]string
+	nags                 [][]int
+	variations           [][][]*Move
 	positions            []*Position
 	pos                  *Position
 	outcome              Outcome
@@ -103,7 +123,17 @@ func PGN(r io.Reader) (func(*Game), error) {
 // for 960 mode it can decode both shredder-fen and x-fen wuthout knowing
 // which one it is
 func FEN(fen string, isNineSixty bool) (func(*Game), error) {
-	pos, err := decodeFEN(fen, isNineSixty)
+	if isNineSixty {
+		return VariantFEN(Chess960{}, fen)
+	}
+	return VariantFEN(Standard{}, fen)
+}
+
+// VariantFEN is like FEN but decodes the position with the rules
+// of the given variant.  The returned function is designed to be
+// used in the NewGame constructor.
+func VariantFEN(v Variant, fen string) (func(*Game), error) {
+	pos, err := decodeVariantFEN(fen, v)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +145,21 @@ func FEN(fen string, isNineSixty bool) (func(*Game), error) {
 	}, nil
 }
 
+// UseVariant returns a function that sets the game's variant and
+// resets the game to the variant's starting position.  The returned
+// function is designed to be used in the NewGame constructor before
+// any option setting the position.
+func UseVariant(v Variant) func(*Game) {
+	return func(g *Game) {
+		pos := v.StartingPosition()
+		pos.variant = v
+		pos.inCheck = isInCheck(pos)
+		g.pos = pos
+		g.positions = []*Position{pos}
+		g.updatePosition()
+	}
+}
+
 // TagPairs returns a function that sets the tag pairs
 // to the given value.  The returned function is designed
 // to be used in the NewGame constructor.
@@ -170,6 +215,8 @@ func (g *Game) Move(m *Move) error {
 	g.pos = g.pos.Update(valid)
 	g.positions = append(g.positions, g.pos)
 	g.comments = append(g.comments, []string{})
+	g.nags = append(g.nags, nil)
+	g.variations = append(g.variations, nil)
 	g.updatePosition()
 	return nil
 }
@@ -206,6 +253,65 @@ func (g *Game) Comments() [][]string {
 	return append([][]string(nil), g.comments...)
 }
 
+// AddComment adds the comment to the last move of the game.  An error is
+// returned if the game has no moves.
+func (g *Game) AddComment(comment string) error {
+	if len(g.moves) == 0 {
+		return errors.New("chess: comment added to game without moves")
+	}
+	i := len(g.moves) - 1
+	g.comments[i] = append(g.comments[i], comment)
+	return nil
+}
+
+// NAGs returns the numeric annotation glyphs for the game indexed by moves.
+func (g *Game) NAGs() [][]int {
+	return append([][]int(nil), g.nags...)
+}
+
+// AddNAG adds the numeric annotation glyph, such as 2 for a mistake ("?"),
+// to the last move of the game.  An error is returned if the game has no
+// moves or the glyph is outside of 0 to 255.
+func (g *Game) AddNAG(nag int) error {
+	if len(g.moves) == 0 {
+		return errors.New("chess: nag added to game without moves")
+	}
+	if nag < 0 || nag > 255 {
+		return fmt.Errorf("chess: invalid nag %d", nag)
+	}
+	i := len(g.moves) - 1
+	g.nags[i] = append(g.nags[i], nag)
+	return nil
+}
+
+// Variations returns the variations for the game indexed by moves.  A
+// variation is an alternative to its move played from the same position.
+func (g *Game) Variations() [][][]*Move {
+	return append([][][]*Move(nil), g.variations...)
+}
+
+// AddVariation adds the moves as an alternative to the last move of the
+// game.  An error is returned if the game has no moves or the moves aren't
+// valid from the position before the last move.
+func (g *Game) AddVariation(moves []*Move) error {
+	if len(g.moves) == 0 {
+		return errors.New("chess: variation added to game without moves")
+	}
+	i := len(g.moves) - 1
+	pos := g.positions[i]
+	variation := make([]*Move, 0, len(moves))
+	for _, m := range moves {
+		valid := moveSlice(pos.ValidMoves()).find(m)
+		if valid == nil {
+			return fmt.Errorf("chess: invalid variation move %s", m)
+		}
+		variation = append(variation, valid)
+		pos = pos.Update(valid)
+	}
+	g.variations[i] = append(g.variations[i], variation)
+	return nil
+}
+
 // TagPairs returns the game's tag pairs.
 func (g *Game) TagPairs() []*TagPair {
 	return append([]*TagPair(nil), g.tagPairs...)
@@ -216,6 +322,11 @@ func (g *Game) Position() *Position {
 	return g.pos
 }
 
+// Variant returns the rules the game is played under.
+func (g *Game) Variant() Variant {
+	return g.pos.rules()
+}
+
 // Outcome returns the game outcome.
 func (g *Game) Outcome() Outcome {
 	return g.outcome
@@ -375,16 +486,10 @@ func (g *Game) MoveHistory() []*MoveHistory {
 }
 
 func (g *Game) updatePosition() {
-	method := g.pos.Status()
-	if method == Stalemate {
-		g.method = Stalemate
-		g.outcome = Draw
-	} else if method == Checkmate {
-		g.method = Checkmate
-		g.outcome = WhiteWon
-		if g.pos.Turn() == White {
-			g.outcome = BlackWon
-		}
+	rules := g.pos.rules()
+	if outcome, method := rules.Status(g.pos); outcome != NoOutcome {
+		g.outcome = outcome
+		g.method = method
 	}
 	if g.outcome != NoOutcome {
 		return
@@ -403,7 +508,7 @@ func (g *Game) updatePosition() {
 	}
 
 	// insufficient material creates automatic draw
-	if !g.ignoreAutomaticDraws && !g.pos.board.hasSufficientMaterial() {
+	if !g.ignoreAutomaticDraws && rules.InsufficientMaterial(g.pos) {
 		g.outcome = Draw
 		g.method = InsufficientMaterial
 	}
@@ -417,17 +522,22 @@ func (g *Game) copy(game *Game) {
 	g.outcome = game.outcome
 	g.method = game.method
 	g.comments = game.Comments()
+	g.nags = game.NAGs()
+	g.variations = game.Variations()
 }
 
 func (g *Game) Clone() *Game {
 	return &Game{
-		tagPairs:  g.TagPairs(),
-		notation:  g.notation,
-		moves:     g.Moves(),
-		positions: g.Positions(),
-		pos:       g.pos,
-		outcome:   g.outcome,
-		method:    g.method,
+		tagPairs:   g.TagPairs(),
+		notation:   g.notation,
+		moves:      g.Moves(),
+		comments:   g.Comments(),
+		nags:       g.NAGs(),
+		variations: g.Variations(),
+		positions:  g.Positions(),
+		pos:        g.pos,
+		outcome:    g.outcome,
+		method:     g.method,
 	}
 }
 
@@ -291,6 +291,66 @@ func TestMoveHistoryProgrammatic(t *testing.T) {
 	}
 }
 
+func TestAddComment(t *testing.T) {
+	g := NewGame()
+	if err := g.AddComment("no moves"); err == nil {
+		t.Fatal("expected error for game without moves")
+	}
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddComment("+0.25/12 0.51s"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(g.String(), "1. e4 { +0.25/12 0.51s }") {
+		t.Fatalf("expected comment in pgn but got %s", g.String())
+	}
+}
+
+func TestAddVariation(t *testing.T) {
+	g := NewGame()
+	if err := g.AddNAG(2); err == nil {
+		t.Fatal("expected error for game without moves")
+	}
+	for _, s := range []string{"e4", "e5", "Nf3", "f6"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AddNAG(2); err != nil {
+		t.Fatal(err)
+	}
+	nc6 := &Move{s1: B8, s2: C6}
+	nf6 := &Move{s1: G8, s2: F6}
+	if err := g.AddVariation([]*Move{nc6, {s1: F1, s2: B5}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddVariation([]*Move{nc6, nf6}); err == nil {
+		t.Fatal("expected error for invalid variation")
+	}
+	if err := g.MoveStr("Nxe5"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "2. Nf3 f6 $2 ( 2... Nc6 3. Bb5 ) 3. Nxe5"
+	if pgn := strings.Join(strings.Fields(g.String()), " "); !strings.Contains(pgn, expected) {
+		t.Fatalf("expected %s in pgn but got %s", expected, pgn)
+	}
+	if len(g.Clone().Variations()[3]) != 1 || g.Clone().NAGs()[3][0] != 2 {
+		t.Fatal("expected clone to keep variations and nags")
+	}
+	opt, err := PGN(strings.NewReader(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewGame(opt)
+	if moves := decoded.Moves(); len(moves) != 5 {
+		t.Fatalf("expected variation to stay off the main line when decoding but got %v", moves)
+	}
+	if len(decoded.Variations()[3]) != 1 || decoded.NAGs()[3][0] != 2 {
+		t.Fatal("expected decoding to keep variations and nags")
+	}
+}
+
 func BenchmarkStalemateStatus(b *testing.B) {
 	fenStr := "k1K5/8/8/8/8/8/8/1Q6 w - - 0 1"
 	fen, err := FEN(fenStr, false)
@@ -0,0 +1,75 @@
+package chess
+
+import "errors"
+
+// Horde is a variant in which white has a horde of 36 pawns and no king
+// against black's standard army.  White wins by checkmate and black wins
+// by capturing all white pieces.  White pawns on the first rank may move
+// two squares but can't be captured en passant after doing so.
+type Horde struct {
+	Standard
+}
+
+const hordeFEN = "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+
+// Name implements the Variant interface.
+func (Horde) Name() string {
+	return "Horde"
+}
+
+// StartingPosition implements the Variant interface.
+func (Horde) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(hordeFEN, Horde{})
+	return pos
+}
+
+// Moves implements the Variant interface and adds the double steps of
+// white pawns on the first rank.
+func (Horde) Moves(pos *Position) []*Move {
+	moves := engine{}.CalcMoves(pos, false)
+	if pos.turn == Black {
+		return moves
+	}
+	pawns := pos.board.bbWhitePawn & bbRank1
+	for sq := A1; sq <= H1; sq++ {
+		if !pawns.Occupied(sq) || pos.board.isOccupied(sq+8) || pos.board.isOccupied(sq+16) {
+			continue
+		}
+		m := &Move{s1: sq, s2: sq + 16}
+		addTags(m, pos)
+		if !m.HasTag(inCheck) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// Status implements the Variant interface.  Possible methods are
+// AllPiecesCaptured, Checkmate, Stalemate and NoMethod.
+func (Horde) Status(pos *Position) (Outcome, Method) {
+	if pos.board.whiteSqs == 0 {
+		return BlackWon, AllPiecesCaptured
+	}
+	return Standard{}.Status(pos)
+}
+
+// InsufficientMaterial implements the Variant interface and always
+// returns false.
+func (Horde) InsufficientMaterial(pos *Position) bool {
+	return false
+}
+
+func (Horde) validate(pos *Position) error {
+	if pos.board.bbWhitePawn&bbRank8 != 0 || pos.board.bbBlackPawn&(bbRank1|bbRank8) != 0 {
+		return errPawnsOnBackRank
+	}
+	if pos.board.bbWhiteKing != 0 || !pos.board.bbBlackKing.single() {
+		return errors.New("black should have one king and white none")
+	}
+	cp := pos.copy()
+	cp.turn = cp.turn.Other()
+	if isInCheck(cp) {
+		return errors.New("king can be captured in next move")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package chess
+
+import "testing"
+
+func TestHordePerft(t *testing.T) {
+	pos := Horde{}.StartingPosition()
+	for depth, expected := range []int{1, 8, 128, 1274, 23310} {
+		if n := perft(pos, depth); n != expected {
+			t.Fatalf("expected perft(%d) to be %d but got %d", depth, expected, n)
+		}
+	}
+}
+
+func TestHorde(t *testing.T) {
+	opt, err := VariantFEN(Horde{}, "4k3/8/8/8/8/8/8/P3r3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if err := g.MoveStr("a3"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Position().EnPassantSquare() != NoSquare {
+		t.Fatal("expected no en passant square after a double step from the first rank")
+	}
+	for _, m := range []string{"Ra1", "a4", "Rxa4"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.Outcome() != BlackWon || g.Method() != AllPiecesCaptured {
+		t.Fatalf("expected black to win by capturing all pieces but got %s %s", g.Outcome(), g.Method())
+	}
+	if _, err := VariantFEN(Horde{}, "4k3/8/8/8/8/8/8/P3K3 w - - 0 1"); err == nil {
+		t.Fatal("expected error for white king in horde")
+	}
+}
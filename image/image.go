@@ -75,6 +75,18 @@ func Perspective(c chess.Color) func(*Encoder) {
 	}
 }
 
+// Size is designed to be used as an optional argument to the
+// SVG and PNG functions.  It changes the width and height of the
+// image from the default of 360 pixels.  Sizes smaller than one
+// pixel are ignored.
+func Size(pixels int) func(*Encoder) {
+	return func(e *Encoder) {
+		if pixels > 0 {
+			e.size = pixels
+		}
+	}
+}
+
 // A Encoder encodes chess boards into images.
 type Encoder struct {
 	w           io.Writer
@@ -83,6 +95,7 @@ type Encoder struct {
 	perspective chess.Color
 	marks       map[chess.Square]color.Color
 	arrows      []arrow
+	size        int
 }
 
 // An arrow represents the visualization of a move
@@ -103,6 +116,7 @@ func new(w io.Writer, options []func(*Encoder)) *Encoder {
 		perspective: chess.White,
 		marks:       map[chess.Square]color.Color{},
 		arrows:      []arrow{},
+		size:        boardWidth,
 	}
 	for _, op := range options {
 		op(e)
@@ -119,6 +133,7 @@ const (
 
 	arrowWidth   = 8
 	arrowOpacity = "75%"
+	arrowAlpha   = 0.75
 )
 
 var (
@@ -134,7 +149,11 @@ var (
 func (e *Encoder) EncodeSVG(b *chess.Board) error {
 	boardMap := b.SquareMap()
 	canvas := svg.New(e.w)
-	canvas.Start(boardWidth, boardHeight)
+	if e.size == boardWidth {
+		canvas.Start(boardWidth, boardHeight)
+	} else {
+		canvas.Startview(e.size, e.size, 0, 0, boardWidth, boardHeight)
+	}
 	canvas.Rect(0, 0, boardWidth, boardHeight)
 
 	ranks := orderOfRanks
@@ -223,8 +242,109 @@ func pieceXML(x, y int, p chess.Piece) string {
 
 func straightArrowXML(id int, a arrow, perspective chess.Color) string {
 	c := colorToHex(a.color)
-	x1, y1 := squareCenter(a.from, perspective)
-	x2, y2 := squareCenter(a.to, perspective)
+	x1, y1, x2, y2 := straightArrowLine(a, perspective)
+
+	return fmt.Sprintf(`<svg>
+  <defs>
+    <marker 
+      id="head-%d" 
+      orient="auto"
+      markerWidth="3"
+      markerHeight="4"
+      refX="0"
+      refY="1.5">
+      <path d="M0,0 V3 L2,1.5 Z" fill="%s" fill-opacity="%s" />
+    </marker>
+  </defs>
+  <path
+    marker-end="url(#head-%d)"
+    stroke-width="%dpx"
+    d="M%f,%f %f,%f"
+    stroke="%s"
+    stroke-opacity="%s" />
+</svg>`,
+		// arrow head
+		id,
+		c,
+		arrowOpacity,
+
+		// line
+		id,
+		arrowWidth,
+		x1,
+		y1,
+		x2,
+		y2,
+		c,
+		arrowOpacity,
+	)
+}
+
+func knightArrowXML(id int, a arrow, perspective chess.Color) string {
+	c := colorToHex(a.color)
+	first, second := knightArrowLines(a, perspective)
+	x11, y11, x12, y12 := first[0], first[1], first[2], first[3]
+	x21, y21, x22, y22 := second[0], second[1], second[2], second[3]
+
+	template := `<svg>
+  <defs>
+    <marker 
+      id="head-%d" 
+      orient="auto"
+      markerWidth="3"
+      markerHeight="4"
+      refX="0"
+      refY="1.5">
+      <path d="M0,0 V3 L2,1.5 Z" fill="%s" fill-opacity="%s" />
+    </marker>
+  </defs>
+  <path
+    stroke-width="%dpx"
+    d="M%f,%f %f,%f"
+    stroke="%s"
+    stroke-opacity="%s" />
+  <path
+    marker-end="url(#head-%d)"
+    stroke-width="%dpx"
+    d="M%f,%f %f,%f"
+    stroke="%s"
+    stroke-opacity="%s" />
+</svg>`
+
+	return fmt.Sprintf(
+		template,
+
+		// arrow head
+		id,
+		c,
+		arrowOpacity,
+
+		// first line
+		arrowWidth,
+		x11,
+		y11,
+		x12,
+		y12,
+		c,
+		arrowOpacity,
+
+		// second line
+		id,
+		arrowWidth,
+		x21,
+		y21,
+		x22,
+		y22,
+		c,
+		arrowOpacity,
+	)
+}
+
+// straightArrowLine returns the line of a straight arrow from the edge of
+// its square to the base of its head.
+func straightArrowLine(a arrow, perspective chess.Color) (x1, y1, x2, y2 float32) {
+	x1, y1 = squareCenter(a.from, perspective)
+	x2, y2 = squareCenter(a.to, perspective)
 
 	// move the start of the arrow away from the center of the square
 	// as well as head of the arrow to the center of its square
@@ -266,46 +386,13 @@ func straightArrowXML(id int, a arrow, perspective chess.Color) string {
 			y2 += offset
 		}
 	}
-
-	return fmt.Sprintf(`<svg>
-  <defs>
-    <marker 
-      id="head-%d" 
-      orient="auto"
-      markerWidth="3"
-      markerHeight="4"
-      refX="0"
-      refY="1.5">
-      <path d="M0,0 V3 L2,1.5 Z" fill="%s" fill-opacity="%s" />
-    </marker>
-  </defs>
-  <path
-    marker-end="url(#head-%d)"
-    stroke-width="%dpx"
-    d="M%f,%f %f,%f"
-    stroke="%s"
-    stroke-opacity="%s" />
-</svg>`,
-		// arrow head
-		id,
-		c,
-		arrowOpacity,
-
-		// line
-		id,
-		arrowWidth,
-		x1,
-		y1,
-		x2,
-		y2,
-		c,
-		arrowOpacity,
-	)
+	return x1, y1, x2, y2
 }
 
-func knightArrowXML(id int, a arrow, perspective chess.Color) string {
-	c := colorToHex(a.color)
-
+// knightArrowLines returns the two lines of a knight arrow, the first
+// from the edge of its square to the corner and the second from the corner
+// to the base of its head.
+func knightArrowLines(a arrow, perspective chess.Color) (first, second [4]float32) {
 	horizontal := horizontalMoves(a.from, a.to)
 	vertical := verticalMoves(a.from, a.to)
 
@@ -368,59 +455,7 @@ func knightArrowXML(id int, a arrow, perspective chess.Color) string {
 			x22 += offset
 		}
 	}
-
-	template := `<svg>
-  <defs>
-    <marker 
-      id="head-%d" 
-      orient="auto"
-      markerWidth="3"
-      markerHeight="4"
-      refX="0"
-      refY="1.5">
-      <path d="M0,0 V3 L2,1.5 Z" fill="%s" fill-opacity="%s" />
-    </marker>
-  </defs>
-  <path
-    stroke-width="%dpx"
-    d="M%f,%f %f,%f"
-    stroke="%s"
-    stroke-opacity="%s" />
-  <path
-    marker-end="url(#head-%d)"
-    stroke-width="%dpx"
-    d="M%f,%f %f,%f"
-    stroke="%s"
-    stroke-opacity="%s" />
-</svg>`
-
-	return fmt.Sprintf(
-		template,
-
-		// arrow head
-		id,
-		c,
-		arrowOpacity,
-
-		// first line
-		arrowWidth,
-		x11,
-		y11,
-		x12,
-		y12,
-		c,
-		arrowOpacity,
-
-		// second line
-		id,
-		arrowWidth,
-		x21,
-		y21,
-		x22,
-		y22,
-		c,
-		arrowOpacity,
-	)
+	return [4]float32{x11, y11, x12, y12}, [4]float32{x21, y21, x22, y22}
 }
 
 func isKnightMove(from, to chess.Square) bool {
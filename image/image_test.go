@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"crypto/md5"
 	"fmt"
+	goimage "image"
 	"image/color"
+	"image/png"
 	"strings"
 	"testing"
 
@@ -104,3 +106,127 @@ func TestSVGKnightsAndDiagonals(t *testing.T) {
 		t.Errorf("expected actual md5 hash to be %s but got %s", expectedMD5KnightsAndDiagonalArrows, actualMD5)
 	}
 }
+
+func TestSVGSize(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	if err := image.SVG(buf, chess.NewGame().Position().Board(), image.Size(720)); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, `width="720" height="720"`) || !strings.Contains(s, `viewBox="0 0 360 360"`) {
+		t.Fatalf("expected a 720 pixel svg of the 360 pixel board but got %s", s[:200])
+	}
+}
+
+func TestPNG(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	if err := image.PNG(buf, chess.NewGame().Position().Board(), image.Size(200)); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+		t.Fatalf("expected a 200 by 200 image but got %v", b)
+	}
+}
+
+var (
+	light  = color.RGBA{235, 209, 166, 255}
+	dark   = color.RGBA{165, 117, 81, 255}
+	orange = color.RGBA{247, 181, 75, 255}
+	yellow = color.RGBA{255, 255, 0, 100}
+)
+
+func TestRaster(t *testing.T) {
+	fenStr := "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq - 0 1"
+	pos := &chess.Position{}
+	if err := pos.UnmarshalText([]byte(fenStr)); err != nil {
+		t.Fatal(err)
+	}
+	mark := image.MarkSquares(yellow, chess.D2, chess.D4)
+	arrows := image.MarkArrows(image.Arrow(chess.D2, chess.D4))
+	img := image.Raster(pos.Board(), mark, arrows)
+	if b := img.Bounds(); b.Dx() != 360 || b.Dy() != 360 {
+		t.Fatalf("expected a 360 by 360 image but got %v", b)
+	}
+	// e4 is empty
+	expectColor(t, img.At(202, 202), light)
+	// d2 is empty and marked
+	expectColor(t, img.At(140, 300), blend(dark, yellow, 100.0/255))
+	// the arrow crosses d3
+	expectColor(t, img.At(157, 247), blend(light, orange, 0.75))
+	// e1 has the white king and e8 the black king
+	if n := count(img, chess.E1, color.White); n < 200 {
+		t.Fatalf("expected white king pixels on e1 but got %d", n)
+	}
+	if n := count(img, chess.E8, color.Black); n < 200 {
+		t.Fatalf("expected black king pixels on e8 but got %d", n)
+	}
+	if n := count(img, chess.E4, color.Black); n != 0 {
+		t.Fatalf("expected no piece on e4 but got %d black pixels", n)
+	}
+}
+
+func TestRasterFromBlack(t *testing.T) {
+	img := image.Raster(chess.NewGame().Position().Board(), image.Perspective(chess.Black), image.Size(720))
+	if b := img.Bounds(); b.Dx() != 720 || b.Dy() != 720 {
+		t.Fatalf("expected a 720 by 720 image but got %v", b)
+	}
+	// from black the h1 rook is in the top left corner
+	for x := 0; x < 90; x++ {
+		for y := 0; y < 90; y++ {
+			if c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA); c == (color.RGBA{255, 255, 255, 255}) {
+				return
+			}
+		}
+	}
+	t.Fatal("expected the white rook in the top left corner")
+}
+
+func TestRasterSizes(t *testing.T) {
+	for _, size := range []int{0, 1, 7, 101} {
+		img := image.Raster(chess.NewGame().Position().Board(), image.Size(size))
+		want := size
+		if size == 0 {
+			want = 360
+		}
+		if b := img.Bounds(); b.Dx() != want || b.Dy() != want {
+			t.Fatalf("expected a %d pixel image but got %v", want, b)
+		}
+	}
+}
+
+// count returns the number of pixels of the color on the square of a 360
+// pixel image from white.
+func count(img goimage.Image, sq chess.Square, c color.Color) int {
+	want := color.RGBAModel.Convert(c)
+	x0, y0 := int(sq.File())*45, (7-int(sq.Rank()))*45
+	n := 0
+	for x := x0; x < x0+45; x++ {
+		for y := y0; y < y0+45; y++ {
+			if color.RGBAModel.Convert(img.At(x, y)) == want {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func blend(dst, src color.RGBA, a float64) color.RGBA {
+	mix := func(d, s uint8) uint8 {
+		return uint8(float64(d)*(1-a) + float64(s)*a + 0.5)
+	}
+	return color.RGBA{mix(dst.R, src.R), mix(dst.G, src.G), mix(dst.B, src.B), 255}
+}
+
+func expectColor(t *testing.T, got color.Color, want color.RGBA) {
+	t.Helper()
+	c := color.RGBAModel.Convert(got).(color.RGBA)
+	diff := func(a, b uint8) bool {
+		return int(a)-int(b) > 1 || int(b)-int(a) > 1
+	}
+	if diff(c.R, want.R) || diff(c.G, want.G) || diff(c.B, want.B) || c.A != 255 {
+		t.Fatalf("expected color %v but got %v", want, c)
+	}
+}
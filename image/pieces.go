@@ -0,0 +1,514 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/image/internal"
+)
+
+// matrix is an affine transform mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f).
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+func (m matrix) apply(p point) point {
+	return point{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+// mul returns the transform applying n and then m.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+// segment is a line, or a cubic Bézier curve if curve is true, from the end
+// of the previous segment.
+type segment struct {
+	c1, c2, to point
+	curve      bool
+}
+
+type subpath struct {
+	start  point
+	segs   []segment
+	closed bool
+}
+
+// style holds the presentation properties of a piece SVG element.  A nil
+// color is none.
+type style struct {
+	fill, stroke color.Color
+	rule         fillRule
+	width        float64
+	cap, join    string
+	miterLimit   float64
+	transform    matrix
+}
+
+// shape is a path or circle of a piece SVG in its coordinates.
+type shape struct {
+	style
+	paths []subpath
+}
+
+var (
+	pieceShapesOnce sync.Once
+	pieceShapes     map[chess.Piece][]shape
+)
+
+// drawPiece draws the piece with the top left of its square at x, y and
+// the square scale times the size of the piece SVGs.
+func (c *canvas) drawPiece(p chess.Piece, x, y, scale float64) {
+	pieceShapesOnce.Do(func() {
+		pieceShapes = map[chess.Piece][]shape{}
+		for _, p := range allPieces {
+			fileName := fmt.Sprintf("pieces/%s%s.svg", p.Color().String(), pieceTypeMap[p.Type()])
+			shapes, err := parsePiece(internal.MustAsset(fileName))
+			if err != nil {
+				panic(fmt.Sprintf("image: %s: %s", fileName, err))
+			}
+			pieceShapes[p] = shapes
+		}
+	})
+	place := matrix{scale, 0, 0, scale, x, y}
+	for _, s := range pieceShapes[p] {
+		m := place.mul(s.transform)
+		lines := make([]polyline, len(s.paths))
+		for i, sp := range s.paths {
+			lines[i] = sp.flatten(m)
+		}
+		if s.fill != nil {
+			polys := make([]polygon, len(lines))
+			for i, l := range lines {
+				polys[i] = l.points
+			}
+			c.fill(polys, s.fill, 1, s.rule)
+		}
+		if s.stroke != nil {
+			width := s.width * math.Sqrt(math.Abs(m[0]*m[3]-m[1]*m[2]))
+			c.fill(stroke(lines, width, s.cap, s.join, s.miterLimit), s.stroke, 1, nonZero)
+		}
+	}
+}
+
+var allPieces = []chess.Piece{
+	chess.WhiteKing, chess.WhiteQueen, chess.WhiteRook, chess.WhiteBishop, chess.WhiteKnight, chess.WhitePawn,
+	chess.BlackKing, chess.BlackQueen, chess.BlackRook, chess.BlackBishop, chess.BlackKnight, chess.BlackPawn,
+}
+
+// flatten returns the subpath transformed by m with its curves split into
+// lines.
+func (sp subpath) flatten(m matrix) polyline {
+	cur := m.apply(sp.start)
+	pts := []point{cur}
+	for _, seg := range sp.segs {
+		to := m.apply(seg.to)
+		if !seg.curve {
+			pts = append(pts, to)
+			cur = to
+			continue
+		}
+		c1, c2 := m.apply(seg.c1), m.apply(seg.c2)
+		n := clamp(int(math.Ceil((c1.sub(cur).length()+c2.sub(c1).length()+to.sub(c2).length())/tolerance)), 1, 64)
+		for i := 1; i <= n; i++ {
+			t := float64(i) / float64(n)
+			u := 1 - t
+			pts = append(pts, cur.mul(u*u*u).add(c1.mul(3*u*u*t)).add(c2.mul(3*u*t*t)).add(to.mul(t*t*t)))
+		}
+		cur = to
+	}
+	return polyline{points: pts, closed: sp.closed}
+}
+
+// parsePiece parses the paths and circles of a piece SVG.
+func parsePiece(data []byte) ([]shape, error) {
+	stack := []style{{
+		fill:       color.Black,
+		width:      1,
+		cap:        "butt",
+		join:       "miter",
+		miterLimit: 4,
+		transform:  identity,
+	}}
+	shapes := []shape{}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return shapes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			st, err := elementStyle(stack[len(stack)-1], tok.Attr)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, st)
+			var paths []subpath
+			switch tok.Name.Local {
+			case "path":
+				paths, err = parsePath(attr(tok.Attr, "d"))
+			case "circle":
+				paths, err = parseCircle(tok.Attr)
+			default:
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			shapes = append(shapes, shape{style: st, paths: paths})
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+func attr(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// elementStyle returns the style of an element inheriting from its parent.
+// Invalid colors are ignored.
+func elementStyle(parent style, attrs []xml.Attr) (style, error) {
+	st := parent
+	props := [][2]string{}
+	for _, a := range attrs {
+		props = append(props, [2]string{a.Name.Local, a.Value})
+	}
+	for _, decl := range strings.Split(attr(attrs, "style"), ";") {
+		if kv := strings.SplitN(decl, ":", 2); len(kv) == 2 {
+			props = append(props, [2]string{strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])})
+		}
+	}
+	var err error
+	for _, prop := range props {
+		v := prop[1]
+		switch prop[0] {
+		case "fill":
+			st.fill = parseColor(v, st.fill)
+		case "stroke":
+			st.stroke = parseColor(v, st.stroke)
+		case "fill-rule":
+			st.rule = nonZero
+			if v == "evenodd" {
+				st.rule = evenOdd
+			}
+		case "stroke-width":
+			st.width, err = strconv.ParseFloat(strings.TrimSuffix(v, "px"), 64)
+		case "stroke-linecap":
+			st.cap = v
+		case "stroke-linejoin":
+			st.join = v
+		case "stroke-miterlimit":
+			st.miterLimit, err = strconv.ParseFloat(v, 64)
+		case "transform":
+			var m matrix
+			m, err = parseTransform(v)
+			st.transform = parent.transform.mul(m)
+		}
+		if err != nil {
+			return st, err
+		}
+	}
+	return st, nil
+}
+
+func parseColor(v string, inherited color.Color) color.Color {
+	if v == "none" {
+		return nil
+	}
+	if len(v) != 7 || v[0] != '#' {
+		return inherited
+	}
+	rgb, err := strconv.ParseUint(v[1:], 16, 32)
+	if err != nil {
+		return inherited
+	}
+	return color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xff}
+}
+
+// parseTransform parses a translate or matrix transform.
+func parseTransform(v string) (matrix, error) {
+	open, close := strings.Index(v, "("), strings.LastIndex(v, ")")
+	if open < 0 || close < open {
+		return identity, fmt.Errorf("invalid transform %q", v)
+	}
+	args, err := numbers(v[open+1 : close])
+	if err != nil {
+		return identity, err
+	}
+	switch name := strings.TrimSpace(v[:open]); {
+	case name == "translate" && len(args) == 1:
+		return matrix{1, 0, 0, 1, args[0], 0}, nil
+	case name == "translate" && len(args) == 2:
+		return matrix{1, 0, 0, 1, args[0], args[1]}, nil
+	case name == "matrix" && len(args) == 6:
+		var m matrix
+		copy(m[:], args)
+		return m, nil
+	}
+	return identity, fmt.Errorf("unsupported transform %q", v)
+}
+
+func numbers(v string) ([]float64, error) {
+	s := &pathScanner{s: v}
+	nums := []float64{}
+	for !s.done() {
+		f, err := s.number()
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, f)
+	}
+	return nums, nil
+}
+
+func parseCircle(attrs []xml.Attr) ([]subpath, error) {
+	var v [3]float64
+	for i, name := range []string{"cx", "cy", "r"} {
+		f, err := strconv.ParseFloat(attr(attrs, name), 64)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = f
+	}
+	c, r := point{v[0], v[1]}, v[2]
+	sp := subpath{start: point{c.x + r, c.y}, closed: true}
+	sp.segs = arcSegments(c, r, r, 0, 0, 2*math.Pi)
+	return []subpath{sp}, nil
+}
+
+// parsePath parses the M, L, H, V, C, A and Z commands of path data.
+func parsePath(d string) ([]subpath, error) {
+	s := &pathScanner{s: d}
+	paths := []subpath{}
+	var cur, start point
+	var cmd byte
+	for !s.done() {
+		if c, ok := s.command(); ok {
+			cmd = c
+		} else if cmd == 0 {
+			return nil, fmt.Errorf("path data %q does not start with a command", d)
+		}
+		rel := cmd >= 'a'
+		abs := func(p point) point {
+			if rel {
+				return p.add(cur)
+			}
+			return p
+		}
+		switch cmd {
+		case 'Z', 'z':
+			if len(paths) > 0 {
+				paths[len(paths)-1].closed = true
+			}
+			cur = start
+			cmd = 0
+			continue
+		case 'H', 'h', 'V', 'v':
+			f, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			to := cur
+			switch cmd {
+			case 'H':
+				to.x = f
+			case 'h':
+				to.x += f
+			case 'V':
+				to.y = f
+			case 'v':
+				to.y += f
+			}
+			cur = to
+			paths = appendSegment(paths, cur, segment{to: to})
+			continue
+		}
+		args := map[byte]int{'M': 2, 'L': 2, 'C': 6, 'A': 7}[cmd&^0x20]
+		if args == 0 {
+			return nil, fmt.Errorf("unsupported path command %q", cmd)
+		}
+		v := make([]float64, args)
+		for i := range v {
+			f, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			v[i] = f
+		}
+		switch cmd &^ 0x20 {
+		case 'M':
+			cur = abs(point{v[0], v[1]})
+			start = cur
+			paths = append(paths, subpath{start: cur})
+			// Coordinates following a move are lines.
+			cmd = 'L' | cmd&0x20
+		case 'L':
+			cur = abs(point{v[0], v[1]})
+			paths = appendSegment(paths, cur, segment{to: cur})
+		case 'C':
+			seg := segment{c1: abs(point{v[0], v[1]}), c2: abs(point{v[2], v[3]}), to: abs(point{v[4], v[5]}), curve: true}
+			paths = appendSegment(paths, cur, seg)
+			cur = seg.to
+		case 'A':
+			to := abs(point{v[5], v[6]})
+			for _, seg := range arc(cur, to, v[0], v[1], v[2], v[3] != 0, v[4] != 0) {
+				paths = appendSegment(paths, cur, seg)
+			}
+			cur = to
+		}
+	}
+	return paths, nil
+}
+
+// appendSegment appends the segment to the last subpath, starting a new
+// one at cur after a close.
+func appendSegment(paths []subpath, cur point, seg segment) []subpath {
+	if len(paths) == 0 || paths[len(paths)-1].closed {
+		paths = append(paths, subpath{start: cur})
+	}
+	last := &paths[len(paths)-1]
+	last.segs = append(last.segs, seg)
+	return paths
+}
+
+// arc returns the Bézier curves of an SVG elliptical arc from p to q.
+func arc(p, q point, rx, ry, rotation float64, large, sweep bool) []segment {
+	if p == q {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return []segment{{to: q}}
+	}
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx, dy := (p.x-q.x)/2, (p.y-q.y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1, cy1 := coef*rx*y1/ry, -coef*ry*x1/rx
+	center := point{cos*cx1 - sin*cy1 + (p.x+q.x)/2, sin*cx1 + cos*cy1 + (p.y+q.y)/2}
+	start := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	end := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx)
+	delta := end - start
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	segs := arcSegments(center, rx, ry, phi, start, delta)
+	segs[len(segs)-1].to = q
+	return segs
+}
+
+// arcSegments returns Bézier curves of at most a quarter turn each along
+// the ellipse from angle start turning by delta.
+func arcSegments(center point, rx, ry, phi, start, delta float64) []segment {
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	if n == 0 {
+		n = 1
+	}
+	step := delta / float64(n)
+	k := 4.0 / 3 * math.Tan(step/4)
+	m := matrix{rx * math.Cos(phi), rx * math.Sin(phi), -ry * math.Sin(phi), ry * math.Cos(phi), center.x, center.y}
+	segs := make([]segment, n)
+	for i := range segs {
+		a, b := start+step*float64(i), start+step*float64(i+1)
+		p0 := point{math.Cos(a), math.Sin(a)}
+		p3 := point{math.Cos(b), math.Sin(b)}
+		segs[i] = segment{
+			c1:    m.apply(p0.add(point{-p0.y, p0.x}.mul(k))),
+			c2:    m.apply(p3.sub(point{-p3.y, p3.x}.mul(k))),
+			to:    m.apply(p3),
+			curve: true,
+		}
+	}
+	return segs
+}
+
+// pathScanner splits path data into commands and numbers.
+type pathScanner struct {
+	s string
+	i int
+}
+
+func (s *pathScanner) skip() {
+	for s.i < len(s.s) && strings.IndexByte(" \t\r\n,", s.s[s.i]) >= 0 {
+		s.i++
+	}
+}
+
+func (s *pathScanner) done() bool {
+	s.skip()
+	return s.i == len(s.s)
+}
+
+func (s *pathScanner) command() (byte, bool) {
+	s.skip()
+	if s.i < len(s.s) {
+		if c := s.s[s.i]; (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') && c != 'e' && c != 'E' {
+			s.i++
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+func (s *pathScanner) number() (float64, error) {
+	s.skip()
+	start := s.i
+	if s.i < len(s.s) && (s.s[s.i] == '-' || s.s[s.i] == '+') {
+		s.i++
+	}
+	dot := false
+	for s.i < len(s.s) {
+		c := s.s[s.i]
+		if c == '.' && !dot {
+			dot = true
+		} else if c == 'e' || c == 'E' {
+			s.i++
+			if s.i < len(s.s) && (s.s[s.i] == '-' || s.s[s.i] == '+') {
+				s.i++
+			}
+			continue
+		} else if c < '0' || c > '9' {
+			break
+		}
+		s.i++
+	}
+	return strconv.ParseFloat(s.s[start:s.i], 64)
+}
@@ -0,0 +1,162 @@
+package image
+
+import (
+	goimage "image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/notnil/chess"
+)
+
+// PNG writes the board PNG representation into the writer.
+// An error is returned if there is there is an error writing data.
+// PNG takes the same options as SVG.
+func PNG(w io.Writer, b *chess.Board, opts ...func(*Encoder)) error {
+	e := new(w, opts)
+	return e.EncodePNG(b)
+}
+
+// Raster returns the board drawn as an image.  Raster takes the
+// same options as SVG.
+func Raster(b *chess.Board, opts ...func(*Encoder)) *goimage.RGBA {
+	e := new(nil, opts)
+	return e.Raster(b)
+}
+
+// EncodePNG writes the board PNG representation into
+// the Encoder's writer.  An error is returned if there
+// is there is an error writing data.
+func (e *Encoder) EncodePNG(b *chess.Board) error {
+	return png.Encode(e.w, e.Raster(b))
+}
+
+// Raster returns the board drawn as an image the size of the
+// Encoder's images.  The pieces are rasterised from the same
+// SVGs as the SVG output.
+func (e *Encoder) Raster(b *chess.Board) *goimage.RGBA {
+	boardMap := b.SquareMap()
+	c := newCanvas(e.size, e.size)
+	scale := float64(e.size) / boardWidth
+
+	ranks := orderOfRanks
+	files := orderOfFiles
+	if e.perspective == chess.Black {
+		ranks = orderOfRanksBlack
+		files = orderOfFilesBlack
+	}
+	for i, rank := range ranks {
+		for j, file := range files {
+			// squares are whole pixels so they don't blend at their edges
+			x := float64(j * e.size / 8)
+			y := float64(i * e.size / 8)
+			x1 := float64((j + 1) * e.size / 8)
+			y1 := float64((i + 1) * e.size / 8)
+			sq := chess.NewSquare(file, rank)
+			c.rect(x, y, x1, y1, e.colorForSquare(sq), 1)
+			markColor, ok := e.marks[sq]
+			if ok {
+				_, _, _, a := markColor.RGBA()
+				c.rect(x, y, x1, y1, markColor, float64(a)/0xffff)
+			}
+			// draw piece
+			if p := boardMap[sq]; p != chess.NoPiece {
+				c.drawPiece(p, x, y, scale)
+			}
+			// draw rank text on file A
+			txtColor := e.colorForText(sq)
+			if j == 0 {
+				c.text(sq.Rank().String(), x+sqWidth*1/20*scale, y+sqHeight*5/20*scale, scale, false, txtColor)
+			}
+			// draw file text on rank 1
+			if i == 7 {
+				c.text(sq.File().String(), x+sqWidth*19/20*scale, y+(sqHeight-sqHeight*1/15)*scale, scale, true, txtColor)
+			}
+		}
+	}
+	for _, arrow := range e.arrows {
+		if isKnightMove(arrow.from, arrow.to) {
+			first, second := knightArrowLines(arrow, e.perspective)
+			c.arrowLine(first, arrow.color, scale)
+			c.arrowLine(second, arrow.color, scale)
+			c.arrowHead(second, arrow.color, scale)
+		} else {
+			x1, y1, x2, y2 := straightArrowLine(arrow, e.perspective)
+			line := [4]float32{x1, y1, x2, y2}
+			c.arrowLine(line, arrow.color, scale)
+			c.arrowHead(line, arrow.color, scale)
+		}
+	}
+	return c.img
+}
+
+func (c *canvas) arrowLine(line [4]float32, col color.Color, scale float64) {
+	from := point{float64(line[0]), float64(line[1])}.mul(scale)
+	to := point{float64(line[2]), float64(line[3])}.mul(scale)
+	lines := []polyline{{points: []point{from, to}}}
+	c.fill(stroke(lines, arrowWidth*scale, "butt", "miter", 4), col, arrowAlpha, nonZero)
+}
+
+// arrowHead draws the head at the end of the line like the SVG
+// marker, a triangle three stroke widths wide and two long.
+func (c *canvas) arrowHead(line [4]float32, col color.Color, scale float64) {
+	from := point{float64(line[0]), float64(line[1])}.mul(scale)
+	to := point{float64(line[2]), float64(line[3])}.mul(scale)
+	w := arrowWidth * scale
+	d := unit(to.sub(from))
+	n := normal(d).mul(1.5 * w)
+	c.fill([]polygon{{to.add(n), to.add(d.mul(2 * w)), to.sub(n)}}, col, arrowAlpha, nonZero)
+}
+
+// text draws the coordinate label with its baseline at y starting at x, or
+// ending at x if alignEnd is true.
+func (c *canvas) text(s string, x, y, scale float64, alignEnd bool, col color.Color) {
+	cell := float64(labelSize) / glyphHeight * scale
+	if alignEnd {
+		x -= float64(len(s)*glyphWidth) * cell
+	}
+	polys := []polygon{}
+	for _, r := range s {
+		glyph := glyphs[r]
+		for row, bits := range glyph {
+			top := y + float64(row-glyphBaseline)*cell
+			for k, bit := range bits {
+				if bit == '#' {
+					left := x + float64(k)*cell
+					polys = append(polys, rectangle(left, top, left+cell, top+cell))
+				}
+			}
+		}
+		x += float64(glyphWidth) * cell
+	}
+	c.fill(polys, col, 1, nonZero)
+}
+
+const (
+	// labelSize is the font size of the SVG coordinate labels.
+	labelSize     = 11
+	glyphWidth    = 6
+	glyphHeight   = 9
+	glyphBaseline = 7
+)
+
+// glyphs are bitmaps of the coordinate labels.  Rows below the baseline
+// are only used by descenders.
+var glyphs = map[rune][]string{
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'a': {".....", ".....", ".###.", "....#", ".####", "#...#", ".####"},
+	'b': {"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "####."},
+	'c': {".....", ".....", ".###.", "#....", "#....", "#...#", ".###."},
+	'd': {"....#", "....#", ".##.#", "#..##", "#...#", "#...#", ".####"},
+	'e': {".....", ".....", ".###.", "#...#", "#####", "#....", ".###."},
+	'f': {"..##.", ".#..#", ".#...", "###..", ".#...", ".#...", ".#..."},
+	'g': {".....", ".....", ".####", "#...#", "#...#", ".####", "....#", ".###."},
+	'h': {"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "#...#"},
+}
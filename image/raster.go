@@ -0,0 +1,328 @@
+package image
+
+import (
+	goimage "image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// point is a point in pixel coordinates.
+type point struct {
+	x, y float64
+}
+
+func (p point) add(q point) point {
+	return point{p.x + q.x, p.y + q.y}
+}
+
+func (p point) sub(q point) point {
+	return point{p.x - q.x, p.y - q.y}
+}
+
+func (p point) mul(f float64) point {
+	return point{p.x * f, p.y * f}
+}
+
+func (p point) length() float64 {
+	return math.Hypot(p.x, p.y)
+}
+
+// polygon is a closed polygon in pixel coordinates.
+type polygon []point
+
+// polyline is a flattened subpath which is closed back to its first point
+// if closed is true.
+type polyline struct {
+	points []point
+	closed bool
+}
+
+type fillRule int
+
+const (
+	nonZero fillRule = iota
+	evenOdd
+)
+
+const (
+	// subsamples is the number of scanlines sampled for every row of
+	// pixels, coverage along the scanlines is exact.
+	subsamples = 4
+	// tolerance is the length in pixels of the segments curves and
+	// circles are flattened into.
+	tolerance = 1.5
+)
+
+// canvas draws anti-aliased polygons onto an opaque image.
+type canvas struct {
+	img   *goimage.RGBA
+	cover []float64
+}
+
+func newCanvas(width, height int) *canvas {
+	return &canvas{
+		img:   goimage.NewRGBA(goimage.Rect(0, 0, width, height)),
+		cover: make([]float64, width+1),
+	}
+}
+
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// rect fills the rectangle between the given pixel coordinates.
+func (c *canvas) rect(x0, y0, x1, y1 float64, col color.Color, opacity float64) {
+	c.fill([]polygon{rectangle(x0, y0, x1, y1)}, col, opacity, nonZero)
+}
+
+// fill fills the polygons with the color, ignoring its alpha, blended
+// with the opacity.
+func (c *canvas) fill(polys []polygon, col color.Color, opacity float64, rule fillRule) {
+	bounds := c.img.Bounds()
+	edges := []edge{}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, poly := range polys {
+		for i, p := range poly {
+			q := poly[(i+1)%len(poly)]
+			minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+			minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+			switch {
+			case p.y < q.y:
+				edges = append(edges, edge{p.x, p.y, q.x, q.y, 1})
+			case p.y > q.y:
+				edges = append(edges, edge{q.x, q.y, p.x, p.y, -1})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+	top := clamp(int(math.Floor(minY)), 0, bounds.Dy())
+	bottom := clamp(int(math.Ceil(maxY)), 0, bounds.Dy())
+	left := clamp(int(math.Floor(minX)), 0, bounds.Dx())
+	right := clamp(int(math.Ceil(maxX))+1, 0, bounds.Dx())
+	r, g, b, _ := col.RGBA()
+	src := [3]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8)}
+
+	next := 0
+	active := []edge{}
+	crossings := []crossing{}
+	for y := top; y < bottom; y++ {
+		cover := c.cover[left:right]
+		for i := range cover {
+			cover[i] = 0
+		}
+		for s := 0; s < subsamples; s++ {
+			sy := float64(y) + (float64(s)+0.5)/subsamples
+			for next < len(edges) && edges[next].y0 <= sy {
+				active = append(active, edges[next])
+				next++
+			}
+			crossings = crossings[:0]
+			kept := active[:0]
+			for _, e := range active {
+				if e.y1 <= sy {
+					continue
+				}
+				kept = append(kept, e)
+				if e.y0 <= sy {
+					x := e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+					crossings = append(crossings, crossing{x, e.dir})
+				}
+			}
+			active = kept
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding, start := 0, 0.0
+			for _, cr := range crossings {
+				was := inside(winding, rule)
+				winding += cr.dir
+				if is := inside(winding, rule); is && !was {
+					start = cr.x
+				} else if was && !is {
+					c.span(start, cr.x, left, right)
+				}
+			}
+		}
+		for x := left; x < right; x++ {
+			a := math.Min(c.cover[x]/subsamples, 1) * opacity
+			if a <= 0 {
+				continue
+			}
+			i := c.img.PixOffset(x, y)
+			pix := c.img.Pix[i : i+4]
+			for k := 0; k < 3; k++ {
+				pix[k] = uint8(float64(pix[k])*(1-a) + src[k]*a + 0.5)
+			}
+			pix[3] = 0xff
+		}
+	}
+}
+
+// span adds the coverage of a scanline between x0 and x1.
+func (c *canvas) span(x0, x1 float64, left, right int) {
+	x0 = math.Max(x0, float64(left))
+	x1 = math.Min(x1, float64(right))
+	for x := int(math.Floor(x0)); float64(x) < x1 && x < right; x++ {
+		c.cover[x] += math.Min(x1, float64(x+1)) - math.Max(x0, float64(x))
+	}
+}
+
+func inside(winding int, rule fillRule) bool {
+	if rule == evenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func rectangle(x0, y0, x1, y1 float64) polygon {
+	return polygon{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+}
+
+func circle(center point, radius float64) polygon {
+	n := clampSegments(2 * math.Pi * radius / tolerance)
+	poly := make(polygon, n)
+	for i := range poly {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		poly[i] = point{center.x + radius*math.Cos(a), center.y + radius*math.Sin(a)}
+	}
+	return poly
+}
+
+func clampSegments(n float64) int {
+	return clamp(int(math.Ceil(n)), 8, 64)
+}
+
+// stroke returns polygons covering the lines stroked with the width.  The
+// polygons are turned the same way so they are filled as their union with
+// the non-zero rule.
+func stroke(lines []polyline, width float64, cap, join string, miterLimit float64) []polygon {
+	hw := width / 2
+	polys := []polygon{}
+	for _, line := range lines {
+		pts := dedupe(line.points, line.closed)
+		if len(pts) == 1 {
+			if cap == "round" {
+				polys = append(polys, circle(pts[0], hw))
+			}
+			continue
+		}
+		n := len(pts) - 1
+		if line.closed {
+			n = len(pts)
+		}
+		for i := 0; i < n; i++ {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			off := normal(b.sub(a)).mul(hw)
+			polys = append(polys, polygon{a.add(off), b.add(off), b.sub(off), a.sub(off)})
+		}
+		for i := range pts {
+			if !line.closed && (i == 0 || i == n) {
+				continue
+			}
+			prev := pts[(i+len(pts)-1)%len(pts)]
+			next := pts[(i+1)%len(pts)]
+			if j := strokeJoin(prev, pts[i], next, hw, join, miterLimit); j != nil {
+				polys = append(polys, j)
+			}
+		}
+		if !line.closed && cap == "round" {
+			polys = append(polys, circle(pts[0], hw), circle(pts[n], hw))
+		}
+	}
+	for _, poly := range polys {
+		if area(poly) < 0 {
+			for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+				poly[i], poly[j] = poly[j], poly[i]
+			}
+		}
+	}
+	return polys
+}
+
+// strokeJoin returns the polygon filling the outside of the corner at p.
+func strokeJoin(prev, p, next point, hw float64, join string, miterLimit float64) polygon {
+	d0, d1 := unit(p.sub(prev)), unit(next.sub(p))
+	cross := d0.x*d1.y - d0.y*d1.x
+	dot := d0.x*d1.x + d0.y*d1.y
+	turn := math.Atan2(math.Abs(cross), dot)
+	if turn < 1e-6 {
+		return nil
+	}
+	side := -1.0
+	if cross < 0 {
+		side = 1
+	}
+	n0, n1 := normal(d0).mul(hw*side), normal(d1).mul(hw*side)
+	bevel := polygon{p, p.add(n0), p.add(n1)}
+	switch join {
+	case "round":
+		// Corners of flattened curves are too small to tell from bevels.
+		if turn < math.Pi/6 {
+			return bevel
+		}
+		return circle(p, hw)
+	case "miter":
+		ratio := 1 / math.Cos(turn/2)
+		if ratio > miterLimit {
+			return bevel
+		}
+		tip := p.add(unit(n0.add(n1)).mul(hw * ratio))
+		return polygon{p, p.add(n0), tip, p.add(n1)}
+	}
+	return bevel
+}
+
+// dedupe removes repeated points and, of closed lines, the last point if
+// it repeats the first.
+func dedupe(pts []point, closed bool) []point {
+	out := []point{}
+	for _, p := range pts {
+		if len(out) == 0 || p.sub(out[len(out)-1]).length() > 1e-9 {
+			out = append(out, p)
+		}
+	}
+	if closed && len(out) > 1 && out[0].sub(out[len(out)-1]).length() <= 1e-9 {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func unit(p point) point {
+	l := p.length()
+	if l == 0 {
+		return p
+	}
+	return p.mul(1 / l)
+}
+
+func normal(d point) point {
+	return unit(point{-d.y, d.x})
+}
+
+func area(poly polygon) float64 {
+	a := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		a += p.x*q.y - q.x*p.y
+	}
+	return a / 2
+}
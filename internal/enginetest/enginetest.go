@@ -0,0 +1,50 @@
+// Package enginetest runs the test binary as a chess engine so the engine
+// clients of the uci, xboard and dependent packages can be tested without
+// external executables.
+package enginetest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/notnil/chess/uci"
+)
+
+// env holds the name of the engine the test binary runs as.
+const env = "CHESS_TEST_ENGINE"
+
+// Engine runs the engine side of a protocol reading commands from r and
+// writing responses to w until the input ends.
+type Engine func(r io.Reader, w io.Writer)
+
+// Server returns an Engine serving the searcher with uci.Server.
+func Server(searcher uci.Searcher, opts ...func(srv *uci.Server)) Engine {
+	return func(r io.Reader, w io.Writer) {
+		uci.NewServer(searcher, opts...).Serve(r, w)
+	}
+}
+
+// Main is called from TestMain with the engines of the package by name.
+// If the test binary was started by Path it runs the engine and exits,
+// otherwise it runs the tests.
+func Main(m *testing.M, engines map[string]Engine) {
+	if name := os.Getenv(env); name != "" {
+		engine, ok := engines[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "enginetest: unknown engine %s\n", name)
+			os.Exit(2)
+		}
+		engine(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// Path returns the path of the test binary and makes the processes started
+// from it run the named engine.
+func Path(name string) string {
+	os.Setenv(env, name)
+	return os.Args[0]
+}
@@ -0,0 +1,46 @@
+// Package movecode encodes moves as 16 bit codes for the database and
+// opening packages.  Codes hold the origin, destination and promotion of a
+// move, or the dropped piece type and its square, so they are independent
+// of move generation order.
+package movecode
+
+import (
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// dropBit marks the codes of drops which hold the dropped piece type in
+// place of the origin square.
+const dropBit uint16 = 1 << 15
+
+// Encode returns the code of the move.
+func Encode(m *chess.Move) uint16 {
+	if m.Drop() != chess.NoPieceType {
+		return dropBit | uint16(m.Drop())<<9 | uint16(m.S2())<<3
+	}
+	return uint16(m.S1())<<9 | uint16(m.S2())<<3 | uint16(m.Promo())
+}
+
+// String returns the code in UCI notation, or like "N@d3" for drops.
+func String(code uint16) string {
+	s2 := chess.Square((code >> 3) & 63)
+	if code&dropBit != 0 {
+		drop := chess.PieceType((code >> 9) & 63)
+		return strings.ToUpper(drop.String()) + "@" + s2.String()
+	}
+	s1 := chess.Square((code >> 9) & 63)
+	promo := chess.PieceType(code & 7)
+	return s1.String() + s2.String() + promo.String()
+}
+
+// Find returns the valid move in the position matching the code or nil if
+// there is none.
+func Find(pos *chess.Position, code uint16) *chess.Move {
+	for _, m := range pos.ValidMoves() {
+		if Encode(m) == code {
+			return m
+		}
+	}
+	return nil
+}
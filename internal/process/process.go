@@ -0,0 +1,189 @@
+// Package process runs the engine executables of the uci and xboard
+// packages.
+package process
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExitError is returned when the engine process exits while a command is
+// waiting for its response.
+type ExitError struct {
+	// ExitCode is the exit status of the process or -1 if it was
+	// terminated by a signal.
+	ExitCode int
+	// Stderr is the end of the output the process wrote to stderr.
+	Stderr string
+	// Err is the error returned from waiting on the process, nil if the
+	// process exited with status 0.
+	Err error
+
+	prefix string
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	s := fmt.Sprintf("%s: engine exited with status %d", e.prefix, e.ExitCode)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		s += ": " + stderr
+	}
+	return s
+}
+
+// Unwrap returns the error returned from waiting on the process.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// maxStderr is the number of bytes of stderr kept for ExitError.
+const maxStderr = 4096
+
+// Process is a running engine executable.
+type Process struct {
+	prefix string
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	lines  chan string
+	exited chan struct{}
+	stderr *tailBuffer
+	err    error
+}
+
+// Start starts the executable and reads its output line by line until it
+// exits.  The prefix is the package name errors start with.
+func Start(prefix, path string) (*Process, error) {
+	p := &Process{
+		prefix: prefix,
+		cmd:    exec.Command(path),
+		lines:  make(chan string, 64),
+		exited: make(chan struct{}),
+		stderr: &tailBuffer{max: maxStderr},
+	}
+	p.cmd.Stderr = p.stderr
+	in, err := p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.in = in
+	if err := p.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s: failed to start engine %s %w", prefix, path, err)
+	}
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			p.lines <- scanner.Text()
+		}
+		p.err = p.cmd.Wait()
+		close(p.exited)
+		close(p.lines)
+	}()
+	return p, nil
+}
+
+// Lines returns the lines written by the process to stdout.  The channel
+// is closed when the process exits.
+func (p *Process) Lines() <-chan string {
+	return p.lines
+}
+
+// Send writes the line to the process.  An *ExitError is returned if the
+// process has exited.
+func (p *Process) Send(s string) error {
+	if _, err := fmt.Fprintln(p.in, s); err != nil {
+		// the pipe is closed when the process exits
+		select {
+		case <-p.exited:
+			return p.ExitError()
+		case <-time.After(time.Second):
+			return err
+		}
+	}
+	return nil
+}
+
+// Quit sends the quit command and kills the process if it hasn't exited
+// after the timeout.
+func (p *Process) Quit(quit string, timeout time.Duration) error {
+	fmt.Fprintln(p.in, quit)
+	p.in.Close()
+	p.discard()
+	select {
+	case <-p.exited:
+		return nil
+	case <-time.After(timeout):
+	}
+	if err := p.cmd.Process.Kill(); err != nil && !p.HasExited() {
+		return err
+	}
+	<-p.exited
+	return nil
+}
+
+// Kill kills the process without waiting for it to exit.
+func (p *Process) Kill() {
+	p.in.Close()
+	p.cmd.Process.Kill()
+	p.discard()
+}
+
+// discard reads the remaining output so the process isn't blocked
+// writing it.
+func (p *Process) discard() {
+	go func() {
+		for range p.lines {
+		}
+	}()
+}
+
+// HasExited returns true if the process has exited.
+func (p *Process) HasExited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExitError waits for the process to exit and returns its *ExitError.
+func (p *Process) ExitError() error {
+	<-p.exited
+	e := &ExitError{ExitCode: -1, Stderr: p.stderr.String(), Err: p.err, prefix: p.prefix}
+	if p.cmd.ProcessState != nil {
+		e.ExitCode = p.cmd.ProcessState.ExitCode()
+	}
+	return e
+}
+
+// tailBuffer is a writer which keeps the last max bytes written.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	b   []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.b = append(t.b, p...)
+	if len(t.b) > t.max {
+		t.b = t.b[len(t.b)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.b)
+}
@@ -0,0 +1,38 @@
+package chess
+
+// KingOfTheHill is standard chess in which a player also wins by moving
+// their king to one of the four central squares (d4, e4, d5 and e5).
+type KingOfTheHill struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (KingOfTheHill) Name() string {
+	return "King of the Hill"
+}
+
+// StartingPosition implements the Variant interface.
+func (KingOfTheHill) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(startFEN, KingOfTheHill{})
+	return pos
+}
+
+// Status implements the Variant interface.  In addition to the standard
+// methods it returns KingInCenter if the player who just moved reached
+// the center with their king.
+func (KingOfTheHill) Status(pos *Position) (Outcome, Method) {
+	bbCenter := bbForSquare(D4) | bbForSquare(E4) | bbForSquare(D5) | bbForSquare(E5)
+	if pos.turn == Black && pos.board.bbWhiteKing&bbCenter != 0 {
+		return WhiteWon, KingInCenter
+	}
+	if pos.turn == White && pos.board.bbBlackKing&bbCenter != 0 {
+		return BlackWon, KingInCenter
+	}
+	return Standard{}.Status(pos)
+}
+
+// InsufficientMaterial implements the Variant interface and always
+// returns false since a bare king can still reach the center.
+func (KingOfTheHill) InsufficientMaterial(pos *Position) bool {
+	return false
+}
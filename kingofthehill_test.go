@@ -0,0 +1,28 @@
+package chess
+
+import "testing"
+
+func TestKingOfTheHill(t *testing.T) {
+	g := NewGame(UseVariant(KingOfTheHill{}))
+	for _, m := range []string{"e4", "Nf6", "Ke2", "Ng8", "Ke3", "Nf6"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.Outcome() != NoOutcome {
+		t.Fatalf("expected game in progress but got %s", g.Outcome())
+	}
+	if err := g.MoveStr("Kd4"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != KingInCenter {
+		t.Fatalf("expected white to win with the king in the center but got %s %s", g.Outcome(), g.Method())
+	}
+	opt, err := VariantFEN(KingOfTheHill{}, "8/8/8/8/8/8/8/K6k w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := NewGame(opt); g.Outcome() != NoOutcome {
+		t.Fatal("expected no insufficient material draw in king of the hill")
+	}
+}
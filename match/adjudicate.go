@@ -0,0 +1,65 @@
+package match
+
+import (
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// mateCP is the centipawn score of mate in 0 moves.
+const mateCP = 100000
+
+// adjudicator keeps the scores of consecutive moves.
+type adjudicator struct {
+	Adjudication
+	last        [3]int
+	resignCount [3]int
+	drawCount   int
+}
+
+// add records the score of the move by the color and returns the loser,
+// or NoColor for a draw, if the game is adjudicated.
+func (a *adjudicator) add(g *chess.Game, color chess.Color, info uci.Info) (chess.Color, bool) {
+	score := centipawns(info.Score)
+	opponent := a.last[color.Other()]
+	a.last[color] = score
+	if a.ResignMoves > 0 {
+		if score <= -a.ResignScore && opponent >= a.ResignScore {
+			a.resignCount[color]++
+		} else {
+			a.resignCount[color] = 0
+		}
+		if a.resignCount[color] >= a.ResignMoves {
+			return color, true
+		}
+	}
+	if a.DrawMoves > 0 && len(g.Moves())/2+1 >= a.DrawMoveNumber {
+		if abs(score) <= a.DrawScore {
+			a.drawCount++
+		} else {
+			a.drawCount = 0
+		}
+		// both players' moves are counted
+		if a.drawCount >= 2*a.DrawMoves {
+			return chess.NoColor, true
+		}
+	}
+	return chess.NoColor, false
+}
+
+// centipawns returns the score with mate scores beyond any centipawn score.
+func centipawns(s uci.Score) int {
+	switch {
+	case s.Mate > 0:
+		return mateCP - s.Mate
+	case s.Mate < 0:
+		return -mateCP - s.Mate
+	}
+	return s.CP
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,78 @@
+package match
+
+import (
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// clock keeps the time left of both players.
+type clock struct {
+	tc    TimeControl
+	left  [3]time.Duration
+	moves [3]int
+}
+
+func newClock(tc TimeControl) *clock {
+	c := &clock{tc: tc}
+	c.left[chess.White] = tc.Time
+	c.left[chess.Black] = tc.Time
+	return c
+}
+
+// hasClock returns true if the players have a clock instead of fixed
+// limits per move.
+func (c *clock) hasClock() bool {
+	return c.tc.MoveTime == 0 && c.tc.Time > 0
+}
+
+// clocks returns the clocks sent to the player of the color.
+func (c *clock) clocks(color chess.Color) Clocks {
+	clocks := Clocks{MoveTime: c.tc.MoveTime, Depth: c.tc.Depth, Nodes: c.tc.Nodes}
+	if !c.hasClock() {
+		return clocks
+	}
+	clocks.WhiteTime = c.left[chess.White]
+	clocks.BlackTime = c.left[chess.Black]
+	clocks.WhiteIncrement = c.tc.Increment
+	clocks.BlackIncrement = c.tc.Increment
+	if c.tc.Moves > 0 {
+		clocks.MovesToGo = c.tc.Moves - c.moves[color]%c.tc.Moves
+	}
+	return clocks
+}
+
+// noDeadline is the time allowed for moves limited by depth or nodes.
+const noDeadline = 24 * time.Hour
+
+// deadline returns how long the player of the color has for the move.
+func (c *clock) deadline(color chess.Color) time.Duration {
+	switch {
+	case c.tc.MoveTime > 0:
+		return c.tc.MoveTime + c.tc.Margin
+	case c.hasClock():
+		return c.left[color] + c.tc.Margin
+	}
+	return noDeadline
+}
+
+// spend subtracts the time of the move and returns false if the player
+// exceeded its time.
+func (c *clock) spend(color chess.Color, elapsed time.Duration) bool {
+	if c.tc.MoveTime > 0 {
+		return elapsed <= c.tc.MoveTime+c.tc.Margin
+	}
+	if !c.hasClock() {
+		return true
+	}
+	c.left[color] -= elapsed
+	if c.left[color] < -c.tc.Margin {
+		return false
+	}
+	c.left[color] += c.tc.Increment
+	c.moves[color]++
+	if c.tc.Moves > 0 && c.moves[color]%c.tc.Moves == 0 {
+		c.left[color] += c.tc.Time
+	}
+	return true
+}
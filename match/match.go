@@ -0,0 +1,284 @@
+// Package match plays games between engines with time controls and
+// adjudication and writes the games as PGN.
+package match
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// TimeControl is the time control of every game.  MoveTime, Depth and Nodes
+// limit every move instead of a clock if set.
+type TimeControl struct {
+	// Moves is the number of moves per period or 0 for the whole game.
+	Moves     int
+	Time      time.Duration
+	Increment time.Duration
+	MoveTime  time.Duration
+	Depth     int
+	Nodes     int
+	// Margin is how long a player may exceed its time before losing.
+	Margin time.Duration
+}
+
+// String returns the TimeControl PGN tag value such as 40/300+2.
+func (tc TimeControl) String() string {
+	switch {
+	case tc.MoveTime > 0:
+		return strconv.FormatFloat(tc.MoveTime.Seconds(), 'f', -1, 64) + "/move"
+	case tc.Time <= 0:
+		return "-"
+	}
+	s := strconv.FormatFloat(tc.Time.Seconds(), 'f', -1, 64)
+	if tc.Moves > 0 {
+		s = strconv.Itoa(tc.Moves) + "/" + s
+	}
+	if tc.Increment > 0 {
+		s += "+" + strconv.FormatFloat(tc.Increment.Seconds(), 'f', -1, 64)
+	}
+	return s
+}
+
+// Adjudication ends games early based on the scores reported by the
+// players.  Zero values disable a rule.
+type Adjudication struct {
+	// ResignScore and ResignMoves resign for a player whose score is at
+	// most -ResignScore centipawns for ResignMoves consecutive moves while
+	// the opponent's score is at least ResignScore.
+	ResignScore int
+	ResignMoves int
+	// DrawScore and DrawMoves draw the game once both scores are within
+	// DrawScore centipawns of zero for DrawMoves consecutive moves, starting
+	// from move DrawMoveNumber.
+	DrawScore      int
+	DrawMoves      int
+	DrawMoveNumber int
+	// MaxMoves draws the game after the number of moves.
+	MaxMoves int
+}
+
+// Result is the score of a match from the first player's point of view.
+type Result struct {
+	Wins   int
+	Losses int
+	Draws  int
+	Games  []*chess.Game
+}
+
+// Score returns the points of the first player, a win counting 1 and a
+// draw 1/2.
+func (r Result) Score() float64 {
+	return float64(r.Wins) + float64(r.Draws)/2
+}
+
+// Match plays games between two players with alternating colours.
+type Match struct {
+	players  [2]Player
+	games    int
+	tc       TimeControl
+	adj      Adjudication
+	openings []Opening
+	pgn      io.Writer
+	event    string
+	onGame   func(g *chess.Game)
+}
+
+// WithTimeControl is an option for the New function to set the time
+// control.  The default is one second per move.
+func WithTimeControl(tc TimeControl) func(m *Match) {
+	return func(m *Match) {
+		m.tc = tc
+	}
+}
+
+// WithAdjudication is an option for the New function to adjudicate games.
+func WithAdjudication(adj Adjudication) func(m *Match) {
+	return func(m *Match) {
+		m.adj = adj
+	}
+}
+
+// WithOpenings is an option for the New function to start games from the
+// openings.  Every opening is played twice so each player has both colours.
+func WithOpenings(openings []Opening) func(m *Match) {
+	return func(m *Match) {
+		m.openings = openings
+	}
+}
+
+// WithPGN is an option for the New function to write every game to w as
+// PGN once it is finished.
+func WithPGN(w io.Writer) func(m *Match) {
+	return func(m *Match) {
+		m.pgn = w
+	}
+}
+
+// WithEvent is an option for the New function to set the Event PGN tag.
+func WithEvent(name string) func(m *Match) {
+	return func(m *Match) {
+		m.event = name
+	}
+}
+
+// OnGame is an option for the New function to call f after every game.
+func OnGame(f func(g *chess.Game)) func(m *Match) {
+	return func(m *Match) {
+		m.onGame = f
+	}
+}
+
+// New returns a match of the number of games between the players.  The
+// first player has white in odd games.
+func New(a, b Player, games int, opts ...func(m *Match)) *Match {
+	m := &Match{
+		players: [2]Player{a, b},
+		games:   games,
+		tc:      TimeControl{MoveTime: time.Second, Margin: time.Second},
+		event:   "Engine Match",
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Run plays the games.  Games are adjudicated as losses for players that
+// send illegal moves, exceed their time or fail.  The context's error is
+// returned if the context is done before the match ends.
+func (m *Match) Run(ctx context.Context) (Result, error) {
+	result := Result{}
+	for i := 0; i < m.games; i++ {
+		white, black := m.players[i%2], m.players[(i+1)%2]
+		o := Opening{}
+		if len(m.openings) > 0 {
+			o = m.openings[(i/2)%len(m.openings)]
+		}
+		g, err := m.play(ctx, i+1, white, black, o)
+		if err != nil {
+			return result, err
+		}
+		result.Games = append(result.Games, g)
+		switch {
+		case g.Outcome() == chess.Draw:
+			result.Draws++
+		case (g.Outcome() == chess.WhiteWon) == (i%2 == 0):
+			result.Wins++
+		default:
+			result.Losses++
+		}
+		if m.pgn != nil {
+			if _, err := fmt.Fprintf(m.pgn, "%s\n\n", g); err != nil {
+				return result, err
+			}
+		}
+		if m.onGame != nil {
+			m.onGame(g)
+		}
+	}
+	return result, nil
+}
+
+// Termination values written to the Termination PGN tag.
+const (
+	terminationAdjudication = "adjudication"
+	terminationTimeForfeit  = "time forfeit"
+	terminationIllegalMove  = "rules infraction"
+	terminationAbandoned    = "abandoned"
+)
+
+// play plays one game and returns it once it is finished.
+func (m *Match) play(ctx context.Context, round int, white, black Player, o Opening) (*chess.Game, error) {
+	g, err := o.game()
+	if err != nil {
+		return nil, err
+	}
+	g.AddTagPair("Event", m.event)
+	g.AddTagPair("Round", strconv.Itoa(round))
+	g.AddTagPair("White", white.Name())
+	g.AddTagPair("Black", black.Name())
+	g.AddTagPair("TimeControl", m.tc.String())
+	if start := g.Positions()[0]; start.String() != chess.StartingPosition().String() {
+		fen := start.String()
+		if _, ok := start.Variant().(chess.Chess960); ok {
+			fen = start.XFENString()
+		}
+		g.AddTagPair("SetUp", "1")
+		g.AddTagPair("FEN", fen)
+	}
+	for _, p := range []Player{white, black} {
+		if err := p.NewGame(ctx); err != nil {
+			return nil, err
+		}
+	}
+	clock := newClock(m.tc)
+	adj := &adjudicator{Adjudication: m.adj}
+	for g.Outcome() == chess.NoOutcome {
+		if m.adj.MaxMoves > 0 && len(g.Moves())/2 >= m.adj.MaxMoves {
+			g.Draw(chess.DrawOffer)
+			g.AddTagPair("Termination", terminationAdjudication)
+			break
+		}
+		turn := g.Position().Turn()
+		player := white
+		if turn == chess.Black {
+			player = black
+		}
+		moveCtx, cancel := context.WithTimeout(ctx, clock.deadline(turn))
+		start := time.Now()
+		move, info, err := player.Play(moveCtx, g, clock.clocks(turn))
+		elapsed := time.Since(start)
+		timedOut := moveCtx.Err() != nil
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if timedOut || !clock.spend(turn, elapsed) {
+			return m.forfeit(g, turn, terminationTimeForfeit), nil
+		}
+		if err != nil {
+			// e.g. the engine crashed
+			return m.forfeit(g, turn, terminationAbandoned), nil
+		}
+		if move == nil || g.Move(move) != nil {
+			return m.forfeit(g, turn, terminationIllegalMove), nil
+		}
+		g.AddComment(evalComment(info, elapsed))
+		if loser, ok := adj.add(g, turn, info); ok {
+			if loser == chess.NoColor {
+				g.Draw(chess.DrawOffer)
+			} else {
+				g.Resign(loser)
+			}
+			g.AddTagPair("Termination", terminationAdjudication)
+		}
+	}
+	g.AddTagPair("Result", g.Outcome().String())
+	return g, nil
+}
+
+// forfeit ends the game as a loss for the color.
+func (m *Match) forfeit(g *chess.Game, color chess.Color, termination string) *chess.Game {
+	g.Resign(color)
+	g.AddTagPair("Termination", termination)
+	g.AddTagPair("Result", g.Outcome().String())
+	return g
+}
+
+// evalComment returns the comment with the score from the mover's point of
+// view, depth and time such as +0.25/12 0.51s.
+func evalComment(info uci.Info, elapsed time.Duration) string {
+	score := fmt.Sprintf("%+.2f", float64(info.Score.CP)/100)
+	if info.Score.Mate > 0 {
+		score = fmt.Sprintf("+M%d", info.Score.Mate)
+	} else if info.Score.Mate < 0 {
+		score = fmt.Sprintf("-M%d", -info.Score.Mate)
+	}
+	return fmt.Sprintf("%s/%d %.2fs", score, info.Depth, elapsed.Seconds())
+}
@@ -0,0 +1,192 @@
+package match_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/enginetest"
+	"github.com/notnil/chess/match"
+	"github.com/notnil/chess/uci"
+)
+
+// TestMain makes the test binary act as a UCI engine playing the first
+// legal move so UCIPlayer can be tested.
+func TestMain(m *testing.M) {
+	enginetest.Main(m, map[string]enginetest.Engine{
+		"server": enginetest.Server(firstMoveSearcher{}),
+	})
+}
+
+type firstMoveSearcher struct{}
+
+func (firstMoveSearcher) Search(ctx context.Context, pos *chess.Position, cmd uci.CmdGo, send func(uci.Info)) (*chess.Move, *chess.Move) {
+	m := firstMove(pos)
+	send(uci.Info{Depth: 3, Score: uci.Score{CP: 25}, PV: []*chess.Move{m}})
+	return m, nil
+}
+
+func firstMove(pos *chess.Position) *chess.Move {
+	moves := pos.ValidMoves()
+	sort.Slice(moves, func(i, j int) bool { return moves[i].String() < moves[j].String() })
+	return moves[0]
+}
+
+// player plays the first legal move in alphabetical order reporting score.
+type player struct {
+	name  string
+	score int
+	play  func(ctx context.Context, g *chess.Game) (*chess.Move, error)
+}
+
+func (p *player) Name() string {
+	return p.name
+}
+
+func (p *player) NewGame(ctx context.Context) error {
+	return nil
+}
+
+func (p *player) Play(ctx context.Context, g *chess.Game, clocks match.Clocks) (*chess.Move, uci.Info, error) {
+	info := uci.Info{Depth: 1, Score: uci.Score{CP: p.score}}
+	if p.play != nil {
+		m, err := p.play(ctx, g)
+		return m, info, err
+	}
+	return firstMove(g.Position()), info, nil
+}
+
+func TestMatch(t *testing.T) {
+	a := &player{name: "A"}
+	b := &player{name: "B"}
+	buf := &bytes.Buffer{}
+	adj := match.Adjudication{MaxMoves: 10}
+	result, err := match.New(a, b, 2, match.WithAdjudication(adj), match.WithPGN(buf)).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Draws != 2 || len(result.Games) != 2 {
+		t.Fatalf("expected two adjudicated draws but got %+v", result)
+	}
+	g := result.Games[1]
+	if g.GetTagPair("White").Value != "B" || g.GetTagPair("Black").Value != "A" {
+		t.Fatal("expected colours to alternate")
+	}
+	if g.GetTagPair("Termination").Value != "adjudication" || len(g.Moves()) != 20 {
+		t.Fatalf("expected adjudication after 10 moves but got %d moves", len(g.Moves()))
+	}
+	pgn := buf.String()
+	if strings.Count(pgn, `[Result "1/2-1/2"]`) != 2 || !strings.Contains(pgn, "1. a3 { +0.00/1") {
+		t.Fatalf("expected pgn with evals but got %s", pgn)
+	}
+}
+
+func TestMatchForfeits(t *testing.T) {
+	illegal := &player{name: "Illegal", play: func(ctx context.Context, g *chess.Game) (*chess.Move, error) {
+		return chess.UCINotation{}.Decode(nil, "e2e5")
+	}}
+	slow := &player{name: "Slow", play: func(ctx context.Context, g *chess.Game) (*chess.Move, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	crashed := &player{name: "Crashed", play: func(ctx context.Context, g *chess.Game) (*chess.Move, error) {
+		return nil, errors.New("engine exited")
+	}}
+	tc := match.TimeControl{Time: 50 * time.Millisecond, Margin: 10 * time.Millisecond}
+	tests := []struct {
+		p           match.Player
+		termination string
+	}{
+		{illegal, "rules infraction"},
+		{slow, "time forfeit"},
+		{crashed, "abandoned"},
+	}
+	for _, test := range tests {
+		result, err := match.New(test.p, &player{name: "Good"}, 1, match.WithTimeControl(tc)).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := result.Games[0]
+		if result.Losses != 1 || g.Outcome() != chess.BlackWon || g.GetTagPair("Termination").Value != test.termination {
+			t.Fatalf("expected %s loss for %s but got %+v %s", test.termination, test.p.Name(), result, g)
+		}
+	}
+}
+
+func TestMatchResignAdjudication(t *testing.T) {
+	winning := &player{name: "Winning", score: 600}
+	losing := &player{name: "Losing", score: -600}
+	adj := match.Adjudication{ResignScore: 500, ResignMoves: 3}
+	result, err := match.New(winning, losing, 2, match.WithAdjudication(adj)).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Wins != 2 {
+		t.Fatalf("expected two wins by adjudication but got %+v", result)
+	}
+	if n := len(result.Games[0].Moves()); n != 6 {
+		t.Fatalf("expected resignation after 3 moves each but got %d moves", n)
+	}
+}
+
+func TestMatchOpenings(t *testing.T) {
+	epd := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - bm e5; id \"e4\";\n"
+	openings, err := match.OpeningsFromEPD(strings.NewReader(epd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgnOpenings, err := match.OpeningsFromPGN(strings.NewReader("[Event \"A\"]\n\n1. d4 d5 *\n\n[Event \"B\"]\n\n1. c4 *\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pgnOpenings) != 2 || len(pgnOpenings[0].Moves) != 2 {
+		t.Fatalf("expected two pgn openings but got %+v", pgnOpenings)
+	}
+	openings = append(openings, pgnOpenings...)
+	adj := match.Adjudication{MaxMoves: 3}
+	result, err := match.New(&player{name: "A"}, &player{name: "B"}, 6, match.WithOpenings(openings), match.WithAdjudication(adj)).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := result.Games[1].GetTagPair("FEN"); fen == nil || !strings.HasPrefix(fen.Value, "rnbqkbnr/pppppppp/8/8/4P3") {
+		t.Fatalf("expected epd start position but got %v", fen)
+	}
+	if moves := result.Games[2].Moves(); moves[0].String() != "d2d4" || moves[1].String() != "d7d5" {
+		t.Fatalf("expected pgn opening but got %v", moves)
+	}
+}
+
+func TestUCIPlayer(t *testing.T) {
+	engines := []*uci.Engine{}
+	for i := 0; i < 2; i++ {
+		eng, err := uci.New(enginetest.Path("server"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eng.Close()
+		if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+			t.Fatal(err)
+		}
+		engines = append(engines, eng)
+	}
+	a := match.UCIPlayer("First A", engines[0])
+	b := match.UCIPlayer("First B", engines[1])
+	tc := match.TimeControl{Moves: 40, Time: 10 * time.Second, Increment: time.Second, Margin: time.Second}
+	adj := match.Adjudication{DrawScore: 30, DrawMoves: 5, DrawMoveNumber: 1}
+	result, err := match.New(a, b, 1, match.WithTimeControl(tc), match.WithAdjudication(adj)).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := result.Games[0]
+	if g.Outcome() != chess.Draw || len(g.Moves()) != 10 || g.GetTagPair("TimeControl").Value != "40/10+1" {
+		t.Fatalf("expected draw adjudication after 5 moves but got %s", g)
+	}
+	if !strings.Contains(g.String(), "{ +0.25/3") {
+		t.Fatalf("expected engine evals but got %s", g)
+	}
+}
@@ -0,0 +1,92 @@
+package match
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/opening"
+)
+
+// Opening is the start of a game: the moves are played from the position
+// before the players take over.
+type Opening struct {
+	Position *chess.Position
+	Moves    []*chess.Move
+}
+
+// OpeningsFromPGN returns the starting position and moves of every game in
+// the PGN.
+func OpeningsFromPGN(r io.Reader) ([]Opening, error) {
+	openings := []Opening{}
+	scanner := chess.NewScanner(r)
+	for scanner.Scan() {
+		g := scanner.Next()
+		if len(g.TagPairs()) == 0 && len(g.Moves()) == 0 {
+			// trailing blank lines are scanned as an empty game
+			continue
+		}
+		openings = append(openings, Opening{Position: g.Positions()[0], Moves: g.Moves()})
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return openings, nil
+}
+
+// OpeningsFromEPD returns the positions of the EPD lines.  Operations such
+// as "bm" or "id" after the four position fields are ignored.
+func OpeningsFromEPD(r io.Reader) ([]Opening, error) {
+	openings := []Opening{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("match: invalid epd %s", scanner.Text())
+		}
+		fen := strings.Join(fields[:4], " ") + " 0 1"
+		opt, err := chess.FEN(fen, false)
+		if err != nil {
+			return nil, err
+		}
+		openings = append(openings, Opening{Position: chess.NewGame(opt).Position()})
+	}
+	return openings, scanner.Err()
+}
+
+// OpeningsFromBook returns every opening of the book.
+func OpeningsFromBook(book opening.Book) []Opening {
+	openings := []Opening{}
+	for _, o := range book.Possible(nil) {
+		g := o.Game()
+		openings = append(openings, Opening{Position: g.Positions()[0], Moves: g.Moves()})
+	}
+	return openings
+}
+
+// game returns a new game starting with the opening.
+func (o Opening) game() (*chess.Game, error) {
+	if o.Position == nil {
+		o.Position = chess.StartingPosition()
+	}
+	fen := o.Position.String()
+	if _, ok := o.Position.Variant().(chess.Chess960); ok {
+		fen = o.Position.XFENString()
+	}
+	opt, err := chess.VariantFEN(o.Position.Variant(), fen)
+	if err != nil {
+		return nil, err
+	}
+	g := chess.NewGame(opt)
+	for _, m := range o.Moves {
+		if err := g.Move(m); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,72 @@
+package match
+
+import (
+	"context"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// Player plays the moves of one side in a match.
+type Player interface {
+	// Name returns the name written to the White and Black PGN tags.
+	Name() string
+	// NewGame is called before every game.
+	NewGame(ctx context.Context) error
+	// Play returns the move for the side to move in the game's position
+	// with the time left on the clocks and the info of the search.  The
+	// context is done when the player runs out of time.
+	Play(ctx context.Context, game *chess.Game, clocks Clocks) (*chess.Move, uci.Info, error)
+}
+
+// Clocks is the time control of the next move.  Durations are zero
+// without a clock.
+type Clocks struct {
+	WhiteTime      time.Duration
+	BlackTime      time.Duration
+	WhiteIncrement time.Duration
+	BlackIncrement time.Duration
+	MovesToGo      int
+	MoveTime       time.Duration
+	Depth          int
+	Nodes          int
+}
+
+// UCIPlayer returns a Player for the engine.  CmdUCI should be run before
+// the match.
+func UCIPlayer(name string, eng *uci.Engine) Player {
+	return &uciPlayer{name: name, eng: eng}
+}
+
+type uciPlayer struct {
+	name string
+	eng  *uci.Engine
+}
+
+func (p *uciPlayer) Name() string {
+	return p.name
+}
+
+func (p *uciPlayer) NewGame(ctx context.Context) error {
+	return p.eng.RunContext(ctx, uci.CmdUCINewGame, uci.CmdIsReady)
+}
+
+func (p *uciPlayer) Play(ctx context.Context, game *chess.Game, clocks Clocks) (*chess.Move, uci.Info, error) {
+	cmdPos := uci.CmdPosition{Position: game.Positions()[0], Moves: game.Moves()}
+	cmdGo := uci.CmdGo{
+		WhiteTime:      clocks.WhiteTime,
+		BlackTime:      clocks.BlackTime,
+		WhiteIncrement: clocks.WhiteIncrement,
+		BlackIncrement: clocks.BlackIncrement,
+		MovesToGo:      clocks.MovesToGo,
+		MoveTime:       clocks.MoveTime,
+		Depth:          clocks.Depth,
+		Nodes:          clocks.Nodes,
+	}
+	if err := p.eng.RunContext(ctx, cmdPos, cmdGo); err != nil {
+		return nil, uci.Info{}, err
+	}
+	results := p.eng.SearchResults()
+	return results.BestMove, results.Info, nil
+}
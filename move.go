@@ -1,5 +1,7 @@
 package chess
 
+import "strings"
+
 // A MoveTag represents a notable consequence of a move.
 type MoveTag uint16
 
@@ -21,21 +23,27 @@ const (
 	NineSixtyCastle
 )
 
-// A Move is the movement of a piece from one square to another.
+// A Move is the movement of a piece from one square to another or,
+// in variants with pockets, the drop of a piece onto an empty square.
 type Move struct {
 	s1    Square
 	s2    Square
 	promo PieceType
+	drop  Piece
 	tags  MoveTag
 }
 
 // String returns a string useful for debugging.  String doesn't return
-// algebraic notation.
+// algebraic notation.  Drops are formatted as N@f3.
 func (m *Move) String() string {
+	if m.drop != NoPiece {
+		return strings.ToUpper(m.drop.Type().String()) + "@" + m.s2.String()
+	}
 	return m.s1.String() + m.s2.String() + m.promo.String()
 }
 
-// S1 returns the origin square of the move.
+// S1 returns the origin square of the move.  S1 is NoSquare
+// for drops.
 func (m *Move) S1() Square {
 	return m.s1
 }
@@ -50,6 +58,12 @@ func (m *Move) Promo() PieceType {
 	return m.promo
 }
 
+// Drop returns the piece type dropped by the move or NoPieceType
+// if the move isn't a drop.
+func (m *Move) Drop() PieceType {
+	return m.drop.Type()
+}
+
 // HasTag returns true if the move contains the MoveTag given.
 func (m *Move) HasTag(tag MoveTag) bool {
 	return (tag & m.tags) > 0
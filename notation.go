@@ -30,8 +30,18 @@ type Notation interface {
 
 // UCINotation is a more computer friendly alternative to algebraic
 // notation.  This notation uses the same format as the UCI (Universal Chess
-// Interface).  Examples: e2e4, e7e5, e1g1 (white short castling), e7e8q (for promotion)
-type UCINotation struct{}
+// Interface).  Examples: e2e4, e7e5, e1g1 (white short castling), e7e8q (for promotion),
+// N@f3 (drop)
+//
+// Castling in Chess960 positions is written as the king capturing its own
+// rook (e1h1).  Decode accepts both the king's destination and the rook's
+// square for castling moves.
+type UCINotation struct {
+	// Chess960 writes castling in standard positions as the king
+	// capturing its own rook (e1h1 instead of e1g1) as expected by
+	// engines with the UCI_Chess960 option enabled.
+	Chess960 bool
+}
 
 // String implements the fmt.Stringer interface and returns
 // the notation's name.
@@ -40,12 +50,21 @@ func (UCINotation) String() string {
 }
 
 // Encode implements the Encoder interface.
-func (UCINotation) Encode(pos *Position, m *Move) string {
+func (n UCINotation) Encode(pos *Position, m *Move) string {
+	if m.drop != NoPiece {
+		return m.String()
+	}
+	if n.Chess960 && !m.HasTag(NineSixtyCastle) && (m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle)) {
+		return m.s1.String() + castleRookSquare(m).String()
+	}
 	return m.S1().String() + m.S2().String() + m.Promo().String()
 }
 
 // Decode implements the Decoder interface.
 func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
+	if len(s) == 4 && s[1] == '@' {
+		return decodeDrop(pos, s)
+	}
 	l := len(s)
 	if l < 4 || l > 5 {
 		return nil, fmt.Errorf(`chess: failed to decode UCI notation text "%s" , length should be 4 or 5`, s)
@@ -70,18 +89,77 @@ func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
 		return m, nil
 	}
 	mStr := m.String()
-	for _, validMove := range pos.ValidMoves() {
+	validMoves := pos.ValidMoves()
+	for _, validMove := range validMoves {
 		validMoveStr := validMove.String()
 		if validMoveStr == mStr {
 			return validMove, nil // validMove has the tags which m does not
 		}
 	}
+	// castling written in the other convention
+	for _, validMove := range validMoves {
+		if !validMove.HasTag(KingSideCastle) && !validMove.HasTag(QueenSideCastle) {
+			continue
+		}
+		if validMove.s1 != m.s1 || promo != NoPieceType {
+			continue
+		}
+		if m.s2 == castleRookSquare(validMove) || m.s2 == castleKingSquare(validMove) {
+			return validMove, nil
+		}
+	}
 	return nil, fmt.Errorf("chess: could not decode UCI notation %s for position %s , move not a legal move", s, pos.String())
 }
 
+// castleRookSquare returns the starting square of the rook of the castling
+// move.
+func castleRookSquare(m *Move) Square {
+	if m.HasTag(NineSixtyCastle) {
+		return m.s2
+	}
+	if m.HasTag(QueenSideCastle) {
+		return NewSquare(FileA, m.s1.Rank())
+	}
+	return NewSquare(FileH, m.s1.Rank())
+}
+
+// castleKingSquare returns the destination square of the king of the
+// castling move.
+func castleKingSquare(m *Move) Square {
+	if m.HasTag(QueenSideCastle) {
+		return NewSquare(FileC, m.s1.Rank())
+	}
+	return NewSquare(FileG, m.s1.Rank())
+}
+
+// decodeDrop decodes drops such as N@f3 or P@e4.
+func decodeDrop(pos *Position, s string) (*Move, error) {
+	pt := pieceTypeFromChar(strings.ToLower(s[0:1]))
+	if s[0] == 'P' || s[0] == 'p' {
+		pt = Pawn
+	}
+	s2, ok := strToSquareMap[s[2:4]]
+	if pt == NoPieceType || !ok {
+		return nil, fmt.Errorf(`chess: failed to decode drop "%s"`, s)
+	}
+	c := White
+	if pos != nil {
+		c = pos.Turn()
+	}
+	m := &Move{s1: NoSquare, s2: s2, drop: NewPiece(pt, c)}
+	if pos == nil {
+		return m, nil
+	}
+	if valid := moveSlice(pos.ValidMoves()).find(m); valid != nil {
+		return valid, nil
+	}
+	return nil, fmt.Errorf("chess: could not decode drop %s for position %s , move not a legal move", s, pos.String())
+}
+
 // AlgebraicNotation (or Standard Algebraic Notation) is the
 // official chess notation used by FIDE. Examples: e4, e5,
 // O-O (short castling), e8=Q (promotion)
+// N@f3 (drop)
 type AlgebraicNotation struct{}
 
 // String implements the fmt.Stringer interface and returns
@@ -97,6 +175,8 @@ func (AlgebraicNotation) Encode(pos *Position, m *Move) string {
 		return "O-O" + checkChar
 	} else if m.HasTag(QueenSideCastle) {
 		return "O-O-O" + checkChar
+	} else if m.drop != NoPiece {
+		return m.String() + checkChar
 	}
 	p := pos.Board().Piece(m.S1())
 	pChar := charFromPieceType(p.Type())
@@ -112,7 +192,7 @@ func (AlgebraicNotation) Encode(pos *Position, m *Move) string {
 	return pChar + s1Str + capChar + m.s2.String() + promoText + checkChar
 }
 
-var pgnRegex = regexp.MustCompile(`^(?:([RNBQKP]?)([abcdefgh]?)(\d?)(x?)([abcdefgh])(\d)(=[QRBN])?|(O-O(?:-O)?))([+#!?]|e\.p\.)*$`)
+var pgnRegex = regexp.MustCompile(`^(?:([RNBQKP]?)([abcdefgh]?)(\d?)(x?)([abcdefgh])(\d)(=[QRBNK])?|(O-O(?:-O)?))([+#!?]|e\.p\.)*$`)
 
 func algebraicNotationParts(s string) (string, string, string, string, string, string, string, string, error) {
 	submatches := pgnRegex.FindStringSubmatch(s)
@@ -158,6 +238,8 @@ func (LongAlgebraicNotation) Encode(pos *Position, m *Move) string {
 		return "O-O" + checkChar
 	} else if m.HasTag(QueenSideCastle) {
 		return "O-O-O" + checkChar
+	} else if m.drop != NoPiece {
+		return m.String() + checkChar
 	}
 	p := pos.Board().Piece(m.S1())
 	pChar := charFromPieceType(p.Type())
@@ -263,6 +345,8 @@ func charFromPieceType(p PieceType) string {
 
 func pieceTypeFromChar(c string) PieceType {
 	switch c {
+	case "k":
+		return King
 	case "q":
 		return Queen
 	case "r":
@@ -278,6 +362,10 @@ func pieceTypeFromChar(c string) PieceType {
 func sanitizeNotationString(s string) string {
 	s = strings.Replace(s, "!", "", -1)
 	s = strings.Replace(s, "?", "", -1)
+	// pawn drops may omit the piece letter
+	if strings.HasPrefix(s, "@") {
+		s = "P" + s
+	}
 	return s
 }
 
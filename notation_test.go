@@ -79,3 +79,48 @@ func TestInvalidDecoding(t *testing.T) {
 		}
 	}
 }
+
+func TestUCINotationChess960Castling(t *testing.T) {
+	pos := unsafeFEN("r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1")
+	for _, s := range []string{"e1g1", "e1h1"} {
+		m, err := UCINotation{}.Decode(pos, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m.HasTag(KingSideCastle) {
+			t.Fatalf("expected %s to decode as castling", s)
+		}
+		if enc := (UCINotation{}).Encode(pos, m); enc != "e1g1" {
+			t.Fatalf("expected e1g1 but got %s", enc)
+		}
+		if enc := (UCINotation{Chess960: true}).Encode(pos, m); enc != "e1h1" {
+			t.Fatalf("expected e1h1 but got %s", enc)
+		}
+	}
+	m, err := UCINotation{}.Decode(pos, "e1a1")
+	if err != nil || !m.HasTag(QueenSideCastle) {
+		t.Fatalf("expected e1a1 to decode as queen side castling %v", err)
+	}
+	opt, err := Chess960Start(518)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt, UseNotation(UCINotation{}))
+	for _, s := range []string{"e2e4", "e7e5", "g1f3", "g8f6", "f1c4", "f8c5"} {
+		if err := g.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range []string{"e1g1", "e1h1"} {
+		m, err := UCINotation{}.Decode(g.Position(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m.HasTag(NineSixtyCastle) {
+			t.Fatalf("expected %s to decode as chess960 castling", s)
+		}
+		if enc := (UCINotation{}).Encode(g.Position(), m); enc != "e1h1" {
+			t.Fatalf("expected e1h1 but got %s", enc)
+		}
+	}
+}
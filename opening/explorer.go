@@ -0,0 +1,399 @@
+package opening
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/movecode"
+)
+
+// Explorer is an opening explorer built from a collection of games.  Statistics
+// are keyed by position (using chess.Position's ZobristHash) so moves reached
+// by transposition are merged.  Games can be added incrementally and the
+// explorer can be serialized with MarshalBinary.  Explorer is safe for
+// concurrent use.
+type Explorer struct {
+	mu       sync.RWMutex
+	maxPly   int
+	topGames int
+	nodes    map[uint64]map[uint16]*moveStats
+}
+
+// MaxPly is an option for NewExplorer that sets the number of half moves of
+// each game that are added to the explorer.  The default is 50, a value of
+// zero or less adds every move.
+func MaxPly(n int) func(*Explorer) {
+	return func(e *Explorer) {
+		e.maxPly = n
+	}
+}
+
+// TopGames is an option for NewExplorer that sets the number of top games
+// kept per move.  The default is 5 and at most 255 are kept.
+func TopGames(n int) func(*Explorer) {
+	return func(e *Explorer) {
+		if n > maxTopGames {
+			n = maxTopGames
+		}
+		e.topGames = n
+	}
+}
+
+// maxTopGames is the most top games a move's statistics are encoded with.
+const maxTopGames = 255
+
+// NewExplorer returns an empty explorer.
+func NewExplorer(opts ...func(*Explorer)) *Explorer {
+	e := &Explorer{
+		maxPly:   50,
+		topGames: 5,
+		nodes:    map[uint64]map[uint16]*moveStats{},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// GameInfo is a summary of a game used in the top games list of a move.
+type GameInfo struct {
+	White    string
+	Black    string
+	WhiteElo int
+	BlackElo int
+	Event    string
+	Site     string
+	Date     string
+	Outcome  chess.Outcome
+}
+
+// ExplorerMove contains the statistics of a move played from a position.
+type ExplorerMove struct {
+	Move       *chess.Move
+	Games      int
+	WhiteWins  int
+	Draws      int
+	BlackWins  int
+	AverageElo int
+	TopGames   []*GameInfo
+}
+
+// WhitePercent returns the percentage of games won by white.
+func (m *ExplorerMove) WhitePercent() float64 {
+	return percent(m.WhiteWins, m.Games)
+}
+
+// DrawPercent returns the percentage of drawn games.
+func (m *ExplorerMove) DrawPercent() float64 {
+	return percent(m.Draws, m.Games)
+}
+
+// BlackPercent returns the percentage of games won by black.
+func (m *ExplorerMove) BlackPercent() float64 {
+	return percent(m.BlackWins, m.Games)
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+type moveStats struct {
+	games     uint32
+	whiteWins uint32
+	draws     uint32
+	blackWins uint32
+	eloSum    uint64
+	eloCount  uint32
+	top       []*GameInfo
+}
+
+// Add adds the game's moves to the explorer.  A move played again from a
+// repeated position is counted once per game.
+func (e *Explorer) Add(g *chess.Game) {
+	info := gameInfo(g)
+	positions := g.Positions()
+	type played struct {
+		key  uint64
+		code uint16
+	}
+	seen := map[played]bool{}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, m := range g.Moves() {
+		if e.maxPly > 0 && i >= e.maxPly {
+			break
+		}
+		key := positions[i].ZobristHash()
+		code := movecode.Encode(m)
+		if seen[played{key, code}] {
+			continue
+		}
+		seen[played{key, code}] = true
+		moves, ok := e.nodes[key]
+		if !ok {
+			moves = map[uint16]*moveStats{}
+			e.nodes[key] = moves
+		}
+		s, ok := moves[code]
+		if !ok {
+			s = &moveStats{}
+			moves[code] = s
+		}
+		s.add(info, e.topGames)
+	}
+}
+
+// AddGames adds every game read by the scanner and returns the number of
+// games added.
+func (e *Explorer) AddGames(s *chess.Scanner) (int, error) {
+	count := 0
+	for s.Scan() {
+		g := s.Next()
+		if g == nil || len(g.Moves()) == 0 {
+			continue
+		}
+		e.Add(g)
+		count++
+	}
+	if err := s.Err(); err != nil && err != io.EOF {
+		return count, err
+	}
+	return count, nil
+}
+
+// Moves returns the moves played from the position ordered by the number
+// of games, most played first.
+func (e *Explorer) Moves(pos *chess.Position) []*ExplorerMove {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	moves := []*ExplorerMove{}
+	for code, s := range e.nodes[pos.ZobristHash()] {
+		m := movecode.Find(pos, code)
+		if m == nil {
+			continue
+		}
+		em := &ExplorerMove{
+			Move:      m,
+			Games:     int(s.games),
+			WhiteWins: int(s.whiteWins),
+			Draws:     int(s.draws),
+			BlackWins: int(s.blackWins),
+			TopGames:  append([]*GameInfo(nil), s.top...),
+		}
+		if s.eloCount > 0 {
+			em.AverageElo = int(s.eloSum / uint64(s.eloCount))
+		}
+		moves = append(moves, em)
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Games != moves[j].Games {
+			return moves[i].Games > moves[j].Games
+		}
+		return moves[i].Move.String() < moves[j].Move.String()
+	})
+	return moves
+}
+
+func (s *moveStats) add(info *GameInfo, topGames int) {
+	s.games++
+	switch info.Outcome {
+	case chess.WhiteWon:
+		s.whiteWins++
+	case chess.BlackWon:
+		s.blackWins++
+	case chess.Draw:
+		s.draws++
+	}
+	for _, elo := range []int{info.WhiteElo, info.BlackElo} {
+		if elo > 0 {
+			s.eloSum += uint64(elo)
+			s.eloCount++
+		}
+	}
+	if topGames <= 0 {
+		return
+	}
+	i := sort.Search(len(s.top), func(i int) bool {
+		return info.rating() > s.top[i].rating()
+	})
+	if i >= topGames {
+		return
+	}
+	s.top = append(s.top, nil)
+	copy(s.top[i+1:], s.top[i:])
+	s.top[i] = info
+	if len(s.top) > topGames {
+		s.top = s.top[:topGames]
+	}
+}
+
+func (info *GameInfo) rating() int {
+	return info.WhiteElo + info.BlackElo
+}
+
+func gameInfo(g *chess.Game) *GameInfo {
+	tag := func(k string) string {
+		if tp := g.GetTagPair(k); tp != nil {
+			return tp.Value
+		}
+		return ""
+	}
+	elo := func(k string) int {
+		v, err := strconv.Atoi(tag(k))
+		if err != nil {
+			return 0
+		}
+		return v
+	}
+	date := tag("Date")
+	if date == "" {
+		date = tag("UTCDate")
+	}
+	return &GameInfo{
+		White:    tag("White"),
+		Black:    tag("Black"),
+		WhiteElo: elo("WhiteElo"),
+		BlackElo: elo("BlackElo"),
+		Event:    tag("Event"),
+		Site:     tag("Site"),
+		Date:     date,
+		Outcome:  g.Outcome(),
+	}
+}
+
+const explorerMagic = "OPEX\x01"
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface and
+// encodes the explorer's statistics and options.  Positions and moves are
+// written in order so equal explorers encode to equal data.
+func (e *Explorer) MarshalBinary() ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	buf := bytes.NewBufferString(explorerMagic)
+	w := func(v interface{}) {
+		binary.Write(buf, binary.BigEndian, v)
+	}
+	ws := func(s string) {
+		w(uint16(len(s)))
+		buf.WriteString(s)
+	}
+	w(int32(e.maxPly))
+	w(int32(e.topGames))
+	keys := make([]uint64, 0, len(e.nodes))
+	for key := range e.nodes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	w(uint32(len(keys)))
+	for _, key := range keys {
+		moves := e.nodes[key]
+		codes := make([]uint16, 0, len(moves))
+		for code := range moves {
+			codes = append(codes, code)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		w(key)
+		w(uint16(len(codes)))
+		for _, code := range codes {
+			s := moves[code]
+			w(code)
+			w([]uint32{s.games, s.whiteWins, s.draws, s.blackWins, s.eloCount})
+			w(s.eloSum)
+			w(uint8(len(s.top)))
+			for _, info := range s.top {
+				ws(info.White)
+				ws(info.Black)
+				w([]int32{int32(info.WhiteElo), int32(info.BlackElo)})
+				ws(info.Event)
+				ws(info.Site)
+				ws(info.Date)
+				ws(string(info.Outcome))
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface and
+// decodes data produced by MarshalBinary.
+func (e *Explorer) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(explorerMagic)) {
+		return errors.New("opening: invalid explorer data")
+	}
+	buf := bytes.NewReader(data[len(explorerMagic):])
+	var err error
+	r := func(v interface{}) {
+		if err == nil {
+			err = binary.Read(buf, binary.BigEndian, v)
+		}
+	}
+	rs := func() string {
+		var n uint16
+		r(&n)
+		if err != nil {
+			return ""
+		}
+		b := make([]byte, n)
+		if _, rerr := io.ReadFull(buf, b); rerr != nil {
+			err = rerr
+		}
+		return string(b)
+	}
+	var maxPly, topGames int32
+	var n uint32
+	r(&maxPly)
+	r(&topGames)
+	r(&n)
+	nodes := map[uint64]map[uint16]*moveStats{}
+	for i := uint32(0); i < n && err == nil; i++ {
+		var key uint64
+		var count uint16
+		r(&key)
+		r(&count)
+		moves := map[uint16]*moveStats{}
+		for j := uint16(0); j < count && err == nil; j++ {
+			var code uint16
+			counts := make([]uint32, 5)
+			s := &moveStats{}
+			var top uint8
+			r(&code)
+			r(counts)
+			r(&s.eloSum)
+			r(&top)
+			s.games, s.whiteWins, s.draws, s.blackWins, s.eloCount = counts[0], counts[1], counts[2], counts[3], counts[4]
+			for k := uint8(0); k < top && err == nil; k++ {
+				info := &GameInfo{}
+				info.White = rs()
+				info.Black = rs()
+				elos := make([]int32, 2)
+				r(elos)
+				info.WhiteElo, info.BlackElo = int(elos[0]), int(elos[1])
+				info.Event = rs()
+				info.Site = rs()
+				info.Date = rs()
+				info.Outcome = chess.Outcome(rs())
+				s.top = append(s.top, info)
+			}
+			moves[code] = s
+		}
+		nodes[key] = moves
+	}
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxPly = int(maxPly)
+	e.topGames = int(topGames)
+	e.nodes = nodes
+	return nil
+}
@@ -0,0 +1,186 @@
+package opening_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/opening"
+)
+
+func ExampleExplorer() {
+	f, err := os.Open("../fixtures/pgns/0006.pgn")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	explorer := opening.NewExplorer()
+	if _, err := explorer.AddGames(chess.NewScanner(f)); err != nil {
+		panic(err)
+	}
+	for _, m := range explorer.Moves(chess.StartingPosition()) {
+		fmt.Printf("%s games: %d white: %.0f%% draw: %.0f%% black: %.0f%%\n", m.Move, m.Games, m.WhitePercent(), m.DrawPercent(), m.BlackPercent())
+	}
+	// Output:
+	// e2e4 games: 4 white: 25% draw: 0% black: 75%
+	// d2d4 games: 1 white: 0% draw: 0% black: 100%
+}
+
+func mustGame(t *testing.T, tags []*chess.TagPair, moves ...string) *chess.Game {
+	g := chess.NewGame(chess.TagPairs(tags))
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestExplorerTranspositions(t *testing.T) {
+	explorer := opening.NewExplorer(opening.TopGames(1))
+	g1 := mustGame(t, []*chess.TagPair{{Key: "White", Value: "a"}, {Key: "WhiteElo", Value: "2000"}, {Key: "BlackElo", Value: "2200"}}, "d4", "Nf6", "c4", "e6", "Nc3", "Bb4")
+	g1.Resign(chess.White)
+	g2 := mustGame(t, []*chess.TagPair{{Key: "White", Value: "b"}, {Key: "WhiteElo", Value: "2600"}, {Key: "BlackElo", Value: "2600"}}, "c4", "e6", "Nc3", "Nf6", "d4", "Bb4")
+	g2.Draw(chess.DrawOffer)
+	explorer.Add(g1)
+	explorer.Add(g2)
+
+	pos := mustGame(t, nil, "d4", "Nf6", "c4", "e6", "Nc3").Position()
+	moves := explorer.Moves(pos)
+	if len(moves) != 1 {
+		t.Fatalf("expected one move but got %d", len(moves))
+	}
+	m := moves[0]
+	if m.Move.String() != "f8b4" || m.Games != 2 || m.BlackWins != 1 || m.Draws != 1 {
+		t.Fatalf("unexpected move stats %+v", m)
+	}
+	if m.AverageElo != 2350 {
+		t.Fatalf("expected average elo %d but got %d", 2350, m.AverageElo)
+	}
+	if len(m.TopGames) != 1 || m.TopGames[0].White != "b" {
+		t.Fatalf("expected top game of player b but got %+v", m.TopGames)
+	}
+}
+
+func TestExplorerMaxPly(t *testing.T) {
+	explorer := opening.NewExplorer(opening.MaxPly(2))
+	explorer.Add(mustGame(t, nil, "e4", "e5", "Nf3"))
+	pos := mustGame(t, nil, "e4", "e5").Position()
+	if moves := explorer.Moves(pos); len(moves) != 0 {
+		t.Fatalf("expected no moves after max ply but got %d", len(moves))
+	}
+}
+
+func TestExplorerBinary(t *testing.T) {
+	f, err := os.Open("../fixtures/pgns/0007.pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	explorer := opening.NewExplorer()
+	if _, err := explorer.AddGames(chess.NewScanner(f)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := explorer.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := opening.NewExplorer()
+	if err := cp.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	expected := explorer.Moves(chess.StartingPosition())
+	actual := cp.Moves(chess.StartingPosition())
+	if len(expected) == 0 || len(expected) != len(actual) {
+		t.Fatalf("expected %d moves but got %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		if e.Move.String() != a.Move.String() || e.Games != a.Games || e.AverageElo != a.AverageElo || len(e.TopGames) != len(a.TopGames) {
+			t.Fatalf("expected %+v but got %+v", e, a)
+		}
+	}
+	// incremental updates after decoding
+	cp.Add(mustGame(t, nil, "h4"))
+	if len(cp.Moves(chess.StartingPosition())) != len(expected)+1 {
+		t.Fatal("expected added move after decoding")
+	}
+}
+
+func TestExplorerRepetition(t *testing.T) {
+	explorer := opening.NewExplorer()
+	explorer.Add(mustGame(t, nil, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"))
+	moves := explorer.Moves(chess.StartingPosition())
+	if len(moves) != 1 || moves[0].Games != 1 || len(moves[0].TopGames) != 1 {
+		t.Fatalf("expected g1f3 counted once but got %+v", moves)
+	}
+}
+
+func TestExplorerBinaryDeterministic(t *testing.T) {
+	f, err := os.Open("../fixtures/pgns/0007.pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	explorer := opening.NewExplorer()
+	if _, err := explorer.AddGames(chess.NewScanner(f)); err != nil {
+		t.Fatal(err)
+	}
+	b1, err := explorer.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := opening.NewExplorer()
+	if err := cp.UnmarshalBinary(b1); err != nil {
+		t.Fatal(err)
+	}
+	b2, err := cp.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Fatal("expected equal explorers to encode to equal data")
+	}
+}
+
+func TestExplorerTopGamesLimit(t *testing.T) {
+	explorer := opening.NewExplorer(opening.TopGames(300))
+	for i := 0; i < 260; i++ {
+		explorer.Add(mustGame(t, nil, "e4"))
+	}
+	b, err := explorer.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := opening.NewExplorer()
+	if err := cp.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*opening.Explorer{explorer, cp} {
+		moves := e.Moves(chess.StartingPosition())
+		if len(moves) != 1 || moves[0].Games != 260 || len(moves[0].TopGames) != 255 {
+			t.Fatalf("expected 260 games with 255 top games but got %d games and %d top games", moves[0].Games, len(moves[0].TopGames))
+		}
+	}
+}
+
+func TestExplorerDrops(t *testing.T) {
+	explorer := opening.NewExplorer()
+	opt, err := chess.VariantFEN(chess.Crazyhouse{}, "4k3/8/8/8/8/8/8/4K3[NBn] w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []string{"N@d3", "B@d3"} {
+		g := chess.NewGame(opt)
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+		explorer.Add(g)
+	}
+	moves := explorer.Moves(chess.NewGame(opt).Position())
+	if len(moves) != 2 || moves[0].Move.String() != "B@d3" || moves[1].Move.String() != "N@d3" {
+		t.Fatalf("expected B@d3 and N@d3 but got %+v", moves)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -158,21 +159,20 @@ func decodePGN(pgn string) (*Game, error) {
 	}
 
 	gameFuncs := []func(*Game){}
-	is960 := false
+	var variant Variant = Standard{}
 	for _, tp := range tagPairs {
 		if strings.ToLower(tp.Key) == "variant" {
-			if strings.ToLower(tp.Value) == "chess960" ||
-				strings.ToLower(tp.Value) == "chess 960" ||
-				strings.ToLower(tp.Value) == "fischerandom" ||
-				strings.ToLower(tp.Value) == "fischerrandom" ||
-				strings.ToLower(tp.Value) == "fischer random" {
-				is960 = true
+			if v, ok := VariantFromName(tp.Value); ok {
+				variant = v
 			}
 		}
 	}
+	if !isStandard(variant) {
+		gameFuncs = append(gameFuncs, UseVariant(variant))
+	}
 	for _, tp := range tagPairs {
 		if strings.ToLower(tp.Key) == "fen" {
-			fenFunc, err := FEN(tp.Value, is960)
+			fenFunc, err := VariantFEN(variant, tp.Value)
 			if err != nil {
 				return nil, fmt.Errorf("chess: pgn decode error %s on tag %s", err.Error(), tp.Key)
 			}
@@ -194,36 +194,111 @@ func decodePGN(pgn string) (*Game, error) {
 		}
 		g.comments = g.comments[:len(g.comments)-1]
 		g.comments = append(g.comments, move.Comments)
+		for _, nag := range move.NAGs {
+			if err := g.AddNAG(nag); err != nil {
+				return nil, fmt.Errorf("chess: pgn decode error %s on move %d", err.Error(), g.Position().moveCount)
+			}
+		}
+		for _, text := range move.Variations {
+			if err := addVariation(g, decoder, text); err != nil {
+				return nil, fmt.Errorf("chess: pgn decode error %s in variation on move %d", err.Error(), g.Position().moveCount)
+			}
+		}
 	}
 	g.outcome = outcome
 
 	return g, nil
 }
 
+// addVariation decodes the variation of the last move of the game.  Its
+// comments, glyphs and nested variations are skipped.  The variation ends
+// before the first move which can't be decoded, such as a null move, and
+// is dropped if that is its first move so a bad variation doesn't reject
+// the game.
+func addVariation(g *Game, decoder Decoder, text string) error {
+	moves, _, err := moveListWithComments(text)
+	if err != nil {
+		return nil
+	}
+	pos := g.positions[len(g.positions)-2]
+	variation := []*Move{}
+	for _, move := range moves {
+		m, err := decoder.Decode(pos, move.MoveStr)
+		if err != nil {
+			break
+		}
+		variation = append(variation, m)
+		pos = pos.Update(m)
+	}
+	if len(variation) == 0 {
+		return nil
+	}
+	return g.AddVariation(variation)
+}
+
 func encodePGN(g *Game) string {
 	s := ""
+	hasVariant := false
 	for _, tag := range g.tagPairs {
 		s += fmt.Sprintf("[%s \"%s\"]\n", tag.Key, tag.Value)
+		hasVariant = hasVariant || strings.ToLower(tag.Key) == "variant"
+	}
+	if v := g.Variant(); !hasVariant && !isStandard(v) {
+		s += fmt.Sprintf("[Variant \"%s\"]\n", v.Name())
 	}
 	s += "\n"
+	// the move number is repeated for black after variations
+	number := true
 	for i, move := range g.moves {
 		pos := g.positions[i]
 		txt := g.notation.Encode(pos, move)
 		if i%2 == 0 {
 			s += fmt.Sprintf("%d. %s", (i/2)+1, txt)
+		} else if number {
+			s += fmt.Sprintf(" %d... %s ", (i/2)+1, txt)
 		} else {
 			s += fmt.Sprintf(" %s ", txt)
 		}
+		number = false
+		if len(g.nags) > i {
+			for _, nag := range g.nags[i] {
+				s += fmt.Sprintf(" $%d ", nag)
+			}
+		}
 		if len(g.comments) > i {
 			for _, c := range g.comments[i] {
 				s += " { " + c + " } "
 			}
 		}
+		if len(g.variations) > i {
+			for _, v := range g.variations[i] {
+				s += " ( " + encodeVariation(g.notation, pos, v) + " ) "
+				number = true
+			}
+		}
 	}
 	s += " " + string(g.outcome)
 	return s
 }
 
+// encodeVariation returns the moves played from the position with move
+// numbers.
+func encodeVariation(n Notation, pos *Position, moves []*Move) string {
+	parts := []string{}
+	for i, m := range moves {
+		txt := n.Encode(pos, m)
+		switch {
+		case pos.Turn() == White:
+			txt = fmt.Sprintf("%d. %s", pos.moveCount, txt)
+		case i == 0:
+			txt = fmt.Sprintf("%d... %s", pos.moveCount, txt)
+		}
+		parts = append(parts, txt)
+		pos = pos.Update(m)
+	}
+	return strings.Join(parts, " ")
+}
+
 var (
 	tagPairRegex = regexp.MustCompile(`\[(.*)\s\"(.*)\"\]`)
 )
@@ -245,25 +320,27 @@ func getTagPairs(pgn string) []*TagPair {
 }
 
 type moveWithComment struct {
-	MoveStr  string
-	Comments []string
+	MoveStr    string
+	Comments   []string
+	NAGs       []int
+	Variations []string
 }
 
-var moveListTokenRe = regexp.MustCompile(`(?:\d+\.)|(O-O(?:-O)?|\w*[abcdefgh][12345678]\w*(?:=[QRBN])?(?:\+|#)?)|(?:\{([^}]*)\})|(?:\([^)]*\))|(\*|0-1|1-0|1\/2-1\/2)`)
+var moveListTokenRe = regexp.MustCompile(`(?:\d+\.)|(O-O(?:-O)?|[KQRBNP]?@[abcdefgh][12345678](?:\+|#)?|\w*[abcdefgh][12345678]\w*(?:=[QRBNK])?(?:\+|#)?)|(?:\{([^}]*)\})|(?:\((\d+)\))|(?:\$(\d+))|(\*|0-1|1-0|1\/2-1\/2)`)
 
 func moveListWithComments(pgn string) ([]moveWithComment, Outcome, error) {
 	pgn = stripTagPairs(pgn)
 	var outcome Outcome
 	moves := []moveWithComment{}
 	// moveListTokenRe doesn't work w/ nested variations
-	pgn, err := stripVariations(pgn)
+	pgn, variations, err := stripVariations(pgn)
 	if err != nil {
 		return moves, outcome, err
 	}
 
 	for _, match := range moveListTokenRe.FindAllStringSubmatch(pgn, -1) {
-		move, commentText, outcomeText := match[1], match[2], match[3]
-		if len(move+commentText+outcomeText) == 0 {
+		move, commentText, variationText, nagText, outcomeText := match[1], match[2], match[3], match[4], match[5]
+		if len(move+commentText+variationText+nagText+outcomeText) == 0 {
 			continue
 		}
 
@@ -276,6 +353,16 @@ func moveListWithComments(pgn string) ([]moveWithComment, Outcome, error) {
 			moves[len(moves)-1].Comments = append(moves[len(moves)-1].Comments, strings.TrimSpace(commentText))
 		}
 
+		if variationText != "" && len(moves) > 0 {
+			i, _ := strconv.Atoi(variationText)
+			moves[len(moves)-1].Variations = append(moves[len(moves)-1].Variations, variations[i])
+		}
+
+		if nagText != "" && len(moves) > 0 {
+			nag, _ := strconv.Atoi(nagText)
+			moves[len(moves)-1].NAGs = append(moves[len(moves)-1].NAGs, nag)
+		}
+
 		if move != "" {
 			moves = append(moves, moveWithComment{MoveStr: move})
 		}
@@ -295,8 +382,11 @@ func stripTagPairs(pgn string) string {
 	return strings.Join(cp, "\n")
 }
 
-func stripVariations(pgn string) (string, error) {
-	var ret strings.Builder
+// stripVariations returns the pgn with each top level variation replaced
+// by its index in parentheses and the text of the variations.
+func stripVariations(pgn string) (string, []string, error) {
+	var ret, variation strings.Builder
+	variations := []string{}
 
 	variationDepth := 0
 	inCommentSection := false
@@ -304,33 +394,42 @@ func stripVariations(pgn string) (string, error) {
 	for _, c := range pgn {
 		if c == '{' {
 			if inCommentSection {
-				return "", fmt.Errorf("chess: pgn decode mismatched { in variation: %v", pgn)
+				return "", nil, fmt.Errorf("chess: pgn decode mismatched { in variation: %v", pgn)
 			}
 			inCommentSection = true
 		} else if c == '}' {
 			if !inCommentSection {
-				return "", fmt.Errorf("chess: pgn decode mismatched } in variation: %v", pgn)
+				return "", nil, fmt.Errorf("chess: pgn decode mismatched } in variation: %v", pgn)
 			}
 			inCommentSection = false
 		}
 		if !inCommentSection && c == '(' {
 			variationDepth++
-			continue
+			if variationDepth == 1 {
+				continue
+			}
 		}
 		if !inCommentSection && c == ')' {
 			if variationDepth <= 0 {
-				return "", fmt.Errorf("chess: pgn decode mismatched parenthesis in variation: %v", pgn)
+				return "", nil, fmt.Errorf("chess: pgn decode mismatched parenthesis in variation: %v", pgn)
 			}
 			variationDepth--
-			continue
+			if variationDepth == 0 {
+				fmt.Fprintf(&ret, " (%d) ", len(variations))
+				variations = append(variations, variation.String())
+				variation.Reset()
+				continue
+			}
 		}
 		if variationDepth == 0 {
 			_, err := ret.WriteRune(c)
 			if err != nil {
-				return "", err
+				return "", nil, err
 			}
+		} else {
+			variation.WriteRune(c)
 		}
 	}
 
-	return ret.String(), nil
+	return ret.String(), variations, nil
 }
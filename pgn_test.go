@@ -82,6 +82,49 @@ func TestWriteComments(t *testing.T) {
 	}
 }
 
+func TestWriteNAGsAndVariations(t *testing.T) {
+	game, err := decodePGN("1. e4 e5 2. Nf3 f6 $2 $4 { weak } ( 2... Nc6 3. Bb5 ( 3. Bc4 ) a6 ) ( 2... d6 ) 3. Nxe5 $1 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		nags := game.NAGs()
+		if len(nags[3]) != 2 || nags[3][0] != 2 || nags[3][1] != 4 || len(nags[4]) != 1 || nags[4][0] != 1 {
+			t.Fatalf("expected nags $2 $4 and $1 but got %v", nags)
+		}
+		variations := game.Variations()
+		if len(variations[3]) != 2 || len(variations[3][0]) != 3 || variations[3][0][2].String() != "a7a6" || variations[3][1][0].String() != "d7d6" {
+			t.Fatalf("expected variations 2... Nc6 3. Bb5 a6 and 2... d6 but got %v", variations[3])
+		}
+		if comments := game.Comments(); len(comments[3]) != 1 || comments[3][0] != "weak" {
+			t.Fatalf("expected comment weak but got %v", comments[3])
+		}
+		if game, err = decodePGN(game.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDecodeBadVariations(t *testing.T) {
+	game, err := decodePGN("1. e4 e5 ( 1... c5 2. Nf3 -- 3. d4 ) 2. Nf3 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 3 {
+		t.Fatalf("expected main line of 3 moves but got %d", len(game.Moves()))
+	}
+	if variations := game.Variations(); len(variations[1]) != 1 || len(variations[1][0]) != 2 || variations[1][0][1].String() != "g1f3" {
+		t.Fatalf("expected variation to stop before the null move but got %v", variations[1])
+	}
+	game, err = decodePGN("1. e4 e5 ( 1... Ke7 ) 2. Nf3 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 3 || len(game.Variations()[1]) != 0 {
+		t.Fatalf("expected invalid variation to be dropped but got %v", game.Variations())
+	}
+}
+
 func TestScanner(t *testing.T) {
 	m := map[string]int{
 		"fixtures/pgns/0006.pgn": 5,
@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -24,9 +25,11 @@ const (
 // not from starting position making that info unavailable. If both are empty, there is no diff btw 960 and normal
 // game and 960 being enabled or not is mere formality for record keeping / proper PGN generation.
 type CastleRights struct {
-	nineSixtyMode         bool
-	aSideRookStartingFile string
-	hSideRookStartingFile string
+	nineSixtyMode bool
+	// starting files of the castling rooks indexed by color, the files
+	// of white and black differ in Double Chess960
+	aSideRookStartingFile [3]string
+	hSideRookStartingFile [3]string
 	whiteKingSideCastle   bool
 	whiteQueenSideCastle  bool
 	blackKingSideCastle   bool
@@ -71,16 +74,16 @@ func (cr *CastleRights) String() string {
 	rights := ""
 	if cr.nineSixtyMode {
 		if cr.whiteKingSideCastle {
-			rights = rights + strings.ToUpper(cr.hSideRookStartingFile)
+			rights = rights + strings.ToUpper(cr.hSideRookStartingFile[White])
 		}
 		if cr.whiteQueenSideCastle {
-			rights = rights + strings.ToUpper(cr.aSideRookStartingFile)
+			rights = rights + strings.ToUpper(cr.aSideRookStartingFile[White])
 		}
 		if cr.blackKingSideCastle {
-			rights = rights + strings.ToLower(cr.hSideRookStartingFile)
+			rights = rights + strings.ToLower(cr.hSideRookStartingFile[Black])
 		}
 		if cr.blackQueenSideCastle {
-			rights = rights + strings.ToLower(cr.aSideRookStartingFile)
+			rights = rights + strings.ToLower(cr.aSideRookStartingFile[Black])
 		}
 	} else {
 		if cr.whiteKingSideCastle {
@@ -103,6 +106,20 @@ func (cr *CastleRights) String() string {
 	}
 }
 
+// setCastle sets whether the color can castle to the side.
+func (cr *CastleRights) setCastle(c Color, side Side, ok bool) {
+	switch {
+	case c == White && side == KingSide:
+		cr.whiteKingSideCastle = ok
+	case c == White && side == QueenSide:
+		cr.whiteQueenSideCastle = ok
+	case c == Black && side == KingSide:
+		cr.blackKingSideCastle = ok
+	case c == Black && side == QueenSide:
+		cr.blackQueenSideCastle = ok
+	}
+}
+
 // Position represents the state of the game without reguard
 // to its outcome.  Position is translatable to FEN notation.
 type Position struct {
@@ -114,6 +131,10 @@ type Position struct {
 	moveCount       int
 	inCheck         bool
 	validMoves      []*Move
+	variant         Variant
+	pockets         *pockets
+	promoted        bitboard
+	checks          *checkCount
 }
 
 const (
@@ -136,8 +157,17 @@ func (pos *Position) Update(m *Move) *Position {
 	if pos.turn == Black {
 		moveCount++
 	}
-	ncr := pos.updateCastleRights(m)
-	p := pos.board.Piece(m.s1)
+	var ncr *CastleRights
+	var p Piece
+	enPassant := NoSquare
+	if m.drop != NoPiece {
+		ncr = pos.castleRights.copy()
+		p = m.drop
+	} else {
+		ncr = pos.updateCastleRights(m)
+		p = pos.board.Piece(m.s1)
+		enPassant = pos.updateEnPassantSquare(m)
+	}
 	halfMove := pos.halfMoveClock
 	if p.Type() == Pawn || m.HasTag(Capture) {
 		halfMove = 0
@@ -146,15 +176,18 @@ func (pos *Position) Update(m *Move) *Position {
 	}
 	b := pos.board.copy()
 	b.update(m)
-	return &Position{
+	next := &Position{
 		board:           b,
 		turn:            pos.turn.Other(),
 		castleRights:    ncr,
-		enPassantSquare: pos.updateEnPassantSquare(m),
+		enPassantSquare: enPassant,
 		halfMoveClock:   halfMove,
 		moveCount:       moveCount,
 		inCheck:         m.HasTag(Check),
+		variant:         pos.variant,
 	}
+	pos.rules().afterMove(pos, m, next)
+	return next
 }
 
 // ValidMoves returns a list of valid moves for the position.
@@ -162,14 +195,28 @@ func (pos *Position) ValidMoves() []*Move {
 	if pos.validMoves != nil {
 		return append([]*Move(nil), pos.validMoves...)
 	}
-	pos.validMoves = engine{}.CalcMoves(pos, false)
+	pos.validMoves = pos.rules().Moves(pos)
 	return append([]*Move(nil), pos.validMoves...)
 }
 
 // Status returns the position's status as one of the outcome methods.
-// Possible returns values include Checkmate, Stalemate, and NoMethod.
+// Possible returns values include Checkmate, Stalemate, and NoMethod
+// for standard chess and the variant specific methods otherwise.
 func (pos *Position) Status() Method {
-	return engine{}.Status(pos)
+	_, method := pos.rules().Status(pos)
+	return method
+}
+
+// Variant returns the rules the position is played under.
+func (pos *Position) Variant() Variant {
+	return pos.rules()
+}
+
+func (pos *Position) rules() Variant {
+	if pos.variant == nil {
+		return Standard{}
+	}
+	return pos.variant
 }
 
 // Board returns the position's board.
@@ -201,29 +248,29 @@ func (pos *Position) EnPassantSquare() Square {
 // string with the FEN format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
 // For 960 games it returns Shredder-FEN
 func (pos *Position) String() string {
-	b := pos.board.String()
+	b := pos.boardFEN()
 	t := pos.turn.String()
 	c := pos.castleRights.String()
 	sq := "-"
 	if pos.enPassantSquare != NoSquare {
 		sq = pos.enPassantSquare.String()
 	}
-	return fmt.Sprintf("%s %s %s %s %d %d", b, t, c, sq, pos.halfMoveClock, pos.moveCount)
+	return fmt.Sprintf("%s %s %s %s%s %d %d", b, t, c, sq, pos.checksFEN(), pos.halfMoveClock, pos.moveCount)
 }
 
 // XFENString() is similar to String() except that it returns a string with
 // the X-FEN format
 func (pos *Position) XFENString() string {
-	b := pos.board.String()
+	b := pos.boardFEN()
 	t := pos.turn.String()
 	cr := pos.castleRights
 	c := ""
 	if cr.nineSixtyMode {
 		if cr.whiteKingSideCastle {
 			toAdd := "K"
-			for sq := strToSquareMap[cr.hSideRookStartingFile+"1"] + 1; sq <= H1; sq++ {
+			for sq := strToSquareMap[cr.hSideRookStartingFile[White]+"1"] + 1; sq <= H1; sq++ {
 				if pos.board.Piece(sq) == WhiteRook {
-					toAdd = strings.ToUpper(cr.hSideRookStartingFile)
+					toAdd = strings.ToUpper(cr.hSideRookStartingFile[White])
 					break
 				}
 			}
@@ -231,9 +278,9 @@ func (pos *Position) XFENString() string {
 		}
 		if cr.whiteQueenSideCastle {
 			toAdd := "Q"
-			for sq := strToSquareMap[cr.aSideRookStartingFile+"1"] - 1; sq >= A1; sq-- {
+			for sq := strToSquareMap[cr.aSideRookStartingFile[White]+"1"] - 1; sq >= A1; sq-- {
 				if pos.board.Piece(sq) == WhiteRook {
-					toAdd = strings.ToUpper(cr.aSideRookStartingFile)
+					toAdd = strings.ToUpper(cr.aSideRookStartingFile[White])
 					break
 				}
 			}
@@ -241,9 +288,9 @@ func (pos *Position) XFENString() string {
 		}
 		if cr.blackKingSideCastle {
 			toAdd := "k"
-			for sq := strToSquareMap[cr.hSideRookStartingFile+"8"] + 1; sq <= H8; sq++ {
+			for sq := strToSquareMap[cr.hSideRookStartingFile[Black]+"8"] + 1; sq <= H8; sq++ {
 				if pos.board.Piece(sq) == BlackRook {
-					toAdd = strings.ToLower(cr.hSideRookStartingFile)
+					toAdd = strings.ToLower(cr.hSideRookStartingFile[Black])
 					break
 				}
 			}
@@ -251,9 +298,9 @@ func (pos *Position) XFENString() string {
 		}
 		if cr.blackQueenSideCastle {
 			toAdd := "q"
-			for sq := strToSquareMap[cr.aSideRookStartingFile+"8"] - 1; sq >= A8; sq-- {
+			for sq := strToSquareMap[cr.aSideRookStartingFile[Black]+"8"] - 1; sq >= A8; sq-- {
 				if pos.board.Piece(sq) == BlackRook {
-					toAdd = strings.ToLower(cr.aSideRookStartingFile)
+					toAdd = strings.ToLower(cr.aSideRookStartingFile[Black])
 					break
 				}
 			}
@@ -294,10 +341,11 @@ func (pos *Position) XFENString() string {
 			}
 		}
 	}
-	return fmt.Sprintf("%s %s %s %s %d %d", b, t, c, sq, pos.halfMoveClock, pos.moveCount)
+	return fmt.Sprintf("%s %s %s %s%s %d %d", b, t, c, sq, pos.checksFEN(), pos.halfMoveClock, pos.moveCount)
 }
 
-// Hash returns a unique hash of the position
+// Hash returns a unique hash of the position including the variant,
+// pockets and check counter.
 func (pos *Position) Hash() [16]byte {
 	b, _ := pos.MarshalBinary()
 	return md5.Sum(b)
@@ -313,7 +361,9 @@ func (pos *Position) MarshalText() (text []byte, err error) {
 }
 
 // UnmarshalText implements the encoding.TextUnarshaler interface and
-// assumes the data is in the FEN format.
+// assumes the data is in the FEN format of standard chess or Chess960.
+// The position's previous variant isn't kept; VariantFEN reads the FENs of
+// other variants.
 func (pos *Position) UnmarshalText(text []byte) error {
 	cp, err := decodeFEN(string(text), false)
 	if err != nil {
@@ -330,6 +380,11 @@ func (pos *Position) UnmarshalText(text []byte) error {
 	pos.enPassantSquare = cp.enPassantSquare
 	pos.halfMoveClock = cp.halfMoveClock
 	pos.moveCount = cp.moveCount
+	pos.variant = cp.variant
+	pos.pockets = nil
+	pos.promoted = 0
+	pos.checks = nil
+	pos.validMoves = nil
 	pos.inCheck = isInCheck(cp)
 	return nil
 }
@@ -344,7 +399,9 @@ const (
 	bitsIsNineSixty
 )
 
-// MarshalBinary implements the encoding.BinaryMarshaler interface
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+// Positions of variants other than standard chess and Chess960 are
+// followed by the variant name, pockets and check counter.
 func (pos *Position) MarshalBinary() (data []byte, err error) {
 	boardBytes, err := pos.board.MarshalBinary()
 	if err != nil {
@@ -360,21 +417,11 @@ func (pos *Position) MarshalBinary() (data []byte, err error) {
 	if err := binary.Write(buf, binary.BigEndian, pos.enPassantSquare); err != nil {
 		return nil, err
 	}
-	var hsideFile uint8
-	if pos.castleRights.hSideRookStartingFile == "" {
-		hsideFile = 255
-	} else {
-		hsideFile = uint8(strToSquareMap[pos.castleRights.hSideRookStartingFile+"1"].File())
-	}
+	hsideFile := encodeRookFiles(pos.castleRights.hSideRookStartingFile)
 	if err := binary.Write(buf, binary.BigEndian, hsideFile); err != nil {
 		return nil, err
 	}
-	var asideFile uint8
-	if pos.castleRights.aSideRookStartingFile == "" {
-		asideFile = 255
-	} else {
-		asideFile = uint8(strToSquareMap[pos.castleRights.aSideRookStartingFile+"1"].File())
-	}
+	asideFile := encodeRookFiles(pos.castleRights.aSideRookStartingFile)
 	if err := binary.Write(buf, binary.BigEndian, asideFile); err != nil {
 		return nil, err
 	}
@@ -403,20 +450,122 @@ func (pos *Position) MarshalBinary() (data []byte, err error) {
 	if err := binary.Write(buf, binary.BigEndian, b); err != nil {
 		return nil, err
 	}
+	if err := pos.marshalVariant(buf); err != nil {
+		return nil, err
+	}
 	return buf.Bytes(), err
 }
 
+// positionBinaryLen is the length of the binary data of standard chess
+// and Chess960 positions.
+const positionBinaryLen = 103
+
+const (
+	bitsHasPockets uint8 = 1 << iota
+	bitsHasChecks
+)
+
+// marshalVariant writes the variant name, pockets, promoted pieces and
+// check counter of positions of variants other than standard chess and
+// Chess960, which are encoded by the castling bits.
+func (pos *Position) marshalVariant(buf *bytes.Buffer) error {
+	v := pos.Variant()
+	if isStandard(v) || v == (Chess960{}) {
+		return nil
+	}
+	name := v.Name()
+	if len(name) > 255 {
+		return fmt.Errorf("chess: variant name %q is too long", name)
+	}
+	buf.WriteByte(uint8(len(name)))
+	buf.WriteString(name)
+	var flags uint8
+	if pos.pockets != nil {
+		flags |= bitsHasPockets
+	}
+	if pos.checks != nil {
+		flags |= bitsHasChecks
+	}
+	buf.WriteByte(flags)
+	if pos.pockets != nil {
+		if err := binary.Write(buf, binary.BigEndian, *pos.pockets); err != nil {
+			return err
+		}
+		if err := binary.Write(buf, binary.BigEndian, uint64(pos.promoted)); err != nil {
+			return err
+		}
+	}
+	if pos.checks != nil {
+		if err := binary.Write(buf, binary.BigEndian, *pos.checks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pos *Position) unmarshalVariant(data []byte) error {
+	buf := bytes.NewReader(data)
+	n, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	name := make([]byte, n)
+	if _, err := io.ReadFull(buf, name); err != nil {
+		return err
+	}
+	v, ok := VariantFromName(string(name))
+	if !ok {
+		return fmt.Errorf("chess: unknown variant %q in position binary data", name)
+	}
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	pos.variant = v
+	pos.pockets = nil
+	pos.promoted = 0
+	pos.checks = nil
+	if flags&bitsHasPockets != 0 {
+		pk := &pockets{}
+		if err := binary.Read(buf, binary.BigEndian, pk); err != nil {
+			return err
+		}
+		var promoted uint64
+		if err := binary.Read(buf, binary.BigEndian, &promoted); err != nil {
+			return err
+		}
+		pos.pockets = pk
+		pos.promoted = bitboard(promoted)
+	}
+	if flags&bitsHasChecks != 0 {
+		checks := &checkCount{}
+		if err := binary.Read(buf, binary.BigEndian, checks); err != nil {
+			return err
+		}
+		pos.checks = checks
+	}
+	if buf.Len() != 0 {
+		return errors.New("chess: position binary data has trailing bytes")
+	}
+	return nil
+}
+
 // UnmarshalBinary implements the encoding.BinaryMarshaler interface
+// and restores the variant and its pockets and check counter.
 func (pos *Position) UnmarshalBinary(data []byte) error {
-	if len(data) != 103 {
-		return errors.New("chess: position binary data should consist of 101 bytes")
+	if len(data) < positionBinaryLen {
+		return errors.New("chess: position binary data should consist of at least 103 bytes")
 	}
 	board := &Board{}
 	if err := board.UnmarshalBinary(data[:96]); err != nil {
 		return err
 	}
 	pos.board = board
-	buf := bytes.NewBuffer(data[96:])
+	pos.variant = nil
+	pos.pockets = nil
+	pos.promoted = 0
+	pos.checks = nil
+	buf := bytes.NewBuffer(data[96:positionBinaryLen])
 	halfMove := uint8(pos.halfMoveClock)
 	if err := binary.Read(buf, binary.BigEndian, &halfMove); err != nil {
 		return err
@@ -435,20 +584,12 @@ func (pos *Position) UnmarshalBinary(data []byte) error {
 	if err := binary.Read(buf, binary.BigEndian, &hsideFile); err != nil {
 		return err
 	}
-	if hsideFile == 255 {
-		pos.castleRights.hSideRookStartingFile = ""
-	} else {
-		pos.castleRights.hSideRookStartingFile = File(hsideFile).String()
-	}
+	pos.castleRights.hSideRookStartingFile = decodeRookFiles(hsideFile)
 	var asideFile uint8
 	if err := binary.Read(buf, binary.BigEndian, &asideFile); err != nil {
 		return err
 	}
-	if asideFile == 255 {
-		pos.castleRights.aSideRookStartingFile = ""
-	} else {
-		pos.castleRights.aSideRookStartingFile = File(asideFile).String()
-	}
+	pos.castleRights.aSideRookStartingFile = decodeRookFiles(asideFile)
 	var b uint8
 	if err := binary.Read(buf, binary.BigEndian, &b); err != nil {
 		return err
@@ -474,11 +615,46 @@ func (pos *Position) UnmarshalBinary(data []byte) error {
 	}
 	if b&bitsIsNineSixty != 0 {
 		pos.castleRights.nineSixtyMode = true
+		pos.variant = Chess960{}
+	}
+	if len(data) > positionBinaryLen {
+		if err := pos.unmarshalVariant(data[positionBinaryLen:]); err != nil {
+			return err
+		}
 	}
 	pos.inCheck = isInCheck(pos)
 	return nil
 }
 
+// encodeRookFiles encodes the rook starting files of both colors in a
+// byte.  The low bits hold white's file and the high bits the difference
+// to black's file so positions with shared files encode as before.
+func encodeRookFiles(files [3]string) uint8 {
+	w, b := files[White], files[Black]
+	if w == "" {
+		w = b
+	}
+	if b == "" {
+		b = w
+	}
+	if w == "" {
+		return 255
+	}
+	wf := uint8(strToSquareMap[w+"1"].File())
+	bf := uint8(strToSquareMap[b+"1"].File())
+	return wf | (wf^bf)<<4
+}
+
+func decodeRookFiles(v uint8) [3]string {
+	var files [3]string
+	if v == 255 {
+		return files
+	}
+	files[White] = File(v & 0x0F).String()
+	files[Black] = File((v & 0x0F) ^ (v >> 4)).String()
+	return files
+}
+
 func (pos *Position) copy() *Position {
 	return &Position{
 		board:           pos.board.copy(),
@@ -488,6 +664,10 @@ func (pos *Position) copy() *Position {
 		halfMoveClock:   pos.halfMoveClock,
 		moveCount:       pos.moveCount,
 		inCheck:         pos.inCheck,
+		variant:         pos.variant,
+		pockets:         pos.pockets,
+		promoted:        pos.promoted,
+		checks:          pos.checks,
 	}
 }
 
@@ -503,13 +683,17 @@ func (pos *Position) updateCastleRights(m *Move) *CastleRights {
 	var blackRookQueenSideSquare Square = NoSquare
 
 	if newcr.nineSixtyMode {
-		if newcr.hSideRookStartingFile != "" {
-			whiteRookKingSideSquare = strToSquareMap[strings.ToLower(newcr.hSideRookStartingFile)+"1"]
-			blackRookKingSideSquare = strToSquareMap[strings.ToLower(newcr.hSideRookStartingFile)+"8"]
+		if newcr.hSideRookStartingFile[White] != "" {
+			whiteRookKingSideSquare = strToSquareMap[strings.ToLower(newcr.hSideRookStartingFile[White])+"1"]
+		}
+		if newcr.hSideRookStartingFile[Black] != "" {
+			blackRookKingSideSquare = strToSquareMap[strings.ToLower(newcr.hSideRookStartingFile[Black])+"8"]
+		}
+		if newcr.aSideRookStartingFile[White] != "" {
+			whiteRookQueenSideSquare = strToSquareMap[strings.ToLower(newcr.aSideRookStartingFile[White])+"1"]
 		}
-		if newcr.aSideRookStartingFile != "" {
-			whiteRookQueenSideSquare = strToSquareMap[strings.ToLower(newcr.aSideRookStartingFile)+"1"]
-			blackRookQueenSideSquare = strToSquareMap[strings.ToLower(newcr.aSideRookStartingFile)+"8"]
+		if newcr.aSideRookStartingFile[Black] != "" {
+			blackRookQueenSideSquare = strToSquareMap[strings.ToLower(newcr.aSideRookStartingFile[Black])+"8"]
 		}
 	} else {
 		whiteRookKingSideSquare = H1
@@ -554,5 +738,7 @@ func (pos *Position) samePosition(pos2 *Position) bool {
 	return pos.board.String() == pos2.board.String() &&
 		pos.turn == pos2.turn &&
 		pos.castleRights.String() == pos2.castleRights.String() &&
-		pos.enPassantSquare == pos2.enPassantSquare
+		pos.enPassantSquare == pos2.enPassantSquare &&
+		pos.pockets.equal(pos2.pockets) &&
+		pos.checks.equal(pos2.checks)
 }
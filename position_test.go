@@ -23,3 +23,120 @@ func TestPositionBinary(t *testing.T) {
 		}
 	}
 }
+
+func TestPositionZobristHash(t *testing.T) {
+	g1 := NewGame()
+	for _, s := range []string{"Nc3", "e5", "Nf3", "Nc6", "Ng1"} {
+		if err := g1.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g2 := NewGame()
+	for _, s := range []string{"Nc3", "Nc6", "Nf3", "e5", "Ng1"} {
+		if err := g2.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g1.Position().ZobristHash() != g2.Position().ZobristHash() {
+		t.Fatalf("expected transposed positions %s and %s to have equal hashes", g1.Position(), g2.Position())
+	}
+	if g1.Position().Hash() == g2.Position().Hash() {
+		t.Fatalf("expected positions with different half move clocks to have different md5 hashes")
+	}
+	// an en passant square without a capturing pawn doesn't change the hash
+	pos1 := unsafeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	pos2 := unsafeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if pos1.ZobristHash() != pos2.ZobristHash() {
+		t.Fatalf("expected en passant square without capture to be ignored")
+	}
+	pos3 := unsafeFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	pos4 := unsafeFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+	if pos3.ZobristHash() == pos4.ZobristHash() {
+		t.Fatalf("expected en passant square with capture to change hash")
+	}
+	pos5 := unsafeFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1")
+	if pos4.ZobristHash() == pos5.ZobristHash() {
+		t.Fatalf("expected turn to change hash")
+	}
+}
+
+func TestPositionZobristHashVariants(t *testing.T) {
+	hashes := map[uint64]string{}
+	for _, v := range []Variant{Standard{}, Atomic{}, Antichess{}, KingOfTheHill{}} {
+		h := NewGame(UseVariant(v)).Position().ZobristHash()
+		if other, ok := hashes[h]; ok {
+			t.Fatalf("expected %s and %s starting positions to have different hashes", v.Name(), other)
+		}
+		hashes[h] = v.Name()
+	}
+	if NewGame(UseVariant(Standard{})).Position().ZobristHash() != StartingPosition().ZobristHash() {
+		t.Fatal("expected standard hashes to be unchanged by the variant key")
+	}
+}
+
+func TestPositionBinaryVariants(t *testing.T) {
+	tests := []struct {
+		v   Variant
+		fen string
+	}{
+		{Crazyhouse{}, "rQ~bqkbnr/p1pppppp/8/8/8/8/1PPPPPPP/RNBQKBNR[NPp] b KQk - 0 5"},
+		{Bughouse{}, "r1bqkb1r/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQKB1R[Pp] w KQkq - 0 4"},
+		{ThreeCheck{}, "rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 3+2 0 3"},
+		{KingOfTheHill{}, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+	}
+	for _, test := range tests {
+		pos, err := decodeVariantFEN(test.fen, test.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := pos.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp := &Position{}
+		if err := cp.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if cp.String() != test.fen || cp.Variant() != test.v || cp.Hash() != pos.Hash() || !cp.samePosition(pos) {
+			t.Fatalf("expected %s %s but got %s %s", test.v.Name(), test.fen, cp.Variant().Name(), cp)
+		}
+	}
+}
+
+func TestPositionHashVariants(t *testing.T) {
+	hash := func(v Variant, fen string) [16]byte {
+		pos, err := decodeVariantFEN(fen, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pos.Hash()
+	}
+	if hash(Crazyhouse{}, "4k3/8/8/8/8/8/8/4K3[NNp] w - - 0 1") == hash(Crazyhouse{}, "4k3/8/8/8/8/8/8/4K3[QQp] w - - 0 1") {
+		t.Fatal("expected positions with different pockets to have different hashes")
+	}
+	if hash(ThreeCheck{}, "4k3/8/8/8/8/8/8/4K3 w - - 3+3 0 1") == hash(ThreeCheck{}, "4k3/8/8/8/8/8/8/4K3 w - - 2+3 0 1") {
+		t.Fatal("expected positions with different check counters to have different hashes")
+	}
+	if hash(Standard{}, "4k3/8/8/8/8/8/8/4K3 w - - 0 1") == hash(KingOfTheHill{}, "4k3/8/8/8/8/8/8/4K3 w - - 0 1") {
+		t.Fatal("expected positions of different variants to have different hashes")
+	}
+}
+
+func TestPositionTextVariants(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	pos := NewGame(UseVariant(Atomic{})).Position()
+	if err := pos.UnmarshalText([]byte(fen)); err != nil {
+		t.Fatal(err)
+	}
+	if !isStandard(pos.Variant()) || pos.String() != fen {
+		t.Fatalf("expected standard %s but got %s %s", fen, pos.Variant().Name(), pos)
+	}
+	for _, fen := range []string{
+		"rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 3+2 0 3",
+		"r1bqkb1r/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQKB1R[Pp] w KQkq - 0 4",
+	} {
+		if err := (&Position{}).UnmarshalText([]byte(fen)); err == nil {
+			t.Fatalf("expected error for variant fen %s", fen)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package problem solves chess problems exhaustively without an engine.
+// Directmates, helpmates and selfmates in n moves are solved with the legal
+// moves of the positions and the solutions are returned as a tree listing
+// every key, defence and continuation so problems can be checked for cooks
+// and duals.
+package problem
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Stipulation is the aim of a problem.
+type Stipulation int
+
+const (
+	// Directmate (#n) indicates that the side to move mates in n moves
+	// against any defence.
+	Directmate Stipulation = iota
+	// Helpmate (h#n) indicates that the side to move cooperates with the
+	// opponent to be mated on the opponent's nth move.
+	Helpmate
+	// Selfmate (s#n) indicates that the side to move forces the opponent
+	// to mate it in n moves against any defence.
+	Selfmate
+)
+
+// String implements the fmt.Stringer interface.
+func (s Stipulation) String() string {
+	switch s {
+	case Directmate:
+		return "#"
+	case Helpmate:
+		return "h#"
+	case Selfmate:
+		return "s#"
+	}
+	return ""
+}
+
+// Node is a move of a solution.  Children are the replies to the move:
+// every legal defence after a move of the side solving a directmate or
+// selfmate and the moves keeping the stipulation otherwise.  Mating moves
+// have no children.
+type Node struct {
+	Move *chess.Move
+	// Position is the position after the move.
+	Position *chess.Position
+	Children []*Node
+}
+
+// Solution is the solution tree of a problem.  Mates in fewer than the
+// stipulated number of moves are solutions.
+type Solution struct {
+	Stipulation Stipulation
+	Moves       int
+	Position    *chess.Position
+	// Keys are the first moves solving the problem.
+	Keys []*Node
+}
+
+// Solved returns true if the problem has a solution.
+func (s *Solution) Solved() bool {
+	return len(s.Keys) > 0
+}
+
+// Cooked returns true if the problem has more than one key.
+func (s *Solution) Cooked() bool {
+	return len(s.Keys) > 1
+}
+
+// Duals returns the lines after the key which can be continued by more than
+// one move of the solving side: both sides in helpmates and the side to
+// move in directmates and selfmates.  The lines start with the key and end
+// with the move before the choice.
+func (s *Solution) Duals() [][]*chess.Move {
+	duals := [][]*chess.Move{}
+	var walk func(n *Node, line []*chess.Move)
+	walk = func(n *Node, line []*chess.Move) {
+		line = append(line[:len(line):len(line)], n.Move)
+		if len(n.Children) > 1 && (s.Stipulation == Helpmate || len(line)%2 == 0) {
+			duals = append(duals, line)
+		}
+		for _, c := range n.Children {
+			walk(c, line)
+		}
+	}
+	for _, k := range s.Keys {
+		walk(k, nil)
+	}
+	return duals
+}
+
+// String returns the solution tree in algebraic notation with one move per
+// line indented by its depth.  Keys of directmates and selfmates are marked
+// with "!".
+func (s *Solution) String() string {
+	sb := &strings.Builder{}
+	var write func(pos *chess.Position, n *Node, ply int)
+	write = func(pos *chess.Position, n *Node, ply int) {
+		sb.WriteString(strings.Repeat("  ", ply))
+		sb.WriteString(strconv.Itoa(ply/2 + 1))
+		if ply%2 == 0 {
+			sb.WriteString(".")
+		} else {
+			sb.WriteString("...")
+		}
+		sb.WriteString(chess.AlgebraicNotation{}.Encode(pos, n.Move))
+		if ply == 0 && s.Stipulation != Helpmate {
+			sb.WriteString("!")
+		}
+		sb.WriteString("\n")
+		for _, c := range n.Children {
+			write(n.Position, c, ply+1)
+		}
+	}
+	for _, k := range s.Keys {
+		write(s.Position, k, 0)
+	}
+	return sb.String()
+}
+
+// Solve returns the solution of the problem with the stipulation in n
+// moves from the position.  Every line is searched so the time grows
+// exponentially with n; the search stops with the context's error when it
+// is done.
+func Solve(ctx context.Context, pos *chess.Position, s Stipulation, n int) (*Solution, error) {
+	if n < 1 {
+		return nil, errors.New("problem: number of moves must be at least one")
+	}
+	sv := &solver{ctx: ctx}
+	var keys []*Node
+	switch s {
+	case Directmate:
+		keys = sv.attack(pos, n)
+	case Helpmate:
+		keys = sv.help(pos, n)
+	case Selfmate:
+		keys = sv.selfAttack(pos, n)
+	default:
+		return nil, errors.New("problem: unknown stipulation")
+	}
+	if sv.err != nil {
+		return nil, sv.err
+	}
+	return &Solution{Stipulation: s, Moves: n, Position: pos, Keys: keys}, nil
+}
+
+type solver struct {
+	ctx context.Context
+	err error
+}
+
+// done records the context's error and returns true once it is done.
+func (sv *solver) done() bool {
+	if sv.err == nil {
+		sv.err = sv.ctx.Err()
+	}
+	return sv.err != nil
+}
+
+func mated(pos *chess.Position) bool {
+	return pos.Status() == chess.Checkmate
+}
+
+// attack returns the moves of the side to move mating in at most n moves
+// against any defence.
+func (sv *solver) attack(pos *chess.Position, n int) []*Node {
+	nodes := []*Node{}
+	for _, m := range pos.ValidMoves() {
+		if sv.done() {
+			return nil
+		}
+		next := pos.Update(m)
+		if mated(next) {
+			nodes = append(nodes, &Node{Move: m, Position: next})
+			continue
+		}
+		if n == 1 {
+			continue
+		}
+		if defences := sv.defend(next, n-1); len(defences) > 0 {
+			nodes = append(nodes, &Node{Move: m, Position: next, Children: defences})
+		}
+	}
+	return nodes
+}
+
+// defend returns every move of the side to move with the opponent's mates
+// in at most n moves or nil if a move escapes mate or there are no moves.
+func (sv *solver) defend(pos *chess.Position, n int) []*Node {
+	nodes := []*Node{}
+	for _, m := range pos.ValidMoves() {
+		next := pos.Update(m)
+		mates := sv.attack(next, n)
+		if len(mates) == 0 {
+			return nil
+		}
+		nodes = append(nodes, &Node{Move: m, Position: next, Children: mates})
+	}
+	return nodes
+}
+
+// selfAttack returns the moves of the side to move forcing the opponent to
+// mate it in at most n moves.
+func (sv *solver) selfAttack(pos *chess.Position, n int) []*Node {
+	nodes := []*Node{}
+	for _, m := range pos.ValidMoves() {
+		if sv.done() {
+			return nil
+		}
+		next := pos.Update(m)
+		if mated(next) {
+			continue
+		}
+		if defences := sv.selfDefend(next, n); len(defences) > 0 {
+			nodes = append(nodes, &Node{Move: m, Position: next, Children: defences})
+		}
+	}
+	return nodes
+}
+
+// selfDefend returns every move of the side to move, each mating or
+// followed by the opponent's forcing moves, or nil if a move avoids giving
+// mate in n moves or there are no moves.
+func (sv *solver) selfDefend(pos *chess.Position, n int) []*Node {
+	nodes := []*Node{}
+	for _, m := range pos.ValidMoves() {
+		next := pos.Update(m)
+		if mated(next) {
+			nodes = append(nodes, &Node{Move: m, Position: next})
+			continue
+		}
+		if n == 1 {
+			return nil
+		}
+		forcing := sv.selfAttack(next, n-1)
+		if len(forcing) == 0 {
+			return nil
+		}
+		nodes = append(nodes, &Node{Move: m, Position: next, Children: forcing})
+	}
+	return nodes
+}
+
+// help returns the moves of the side to move after which the opponent can
+// mate it in at most n moves with its cooperation.
+func (sv *solver) help(pos *chess.Position, n int) []*Node {
+	nodes := []*Node{}
+	for _, m := range pos.ValidMoves() {
+		if sv.done() {
+			return nil
+		}
+		next := pos.Update(m)
+		if mated(next) {
+			continue
+		}
+		mates := []*Node{}
+		for _, reply := range next.ValidMoves() {
+			after := next.Update(reply)
+			if mated(after) {
+				mates = append(mates, &Node{Move: reply, Position: after})
+				continue
+			}
+			if n == 1 {
+				continue
+			}
+			if rest := sv.help(after, n-1); len(rest) > 0 {
+				mates = append(mates, &Node{Move: reply, Position: after, Children: rest})
+			}
+		}
+		if len(mates) > 0 {
+			nodes = append(nodes, &Node{Move: m, Position: next, Children: mates})
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,110 @@
+package problem_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/problem"
+)
+
+func solve(t *testing.T, fen string, s problem.Stipulation, n int) *problem.Solution {
+	t.Helper()
+	opt, err := chess.FEN(fen, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sol, err := problem.Solve(context.Background(), chess.NewGame(opt).Position(), s, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sol
+}
+
+func TestDirectmate(t *testing.T) {
+	sol := solve(t, "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", problem.Directmate, 1)
+	if !sol.Solved() || sol.Cooked() {
+		t.Fatalf("expected one key but got %d", len(sol.Keys))
+	}
+	if s := sol.String(); s != "1.Ra8#!\n" {
+		t.Fatalf("expected 1.Ra8#! but got %q", s)
+	}
+}
+
+func TestDirectmateCooked(t *testing.T) {
+	sol := solve(t, "6k1/5ppp/8/8/8/8/8/RR4K1 w - - 0 1", problem.Directmate, 1)
+	if !sol.Cooked() {
+		t.Fatalf("expected cook but got %d keys", len(sol.Keys))
+	}
+}
+
+func TestDirectmateTree(t *testing.T) {
+	sol := solve(t, "k7/8/2K5/8/8/8/8/7R w - - 0 1", problem.Directmate, 2)
+	expected := "1.Kb6!\n  1...Kb8\n    2.Rh8#\n1.Kc7!\n  1...Ka7\n    2.Ra1#\n"
+	if s := sol.String(); s != expected {
+		t.Fatalf("expected %q but got %q", expected, s)
+	}
+	if len(sol.Duals()) != 0 {
+		t.Fatalf("expected no duals but got %v", sol.Duals())
+	}
+	// no mate in one
+	if sol := solve(t, "k7/8/2K5/8/8/8/8/7R w - - 0 1", problem.Directmate, 1); sol.Solved() {
+		t.Fatalf("expected no solution but got %s", sol)
+	}
+}
+
+func TestSelfmate(t *testing.T) {
+	sol := solve(t, "7k/5P1p/7P/8/2P5/8/p5PP/rb5K w - - 0 1", problem.Selfmate, 1)
+	if len(sol.Keys) != 1 || sol.Keys[0].Move.String() != "c4c5" {
+		t.Fatalf("expected key c5 but got %s", sol)
+	}
+	if n := len(sol.Keys[0].Children); n != 5 {
+		t.Fatalf("expected 5 defences but got %d", n)
+	}
+	for _, d := range sol.Keys[0].Children {
+		if d.Position.Status() != chess.Checkmate {
+			t.Fatalf("expected %s to mate", d.Move)
+		}
+	}
+}
+
+func TestHelpmate(t *testing.T) {
+	fen := "7k/8/5K2/8/8/8/8/R7 b - - 0 1"
+	if sol := solve(t, fen, problem.Helpmate, 1); sol.Solved() {
+		t.Fatalf("expected no solution but got %s", sol)
+	}
+	sol := solve(t, fen, problem.Helpmate, 2)
+	if len(sol.Keys) != 2 {
+		t.Fatalf("expected 2 first moves but got %s", sol)
+	}
+	for _, k := range sol.Keys {
+		for _, w := range k.Children {
+			for _, b := range w.Children {
+				for _, mate := range b.Children {
+					if mate.Position.Status() != chess.Checkmate || len(mate.Children) != 0 {
+						t.Fatalf("expected %s to mate", mate.Move)
+					}
+				}
+			}
+		}
+	}
+	duals := sol.Duals()
+	if len(duals) != 2 || len(duals[0]) != 1 {
+		t.Fatalf("expected duals after both first moves but got %v", duals)
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	pos := chess.NewGame().Position()
+	if _, err := problem.Solve(context.Background(), pos, problem.Directmate, 0); err == nil {
+		t.Fatal("expected error for zero moves")
+	}
+	if _, err := problem.Solve(context.Background(), pos, problem.Stipulation(9), 1); err == nil {
+		t.Fatal("expected error for unknown stipulation")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := problem.Solve(ctx, pos, problem.Directmate, 3); err != context.Canceled {
+		t.Fatalf("expected %v but got %v", context.Canceled, err)
+	}
+}
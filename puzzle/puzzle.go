@@ -0,0 +1,290 @@
+// Package puzzle extracts tactical puzzles from analysed games.  Candidate
+// positions are found from the [%eval] comments of a game, such as those
+// written by the analysis package, and verified with a multi-PV search:
+// the solver must have exactly one winning move at every step.
+package puzzle
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/analysis"
+	"github.com/notnil/chess/uci"
+)
+
+// Puzzle is a puzzle record.
+type Puzzle struct {
+	// FEN is the position the solver faces.
+	FEN string
+	// LastMove is the opponent's move leading to the position in UCI
+	// notation or empty if the puzzle doesn't come from a game.
+	LastMove string
+	// Solution alternates the solver's and the opponent's moves, starting
+	// and ending with the solver's, in UCI notation.  SAN is the same line
+	// in algebraic notation.
+	Solution []string
+	SAN      []string
+	Themes   []Theme
+	// Rating is a rough estimate of the difficulty.
+	Rating int
+	// Ply is the number of moves of the game played before the position.
+	Ply int
+}
+
+// HasTheme returns true if the puzzle is tagged with the theme.
+func (p Puzzle) HasTheme(t Theme) bool {
+	for _, theme := range p.Themes {
+		if theme == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Finder finds and verifies puzzles with a Searcher.
+type Finder struct {
+	searcher    Searcher
+	winning     int
+	alternative int
+	maxMoves    int
+}
+
+// WinningScore is an option for the New function to set the centipawn
+// score the solver must reach with the solution.  The default is 200.
+func WinningScore(cp int) func(f *Finder) {
+	return func(f *Finder) {
+		f.winning = cp
+	}
+}
+
+// AlternativeScore is an option for the New function to set the centipawn
+// score every alternative to a solution move must stay at or below.  The
+// default is 100.
+func AlternativeScore(cp int) func(f *Finder) {
+	return func(f *Finder) {
+		f.alternative = cp
+	}
+}
+
+// MaxMoves is an option for the New function to set the maximum number of
+// the solver's moves in a solution.  The default is 5.
+func MaxMoves(n int) func(f *Finder) {
+	return func(f *Finder) {
+		f.maxMoves = n
+	}
+}
+
+// New returns a Finder verifying puzzles with the Searcher.
+func New(s Searcher, opts ...func(f *Finder)) *Finder {
+	f := &Finder{searcher: s, winning: 200, alternative: 100, maxMoves: 5}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Find returns the puzzles of the game.  Candidates are positions after a
+// move that gave the opponent a winning score according to the [%eval]
+// comments while the previous score wasn't winning for the opponent.
+func (f *Finder) Find(ctx context.Context, g *chess.Game) ([]Puzzle, error) {
+	puzzles := []Puzzle{}
+	positions := g.Positions()
+	moves := g.Moves()
+	comments := g.Comments()
+	prev, prevOK := 0, true
+	for i, m := range moves {
+		cp, ok := eval(comments[i])
+		pos := positions[i+1]
+		// scores are from white's point of view
+		solver := cp
+		before := prev
+		if pos.Turn() == chess.Black {
+			solver, before = -cp, -prev
+		}
+		if ok && prevOK && solver >= f.winning && before < f.winning {
+			p, found, err := f.Solve(ctx, pos)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				p.LastMove = m.String()
+				p.Ply = i + 1
+				puzzles = append(puzzles, p)
+			}
+		}
+		prev, prevOK = cp, ok
+	}
+	return puzzles, nil
+}
+
+// Solve returns the puzzle of the position if the side to move has a
+// unique winning move.  The solution continues while the solver's moves
+// stay unique and, unless the solver mates, forcing: after the first move
+// the solution ends before a move that isn't a check, capture or
+// promotion.
+func (f *Finder) Solve(ctx context.Context, pos *chess.Position) (Puzzle, bool, error) {
+	if len(pos.ValidMoves()) < 2 {
+		return Puzzle{}, false, nil
+	}
+	start := pos
+	solution := []*chess.Move{}
+	solverMoves := 0
+	mate := false
+	for solverMoves < f.maxMoves {
+		lines, err := f.searcher.MultiPV(ctx, pos, 2)
+		if err != nil {
+			return Puzzle{}, false, err
+		}
+		if len(lines) == 0 || len(lines[0].PV) == 0 {
+			break
+		}
+		best := lines[0]
+		score := centipawns(best.Score)
+		if solverMoves == 0 {
+			mate = best.Score.Mate > 0
+		}
+		if score < f.winning || mate && best.Score.Mate <= 0 {
+			break
+		}
+		m := legal(pos, best.PV[0])
+		if m == nil {
+			break
+		}
+		if m.Promo() != chess.NoPieceType {
+			// underpromotions aren't alternatives
+			if lines, err = f.searcher.MultiPV(ctx, pos, 5); err != nil {
+				return Puzzle{}, false, err
+			}
+		}
+		if alt, ok := alternative(lines, m); ok && centipawns(alt.Score) > f.alternative {
+			break
+		}
+		if solverMoves > 0 && !mate && !forcing(m) {
+			break
+		}
+		solution = append(solution, m)
+		solverMoves++
+		pos = pos.Update(m)
+		if len(pos.ValidMoves()) == 0 {
+			break
+		}
+		replies, err := f.searcher.MultiPV(ctx, pos, 1)
+		if err != nil {
+			return Puzzle{}, false, err
+		}
+		if len(replies) == 0 || len(replies[0].PV) == 0 {
+			break
+		}
+		reply := legal(pos, replies[0].PV[0])
+		if reply == nil {
+			break
+		}
+		solution = append(solution, reply)
+		pos = pos.Update(reply)
+	}
+	// the solution ends with the solver's move
+	if len(solution)%2 == 0 && len(solution) > 0 {
+		solution = solution[:len(solution)-1]
+	}
+	if len(solution) == 0 {
+		return Puzzle{}, false, nil
+	}
+	p := Puzzle{FEN: start.String(), Themes: themes(start, solution)}
+	pos = start
+	for _, m := range solution {
+		p.Solution = append(p.Solution, m.String())
+		p.SAN = append(p.SAN, chess.AlgebraicNotation{}.Encode(pos, m))
+		pos = pos.Update(m)
+	}
+	p.Rating = rating(p, solution)
+	return p, true, nil
+}
+
+// legal returns the legal move of the position matching m.
+func legal(pos *chess.Position, m *chess.Move) *chess.Move {
+	for _, valid := range pos.ValidMoves() {
+		if valid.S1() == m.S1() && valid.S2() == m.S2() && valid.Promo() == m.Promo() {
+			return valid
+		}
+	}
+	return nil
+}
+
+// alternative returns the best line starting with a different move than m
+// or a different promotion of it.
+func alternative(lines []uci.Info, m *chess.Move) (uci.Info, bool) {
+	for _, line := range lines {
+		if len(line.PV) > 0 && (line.PV[0].S1() != m.S1() || line.PV[0].S2() != m.S2()) {
+			return line, true
+		}
+	}
+	return uci.Info{}, false
+}
+
+func forcing(m *chess.Move) bool {
+	return m.HasTag(chess.Check) || m.HasTag(chess.Capture) || m.Promo() != chess.NoPieceType
+}
+
+// centipawns returns the score in centipawns with mates scored like the
+// analysis package.
+func centipawns(s uci.Score) int {
+	switch {
+	case s.Mate > 0:
+		return analysis.MateScore - s.Mate
+	case s.Mate < 0:
+		return -analysis.MateScore - s.Mate
+	}
+	return s.CP
+}
+
+var evalRe = regexp.MustCompile(`\[%eval\s+(#?)(-?[\d.]+)\]`)
+
+// eval returns the score of the [%eval] comment in centipawns from white's
+// point of view.
+func eval(comments []string) (int, bool) {
+	for _, c := range comments {
+		match := evalRe.FindStringSubmatch(c)
+		if match == nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			return 0, false
+		}
+		if match[1] == "" {
+			return int(math.Round(v * 100)), true
+		}
+		n := int(v)
+		if strings.HasPrefix(match[2], "-") {
+			return -analysis.MateScore - n, true
+		}
+		return analysis.MateScore - n, true
+	}
+	return 0, false
+}
+
+// rating estimates the difficulty of the puzzle from the length of the
+// solution, whether the first move is forcing and its themes.
+func rating(p Puzzle, solution []*chess.Move) int {
+	r := 1000 + 250*(len(solution)-1)/2
+	if !forcing(solution[0]) {
+		r += 300
+	}
+	if p.HasTheme(MateIn(1)) {
+		r -= 200
+	}
+	if p.HasTheme(DiscoveredAttack) || p.HasTheme(Skewer) {
+		r += 100
+	}
+	switch {
+	case r < 600:
+		return 600
+	case r > 2800:
+		return 2800
+	}
+	return r
+}
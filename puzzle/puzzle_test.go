@@ -0,0 +1,262 @@
+package puzzle_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/enginetest"
+	"github.com/notnil/chess/puzzle"
+	"github.com/notnil/chess/uci"
+)
+
+const mateScore = 100000
+
+// TestMain makes the test binary act as a UCI engine so UCISearcher can be
+// tested.
+func TestMain(m *testing.M) {
+	enginetest.Main(m, map[string]enginetest.Engine{
+		"server": enginetest.Server(&uciSearcher{multiPV: 1}),
+	})
+}
+
+// uciSearcher serves searcher with the MultiPV option.
+type uciSearcher struct {
+	multiPV int
+}
+
+func (s *uciSearcher) Options() []uci.Option {
+	return []uci.Option{{Name: "MultiPV", Type: uci.OptionSpin, Default: "1", Min: "1", Max: "10"}}
+}
+
+func (s *uciSearcher) SetOption(name, value string) error {
+	n, err := strconv.Atoi(value)
+	s.multiPV = n
+	return err
+}
+
+func (s *uciSearcher) Search(ctx context.Context, pos *chess.Position, cmd uci.CmdGo, send func(uci.Info)) (*chess.Move, *chess.Move) {
+	lines, _ := searcher{depth: cmd.Depth}.MultiPV(ctx, pos, s.multiPV)
+	for i, line := range lines {
+		line.Multipv = i + 1
+		line.Depth = cmd.Depth
+		send(line)
+	}
+	return lines[0].PV[0], nil
+}
+
+// searcher is a full width material searcher to the depth.
+type searcher struct {
+	depth int
+}
+
+func (s searcher) MultiPV(ctx context.Context, pos *chess.Position, n int) ([]uci.Info, error) {
+	lines := []uci.Info{}
+	for _, m := range pos.ValidMoves() {
+		score, pv := negamax(pos.Update(m), s.depth-1, 1)
+		lines = append(lines, uci.Info{Score: toScore(-score), PV: append([]*chess.Move{m}, pv...)})
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return toCP(lines[i].Score) > toCP(lines[j].Score)
+	})
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return lines, nil
+}
+
+func negamax(pos *chess.Position, depth, ply int) (int, []*chess.Move) {
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		if pos.Status() == chess.Checkmate {
+			return -mateScore + ply, nil
+		}
+		return 0, nil
+	}
+	if depth == 0 {
+		return material(pos), nil
+	}
+	best, bestPV := -2*mateScore, []*chess.Move(nil)
+	for _, m := range moves {
+		score, pv := negamax(pos.Update(m), depth-1, ply+1)
+		if -score > best {
+			best, bestPV = -score, append([]*chess.Move{m}, pv...)
+		}
+	}
+	return best, bestPV
+}
+
+func material(pos *chess.Position) int {
+	values := map[chess.PieceType]int{chess.Pawn: 100, chess.Knight: 300, chess.Bishop: 300, chess.Rook: 500, chess.Queen: 900}
+	score := 0
+	for _, p := range pos.Board().SquareMap() {
+		if p.Color() == pos.Turn() {
+			score += values[p.Type()]
+		} else {
+			score -= values[p.Type()]
+		}
+	}
+	return score
+}
+
+func toScore(score int) uci.Score {
+	switch {
+	case score > mateScore-1000:
+		return uci.Score{Mate: (mateScore - score + 1) / 2}
+	case score < -mateScore+1000:
+		return uci.Score{Mate: -(mateScore + score) / 2}
+	}
+	return uci.Score{CP: score}
+}
+
+func toCP(s uci.Score) int {
+	switch {
+	case s.Mate > 0:
+		return mateScore - s.Mate
+	case s.Mate < 0:
+		return -mateScore - s.Mate
+	}
+	return s.CP
+}
+
+func game(t *testing.T, fen string, moves ...string) *chess.Game {
+	opt, err := chess.FEN(fen, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := chess.NewGame(opt)
+	for i := 0; i < len(moves); i += 2 {
+		if err := g.MoveStr(moves[i]); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddComment(moves[i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestFindFork(t *testing.T) {
+	g := game(t, "r4k2/8/8/1N6/8/8/8/6K1 b - - 0 1", "Ke8", "[%eval 3.00]")
+	puzzles, err := puzzle.New(searcher{depth: 3}).Find(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 1 {
+		t.Fatalf("expected one puzzle but got %d", len(puzzles))
+	}
+	p := puzzles[0]
+	if p.FEN != "r3k3/8/8/1N6/8/8/8/6K1 w - - 1 2" || p.LastMove != "f8e8" || p.Ply != 1 {
+		t.Fatalf("unexpected puzzle %+v", p)
+	}
+	if len(p.Solution) != 3 || p.SAN[0] != "Nc7+" || p.SAN[2] != "Nxa8" {
+		t.Fatalf("expected Nc7+ and Nxa8 but got %v", p.SAN)
+	}
+	if !p.HasTheme(puzzle.Fork) || p.HasTheme(puzzle.Mate) {
+		t.Fatalf("expected fork but got %v", p.Themes)
+	}
+	if p.Rating < 600 || p.Rating > 2800 {
+		t.Fatalf("unexpected rating %d", p.Rating)
+	}
+}
+
+func TestFindBackRankMate(t *testing.T) {
+	g := game(t, "r5k1/5ppp/8/8/8/8/5PPP/3R2K1 b - - 0 1", "Ra2", "[%eval #1]")
+	puzzles, err := puzzle.New(searcher{depth: 2}).Find(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 1 {
+		t.Fatalf("expected one puzzle but got %d", len(puzzles))
+	}
+	p := puzzles[0]
+	if !reflect.DeepEqual(p.Solution, []string{"d1d8"}) || p.SAN[0] != "Rd8#" {
+		t.Fatalf("expected Rd8# but got %v", p.SAN)
+	}
+	for _, theme := range []puzzle.Theme{puzzle.Mate, puzzle.MateIn(1), puzzle.BackRankMate} {
+		if !p.HasTheme(theme) {
+			t.Fatalf("expected theme %s in %v", theme, p.Themes)
+		}
+	}
+}
+
+func TestSolveNotUnique(t *testing.T) {
+	// two rooks can mate on the back rank
+	opt, err := chess.FEN("6k1/5ppp/8/8/8/8/5PPP/R2R2K1 w - - 0 1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, found, err := puzzle.New(searcher{depth: 2}).Solve(context.Background(), chess.NewGame(opt).Position())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Fatal("expected no puzzle with two mating moves")
+	}
+}
+
+func TestFindSkipsEqualPositions(t *testing.T) {
+	g := game(t, "r4k2/8/8/1N6/8/8/8/6K1 b - - 0 1", "Kg8", "[%eval -2.00]", "Kg2", "[%eval -2.00]")
+	puzzles, err := puzzle.New(searcher{depth: 3}).Find(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 0 {
+		t.Fatalf("expected no puzzles but got %+v", puzzles)
+	}
+}
+
+func TestThemes(t *testing.T) {
+	tests := []struct {
+		fen   string
+		theme puzzle.Theme
+	}{
+		// Bb5 pins the knight to the king
+		{"4k3/8/2n5/8/8/8/8/4KB2 w - - 0 1", puzzle.Pin},
+		// Rh5+ skewers the king and queen
+		{"8/8/8/q3k3/8/8/7R/1K6 w - - 0 1", puzzle.Skewer},
+		// Bd5+ uncovers the rook's attack on the queen
+		{"4q1k1/8/6p1/8/4B3/8/8/K3R3 w - - 0 1", puzzle.DiscoveredAttack},
+		// the pawn promotes
+		{"1r4k1/P7/8/8/8/8/8/K7 w - - 0 1", puzzle.Promotion},
+	}
+	for _, test := range tests {
+		opt, err := chess.FEN(test.fen, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, found, err := puzzle.New(searcher{depth: 3}, puzzle.MaxMoves(2)).Solve(context.Background(), chess.NewGame(opt).Position())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || !p.HasTheme(test.theme) {
+			t.Fatalf("expected %s puzzle in %s but got %v %+v", test.theme, test.fen, found, p)
+		}
+		if !strings.HasPrefix(p.FEN, strings.Fields(test.fen)[0]) {
+			t.Fatalf("unexpected fen %s", p.FEN)
+		}
+	}
+}
+
+func TestUCISearcher(t *testing.T) {
+	eng, err := uci.New(enginetest.Path("server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	g := game(t, "r4k2/8/8/1N6/8/8/8/6K1 b - - 0 1", "Ke8", "[%eval 3.00]")
+	puzzles, err := puzzle.New(puzzle.UCISearcher(eng, uci.CmdGo{Depth: 3})).Find(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 1 || puzzles[0].SAN[0] != "Nc7+" {
+		t.Fatalf("expected fork puzzle but got %+v", puzzles)
+	}
+}
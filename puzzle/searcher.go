@@ -0,0 +1,49 @@
+package puzzle
+
+import (
+	"context"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// Searcher returns the best lines of positions to verify puzzles.  MultiPV
+// returns up to n lines ordered from best to worst with scores from the
+// point of view of the side to move.  Fewer lines are returned if the
+// position has fewer legal moves.
+type Searcher interface {
+	MultiPV(ctx context.Context, pos *chess.Position, n int) ([]uci.Info, error)
+}
+
+// UCISearcher returns a Searcher searching with the engine and the go
+// command, such as uci.CmdGo{Depth: 20}.  The engine's MultiPV option is
+// set before every search.  CmdUCI should be run before searching.
+func UCISearcher(eng *uci.Engine, cmd uci.CmdGo) Searcher {
+	return &uciSearcher{eng: eng, cmd: cmd}
+}
+
+type uciSearcher struct {
+	eng     *uci.Engine
+	cmd     uci.CmdGo
+	multiPV int
+}
+
+func (s *uciSearcher) MultiPV(ctx context.Context, pos *chess.Position, n int) ([]uci.Info, error) {
+	if n != s.multiPV {
+		if err := s.eng.SetSpin("MultiPV", n); err != nil {
+			return nil, err
+		}
+		s.multiPV = n
+	}
+	if err := s.eng.RunContext(ctx, uci.CmdPosition{Position: pos}, s.cmd); err != nil {
+		return nil, err
+	}
+	results := s.eng.SearchResults()
+	if len(results.MultiPV) > 0 {
+		return results.MultiPV, nil
+	}
+	if len(results.Info.PV) == 0 {
+		return nil, nil
+	}
+	return []uci.Info{results.Info}, nil
+}
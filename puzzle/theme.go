@@ -0,0 +1,102 @@
+package puzzle
+
+import (
+	"strconv"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/tactics"
+)
+
+// Theme is a tactical theme of a puzzle named like the themes of
+// lichess.org.
+type Theme string
+
+const (
+	// Fork indicates that a piece attacks two valuable targets.
+	Fork Theme = "fork"
+	// Pin indicates that a piece is pinned to a more valuable piece.
+	Pin Theme = "pin"
+	// Skewer indicates that a valuable piece is attacked with a piece
+	// behind it.
+	Skewer Theme = "skewer"
+	// DiscoveredAttack indicates that a piece moves out of the way of an
+	// attack by another piece.
+	DiscoveredAttack Theme = "discoveredAttack"
+	// Promotion indicates that a pawn promotes.
+	Promotion Theme = "promotion"
+	// Mate indicates that the solution ends with checkmate.
+	Mate Theme = "mate"
+	// BackRankMate indicates a mate by a rook or queen on the king's first
+	// rank.
+	BackRankMate Theme = "backRankMate"
+)
+
+// MateIn returns the theme of a mate in n moves such as mateIn2.
+func MateIn(n int) Theme {
+	return Theme("mateIn" + strconv.Itoa(n))
+}
+
+// themes returns the themes of the solution from the position.
+func themes(pos *chess.Position, solution []*chess.Move) []Theme {
+	found := map[Theme]bool{}
+	themes := []Theme{}
+	add := func(t Theme) {
+		if !found[t] {
+			found[t] = true
+			themes = append(themes, t)
+		}
+	}
+	solver := pos.Turn()
+	var last *chess.Move
+	for i, m := range solution {
+		post := pos.Update(m)
+		if i%2 == 0 {
+			if m.Promo() != chess.NoPieceType {
+				add(Promotion)
+			}
+			for _, motif := range tactics.Move(pos, m) {
+				switch motif.Kind {
+				case tactics.Fork:
+					add(Fork)
+				case tactics.AbsolutePin, tactics.RelativePin:
+					add(Pin)
+				case tactics.Skewer:
+					add(Skewer)
+				case tactics.DiscoveredAttack, tactics.DiscoveredCheck:
+					add(DiscoveredAttack)
+				}
+			}
+			last = m
+		}
+		pos = post
+	}
+	if pos.Status() == chess.Checkmate {
+		add(Mate)
+		add(MateIn((len(solution) + 1) / 2))
+		if isBackRankMate(pos.Board(), last, solver.Other()) {
+			add(BackRankMate)
+		}
+	}
+	return themes
+}
+
+// isBackRankMate returns true if the mated king of the color is on its
+// first rank and the mating piece is a rook or queen on the same rank.
+func isBackRankMate(b *chess.Board, last *chess.Move, c chess.Color) bool {
+	if last == nil {
+		return false
+	}
+	rank := chess.Rank1
+	if c == chess.Black {
+		rank = chess.Rank8
+	}
+	for sq, p := range b.SquareMap() {
+		if p.Type() != chess.King || p.Color() != c {
+			continue
+		}
+		mater := b.Piece(last.S2())
+		return sq.Rank() == rank && last.S2().Rank() == rank &&
+			(mater.Type() == chess.Rook || mater.Type() == chess.Queen)
+	}
+	return false
+}
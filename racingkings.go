@@ -0,0 +1,66 @@
+package chess
+
+// RacingKings is a variant in which both players race their king to the
+// eighth rank.  Giving check is illegal and there is no checkmate.  If
+// white reaches the eighth rank first, black gets one last move to reach
+// it too and draw the game.
+type RacingKings struct {
+	Standard
+}
+
+const racingKingsFEN = "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+
+// Name implements the Variant interface.
+func (RacingKings) Name() string {
+	return "Racing Kings"
+}
+
+// StartingPosition implements the Variant interface.
+func (RacingKings) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(racingKingsFEN, RacingKings{})
+	return pos
+}
+
+// Moves implements the Variant interface and excludes moves giving check.
+func (RacingKings) Moves(pos *Position) []*Move {
+	moves := []*Move{}
+	for _, m := range (engine{}).CalcMoves(pos, false) {
+		if !m.HasTag(Check) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// Status implements the Variant interface.  Possible methods are
+// KingReachedGoal, Stalemate and NoMethod.
+func (RacingKings) Status(pos *Position) (Outcome, Method) {
+	white := pos.board.bbWhiteKing&bbRank8 != 0
+	black := pos.board.bbBlackKing&bbRank8 != 0
+	switch {
+	case white && black:
+		return Draw, KingReachedGoal
+	case black:
+		return BlackWon, KingReachedGoal
+	case white && pos.turn == White:
+		return WhiteWon, KingReachedGoal
+	case white:
+		// black gets a last move to reach the goal
+		for _, m := range pos.ValidMoves() {
+			if m.s1 == pos.board.blackKingSq && m.s2.Rank() == Rank8 {
+				return NoOutcome, NoMethod
+			}
+		}
+		return WhiteWon, KingReachedGoal
+	}
+	if len(pos.ValidMoves()) == 0 {
+		return Draw, Stalemate
+	}
+	return NoOutcome, NoMethod
+}
+
+// InsufficientMaterial implements the Variant interface and always
+// returns false since kings can always race.
+func (RacingKings) InsufficientMaterial(pos *Position) bool {
+	return false
+}
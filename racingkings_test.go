@@ -0,0 +1,78 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRacingKingsPerft(t *testing.T) {
+	pos := RacingKings{}.StartingPosition()
+	for depth, expected := range []int{1, 21, 421, 11264, 296242} {
+		if n := perft(pos, depth); n != expected {
+			t.Fatalf("expected perft(%d) to be %d but got %d", depth, expected, n)
+		}
+	}
+}
+
+func TestRacingKings(t *testing.T) {
+	tests := []struct {
+		fen     string
+		move    string
+		outcome Outcome
+	}{
+		// white reaches the goal and black can't follow
+		{"8/1K6/8/8/8/8/8/7k w - - 0 1", "Kb8", WhiteWon},
+		// black reaches the goal first
+		{"8/1k6/8/8/8/8/8/7K b - - 0 1", "Kb8", BlackWon},
+		// white reaches the goal and black can still follow
+		{"8/1K4k1/8/8/8/8/8/8 w - - 0 1", "Kb8", NoOutcome},
+	}
+	for _, test := range tests {
+		opt, err := VariantFEN(RacingKings{}, test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(opt)
+		if err := g.MoveStr(test.move); err != nil {
+			t.Fatal(err)
+		}
+		if g.Outcome() != test.outcome {
+			t.Fatalf("%s: expected %s but got %s", test.fen, test.outcome, g.Outcome())
+		}
+		if test.outcome == NoOutcome {
+			if err := g.MoveStr("Kg8"); err != nil {
+				t.Fatal(err)
+			}
+			if g.Outcome() != Draw || g.Method() != KingReachedGoal {
+				t.Fatalf("expected draw when both kings reach the goal but got %s %s", g.Outcome(), g.Method())
+			}
+		}
+	}
+	opt, err := VariantFEN(RacingKings{}, "8/8/8/8/8/8/k7/2R4K b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	if err := g.MoveStr("Kb2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MoveStr("Rc2"); err == nil {
+		t.Fatal("expected giving check to be illegal")
+	}
+	pgn := `[Variant "Racing Kings"]
+
+1. Ka3 *`
+	opt, err = PGN(strings.NewReader(pgn))
+	if err == nil {
+		t.Fatal("expected Ka3 to be illegal from the racing kings start")
+	}
+	pgn = `[Variant "Racing Kings"]
+
+1. Kh3 *`
+	if opt, err = PGN(strings.NewReader(pgn)); err != nil {
+		t.Fatal(err)
+	}
+	if g := NewGame(opt); g.Variant() != (RacingKings{}) {
+		t.Fatalf("expected racing kings but got %s", g.Variant().Name())
+	}
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// Pentanomial counts game pairs played from the same opening with reversed
+// colors by the score of the pair: 0, 1/2, 1, 3/2 and 2.  Pairing removes
+// the bias of unbalanced openings from the variance.
+type Pentanomial [5]int
+
+// AddPair adds a game pair from the outcomes of the game in which the
+// player had the color and the reversed game.  Pairs with a game without an
+// outcome are ignored.
+func (p *Pentanomial) AddPair(first, second chess.Outcome, c chess.Color) {
+	a, b := score(first, c), score(second, c.Other())
+	if a < 0 || b < 0 {
+		return
+	}
+	p[int((a+b)*2)]++
+}
+
+// PentanomialFromGames returns the pentanomial of the player with the name
+// from games paired in order, as played by the match package with openings.
+// The player's color is read from the White tag pair.  A trailing unpaired
+// game is ignored.
+func PentanomialFromGames(games []*chess.Game, name string) Pentanomial {
+	p := Pentanomial{}
+	for i := 0; i+1 < len(games); i += 2 {
+		c := chess.Black
+		if tag := games[i].GetTagPair("White"); tag != nil && tag.Value == name {
+			c = chess.White
+		}
+		p.AddPair(games[i].Outcome(), games[i+1].Outcome(), c)
+	}
+	return p
+}
+
+// Pairs returns the number of game pairs.
+func (p Pentanomial) Pairs() int {
+	n := 0
+	for _, c := range p {
+		n += c
+	}
+	return n
+}
+
+// Samples implements the Sample interface.
+func (p Pentanomial) Samples() int {
+	return p.Pairs()
+}
+
+// Score returns the mean score per game or 0.5 without games.
+func (p Pentanomial) Score() float64 {
+	n := p.Pairs()
+	if n == 0 {
+		return 0.5
+	}
+	sum := 0.0
+	for i, c := range p {
+		sum += float64(c) * float64(i) / 4
+	}
+	return sum / float64(n)
+}
+
+// Variance returns the variance of the mean score per game of one pair.
+func (p Pentanomial) Variance() float64 {
+	n := p.Pairs()
+	if n == 0 {
+		return 0
+	}
+	s := p.Score()
+	v := 0.0
+	for i, c := range p {
+		d := float64(i)/4 - s
+		v += float64(c) * d * d
+	}
+	return v / float64(n)
+}
+
+// WDL returns the wins, draws and losses of the pairs' games.  Pairs
+// scoring 1 are counted as two draws.
+func (p Pentanomial) WDL() WDL {
+	return WDL{
+		Wins:   2*p[4] + p[3],
+		Draws:  p[3] + 2*p[2] + p[1],
+		Losses: p[1] + 2*p[0],
+	}
+}
+
+// String returns the counts such as [0, 3, 10, 5, 1].
+func (p Pentanomial) String() string {
+	return fmt.Sprintf("[%d, %d, %d, %d, %d]", p[0], p[1], p[2], p[3], p[4])
+}
@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// Decision is the state of a sequential probability ratio test.
+type Decision int
+
+const (
+	// Continue indicates that more games are needed.
+	Continue Decision = iota
+	// AcceptH0 indicates that the Elo difference is at most Elo0.
+	AcceptH0
+	// AcceptH1 indicates that the Elo difference is at least Elo1.
+	AcceptH1
+)
+
+// String implements the fmt.Stringer interface.
+func (d Decision) String() string {
+	switch d {
+	case AcceptH0:
+		return "H0 accepted"
+	case AcceptH1:
+		return "H1 accepted"
+	}
+	return "continue"
+}
+
+// SPRT is a sequential probability ratio test of the hypotheses that the
+// Elo difference is Elo0 (H0) or Elo1 (H1) with the probabilities Alpha of
+// a false positive and Beta of a false negative, such as Elo0 0, Elo1 5 and
+// Alpha and Beta 0.05.  The test can be evaluated after every game to stop
+// a match early.
+type SPRT struct {
+	Elo0  float64
+	Elo1  float64
+	Alpha float64
+	Beta  float64
+}
+
+// Bounds returns the log-likelihood ratios at which H0 and H1 are
+// accepted.
+func (t SPRT) Bounds() (lower, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// LLR returns the log-likelihood ratio of H1 to H0 for the sample using the
+// normal approximation of the generalized SPRT.  The sample is typically a
+// WDL or, to account for paired openings, a Pentanomial.
+func (t SPRT) LLR(s Sample) float64 {
+	v := s.Variance()
+	if s.Samples() == 0 || v == 0 {
+		return 0
+	}
+	s0, s1 := EloToScore(t.Elo0), EloToScore(t.Elo1)
+	return float64(s.Samples()) * (s1 - s0) * (2*s.Score() - s0 - s1) / (2 * v)
+}
+
+// Test returns the decision for the sample.
+func (t SPRT) Test(s Sample) Decision {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return AcceptH1
+	case llr <= lower:
+		return AcceptH0
+	}
+	return Continue
+}
+
+// String returns the test such as SPRT(0, 5) alpha 0.05 beta 0.05.
+func (t SPRT) String() string {
+	return fmt.Sprintf("SPRT(%g, %g) alpha %g beta %g", t.Elo0, t.Elo1, t.Alpha, t.Beta)
+}
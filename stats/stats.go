@@ -0,0 +1,161 @@
+// Package stats computes Elo differences, likelihood of superiority and
+// sequential probability ratio tests from the results of matches.
+package stats
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/notnil/chess"
+)
+
+// Sample is a set of results from the point of view of one player.  Score
+// is the mean score per game and Variance the variance of the score of one
+// sample where a sample is a game or a game pair.
+type Sample interface {
+	Samples() int
+	Score() float64
+	Variance() float64
+}
+
+// WDL is the number of wins, draws and losses of a player.
+type WDL struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Add adds the outcome of a game in which the player had the color.  Games
+// without an outcome are ignored.
+func (r *WDL) Add(o chess.Outcome, c chess.Color) {
+	switch score(o, c) {
+	case 1:
+		r.Wins++
+	case 0.5:
+		r.Draws++
+	case 0:
+		r.Losses++
+	}
+}
+
+// Games returns the number of games.
+func (r WDL) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Samples implements the Sample interface.
+func (r WDL) Samples() int {
+	return r.Games()
+}
+
+// Score returns the mean score per game or 0.5 without games.
+func (r WDL) Score() float64 {
+	n := r.Games()
+	if n == 0 {
+		return 0.5
+	}
+	return (float64(r.Wins) + float64(r.Draws)/2) / float64(n)
+}
+
+// Variance returns the variance of the score of one game.
+func (r WDL) Variance() float64 {
+	n := r.Games()
+	if n == 0 {
+		return 0
+	}
+	s := r.Score()
+	v := float64(r.Wins)*(1-s)*(1-s) + float64(r.Draws)*(0.5-s)*(0.5-s) + float64(r.Losses)*s*s
+	return v / float64(n)
+}
+
+// DrawRatio returns the fraction of games drawn.
+func (r WDL) DrawRatio() float64 {
+	n := r.Games()
+	if n == 0 {
+		return 0
+	}
+	return float64(r.Draws) / float64(n)
+}
+
+// LOS returns the likelihood of superiority, the probability that the
+// player is stronger.  Draws are ignored.
+func (r WDL) LOS() float64 {
+	if r.Wins+r.Losses == 0 {
+		return 0.5
+	}
+	return 0.5 * (1 + math.Erf(float64(r.Wins-r.Losses)/math.Sqrt(2*float64(r.Wins+r.Losses))))
+}
+
+// String returns the results such as +10 -5 =20.
+func (r WDL) String() string {
+	return fmt.Sprintf("+%d -%d =%d", r.Wins, r.Losses, r.Draws)
+}
+
+// Elo is an Elo difference with its confidence interval.
+type Elo struct {
+	Diff       float64
+	Lower      float64
+	Upper      float64
+	Confidence float64
+}
+
+// Margin returns half the width of the confidence interval.
+func (e Elo) Margin() float64 {
+	return (e.Upper - e.Lower) / 2
+}
+
+// String returns the difference such as 35.2 +/- 12.1.
+func (e Elo) String() string {
+	return fmt.Sprintf("%.1f +/- %.1f", e.Diff, e.Margin())
+}
+
+// EloDiff returns the Elo difference and its confidence interval, such as
+// 0.95, of the sample.  The difference is infinite if the player won or
+// lost every game.
+func EloDiff(s Sample, confidence float64) Elo {
+	score := s.Score()
+	elo := Elo{Diff: ScoreToElo(score), Confidence: confidence}
+	if s.Samples() == 0 {
+		return elo
+	}
+	dev := Quantile((1+confidence)/2) * math.Sqrt(s.Variance()/float64(s.Samples()))
+	elo.Lower = ScoreToElo(score - dev)
+	elo.Upper = ScoreToElo(score + dev)
+	return elo
+}
+
+// ScoreToElo returns the Elo difference of a player with the expected score.
+func ScoreToElo(score float64) float64 {
+	switch {
+	case score <= 0:
+		return math.Inf(-1)
+	case score >= 1:
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+// EloToScore returns the expected score of a player with the Elo difference.
+func EloToScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// Quantile returns the quantile of the standard normal distribution for the
+// probability p, such as 1.96 for 0.975.
+func Quantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// score returns the score of the color for the outcome or -1 without an
+// outcome.
+func score(o chess.Outcome, c chess.Color) float64 {
+	switch {
+	case o == chess.Draw:
+		return 0.5
+	case o == chess.WhiteWon && c == chess.White, o == chess.BlackWon && c == chess.Black:
+		return 1
+	case o == chess.WhiteWon, o == chess.BlackWon:
+		return 0
+	}
+	return -1
+}
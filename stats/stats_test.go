@@ -0,0 +1,105 @@
+package stats_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/stats"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestEloDiff(t *testing.T) {
+	r := stats.WDL{Wins: 100, Draws: 100, Losses: 50}
+	elo := stats.EloDiff(r, 0.95)
+	if !approx(elo.Diff, 70.436504) || !approx(elo.Lower, 37.401964) || !approx(elo.Upper, 104.781358) {
+		t.Fatalf("unexpected elo %+v", elo)
+	}
+	if math.Abs(r.LOS()-0.999978) > 1e-6 {
+		t.Fatalf("unexpected los %f", r.LOS())
+	}
+	if r.DrawRatio() != 0.4 || r.String() != "+100 -50 =100" {
+		t.Fatalf("unexpected draw ratio %f or string %s", r.DrawRatio(), r)
+	}
+	if elo := stats.EloDiff(stats.WDL{Wins: 3}, 0.95); !math.IsInf(elo.Diff, 1) {
+		t.Fatalf("expected infinite elo but got %f", elo.Diff)
+	}
+	if elo := stats.EloDiff(stats.WDL{}, 0.95); elo.Diff != 0 {
+		t.Fatalf("expected no elo difference without games but got %f", elo.Diff)
+	}
+}
+
+func TestWDLAdd(t *testing.T) {
+	r := stats.WDL{}
+	r.Add(chess.WhiteWon, chess.White)
+	r.Add(chess.WhiteWon, chess.Black)
+	r.Add(chess.Draw, chess.Black)
+	r.Add(chess.NoOutcome, chess.White)
+	if r != (stats.WDL{Wins: 1, Draws: 1, Losses: 1}) {
+		t.Fatalf("unexpected results %s", r)
+	}
+}
+
+func TestPentanomial(t *testing.T) {
+	p := stats.Pentanomial{}
+	p.AddPair(chess.WhiteWon, chess.BlackWon, chess.White)
+	p.AddPair(chess.WhiteWon, chess.WhiteWon, chess.White)
+	p.AddPair(chess.Draw, chess.WhiteWon, chess.Black)
+	if p != (stats.Pentanomial{0, 0, 1, 1, 1}) {
+		t.Fatalf("unexpected pentanomial %s", p)
+	}
+	if w := p.WDL(); w != (stats.WDL{Wins: 3, Draws: 3}) {
+		t.Fatalf("unexpected results %s", w)
+	}
+
+	games := []*chess.Game{chess.NewGame(), chess.NewGame()}
+	games[0].AddTagPair("White", "A")
+	games[0].Resign(chess.Black)
+	games[1].AddTagPair("White", "B")
+	games[1].Draw(chess.DrawOffer)
+	if p := stats.PentanomialFromGames(games, "A"); p != (stats.Pentanomial{0, 0, 0, 1, 0}) {
+		t.Fatalf("unexpected pentanomial %s", p)
+	}
+	if p := stats.PentanomialFromGames(games, "B"); p != (stats.Pentanomial{0, 1, 0, 0, 0}) {
+		t.Fatalf("unexpected pentanomial %s", p)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := stats.SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+	lower, upper := sprt.Bounds()
+	if !approx(lower, -2.944439) || !approx(upper, 2.944439) {
+		t.Fatalf("unexpected bounds %f %f", lower, upper)
+	}
+	if llr := sprt.LLR(stats.WDL{Wins: 100, Draws: 100, Losses: 50}); !approx(llr, 1.238614) {
+		t.Fatalf("unexpected llr %f", llr)
+	}
+	if llr := sprt.LLR(stats.Pentanomial{5, 20, 50, 30, 10}); !approx(llr, 0.570002) {
+		t.Fatalf("unexpected pentanomial llr %f", llr)
+	}
+
+	// stop as soon as the test is decided
+	r := stats.WDL{}
+	games := 0
+	for sprt.Test(r) == stats.Continue {
+		r.Wins += 2
+		r.Draws += 4
+		r.Losses++
+		games += 7
+	}
+	if sprt.Test(r) != stats.AcceptH1 || games > 1000 {
+		t.Fatalf("expected early acceptance of h1 but got %s after %d games", sprt.Test(r), games)
+	}
+	r = stats.WDL{}
+	for sprt.Test(r) == stats.Continue {
+		r.Wins++
+		r.Draws += 4
+		r.Losses += 2
+	}
+	if sprt.Test(r) != stats.AcceptH0 {
+		t.Fatalf("expected acceptance of h0 but got %s", sprt.Test(r))
+	}
+}
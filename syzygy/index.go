@@ -0,0 +1,348 @@
+package syzygy
+
+// Positions are indexed as in Ronald de Man's tables.  Pawnless tables use
+// the eight symmetries of the board and encode the first three pieces, or
+// the two kings, together; pawn tables mirror the board left to right and
+// are split by the file of the leading pawn.
+
+var (
+	triangle    [64]int
+	lower       [64]int
+	diag        [64]int
+	flap        [64]int
+	ptwist      [64]int
+	invflap     [24]int
+	kkIndex     [10][64]int
+	pawnIndex   [5][24]int
+	pawnFactor  [5][4]int
+	invTriangle = [10]int{1, 2, 3, 10, 11, 19, 0, 9, 18, 27}
+	fileToFile  = [8]int{0, 1, 2, 3, 3, 2, 1, 0}
+)
+
+// pivotFactor is the number of placements of the pieces encoded together
+// by the encoding types: three unique pieces and two kings.
+var pivotFactor = [3]int{31332, 28056, 462}
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		s := sq
+		if s%8 > 3 {
+			s ^= 7
+		}
+		if s/8 > 3 {
+			s ^= 56
+		}
+		if offdiag(s) > 0 {
+			s = flipdiag(s)
+		}
+		for i, t := range invTriangle {
+			if t == s {
+				triangle[sq] = i
+			}
+		}
+	}
+	n := 0
+	for sq := 0; sq < 64; sq++ {
+		if offdiag(sq) < 0 {
+			lower[sq] = n
+			n++
+		}
+	}
+	for sq := 0; sq < 64; sq++ {
+		switch {
+		case offdiag(sq) > 0:
+			lower[sq] = lower[flipdiag(sq)]
+		case offdiag(sq) == 0:
+			lower[sq] = 28 + sq%8
+		}
+	}
+	for i := 0; i < 8; i++ {
+		diag[i*9] = i
+		diag[7*(i+1)] = 8 + i
+	}
+	for sq := 8; sq < 56; sq++ {
+		f, r := sq%8, sq/8
+		ff := f
+		if ff > 3 {
+			ff = 7 - f
+		}
+		flap[sq] = ff*6 + r - 1
+		ptwist[sq] = 47 - 12*ff - 2*(r-1)
+		if f > 3 {
+			ptwist[sq]--
+		}
+	}
+	for j := range invflap {
+		invflap[j] = (j%6+1)*8 + j/6
+	}
+	n = 0
+	for i, k := range invTriangle {
+		for sq := 0; sq < 64; sq++ {
+			if adjacent(k, sq) || (i >= 6 && offdiag(sq) > 0) {
+				kkIndex[i][sq] = -1
+				continue
+			}
+			kkIndex[i][sq] = n
+			n++
+		}
+	}
+	for i := 0; i < 5; i++ {
+		s := 0
+		for j := 0; j < 24; j++ {
+			if j%6 == 0 {
+				s = 0
+			}
+			pawnIndex[i][j] = s
+			if i == 0 {
+				s++
+			} else {
+				s += binomial(ptwist[invflap[j]], i)
+			}
+			if j%6 == 5 {
+				pawnFactor[i][j/6] = s
+			}
+		}
+	}
+}
+
+func offdiag(sq int) int {
+	return sq/8 - sq%8
+}
+
+func flipdiag(sq int) int {
+	return (sq>>3 | sq<<3) & 63
+}
+
+func adjacent(a, b int) bool {
+	df, dr := a%8-b%8, a/8-b/8
+	return df >= -1 && df <= 1 && dr >= -1 && dr <= 1
+}
+
+// binomial returns n choose k.
+func binomial(n, k int) int {
+	if k < 0 || n < k {
+		return 0
+	}
+	f, l := 1, 1
+	for i := 0; i < k; i++ {
+		f *= n - i
+		l *= i + 1
+	}
+	return f / l
+}
+
+// subfactor returns the number of placements of k identical pieces on n
+// squares.
+func subfactor(k, n int) int {
+	return binomial(n, k)
+}
+
+// encodePiece returns the index of the pieces on the squares in a pawnless
+// table.  The squares are modified.
+func (t *table) encodePiece(norm, factor []int, pos []int) int {
+	n := t.num
+	if pos[0]&0x04 != 0 {
+		for i := range pos[:n] {
+			pos[i] ^= 0x07
+		}
+	}
+	if pos[0]&0x20 != 0 {
+		for i := range pos[:n] {
+			pos[i] ^= 0x38
+		}
+	}
+	i := 0
+	for i < n-1 && offdiag(pos[i]) == 0 {
+		i++
+	}
+	limit := 2
+	if t.encType == 0 {
+		limit = 3
+	}
+	if i < limit && offdiag(pos[i]) > 0 {
+		for i := range pos[:n] {
+			pos[i] = flipdiag(pos[i])
+		}
+	}
+	var idx int
+	if t.encType == 0 {
+		i := btoi(pos[1] > pos[0])
+		j := btoi(pos[2] > pos[0]) + btoi(pos[2] > pos[1])
+		switch {
+		case offdiag(pos[0]) != 0:
+			idx = triangle[pos[0]]*63*62 + (pos[1]-i)*62 + pos[2] - j
+		case offdiag(pos[1]) != 0:
+			idx = 6*63*62 + diag[pos[0]]*28*62 + lower[pos[1]]*62 + pos[2] - j
+		case offdiag(pos[2]) != 0:
+			idx = 6*63*62 + 4*28*62 + diag[pos[0]]*7*28 + (diag[pos[1]]-i)*28 + lower[pos[2]]
+		default:
+			idx = 6*63*62 + 4*28*62 + 4*7*28 + diag[pos[0]]*7*6 + (diag[pos[1]]-i)*6 + diag[pos[2]] - j
+		}
+		i = 3
+		return t.encodeRest(idx*factor[0], norm, factor, pos, i)
+	}
+	idx = kkIndex[triangle[pos[0]]][pos[1]]
+	return t.encodeRest(idx*factor[0], norm, factor, pos, 2)
+}
+
+// encodeRest adds the index of the groups of identical pieces from i on.
+func (t *table) encodeRest(idx int, norm, factor []int, pos []int, i int) int {
+	for i < t.num {
+		n := norm[i]
+		sortSquares(pos[i : i+n])
+		s := 0
+		for m := i; m < i+n; m++ {
+			p := pos[m]
+			j := 0
+			for _, q := range pos[:i] {
+				j += btoi(p > q)
+			}
+			s += binomial(p-j, m-i+1)
+		}
+		idx += s * factor[i]
+		i += n
+	}
+	return idx
+}
+
+// encodePawn returns the index of the pieces on the squares in a pawn
+// table.  The squares are modified.
+func (t *table) encodePawn(norm, factor []int, pos []int) int {
+	n := t.num
+	if pos[0]&0x04 != 0 {
+		for i := range pos[:n] {
+			pos[i] ^= 0x07
+		}
+	}
+	lead := t.pawns[0]
+	for i := 1; i < lead; i++ {
+		for j := i + 1; j < lead; j++ {
+			if ptwist[pos[i]] < ptwist[pos[j]] {
+				pos[i], pos[j] = pos[j], pos[i]
+			}
+		}
+	}
+	k := lead - 1
+	idx := pawnIndex[k][flap[pos[0]]]
+	for i := k; i > 0; i-- {
+		idx += binomial(ptwist[pos[i]], k-i+1)
+	}
+	idx *= factor[0]
+	i := lead
+	if end := i + t.pawns[1]; end > i {
+		sortSquares(pos[i:end])
+		s := 0
+		for m := i; m < end; m++ {
+			p := pos[m]
+			j := 0
+			for _, q := range pos[:i] {
+				j += btoi(p > q)
+			}
+			s += binomial(p-j-8, m-i+1)
+		}
+		idx += s * factor[i]
+		i = end
+	}
+	return t.encodeRest(idx, norm, factor, pos, i)
+}
+
+// pawnFile moves the leading pawn closest to the a file, after mirroring,
+// to the front and returns its file.
+func (t *table) pawnFile(pos []int) int {
+	for i := 1; i < t.pawns[0]; i++ {
+		if flap[pos[0]] > flap[pos[i]] {
+			pos[0], pos[i] = pos[i], pos[0]
+		}
+	}
+	return fileToFile[pos[0]&0x07]
+}
+
+// setNormPiece sets the sizes of the groups of pieces of a pawnless table.
+func (t *table) setNormPiece(norm, pieces []int) {
+	norm[0] = 3
+	if t.encType == 2 {
+		norm[0] = 2
+	}
+	t.setNormRest(norm, pieces, norm[0])
+}
+
+// setNormPawn sets the sizes of the groups of pieces of a pawn table.
+func (t *table) setNormPawn(norm, pieces []int) {
+	norm[0] = t.pawns[0]
+	if t.pawns[1] > 0 {
+		norm[t.pawns[0]] = t.pawns[1]
+	}
+	t.setNormRest(norm, pieces, t.pawns[0]+t.pawns[1])
+}
+
+func (t *table) setNormRest(norm, pieces []int, i int) {
+	for i < t.num {
+		for j := i; j < t.num && pieces[j] == pieces[i]; j++ {
+			norm[i]++
+		}
+		i += norm[i]
+	}
+}
+
+// calcFactorsPiece sets the factors of the groups of a pawnless table and
+// returns its size.
+func (t *table) calcFactorsPiece(factor []int, order int, norm []int) int {
+	n := 64 - norm[0]
+	f := 1
+	for i, k := norm[0], 0; i < t.num || k == order; k++ {
+		if k == order {
+			factor[0] = f
+			f *= pivotFactor[t.encType]
+			continue
+		}
+		factor[i] = f
+		f *= subfactor(norm[i], n)
+		n -= norm[i]
+		i += norm[i]
+	}
+	return f
+}
+
+// calcFactorsPawn sets the factors of the groups of a pawn table for the
+// file and returns its size.
+func (t *table) calcFactorsPawn(factor []int, order, order2 int, norm []int, file int) int {
+	i := norm[0]
+	if order2 < 0x0f {
+		i += norm[i]
+	}
+	n := 64 - i
+	f := 1
+	for k := 0; i < t.num || k == order || k == order2; k++ {
+		switch k {
+		case order:
+			factor[0] = f
+			f *= pawnFactor[norm[0]-1][file]
+		case order2:
+			factor[norm[0]] = f
+			f *= subfactor(norm[norm[0]], 48-norm[0])
+		default:
+			factor[i] = f
+			f *= subfactor(norm[i], n)
+			n -= norm[i]
+			i += norm[i]
+		}
+	}
+	return f
+}
+
+func sortSquares(sqs []int) {
+	for i := range sqs {
+		for j := i + 1; j < len(sqs); j++ {
+			if sqs[i] > sqs[j] {
+				sqs[i], sqs[j] = sqs[j], sqs[i]
+			}
+		}
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,568 @@
+// Package syzygy probes Syzygy endgame tablebases from local files.  WDL
+// tables (.rtbw) give the outcome of positions with up to seven pieces and
+// DTZ tables (.rtbz) the distance to the next capture or pawn move, which
+// together with the halfmove clock decide whether a win can be forced
+// before the fifty move rule.  The tables can be downloaded from
+// https://tablebase.lichess.ovh/tables/standard/ and are read on first use.
+package syzygy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+// WDL is the outcome of a position for the side to move under the fifty
+// move rule, assuming the halfmove clock is zero.
+type WDL int
+
+const (
+	// Loss indicates that the side to move is mated with best play.
+	Loss WDL = -2
+	// BlessedLoss indicates that the side to move is lost but can hold a
+	// draw by the fifty move rule.
+	BlessedLoss WDL = -1
+	// Draw indicates that neither side can force mate.
+	Draw WDL = 0
+	// CursedWin indicates that the side to move can force mate but not
+	// before a draw by the fifty move rule.
+	CursedWin WDL = 1
+	// Win indicates that the side to move mates with best play.
+	Win WDL = 2
+)
+
+// String implements the fmt.Stringer interface.
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "Loss"
+	case BlessedLoss:
+		return "BlessedLoss"
+	case CursedWin:
+		return "CursedWin"
+	case Win:
+		return "Win"
+	}
+	return "Draw"
+}
+
+// wdlToDTZ is the distance to zeroing of an outcome reached by a zeroing
+// move.
+var wdlToDTZ = [5]int{-1, -101, 0, 101, 1}
+
+// Result is the result of probing a position with its halfmove clock.
+type Result struct {
+	// WDL is the outcome taking the halfmove clock into account: a win
+	// which can't be completed before the fifty move rule is a CursedWin.
+	WDL WDL
+	// DTZ is the distance to zeroing of the position, see ProbeDTZ.
+	DTZ int
+	// Moves are the legal moves preserving the outcome, best first: the
+	// fastest to zero when winning and the slowest when losing.
+	Moves []*chess.Move
+}
+
+// Tablebase is a set of Syzygy table files.
+type Tablebase struct {
+	mu  sync.Mutex
+	wdl map[string]*table
+	dtz map[string]*table
+}
+
+// Open returns a Tablebase with the tables in the directories.  A table
+// found in more than one directory is read from the first.
+func Open(dirs ...string) (*Tablebase, error) {
+	tb := &Tablebase{wdl: map[string]*table{}, dtz: map[string]*table{}}
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range files {
+			ext := filepath.Ext(fi.Name())
+			name := strings.TrimSuffix(fi.Name(), ext)
+			if fi.IsDir() || (ext != WDLExt && ext != DTZExt) || !isTableName(name) {
+				continue
+			}
+			tables := tb.wdl
+			if ext == DTZExt {
+				tables = tb.dtz
+			}
+			key := normalize(name, false)
+			if _, ok := tables[key]; !ok {
+				tables[key] = newTable(filepath.Join(dir, fi.Name()), name, ext == DTZExt)
+			}
+		}
+	}
+	return tb, nil
+}
+
+// Materials returns the materials, such as KRvKP, of the WDL tables.
+func (tb *Tablebase) Materials() []string {
+	materials := []string{}
+	for m := range tb.wdl {
+		materials = append(materials, m)
+	}
+	sort.Strings(materials)
+	return materials
+}
+
+// ProbeWDL returns the outcome of the position ignoring its halfmove clock.
+func (tb *Tablebase) ProbeWDL(pos *chess.Position) (WDL, error) {
+	if err := check(pos); err != nil {
+		return Draw, err
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	v, err := tb.probeWDL(pos)
+	return WDL(v), err
+}
+
+// ProbeDTZ returns the distance to zeroing of the position ignoring its
+// halfmove clock: the number of plies to the next capture or pawn move,
+// positive when winning, negative when losing and zero for draws.  It is
+// more than 100 for cursed wins and less than -100 for blessed losses.  The
+// distance may be off by one ply, as in the tables, when a mate follows a
+// zeroing move.
+func (tb *Tablebase) ProbeDTZ(pos *chess.Position) (int, error) {
+	if err := check(pos); err != nil {
+		return 0, err
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.probeDTZ(pos)
+}
+
+// Probe returns the outcome of the position with its halfmove clock and
+// the moves keeping it.  Winning moves are kept if they win before the
+// fifty move rule and losing moves only when the rule is near.
+func (tb *Tablebase) Probe(pos *chess.Position) (Result, error) {
+	if err := check(pos); err != nil {
+		return Result{}, err
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	dtz, err := tb.probeDTZ(pos)
+	if err != nil {
+		return Result{}, err
+	}
+	cnt50 := pos.HalfMoveClock()
+	r := Result{DTZ: dtz}
+	switch {
+	case dtz > 0 && dtz+cnt50 <= 100:
+		r.WDL = Win
+	case dtz > 0:
+		r.WDL = CursedWin
+	case dtz < 0 && -dtz+cnt50 <= 100:
+		r.WDL = Loss
+	case dtz < 0:
+		r.WDL = BlessedLoss
+	}
+	type scored struct {
+		move  *chess.Move
+		score int
+	}
+	moves := []scored{}
+	for _, m := range pos.ValidMoves() {
+		next := pos.Update(m)
+		v := 0
+		switch {
+		case dtz > 0 && next.Status() == chess.Checkmate:
+			v = 1
+		case next.HalfMoveClock() != 0:
+			if v, err = tb.probeDTZ(next); err != nil {
+				return Result{}, err
+			}
+			v = -v
+			if v > 0 {
+				v++
+			} else if v < 0 {
+				v--
+			}
+		default:
+			w, err := tb.probeWDL(next)
+			if err != nil {
+				return Result{}, err
+			}
+			v = wdlToDTZ[-w+2]
+		}
+		moves = append(moves, scored{m, v})
+	}
+	keep := func(v int) bool { return v == 0 }
+	switch {
+	case dtz > 0:
+		best := 0xffff
+		for _, s := range moves {
+			if s.score > 0 && s.score < best {
+				best = s.score
+			}
+		}
+		limit := best
+		if best+cnt50 <= 99 {
+			limit = 99 - cnt50
+		}
+		keep = func(v int) bool { return v > 0 && v <= limit }
+	case dtz < 0:
+		best := 0
+		for _, s := range moves {
+			if s.score < best {
+				best = s.score
+			}
+		}
+		keep = func(v int) bool { return true }
+		if -best*2+cnt50 >= 100 {
+			keep = func(v int) bool { return v == best }
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].score < moves[j].score })
+	for _, s := range moves {
+		if keep(s.score) {
+			r.Moves = append(r.Moves, s.move)
+		}
+	}
+	return r, nil
+}
+
+func check(pos *chess.Position) error {
+	if _, ok := pos.Variant().(chess.Standard); !ok {
+		return errors.New("syzygy: only standard chess is supported")
+	}
+	if strings.Fields(pos.String())[2] != "-" {
+		return errors.New("syzygy: positions with castling rights aren't supported")
+	}
+	if n := len(pos.Board().SquareMap()); n > maxPieces {
+		return fmt.Errorf("syzygy: %d pieces are more than %d", n, maxPieces)
+	}
+	return nil
+}
+
+// probeTable returns the outcome of the position from its WDL table.
+func (tb *Tablebase) probeTable(pos *chess.Position) (int, error) {
+	if len(pos.Board().SquareMap()) == 2 {
+		return 0, nil
+	}
+	key := normalize(positionKey(pos, false), false)
+	t, ok := tb.wdl[key]
+	if !ok {
+		return 0, errors.New("syzygy: no WDL table for " + key)
+	}
+	return t.probeWDL(pos)
+}
+
+// probeAB returns the outcome of the position within alpha and beta after
+// searching its captures.  The second value is 2 if a capture is best.
+func (tb *Tablebase) probeAB(pos *chess.Position, alpha, beta int) (int, int, error) {
+	for _, m := range pos.ValidMoves() {
+		if !m.HasTag(chess.Capture) {
+			continue
+		}
+		v, _, err := tb.probeAB(pos.Update(m), -beta, -alpha)
+		if err != nil {
+			return 0, 0, err
+		}
+		if v = -v; v > alpha {
+			if v >= beta {
+				return v, 2, nil
+			}
+			alpha = v
+		}
+	}
+	v, err := tb.probeTable(pos)
+	if err != nil {
+		return 0, 0, err
+	}
+	if alpha >= v {
+		return alpha, 1 + btoi(alpha > 0), nil
+	}
+	return v, 1, nil
+}
+
+// probeEP returns the best outcome of the en passant captures of the
+// position or -3 if there are none.
+func (tb *Tablebase) probeEP(pos *chess.Position) (int, error) {
+	best := -3
+	for _, m := range pos.ValidMoves() {
+		if !m.HasTag(chess.EnPassant) {
+			continue
+		}
+		v, _, err := tb.probeAB(pos.Update(m), -2, 2)
+		if err != nil {
+			return 0, err
+		}
+		if -v > best {
+			best = -v
+		}
+	}
+	return best, nil
+}
+
+// onlyEP returns true if the position's only legal moves are en passant
+// captures.
+func onlyEP(pos *chess.Position) bool {
+	for _, m := range pos.ValidMoves() {
+		if !m.HasTag(chess.EnPassant) {
+			return false
+		}
+	}
+	return true
+}
+
+func (tb *Tablebase) probeWDL(pos *chess.Position) (int, error) {
+	v, _, err := tb.probeAB(pos, -2, 2)
+	if err != nil || pos.EnPassantSquare() == chess.NoSquare {
+		return v, err
+	}
+	ep, err := tb.probeEP(pos)
+	if err != nil || ep == -3 {
+		return v, err
+	}
+	if ep >= v || (v == 0 && onlyEP(pos)) {
+		v = ep
+	}
+	return v, nil
+}
+
+func (tb *Tablebase) probeDTZ(pos *chess.Position) (int, error) {
+	v, err := tb.probeDTZNoEP(pos)
+	if err != nil || pos.EnPassantSquare() == chess.NoSquare {
+		return v, err
+	}
+	ep, err := tb.probeEP(pos)
+	if err != nil || ep == -3 {
+		return v, err
+	}
+	ep = wdlToDTZ[ep+2]
+	switch {
+	case v < -100:
+		if ep >= 0 {
+			v = ep
+		}
+	case v < 0:
+		if ep >= 0 || ep < -100 {
+			v = ep
+		}
+	case v > 100:
+		if ep > 0 {
+			v = ep
+		}
+	case v > 0:
+		if ep == 1 {
+			v = ep
+		}
+	case ep >= 0:
+		v = ep
+	case onlyEP(pos):
+		v = ep
+	}
+	return v, nil
+}
+
+// zeroing returns the distance to zeroing of a position with the outcome
+// when the best move zeroes.
+func zeroing(wdl int) int {
+	switch {
+	case wdl == 2:
+		return 1
+	case wdl == 1:
+		return 101
+	case wdl == -1:
+		return -101
+	case wdl == -2:
+		return -1
+	}
+	return 0
+}
+
+func isPawnMove(pos *chess.Position, m *chess.Move) bool {
+	return pos.Board().Piece(m.S1()).Type() == chess.Pawn
+}
+
+func (tb *Tablebase) probeDTZNoEP(pos *chess.Position) (int, error) {
+	wdl, success, err := tb.probeAB(pos, -2, 2)
+	if err != nil || wdl == 0 {
+		return 0, err
+	}
+	if success == 2 {
+		return zeroing(wdl), nil
+	}
+	if wdl > 0 {
+		for _, m := range pos.ValidMoves() {
+			if !isPawnMove(pos, m) || m.HasTag(chess.Capture) {
+				continue
+			}
+			v, err := tb.probeWDL(pos.Update(m))
+			if err != nil {
+				return 0, err
+			}
+			if -v == wdl {
+				return zeroing(wdl), nil
+			}
+		}
+	}
+	key := normalize(positionKey(pos, false), false)
+	if t, ok := tb.dtz[key]; ok {
+		dtz, stored, err := t.probeDTZ(pos, wdl)
+		if err != nil {
+			return 0, err
+		}
+		if stored {
+			if wdl > 0 {
+				return zeroing(wdl) + dtz, nil
+			}
+			return zeroing(wdl) - dtz, nil
+		}
+	} else {
+		return 0, errors.New("syzygy: no DTZ table for " + key)
+	}
+	if wdl > 0 {
+		best := 0xffff
+		for _, m := range pos.ValidMoves() {
+			if isPawnMove(pos, m) || m.HasTag(chess.Capture) {
+				continue
+			}
+			next := pos.Update(m)
+			v, err := tb.probeDTZ(next)
+			if err != nil {
+				return 0, err
+			}
+			v = -v
+			if v == 1 && next.Status() == chess.Checkmate {
+				best = 1
+			} else if v > 0 && v+1 < best {
+				best = v + 1
+			}
+		}
+		return best, nil
+	}
+	best := -1
+	for _, m := range pos.ValidMoves() {
+		next := pos.Update(m)
+		var v int
+		if next.HalfMoveClock() == 0 {
+			if wdl == -2 {
+				v = -1
+			} else {
+				w, _, err := tb.probeAB(next, 1, 2)
+				if err != nil {
+					return 0, err
+				}
+				v = -101
+				if w == 2 {
+					v = 0
+				}
+			}
+		} else {
+			d, err := tb.probeDTZ(next)
+			if err != nil {
+				return 0, err
+			}
+			v = -d - 1
+		}
+		if v < best {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// pieceLetters are the letters of the pieces in table name order.
+const pieceLetters = "KQRBNP"
+
+// isTableName returns true if the name is the name of a table such as
+// KRvKP.
+func isTableName(name string) bool {
+	parts := strings.Split(name, "v")
+	if len(parts) != 2 || len(name)-1 > maxPieces {
+		return false
+	}
+	for _, part := range parts {
+		if !strings.HasPrefix(part, "K") || strings.Count(part, "K") != 1 {
+			return false
+		}
+		for _, r := range part {
+			if !strings.ContainsRune(pieceLetters, r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// normalize returns the name of the table of the material, with the
+// stronger side first, or with the colors swapped if mirror is true.
+func normalize(name string, mirror bool) string {
+	parts := strings.Split(name, "v")
+	w, b := sortLetters(parts[0]), sortLetters(parts[1])
+	if mirror != weaker(w, b) {
+		w, b = b, w
+	}
+	return w + "v" + b
+}
+
+// weaker returns true if w has fewer pieces than b or, with as many
+// pieces, the first different piece of w is less valuable.
+func weaker(w, b string) bool {
+	if len(w) != len(b) {
+		return len(w) < len(b)
+	}
+	for i := range w {
+		if w[i] != b[i] {
+			return strings.IndexByte(pieceLetters, b[i]) < strings.IndexByte(pieceLetters, w[i])
+		}
+	}
+	return false
+}
+
+func sortLetters(s string) string {
+	b := []byte(s)
+	sort.Slice(b, func(i, j int) bool {
+		return strings.IndexByte(pieceLetters, b[i]) < strings.IndexByte(pieceLetters, b[j])
+	})
+	return string(b)
+}
+
+// positionKey returns the material of the position with white first, or
+// black first if mirror is true.
+func positionKey(pos *chess.Position, mirror bool) string {
+	counts := map[chess.Piece]int{}
+	for _, p := range pos.Board().SquareMap() {
+		counts[p]++
+	}
+	return materialName(counts, mirror)
+}
+
+// pieceKey returns the material of the table pieces with white first, or
+// black first if mirror is true.
+func pieceKey(pieces []int, mirror bool) string {
+	counts := map[chess.Piece]int{}
+	for _, code := range pieces {
+		counts[tablePiece(code)]++
+	}
+	return materialName(counts, mirror)
+}
+
+func materialName(counts map[chess.Piece]int, mirror bool) string {
+	side := func(c chess.Color) string {
+		s := ""
+		for _, r := range pieceLetters {
+			s += strings.Repeat(string(r), counts[chess.NewPiece(letterType(r), c)])
+		}
+		return s
+	}
+	w, b := side(chess.White), side(chess.Black)
+	if mirror {
+		w, b = b, w
+	}
+	return w + "v" + b
+}
+
+func letterType(r rune) chess.PieceType {
+	types := [...]chess.PieceType{chess.King, chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn}
+	return types[strings.IndexRune(pieceLetters, r)]
+}
@@ -0,0 +1,502 @@
+package syzygy_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/syzygy"
+	"github.com/notnil/chess/tablebase"
+)
+
+var update = flag.Bool("update", false, "regenerate the fixture tables")
+
+// TestWriteFixtures writes the fixture tables when run with -update: WDL
+// and DTZ tables of KQvK, KRvK and KPvK from distance to mate tables.  Wins
+// in KQvK and KRvK need no captures, so the distance to zeroing of the
+// winning side is the distance to mate.  The distances of KPvK are solved
+// by pawnDTZ.
+func TestWriteFixtures(t *testing.T) {
+	if !*update {
+		t.Skip("run with -update to regenerate the fixture tables")
+	}
+	tb := tablebase.New()
+	for _, m := range []string{"KQvK", "KRvK", "KPvK"} {
+		if err := tb.Generate(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	probe := func(pos *chess.Position) tablebase.Result {
+		r, err := tb.Probe(pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	wdl := func(pos *chess.Position) int {
+		return int(outcome(probe(pos).WDL))
+	}
+	dtz := func(pos *chess.Position) int {
+		if r := probe(pos); r.WDL == tablebase.Win {
+			return r.DTM - 1
+		}
+		return 0
+	}
+	pawns := map[string]int{}
+	for sq := chess.A2; sq <= chess.H7; sq++ {
+		for k, v := range pawnDTZ(t, tb, sq) {
+			pawns[k] = v
+		}
+	}
+	pawnsDTZ := func(pos *chess.Position) int {
+		if v := pawns[key(pos)]; v > 0 {
+			return v - 1
+		}
+		return 0
+	}
+	for _, m := range []string{"KQvK", "KRvK", "KPvK"} {
+		if err := syzygy.WriteTable(fixtures, m, false, wdl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range []string{"KQvK", "KRvK"} {
+		if err := syzygy.WriteTable(fixtures, m, true, dtz); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := syzygy.WriteTable(fixtures, "KPvK", true, pawnsDTZ); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pawnDTZ returns the distances to zeroing of the KPvK positions with the
+// white pawn on the square, positive for wins and negative for losses,
+// keyed by board and side to move.  King moves don't leave the positions
+// of the pawn square, so their distances are solved by iterating from
+// the winning pawn moves until no distance changes.
+func pawnDTZ(t *testing.T, ref *tablebase.Tablebase, pawn chess.Square) map[string]int {
+	type node struct {
+		pos  *chess.Position
+		wdl  tablebase.WDL
+		next []string
+	}
+	nodes := map[string]*node{}
+	dtz := map[string]int{}
+	for wk := chess.A1; wk <= chess.H8; wk++ {
+		for bk := chess.A1; bk <= chess.H8; bk++ {
+			if wk == bk || wk == pawn || bk == pawn {
+				continue
+			}
+			for _, turn := range []chess.Color{chess.White, chess.Black} {
+				m := map[chess.Square]chess.Piece{
+					wk:   chess.WhiteKing,
+					bk:   chess.BlackKing,
+					pawn: chess.WhitePawn,
+				}
+				b := chess.NewBoard(m)
+				if b.IsAttacked(kingSquare(m, turn.Other()), turn) {
+					continue
+				}
+				pos := position(t, b.String()+" "+turn.String()+" - - 0 1")
+				r, err := ref.Probe(pos)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r.WDL == tablebase.Draw {
+					continue
+				}
+				n := &node{pos: pos, wdl: r.WDL}
+				for _, mv := range pos.ValidMoves() {
+					next := pos.Update(mv)
+					if next.HalfMoveClock() != 0 {
+						n.next = append(n.next, key(next))
+						continue
+					}
+					// the pawn moves or is captured
+					if r, err := ref.Probe(next); err == nil && r.WDL == tablebase.Loss {
+						dtz[key(pos)] = 1
+					}
+				}
+				nodes[key(pos)] = n
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for k, n := range nodes {
+			if n.wdl == tablebase.Win && dtz[k] == 1 {
+				continue
+			}
+			v := 0
+			if n.wdl == tablebase.Win {
+				for _, next := range n.next {
+					if d := dtz[next]; d < 0 && (v == 0 || 1-d < v) {
+						v = 1 - d
+					}
+				}
+			} else {
+				for _, next := range n.next {
+					d, ok := dtz[next]
+					if !ok {
+						// the distance of a reply isn't known yet
+						v = 0
+						break
+					}
+					if -1-d < v {
+						v = -1 - d
+					}
+				}
+			}
+			if v != 0 && v != dtz[k] {
+				dtz[k] = v
+				changed = true
+			}
+		}
+	}
+	return dtz
+}
+
+// key returns the board and side to move of the position.
+func key(pos *chess.Position) string {
+	return pos.Board().String() + " " + pos.Turn().String()
+}
+
+const fixtures = "fixtures"
+
+func open(t *testing.T) *syzygy.Tablebase {
+	tb, err := syzygy.Open(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+func position(t *testing.T, fen string) *chess.Position {
+	opt, err := chess.FEN(fen, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chess.NewGame(opt).Position()
+}
+
+func TestMaterials(t *testing.T) {
+	got := open(t).Materials()
+	want := []string{"KPvK", "KQvK", "KRvK"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v but got %v", want, got)
+		}
+	}
+}
+
+var wdlTests = []struct {
+	fen string
+	wdl syzygy.WDL
+}{
+	{"8/8/8/8/8/8/8/K1k5 w - - 0 1", syzygy.Draw},
+	{"k7/8/1K6/8/8/8/8/6Q1 w - - 0 1", syzygy.Win},
+	{"k7/8/1K6/8/8/8/8/6Q1 b - - 0 1", syzygy.Loss},
+	{"8/8/8/4k3/8/8/8/R3K3 b - - 0 1", syzygy.Loss},
+	// Black captures the queen.
+	{"8/8/8/8/8/8/1q6/K6k w - - 0 1", syzygy.Draw},
+	{"8/8/8/8/8/8/2q5/K1k5 b - - 0 1", syzygy.Win},
+	{"8/8/8/8/8/2k5/1Q6/7K b - - 0 1", syzygy.Draw},
+	// Stalemate.
+	{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", syzygy.Draw},
+	{"8/8/8/8/8/4k3/4P3/4K3 w - - 0 1", syzygy.Draw},
+	{"8/8/8/8/8/4k3/4P3/4K3 b - - 0 1", syzygy.Draw},
+	{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", syzygy.Win},
+	{"8/8/8/8/8/8/4p3/2k1K3 w - - 0 1", syzygy.Draw},
+	{"8/8/8/8/8/8/4p3/2k4K b - - 0 1", syzygy.Win},
+}
+
+func TestProbeWDL(t *testing.T) {
+	probeWDL(t, open(t))
+}
+
+func probeWDL(t *testing.T, tb *syzygy.Tablebase) {
+	for _, test := range wdlTests {
+		wdl, err := tb.ProbeWDL(position(t, test.fen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wdl != test.wdl {
+			t.Fatalf("%s expected %s but got %s", test.fen, test.wdl, wdl)
+		}
+	}
+}
+
+// TestProbeTables compares positions of both colorings with the distance
+// to mate tables: the outcomes of KPvK and KRvK and the distances of KRvK.
+func TestProbeTables(t *testing.T) {
+	tb := open(t)
+	ref := tablebase.New()
+	for _, m := range []string{"KPvK", "KRvK"} {
+		if err := ref.Generate(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checked := 0
+	for _, pt := range []chess.PieceType{chess.Pawn, chess.Rook} {
+		for k := chess.A1; k <= chess.H8; k += 2 {
+			for p := chess.A1; p <= chess.H8; p += 5 {
+				for o := chess.A1; o <= chess.H8; o += 7 {
+					if k == p || k == o || p == o || (pt == chess.Pawn && (p.Rank() == chess.Rank1 || p.Rank() == chess.Rank8)) {
+						continue
+					}
+					for _, turn := range []chess.Color{chess.White, chess.Black} {
+						for _, c := range []chess.Color{chess.White, chess.Black} {
+							m := map[chess.Square]chess.Piece{
+								k: chess.NewPiece(chess.King, c),
+								p: chess.NewPiece(pt, c),
+								o: chess.NewPiece(chess.King, c.Other()),
+							}
+							b := chess.NewBoard(m)
+							if b.IsAttacked(kingSquare(m, turn.Other()), turn) {
+								continue
+							}
+							compare(t, tb, ref, position(t, b.String()+" "+turn.String()+" - - 0 1"), pt == chess.Rook)
+							checked++
+						}
+					}
+				}
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatal("no positions checked")
+	}
+}
+
+func compare(t *testing.T, tb *syzygy.Tablebase, ref *tablebase.Tablebase, pos *chess.Position, dtz bool) {
+	want, err := ref.Probe(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tb.ProbeWDL(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome(want.WDL) != got {
+		t.Fatalf("%s expected %s but got %s", pos, outcome(want.WDL), got)
+	}
+	if !dtz || want.DTM == 0 {
+		return
+	}
+	d, err := tb.ProbeDTZ(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.WDL == tablebase.Loss {
+		d = -d
+	}
+	if d != want.DTM {
+		t.Fatalf("%s expected distance %d but got %d", pos, want.DTM, d)
+	}
+}
+
+func kingSquare(m map[chess.Square]chess.Piece, c chess.Color) chess.Square {
+	for sq, p := range m {
+		if p == chess.NewPiece(chess.King, c) {
+			return sq
+		}
+	}
+	return chess.NoSquare
+}
+
+func outcome(w tablebase.WDL) syzygy.WDL {
+	switch w {
+	case tablebase.Win:
+		return syzygy.Win
+	case tablebase.Loss:
+		return syzygy.Loss
+	}
+	return syzygy.Draw
+}
+
+var dtzTests = []struct {
+	fen string
+	dtz int
+}{
+	{"k7/8/1K6/8/8/8/8/6Q1 w - - 0 1", 1},
+	{"8/8/8/8/8/8/8/K1k5 w - - 0 1", 0},
+	{"8/8/8/8/8/8/1q6/K6k w - - 0 1", 0},
+	{"8/8/8/8/8/8/2q5/K1k5 b - - 0 1", 1},
+	{"k7/8/2K5/8/8/8/8/7R w - - 0 1", 3},
+	{"k7/8/2K5/8/8/8/8/7R b - - 0 1", -4},
+	{"8/8/8/4k3/8/8/8/R3K3 b - - 0 1", -28},
+	// The promotion is zeroing.
+	{"k7/4P3/4K3/8/8/8/8/8 w - - 0 1", 1},
+}
+
+func TestProbeDTZ(t *testing.T) {
+	probeDTZ(t, open(t))
+}
+
+func probeDTZ(t *testing.T, tb *syzygy.Tablebase) {
+	for _, test := range dtzTests {
+		dtz, err := tb.ProbeDTZ(position(t, test.fen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dtz != test.dtz {
+			t.Fatalf("%s expected %d but got %d", test.fen, test.dtz, dtz)
+		}
+	}
+}
+
+// officialEnv names the directory of the official Syzygy tables.  The
+// fixtures are written by WriteTable in this package, so the same values
+// are checked against the tables published by the Syzygy project when the
+// variable is set.
+const officialEnv = "SYZYGY_PATH"
+
+func TestProbeOfficial(t *testing.T) {
+	dir := os.Getenv(officialEnv)
+	if dir == "" {
+		t.Skip("set " + officialEnv + " to the directory of the official tables")
+	}
+	tb, err := syzygy.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probeWDL(t, tb)
+	probeDTZ(t, tb)
+}
+
+// TestProbeDTZPawns compares the distances of KPvK positions with the
+// pawn on some squares, both the stored pawn side to move and the other
+// side which is found by searching.
+func TestProbeDTZPawns(t *testing.T) {
+	tb := open(t)
+	ref := tablebase.New()
+	if err := ref.Generate("KPvK"); err != nil {
+		t.Fatal(err)
+	}
+	for _, sq := range []chess.Square{chess.B2, chess.H6} {
+		dtz := pawnDTZ(t, ref, sq)
+		if len(dtz) == 0 {
+			t.Fatalf("no positions with the pawn on %s", sq)
+		}
+		for k, want := range dtz {
+			pos := position(t, k+" - - 0 1")
+			got, err := tb.ProbeDTZ(pos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("%s expected %d but got %d", pos, want, got)
+			}
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tb := open(t)
+	pos := position(t, "8/8/8/4k3/8/8/8/R3K3 w - - 0 1")
+	for ply := 0; pos.Status() != chess.Checkmate; ply++ {
+		if ply > 100 {
+			t.Fatal("no mate within 100 plies")
+		}
+		r, err := tb.Probe(pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := syzygy.Win
+		if pos.Turn() == chess.Black {
+			want = syzygy.Loss
+		}
+		if r.WDL != want || len(r.Moves) == 0 {
+			t.Fatalf("%s expected %s with moves but got %+v", pos, want, r)
+		}
+		pos = pos.Update(r.Moves[0])
+	}
+	if pos.Turn() != chess.Black {
+		t.Fatal("expected black to be mated")
+	}
+}
+
+func TestProbeFiftyMoveRule(t *testing.T) {
+	tb := open(t)
+	// Black is mated in 28 plies, after the fifty move rule from ply 73.
+	r, err := tb.Probe(position(t, "8/8/8/4k3/8/8/8/R3K3 b - - 80 41"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.WDL != syzygy.BlessedLoss || r.DTZ != -28 {
+		t.Fatalf("expected %s in 28 plies but got %s in %d", syzygy.BlessedLoss, r.WDL, r.DTZ)
+	}
+	pos := position(t, "8/8/8/4k3/8/8/8/R3K3 b - - 60 31")
+	r, err = tb.Probe(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.WDL != syzygy.Loss || len(r.Moves) == 0 {
+		t.Fatalf("expected %s with moves but got %+v", syzygy.Loss, r)
+	}
+	// Near the rule only the moves delaying the loss longest are kept.
+	for _, m := range pos.ValidMoves() {
+		dtz, err := tb.ProbeDTZ(pos.Update(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept := contains(r.Moves, m); kept != (dtz == 27) {
+			t.Fatalf("%s with distance %d kept %t", m, dtz, kept)
+		}
+	}
+}
+
+func contains(moves []*chess.Move, m *chess.Move) bool {
+	for _, mv := range moves {
+		if mv.String() == m.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func TestErrors(t *testing.T) {
+	tb := open(t)
+	for _, fen := range []string{
+		"r3k3/8/8/8/8/8/8/4K3 b q - 0 1",
+		"8/8/8/8/8/8/4n3/4K2k w - - 0 1",
+	} {
+		if _, err := tb.ProbeWDL(position(t, fen)); err == nil {
+			t.Fatalf("%s expected an error", fen)
+		}
+	}
+	empty, err := ioutil.TempDir("", "syzygy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(empty)
+	if err := ioutil.WriteFile(filepath.Join(empty, "KQvK.rtbw"), []byte("not a table"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wdl, err := ioutil.ReadFile(filepath.Join(fixtures, "KPvK.rtbw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(empty, "KPvK.rtbw"), wdl, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tb, err = syzygy.Open(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tb.ProbeDTZ(position(t, "4k3/8/4K3/4P3/8/8/8/8 b - - 0 1")); err == nil {
+		t.Fatal("expected an error without the KPvK DTZ table")
+	}
+	if _, err := tb.ProbeWDL(position(t, "k7/8/1K6/8/8/8/8/6Q1 w - - 0 1")); err == nil {
+		t.Fatal("expected an error for an invalid table")
+	}
+	if _, err := syzygy.Open(empty + "/missing"); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,581 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// WDLExt is the file extension of win/draw/loss tables.
+	WDLExt = ".rtbw"
+	// DTZExt is the file extension of distance to zeroing tables.
+	DTZExt = ".rtbz"
+
+	// maxPieces is the largest number of pieces of a table.
+	maxPieces = 7
+)
+
+var (
+	wdlMagic = []byte{0x71, 0xe8, 0x23, 0x5d}
+	dtzMagic = []byte{0xd7, 0x66, 0x0c, 0xa5}
+
+	// wdlToMap maps the outcome to the value map of DTZ tables.
+	wdlToMap = [5]int{1, 3, 0, 2, 0}
+	// paFlags are the DTZ flags of exact values for losses and wins.
+	paFlags = [5]int{8, 0, 0, 0, 4}
+)
+
+// pairs is a compressed block of values.  Values are stored as symbols of a
+// canonical Huffman code and each symbol expands into a pair of symbols
+// until it is a single value.
+type pairs struct {
+	indexTable, sizeTable, data int
+	offset, sympat              int
+	symlen                      []int
+	base                        []uint64
+	blockSize, idxBits, minLen  int
+}
+
+// side is the layout of the values of a table for one side to move and,
+// for pawn tables, one file of the leading pawn.
+type side struct {
+	pieces  []int
+	norm    []int
+	factor  []int
+	precomp *pairs
+}
+
+// table is a WDL or DTZ file.  The file is read and its layout parsed on
+// first use.
+type table struct {
+	path      string
+	dtz       bool
+	key       string
+	mirrored  string
+	symmetric bool
+	hasPawns  bool
+	num       int
+	encType   int
+	// pawns are the numbers of pawns of the leading color, the color with
+	// the fewest pawns if both have some, and of the other color.
+	pawns [2]int
+
+	once sync.Once
+	err  error
+	data []byte
+	// sides are indexed by file and the side to move in the table.  DTZ
+	// tables store one side to move given by flags.
+	sides [4][2]side
+	flags [4]int
+	// mapOffset is the start of the value maps of DTZ tables and mapIdx the
+	// start of the map of each outcome.
+	mapOffset int
+	mapIdx    [4][4]int
+}
+
+func newTable(path, name string, dtz bool) *table {
+	t := &table{
+		path:     path,
+		dtz:      dtz,
+		key:      normalize(name, false),
+		mirrored: normalize(name, true),
+		num:      len(name) - 1,
+		hasPawns: strings.Contains(name, "P"),
+	}
+	t.symmetric = t.key == t.mirrored
+	parts := strings.Split(name, "v")
+	if t.hasPawns {
+		t.pawns = [2]int{strings.Count(parts[0], "P"), strings.Count(parts[1], "P")}
+		if t.pawns[1] > 0 && (t.pawns[0] == 0 || t.pawns[1] < t.pawns[0]) {
+			t.pawns[0], t.pawns[1] = t.pawns[1], t.pawns[0]
+		}
+		return t
+	}
+	unique := 0
+	for _, r := range pieceLetters {
+		for _, part := range parts {
+			if strings.Count(part, string(r)) == 1 {
+				unique++
+			}
+		}
+	}
+	t.encType = 2
+	if unique >= 3 {
+		t.encType = 0
+	}
+	return t
+}
+
+func (t *table) load() error {
+	t.once.Do(func() {
+		t.data, t.err = ioutil.ReadFile(t.path)
+		if t.err != nil {
+			return
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				t.err = errors.New("syzygy: corrupt table " + t.path)
+			}
+		}()
+		if t.dtz {
+			t.err = t.setupDTZ()
+		} else {
+			t.err = t.setupWDL()
+		}
+	})
+	return t.err
+}
+
+func (t *table) checkMagic(magic []byte) error {
+	if len(t.data) < 5 || string(t.data[:4]) != string(magic) {
+		return errors.New("syzygy: invalid table " + t.path)
+	}
+	return nil
+}
+
+func (t *table) setupWDL() error {
+	if err := t.checkMagic(wdlMagic); err != nil {
+		return err
+	}
+	split := t.u8(4)&0x01 != 0
+	files := 1
+	if t.u8(4)&0x02 != 0 {
+		files = 4
+	}
+	sides := 1
+	if split {
+		sides = 2
+	}
+	var size [4][2][3]int
+	var tbSize [4][2]int
+	ptr := 5
+	if !t.hasPawns {
+		for s := 0; s < 2; s++ {
+			tbSize[0][s] = t.setupPiece(&t.sides[0][s], ptr, s)
+		}
+		ptr += t.num + 1
+	} else {
+		n := 1 + btoi(t.pawns[1] > 0)
+		for f := 0; f < 4; f++ {
+			for s := 0; s < 2; s++ {
+				tbSize[f][s] = t.setupPawn(&t.sides[f][s], ptr, s, f)
+			}
+			ptr += t.num + n
+		}
+	}
+	ptr += ptr & 0x01
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			t.sides[f][s].precomp, ptr = t.setupPairs(ptr, tbSize[f][s], &size[f][s], true)
+		}
+	}
+	t.setupData(ptr, files, sides, &size)
+	return nil
+}
+
+func (t *table) setupDTZ() error {
+	if err := t.checkMagic(dtzMagic); err != nil {
+		return err
+	}
+	files := 1
+	if t.u8(4)&0x02 != 0 {
+		files = 4
+	}
+	var size [4][2][3]int
+	var tbSize [4]int
+	ptr := 5
+	if !t.hasPawns {
+		tbSize[0] = t.setupPiece(&t.sides[0][0], ptr, 0)
+		ptr += t.num + 1
+		// Some tables are stored with the colors of their name swapped.
+		t.key = pieceKey(t.sides[0][0].pieces, false)
+		t.mirrored = pieceKey(t.sides[0][0].pieces, true)
+	} else {
+		n := 1 + btoi(t.pawns[1] > 0)
+		for f := 0; f < 4; f++ {
+			tbSize[f] = t.setupPawn(&t.sides[f][0], ptr, 0, f)
+			ptr += t.num + n
+		}
+	}
+	ptr += ptr & 0x01
+	for f := 0; f < files; f++ {
+		t.flags[f] = int(t.u8(ptr))
+		t.sides[f][0].precomp, ptr = t.setupPairs(ptr, tbSize[f], &size[f][0], false)
+	}
+	t.mapOffset = ptr
+	for f := 0; f < files; f++ {
+		if t.flags[f]&2 == 0 {
+			continue
+		}
+		if t.flags[f]&16 == 0 {
+			for i := 0; i < 4; i++ {
+				t.mapIdx[f][i] = ptr + 1 - t.mapOffset
+				ptr += 1 + int(t.u8(ptr))
+			}
+			continue
+		}
+		ptr += ptr & 0x01
+		for i := 0; i < 4; i++ {
+			t.mapIdx[f][i] = (ptr + 2 - t.mapOffset) / 2
+			ptr += 2 + 2*int(t.u16(ptr))
+		}
+	}
+	ptr += ptr & 0x01
+	t.setupData(ptr, files, 1, &size)
+	return nil
+}
+
+// setupData sets the offsets of the index, size and data tables, which are
+// stored one kind after the other for all files and sides.
+func (t *table) setupData(ptr, files, sides int, size *[4][2][3]int) {
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			t.sides[f][s].precomp.indexTable = ptr
+			ptr += size[f][s][0]
+		}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			t.sides[f][s].precomp.sizeTable = ptr
+			ptr += size[f][s][1]
+		}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			ptr = (ptr + 0x3f) &^ 0x3f
+			t.sides[f][s].precomp.data = ptr
+			ptr += size[f][s][2]
+		}
+	}
+	if ptr > len(t.data) {
+		panic("syzygy: table too short")
+	}
+}
+
+// setupPiece reads the pieces of a pawnless table for the side to move and
+// returns the size of the table.
+func (t *table) setupPiece(sd *side, ptr, s int) int {
+	shift := uint(4 * s)
+	sd.pieces = make([]int, t.num)
+	for i := range sd.pieces {
+		sd.pieces[i] = int(t.u8(ptr+i+1)>>shift) & 0x0f
+	}
+	order := int(t.u8(ptr)>>shift) & 0x0f
+	sd.norm = make([]int, t.num)
+	sd.factor = make([]int, maxPieces)
+	t.setNormPiece(sd.norm, sd.pieces)
+	return t.calcFactorsPiece(sd.factor, order, sd.norm)
+}
+
+// setupPawn reads the pieces of a pawn table for the side to move and the
+// file and returns the size of the table.
+func (t *table) setupPawn(sd *side, ptr, s, file int) int {
+	shift := uint(4 * s)
+	j := 1 + btoi(t.pawns[1] > 0)
+	order := int(t.u8(ptr)>>shift) & 0x0f
+	order2 := 0x0f
+	if t.pawns[1] > 0 {
+		order2 = int(t.u8(ptr+1)>>shift) & 0x0f
+	}
+	sd.pieces = make([]int, t.num)
+	for i := range sd.pieces {
+		sd.pieces[i] = int(t.u8(ptr+i+j)>>shift) & 0x0f
+	}
+	sd.norm = make([]int, t.num)
+	sd.factor = make([]int, maxPieces)
+	t.setNormPawn(sd.norm, sd.pieces)
+	return t.calcFactorsPawn(sd.factor, order, order2, sd.norm, file)
+}
+
+// setupPairs reads the header of the compressed values of a table of the
+// size, sets the sizes of its index, size and data tables and returns it
+// with the offset after the header.
+func (t *table) setupPairs(ptr, tbSize int, size *[3]int, wdl bool) (*pairs, int) {
+	d := &pairs{}
+	if t.u8(ptr)&0x80 != 0 {
+		// Every position has the same value.
+		if wdl {
+			d.minLen = int(t.u8(ptr + 1))
+		}
+		*size = [3]int{}
+		return d, ptr + 2
+	}
+	d.blockSize = int(t.u8(ptr + 1))
+	d.idxBits = int(t.u8(ptr + 2))
+	realBlocks := int(t.u32(ptr + 4))
+	blocks := realBlocks + int(t.u8(ptr+3))
+	maxLen := int(t.u8(ptr + 8))
+	minLen := int(t.u8(ptr + 9))
+	h := maxLen - minLen + 1
+	numSyms := int(t.u16(ptr + 10 + 2*h))
+	d.offset = ptr + 10
+	d.sympat = ptr + 12 + 2*h
+	d.minLen = minLen
+	next := ptr + 12 + 2*h + 3*numSyms + numSyms&1
+
+	indices := (tbSize + 1<<uint(d.idxBits) - 1) >> uint(d.idxBits)
+	size[0] = 6 * indices
+	size[1] = 2 * blocks
+	size[2] = realBlocks << uint(d.blockSize)
+
+	d.symlen = make([]int, numSyms)
+	done := make([]bool, numSyms)
+	for i := range done {
+		if !done[i] {
+			t.calcSymlen(d, i, done)
+		}
+	}
+	d.base = make([]uint64, h)
+	for i := h - 2; i >= 0; i-- {
+		d.base[i] = (d.base[i+1] + uint64(t.u16(d.offset+2*i)) - uint64(t.u16(d.offset+2*i+2))) / 2
+	}
+	for i := range d.base {
+		d.base[i] <<= uint(64 - minLen - i)
+	}
+	d.offset -= 2 * minLen
+	return d, next
+}
+
+func (t *table) calcSymlen(d *pairs, s int, done []bool) {
+	w := d.sympat + 3*s
+	s2 := int(t.u8(w+2))<<4 | int(t.u8(w+1))>>4
+	if s2 == 0x0fff {
+		d.symlen[s] = 0
+	} else {
+		s1 := int(t.u8(w+1)&0x0f)<<8 | int(t.u8(w))
+		if !done[s1] {
+			t.calcSymlen(d, s1, done)
+		}
+		if !done[s2] {
+			t.calcSymlen(d, s2, done)
+		}
+		d.symlen[s] = d.symlen[s1] + d.symlen[s2] + 1
+	}
+	done[s] = true
+}
+
+// decompress returns the value at the index.
+func (t *table) decompress(d *pairs, idx int) int {
+	if d.idxBits == 0 {
+		return d.minLen
+	}
+	bits := uint(d.idxBits)
+	main := idx >> bits
+	lit := idx&(1<<bits-1) - 1<<(bits-1)
+	block := int(t.u32(d.indexTable + 6*main))
+	lit += int(t.u16(d.indexTable + 6*main + 4))
+	if lit < 0 {
+		for lit < 0 {
+			block--
+			lit += int(t.u16(d.sizeTable+2*block)) + 1
+		}
+	} else {
+		for lit > int(t.u16(d.sizeTable+2*block)) {
+			lit -= int(t.u16(d.sizeTable+2*block)) + 1
+			block++
+		}
+	}
+	ptr := d.data + block<<uint(d.blockSize)
+	m := d.minLen
+	code := t.u64be(ptr)
+	ptr += 8
+	bitcnt := uint(0)
+	var sym int
+	for {
+		l := m
+		for code < d.base[l-m] {
+			l++
+		}
+		sym = int(t.u16(d.offset+2*l)) + int((code-d.base[l-m])>>uint(64-l))
+		if lit < d.symlen[sym]+1 {
+			break
+		}
+		lit -= d.symlen[sym] + 1
+		code <<= uint(l)
+		bitcnt += uint(l)
+		if bitcnt >= 32 {
+			bitcnt -= 32
+			code |= uint64(t.u32be(ptr)) << bitcnt
+			ptr += 4
+		}
+	}
+	for d.symlen[sym] != 0 {
+		w := d.sympat + 3*sym
+		s1 := int(t.u8(w+1)&0x0f)<<8 | int(t.u8(w))
+		if lit < d.symlen[s1]+1 {
+			sym = s1
+		} else {
+			lit -= d.symlen[s1] + 1
+			sym = int(t.u8(w+2))<<4 | int(t.u8(w+1))>>4
+		}
+	}
+	w := d.sympat + 3*sym
+	return int(t.u8(w+1)&0x0f)<<8 | int(t.u8(w))
+}
+
+// orientation returns how the position maps onto the table: whether colors
+// are swapped, how squares are mirrored and which side to move is stored.
+func (t *table) orientation(pos *chess.Position) (cmirror, mirror, bside int) {
+	white := pos.Turn() == chess.White
+	switch {
+	case t.symmetric:
+		if !white {
+			cmirror, mirror = 8, 0x38
+		}
+	case positionKey(pos, false) != t.key:
+		cmirror, mirror, bside = 8, 0x38, btoi(white)
+	default:
+		bside = btoi(!white)
+	}
+	return cmirror, mirror, bside
+}
+
+// squares fills pos with the squares of the pieces of the table from the
+// index i on.
+func squares(b *chess.Board, pieces []int, pos []int, i, cmirror, mirror int) {
+	for i < len(pieces) {
+		p := tablePiece(pieces[i] ^ cmirror)
+		start := i
+		for sq := 0; sq < 64; sq++ {
+			if b.Piece(chess.Square(sq)) == p {
+				pos[i] = sq ^ mirror
+				i++
+			}
+		}
+		if i == start {
+			panic("syzygy: material doesn't match table")
+		}
+	}
+}
+
+// probeWDL returns the outcome of the position from the table, from -2 for
+// a loss to 2 for a win.
+func (t *table) probeWDL(pos *chess.Position) (int, error) {
+	if err := t.load(); err != nil {
+		return 0, err
+	}
+	cmirror, mirror, bside := t.orientation(pos)
+	b := pos.Board()
+	var sqs [maxPieces]int
+	if !t.hasPawns {
+		sd := &t.sides[0][bside]
+		squares(b, sd.pieces, sqs[:], 0, cmirror, 0)
+		idx := t.encodePiece(sd.norm, sd.factor, sqs[:])
+		return t.decompress(sd.precomp, idx) - 2, nil
+	}
+	lead := t.sides[0][0].pieces[0]
+	n := squares1(b, lead, sqs[:], cmirror, mirror)
+	f := t.pawnFile(sqs[:n])
+	sd := &t.sides[f][bside]
+	squares(b, sd.pieces, sqs[:], n, cmirror, mirror)
+	idx := t.encodePawn(sd.norm, sd.factor, sqs[:])
+	return t.decompress(sd.precomp, idx) - 2, nil
+}
+
+// probeDTZ returns the raw distance to zeroing of the position from the
+// table for the outcome and false if the side to move isn't stored.
+func (t *table) probeDTZ(pos *chess.Position, wdl int) (int, bool, error) {
+	if err := t.load(); err != nil {
+		return 0, false, err
+	}
+	cmirror, mirror, bside := t.orientation(pos)
+	b := pos.Board()
+	var sqs [maxPieces]int
+	f := 0
+	var idx int
+	if !t.hasPawns {
+		if t.flags[0]&1 != bside && !t.symmetric {
+			return 0, false, nil
+		}
+		sd := &t.sides[0][0]
+		squares(b, sd.pieces, sqs[:], 0, cmirror, 0)
+		idx = t.encodePiece(sd.norm, sd.factor, sqs[:])
+	} else {
+		lead := t.sides[0][0].pieces[0]
+		n := squares1(b, lead, sqs[:], cmirror, mirror)
+		f = t.pawnFile(sqs[:n])
+		if t.flags[f]&1 != bside {
+			return 0, false, nil
+		}
+		sd := &t.sides[f][0]
+		squares(b, sd.pieces, sqs[:], n, cmirror, mirror)
+		idx = t.encodePawn(sd.norm, sd.factor, sqs[:])
+	}
+	res := t.decompress(t.sides[f][0].precomp, idx)
+	flags := t.flags[f]
+	if flags&2 != 0 {
+		m := t.mapIdx[f][wdlToMap[wdl+2]]
+		if flags&16 == 0 {
+			res = int(t.u8(t.mapOffset + m + res))
+		} else {
+			res = int(t.u16(t.mapOffset + 2*(m+res)))
+		}
+	}
+	if flags&paFlags[wdl+2] == 0 || wdl&1 != 0 {
+		res *= 2
+	}
+	return res, true, nil
+}
+
+// squares1 fills pos with the squares of the leading pawns and returns
+// their number.
+func squares1(b *chess.Board, lead int, pos []int, cmirror, mirror int) int {
+	p := tablePiece(lead ^ cmirror)
+	n := 0
+	for sq := 0; sq < 64; sq++ {
+		if b.Piece(chess.Square(sq)) == p {
+			pos[n] = sq ^ mirror
+			n++
+		}
+	}
+	return n
+}
+
+// tablePiece returns the piece of a table piece code: pawn to king are 1 to
+// 6 and black pieces have bit 3 set.
+func tablePiece(code int) chess.Piece {
+	types := [...]chess.PieceType{chess.NoPieceType, chess.Pawn, chess.Knight, chess.Bishop, chess.Rook, chess.Queen, chess.King}
+	if code&7 == 0 || code&7 > 6 {
+		return chess.NoPiece
+	}
+	c := chess.White
+	if code&8 != 0 {
+		c = chess.Black
+	}
+	return chess.NewPiece(types[code&7], c)
+}
+
+func (t *table) u8(off int) byte {
+	return t.data[off]
+}
+
+func (t *table) u16(off int) uint16 {
+	return binary.LittleEndian.Uint16(t.data[off:])
+}
+
+func (t *table) u32(off int) uint32 {
+	return binary.LittleEndian.Uint32(t.data[off:])
+}
+
+// u32be and u64be read the bit stream of the values, which may end less
+// than eight bytes before the end of the file.
+func (t *table) u32be(off int) uint32 {
+	var b [4]byte
+	if off < len(t.data) {
+		copy(b[:], t.data[off:])
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func (t *table) u64be(off int) uint64 {
+	var b [8]byte
+	if off < len(t.data) {
+		copy(b[:], t.data[off:])
+	}
+	return binary.BigEndian.Uint64(b[:])
+}
@@ -0,0 +1,501 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// TestFixturePairs checks the fixture tables use symbol pairs and codes of
+// several lengths so probing them decompresses like the official tables.
+func TestFixturePairs(t *testing.T) {
+	for _, name := range []string{"KQvK", "KRvK", "KPvK"} {
+		for _, dtz := range []bool{false, true} {
+			ext := WDLExt
+			if dtz {
+				ext = DTZExt
+			}
+			tb := newTable(filepath.Join("fixtures", name+ext), name, dtz)
+			if err := tb.load(); err != nil {
+				t.Fatal(err)
+			}
+			d := tb.sides[0][0].precomp
+			pairs := 0
+			for _, l := range d.symlen {
+				if l > 0 {
+					pairs++
+				}
+			}
+			if pairs == 0 || len(d.base) < 2 {
+				t.Fatalf("%s%s expected symbol pairs and codes of several lengths but got %d pairs and %d lengths", name, ext, pairs, len(d.base))
+			}
+		}
+	}
+}
+
+// WriteTable writes a table of the material, such as KQvK, to the directory
+// in the Syzygy format so the reader can be tested without downloaded
+// tables.  Values are compressed with symbol pairs and a canonical Huffman
+// code like the official tables.
+// value is called with a legal position of every index of the table and
+// returns the outcome for WDL tables and the distance to zeroing minus one
+// for DTZ tables, which store white to move.
+func WriteTable(dir, name string, dtz bool, value func(pos *chess.Position) int) error {
+	ext := WDLExt
+	if dtz {
+		ext = DTZExt
+	}
+	t := newTable("", name, dtz)
+	pieces := namePieces(name)
+	files, sides := 1, 2
+	if t.hasPawns {
+		files = 4
+	}
+	if dtz {
+		sides = 1
+	}
+	var values [4][2][]int
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			sd := &t.sides[f][s]
+			sd.pieces = pieces
+			sd.norm = make([]int, t.num)
+			sd.factor = make([]int, maxPieces)
+			var n int
+			if t.hasPawns {
+				t.setNormPawn(sd.norm, pieces)
+				n = t.calcFactorsPawn(sd.factor, 0, order2(t), sd.norm, f)
+			} else {
+				t.setNormPiece(sd.norm, pieces)
+				n = t.calcFactorsPiece(sd.factor, 0, sd.norm)
+			}
+			values[f][s] = make([]int, n)
+			for i := range values[f][s] {
+				values[f][s][i] = -1
+			}
+		}
+	}
+	sqs := make([]int, t.num)
+	var place func(i int)
+	place = func(i int) {
+		if i < t.num {
+			for sq := 0; sq < 64; sq++ {
+				if !occupied(sqs[:i], sq) && (tablePiece(pieces[i]).Type() != chess.Pawn || (sq >= 8 && sq < 56)) {
+					sqs[i] = sq
+					place(i + 1)
+				}
+			}
+			return
+		}
+		for s := 0; s < sides; s++ {
+			pos := append([]int{}, sqs...)
+			f, idx := 0, 0
+			if t.hasPawns {
+				f = t.pawnFile(pos[:t.pawns[0]])
+				idx = t.encodePawn(t.sides[f][s].norm, t.sides[f][s].factor, pos)
+			} else {
+				idx = t.encodePiece(t.sides[f][s].norm, t.sides[f][s].factor, pos)
+			}
+			if values[f][s][idx] != -1 {
+				continue
+			}
+			// Indexes of illegal positions are marked -2 and stored as 0.
+			values[f][s][idx] = -2
+			if p := testPosition(pieces, sqs, s); p != nil {
+				v := value(p)
+				if !dtz {
+					v += 2
+				}
+				values[f][s][idx] = v
+			}
+		}
+	}
+	place(0)
+
+	b := append([]byte{}, magic(dtz)...)
+	flags := byte(0)
+	if !dtz {
+		flags |= 0x01
+	}
+	if t.hasPawns {
+		flags |= 0x02
+	}
+	b = append(b, flags)
+	for f := 0; f < files; f++ {
+		b = append(b, 0x00)
+		if t.hasPawns && t.pawns[1] > 0 {
+			b = append(b, 0x11)
+		}
+		for _, p := range pieces {
+			b = append(b, byte(p|p<<4))
+		}
+		if !t.hasPawns {
+			break
+		}
+	}
+	if len(b)&1 != 0 {
+		b = append(b, 0)
+	}
+	var parts [4][2]*testPairs
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			flag := byte(0)
+			if dtz {
+				flag = 0x04 | 0x08
+			}
+			parts[f][s] = newTestPairs(values[f][s])
+			b = parts[f][s].appendHeader(b, flag)
+		}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			b = append(b, parts[f][s].index...)
+		}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			b = append(b, parts[f][s].sizes...)
+		}
+	}
+	for f := 0; f < files; f++ {
+		for s := 0; s < sides; s++ {
+			for len(b)%64 != 0 {
+				b = append(b, 0)
+			}
+			b = append(b, parts[f][s].data...)
+		}
+	}
+	return ioutil.WriteFile(filepath.Join(dir, name+ext), b, 0644)
+}
+
+const (
+	testBlockSize = 6
+	testIdxBits   = 8
+)
+
+func magic(dtz bool) []byte {
+	if dtz {
+		return dtzMagic
+	}
+	return wdlMagic
+}
+
+func order2(t *table) int {
+	if t.pawns[1] > 0 {
+		return 1
+	}
+	return 0x0f
+}
+
+// namePieces returns the table piece codes of the material with the
+// leading pawns first.
+func namePieces(name string) []int {
+	codes := map[rune]int{'P': 1, 'N': 2, 'B': 3, 'R': 4, 'Q': 5, 'K': 6}
+	pieces := []int{}
+	color := 0
+	for _, r := range name {
+		if r == 'v' {
+			color = 8
+			continue
+		}
+		pieces = append(pieces, codes[r]|color)
+	}
+	sort.SliceStable(pieces, func(i, j int) bool {
+		return pieces[i]&7 == 1 && pieces[j]&7 != 1
+	})
+	return pieces
+}
+
+func occupied(sqs []int, sq int) bool {
+	for _, s := range sqs {
+		if s == sq {
+			return true
+		}
+	}
+	return false
+}
+
+// testPosition returns the position of the pieces on the squares with
+// white to move if s is zero or nil if it is illegal.
+func testPosition(pieces, sqs []int, s int) *chess.Position {
+	m := map[chess.Square]chess.Piece{}
+	for i, p := range pieces {
+		m[chess.Square(sqs[i])] = tablePiece(p)
+	}
+	b := chess.NewBoard(m)
+	turn, other := "w", chess.Black
+	if s == 1 {
+		turn, other = "b", chess.White
+	}
+	for sq, p := range m {
+		if p == chess.NewPiece(chess.King, other) && b.IsAttacked(sq, other.Other()) {
+			return nil
+		}
+	}
+	pos := &chess.Position{}
+	if err := pos.UnmarshalText([]byte(b.String() + " " + turn + " - - 0 1")); err != nil {
+		return nil
+	}
+	return pos
+}
+
+// testPairs are values compressed like the official tables: frequent
+// pairs of adjacent symbols are replaced with new symbols, which are
+// stored with a canonical Huffman code.
+type testPairs struct {
+	// syms are the values of the leaves and the symbol pairs of the others,
+	// ordered by code length from the longest.
+	syms     [][2]int
+	lens     []int
+	min, max int
+	data     []byte
+	sizes    []byte
+	index    []byte
+	count    int
+}
+
+const (
+	// testMaxPairs is the number of pairs replaced and testMaxExpand the
+	// number of values a symbol may expand into, which keeps the number of
+	// values of a block below 65536.
+	testMaxPairs  = 200
+	testMaxExpand = 64
+	leaf          = 0x0fff
+)
+
+func newTestPairs(vs []int) *testPairs {
+	seq := make([]int, len(vs))
+	syms := [][2]int{}
+	expand := []int{}
+	leaves := map[int]int{}
+	for i, v := range vs {
+		if v < 0 {
+			v = 0
+		}
+		s, ok := leaves[v]
+		if !ok {
+			s = len(syms)
+			leaves[v] = s
+			syms = append(syms, [2]int{v, leaf})
+			expand = append(expand, 1)
+		}
+		seq[i] = s
+	}
+	seq, syms, expand = replacePairs(seq, syms, expand)
+
+	freq := make([]int, len(syms))
+	for _, s := range seq {
+		freq[s]++
+	}
+	lens := codeLengths(freq)
+	// Symbols are numbered from the longest code to the shortest, symbols
+	// only used in pairs last.
+	order := make([]int, len(syms))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return lens[order[i]] > lens[order[j]]
+	})
+	num := make([]int, len(syms))
+	for i, s := range order {
+		num[s] = i
+	}
+	d := &testPairs{min: 64}
+	for _, s := range order {
+		sym := syms[s]
+		if sym[1] != leaf {
+			sym = [2]int{num[sym[0]], num[sym[1]]}
+		}
+		d.syms = append(d.syms, sym)
+		d.lens = append(d.lens, lens[s])
+		if l := lens[s]; l > 0 {
+			if l < d.min {
+				d.min = l
+			}
+			if l > d.max {
+				d.max = l
+			}
+		}
+	}
+	codes := d.codes()
+
+	blockBits := 8 << testBlockSize
+	// starts are the indexes of the first values of the blocks.
+	starts := []int{}
+	for i, start := 0, 0; i < len(seq); {
+		starts = append(starts, start)
+		block := make([]byte, 1<<testBlockSize)
+		bit, values := 0, 0
+		for ; i < len(seq); i++ {
+			s := num[seq[i]]
+			l := d.lens[s]
+			if bit+l > blockBits {
+				break
+			}
+			for k := 0; k < l; k++ {
+				if codes[s]>>uint(l-1-k)&1 != 0 {
+					block[(bit+k)/8] |= 0x80 >> uint((bit+k)%8)
+				}
+			}
+			bit += l
+			values += expand[seq[i]]
+		}
+		d.data = append(d.data, block...)
+		d.sizes = appendUint16(d.sizes, values-1)
+		d.count++
+		start += values
+	}
+	for main := 0; main<<testIdxBits < len(vs); main++ {
+		// The middle of the last index may be after the last value.
+		p := main<<testIdxBits + 1<<(testIdxBits-1)
+		block := sort.SearchInts(starts, p+1) - 1
+		d.index = appendUint32(d.index, block)
+		d.index = appendUint16(d.index, p-starts[block])
+	}
+	return d
+}
+
+// replacePairs replaces the most frequent pair of adjacent symbols with a
+// new symbol until no pair repeats.
+func replacePairs(seq []int, syms [][2]int, expand []int) ([]int, [][2]int, []int) {
+	for n := 0; n < testMaxPairs; n++ {
+		counts := map[[2]int]int{}
+		best, most := [2]int{}, 1
+		for i := 0; i+1 < len(seq); i++ {
+			p := [2]int{seq[i], seq[i+1]}
+			if expand[p[0]]+expand[p[1]] > testMaxExpand {
+				continue
+			}
+			counts[p]++
+			if c := counts[p]; c > most || (c == most && (p[0] < best[0] || (p[0] == best[0] && p[1] < best[1]))) {
+				best, most = p, c
+			}
+		}
+		if most < 2 {
+			break
+		}
+		s := len(syms)
+		syms = append(syms, best)
+		expand = append(expand, expand[best[0]]+expand[best[1]])
+		out := seq[:0]
+		for i := 0; i < len(seq); i++ {
+			if i+1 < len(seq) && seq[i] == best[0] && seq[i+1] == best[1] {
+				out = append(out, s)
+				i++
+				continue
+			}
+			out = append(out, seq[i])
+		}
+		seq = out
+	}
+	return seq, syms, expand
+}
+
+// codeLengths returns the Huffman code lengths of the symbols, zero for
+// unused symbols.  Frequencies are halved until no code is longer than the
+// 32 bits the reader refills at a time.
+func codeLengths(freq []int) []int {
+	for {
+		lens := huffman(freq)
+		longest := 0
+		for _, l := range lens {
+			if l > longest {
+				longest = l
+			}
+		}
+		if longest <= 32 {
+			return lens
+		}
+		for i, f := range freq {
+			if f > 1 {
+				freq[i] = (f + 1) / 2
+			}
+		}
+	}
+}
+
+func huffman(freq []int) []int {
+	type node struct {
+		weight int
+		syms   []int
+	}
+	nodes := []node{}
+	for s, f := range freq {
+		if f > 0 {
+			nodes = append(nodes, node{f, []int{s}})
+		}
+	}
+	lens := make([]int, len(freq))
+	if len(nodes) == 1 {
+		lens[nodes[0].syms[0]] = 1
+		return lens
+	}
+	for len(nodes) > 1 {
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].weight < nodes[j].weight })
+		a, b := nodes[0], nodes[1]
+		for _, s := range append(append([]int{}, a.syms...), b.syms...) {
+			lens[s]++
+		}
+		nodes = append(nodes[2:], node{a.weight + b.weight, append(append([]int{}, a.syms...), b.syms...)})
+	}
+	return lens
+}
+
+// codes returns the canonical codes of the symbols: the codes of each
+// length are consecutive and the longest codes are the smallest.
+func (d *testPairs) codes() []int {
+	codes := make([]int, len(d.syms))
+	base := 0
+	for l := d.max; l >= d.min; l-- {
+		n := 0
+		for s, sl := range d.lens {
+			if sl == l {
+				codes[s] = base + n
+				n++
+			}
+		}
+		base = (base + n) / 2
+	}
+	return codes
+}
+
+func (d *testPairs) appendHeader(b []byte, flags byte) []byte {
+	b = append(b, flags, testBlockSize, testIdxBits, 0)
+	b = appendUint32(b, d.count)
+	b = append(b, byte(d.max), byte(d.min))
+	// the number of the first symbol of each code length
+	for l := d.min; l <= d.max; l++ {
+		first := 0
+		for _, sl := range d.lens {
+			if sl > l {
+				first++
+			}
+		}
+		b = appendUint16(b, first)
+	}
+	b = appendUint16(b, len(d.syms))
+	for _, s := range d.syms {
+		b = append(b, byte(s[0]), byte(s[0]>>8)&0x0f|byte(s[1]<<4), byte(s[1]>>4))
+	}
+	if len(d.syms)&1 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func appendUint16(b []byte, v int) []byte {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], uint16(v))
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v int) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(v))
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,255 @@
+package tablebase
+
+import "github.com/notnil/chess"
+
+// board is a small mailbox board for generating tables.  Squares are
+// numbered like chess.Square and captured pieces are on square -1.
+type board struct {
+	pieces []chess.Piece
+	n      int
+	sqs    [MaxPieces]int
+	occ    [64]int8
+	turn   chess.Color
+	// kings are the indexes of the white and black king.
+	kings [2]int
+}
+
+// move is a move of the piece with the given index.
+type move struct {
+	piece   int
+	to      int
+	capture int
+	promo   chess.PieceType
+}
+
+// setup sets up the board with the pieces on b.sqs and returns false if the
+// position is illegal: pieces share a square, pawns are on the first or
+// last rank or the side not to move is in check.
+func (b *board) setup(pieces []chess.Piece, turn chess.Color) bool {
+	b.pieces, b.n, b.turn = pieces, len(pieces), turn
+	for i := range b.occ {
+		b.occ[i] = -1
+	}
+	for i, sq := range b.sqs[:b.n] {
+		if b.occ[sq] >= 0 {
+			return false
+		}
+		if pieces[i].Type() == chess.Pawn && (sq/8 == 0 || sq/8 == 7) {
+			return false
+		}
+		b.occ[sq] = int8(i)
+		if pieces[i].Type() == chess.King {
+			b.kings[pieces[i].Color()-chess.White] = i
+		}
+	}
+	return !b.inCheck(turn.Other())
+}
+
+// inCheck returns true if the king of the color is attacked.
+func (b *board) inCheck(c chess.Color) bool {
+	king := b.sqs[b.kings[c-chess.White]]
+	for i, p := range b.pieces {
+		if p.Color() != c && b.sqs[i] >= 0 && b.attacks(i, king) {
+			return true
+		}
+	}
+	return false
+}
+
+// attacks returns true if the piece with the index attacks the square.
+func (b *board) attacks(i, to int) bool {
+	from := b.sqs[i]
+	df, dr := to%8-from%8, to/8-from/8
+	p := b.pieces[i]
+	switch p.Type() {
+	case chess.King:
+		return abs(df) <= 1 && abs(dr) <= 1 && from != to
+	case chess.Knight:
+		return abs(df)*abs(dr) == 2
+	case chess.Pawn:
+		if p.Color() == chess.White {
+			return dr == 1 && abs(df) == 1
+		}
+		return dr == -1 && abs(df) == 1
+	}
+	straight := (df == 0) != (dr == 0)
+	diagonal := df != 0 && abs(df) == abs(dr)
+	switch p.Type() {
+	case chess.Rook:
+		if !straight {
+			return false
+		}
+	case chess.Bishop:
+		if !diagonal {
+			return false
+		}
+	default:
+		if !straight && !diagonal {
+			return false
+		}
+	}
+	d := dir{sign(df), sign(dr)}
+	for sq := step(from, d); sq != to; sq = step(sq, d) {
+		if b.occ[sq] >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// moves calls f with every legal move of the side to move and the board
+// after it.
+func (b *board) moves(f func(m move, next board)) {
+	c := b.turn
+	try := func(m move) {
+		next := *b
+		from := b.sqs[m.piece]
+		next.occ[from] = -1
+		if m.capture >= 0 {
+			next.sqs[m.capture] = -1
+		}
+		next.sqs[m.piece] = m.to
+		next.occ[m.to] = int8(m.piece)
+		next.turn = c.Other()
+		if !next.inCheck(c) {
+			f(m, next)
+		}
+	}
+	target := func(i, to int) (move, bool) {
+		o := b.occ[to]
+		if o < 0 {
+			return move{piece: i, to: to, capture: -1}, true
+		}
+		if b.pieces[o].Color() == c || b.pieces[o].Type() == chess.King {
+			return move{}, false
+		}
+		return move{piece: i, to: to, capture: int(o)}, true
+	}
+	for i, p := range b.pieces {
+		from := b.sqs[i]
+		if p.Color() != c || from < 0 {
+			continue
+		}
+		switch p.Type() {
+		case chess.King, chess.Knight:
+			steps := kingSteps[from]
+			if p.Type() == chess.Knight {
+				steps = knightSteps[from]
+			}
+			for _, to := range steps {
+				if m, ok := target(i, to); ok {
+					try(m)
+				}
+			}
+		case chess.Pawn:
+			b.pawnMoves(i, from, try)
+		default:
+			for _, d := range slides(p.Type()) {
+				for to := step(from, d); to >= 0; to = step(to, d) {
+					if m, ok := target(i, to); ok {
+						try(m)
+					}
+					if b.occ[to] >= 0 {
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func (b *board) pawnMoves(i, from int, try func(m move)) {
+	c := b.pieces[i].Color()
+	forward, start, last := 8, 1, 7
+	if c == chess.Black {
+		forward, start, last = -8, 6, 0
+	}
+	add := func(to, capture int) {
+		if to/8 != last {
+			try(move{piece: i, to: to, capture: capture})
+			return
+		}
+		for _, pt := range promotions {
+			try(move{piece: i, to: to, capture: capture, promo: pt})
+		}
+	}
+	to := from + forward
+	if b.occ[to] < 0 {
+		add(to, -1)
+		if from/8 == start && b.occ[to+forward] < 0 {
+			add(to+forward, -1)
+		}
+	}
+	for _, df := range []int{-1, 1} {
+		f := from%8 + df
+		if f < 0 || f > 7 {
+			continue
+		}
+		o := b.occ[to+df]
+		if o >= 0 && b.pieces[o].Color() != c && b.pieces[o].Type() != chess.King {
+			add(to+df, int(o))
+		}
+	}
+}
+
+// dir is a step on the board in files and ranks.
+type dir struct {
+	file, rank int
+}
+
+var (
+	rookDirs    = []dir{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	bishopDirs  = []dir{{1, 1}, {1, -1}, {-1, -1}, {-1, 1}}
+	queenDirs   = append(append([]dir{}, rookDirs...), bishopDirs...)
+	kingSteps   = steps(queenDirs)
+	knightSteps = steps([]dir{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}})
+)
+
+func slides(pt chess.PieceType) []dir {
+	switch pt {
+	case chess.Rook:
+		return rookDirs
+	case chess.Bishop:
+		return bishopDirs
+	}
+	return queenDirs
+}
+
+func steps(dirs []dir) [64][]int {
+	var s [64][]int
+	for sq := 0; sq < 64; sq++ {
+		for _, d := range dirs {
+			if to := step(sq, d); to >= 0 {
+				s[sq] = append(s[sq], to)
+			}
+		}
+	}
+	return s
+}
+
+// step returns the square one step from the square in the direction or -1
+// if it is off the board.
+func step(sq int, d dir) int {
+	f, r := sq%8+d.file, sq/8+d.rank
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return -1
+	}
+	return r*8 + f
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
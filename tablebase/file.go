@@ -0,0 +1,131 @@
+package tablebase
+
+import (
+	"bufio"
+	"compress/flate"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	tableMagic = "CGTB\x01"
+	// Ext is the file extension of tables written by Save.
+	Ext = ".cgtb"
+)
+
+// Table is the table of an ending.
+type Table struct {
+	material string
+	values   []byte
+}
+
+// Material returns the material of the table such as KRvKP.
+func (t *Table) Material() string {
+	return t.material
+}
+
+// WriteTo implements the io.WriterTo interface.  The table is written as
+// the magic "CGTB\x01", the length of the material name in a byte, the
+// material name and the DEFLATE compressed values of the positions.
+func (t *Table) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	header := tableMagic + string([]byte{byte(len(t.material))}) + t.material
+	if _, err := io.WriteString(cw, header); err != nil {
+		return cw.n, err
+	}
+	fw, err := flate.NewWriter(cw, flate.BestCompression)
+	if err != nil {
+		return cw.n, err
+	}
+	if _, err := fw.Write(t.values); err != nil {
+		return cw.n, err
+	}
+	err = fw.Close()
+	return cw.n, err
+}
+
+// ReadTable reads a table written by WriteTo.
+func ReadTable(r io.Reader) (*Table, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(tableMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(tableMagic)]) != tableMagic {
+		return nil, errors.New("tablebase: invalid table file")
+	}
+	name := make([]byte, header[len(tableMagic)])
+	if _, err := io.ReadFull(br, name); err != nil {
+		return nil, errors.New("tablebase: invalid table file")
+	}
+	pieces, err := parseMaterial(string(name))
+	if err != nil {
+		return nil, err
+	}
+	if key, _ := materialKey(pieces); key != string(name) {
+		return nil, errors.New("tablebase: invalid table material " + string(name))
+	}
+	values := make([]byte, size(len(pieces)))
+	fr := flate.NewReader(br)
+	defer fr.Close()
+	if _, err := io.ReadFull(fr, values); err != nil {
+		return nil, errors.New("tablebase: corrupt table " + string(name))
+	}
+	return &Table{material: string(name), values: values}, nil
+}
+
+// Save writes the tables to the directory as files named after their
+// material with the Ext extension.
+func (tb *Tablebase) Save(dir string) error {
+	for _, t := range tb.tables {
+		f, err := os.Create(filepath.Join(dir, t.material+Ext))
+		if err != nil {
+			return err
+		}
+		if _, err := t.WriteTo(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns a Tablebase with the tables saved in the directory.
+func Open(dir string) (*Tablebase, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	tb := New()
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), Ext) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		t, err := ReadTable(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		tb.Add(t)
+	}
+	return tb, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
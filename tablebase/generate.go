@@ -0,0 +1,280 @@
+package tablebase
+
+import (
+	"errors"
+
+	"github.com/notnil/chess"
+)
+
+// Positions are indexed by the squares of the pieces in table order and
+// the side to move.  The board is mirrored so the white king is on files a
+// to d, which halves the size of the tables.
+
+// size returns the number of positions of a table with n pieces.
+func size(n int) int {
+	s := 32 * 2
+	for i := 1; i < n; i++ {
+		s *= 64
+	}
+	return s
+}
+
+// index returns the index of the position with the pieces on the squares.
+func index(sqs []int, turn chess.Color) int {
+	mirror := 0
+	if sqs[0]%8 > 3 {
+		mirror = 7
+	}
+	k := sqs[0] ^ mirror
+	idx := k/8*4 + k%8
+	for _, sq := range sqs[1:] {
+		idx = idx*64 + sq ^ mirror
+	}
+	idx *= 2
+	if turn == chess.Black {
+		idx++
+	}
+	return idx
+}
+
+// decode returns the squares and side to move of the index.
+func decode(idx, n int, sqs []int) chess.Color {
+	turn := chess.White
+	if idx%2 == 1 {
+		turn = chess.Black
+	}
+	idx /= 2
+	for i := n - 1; i > 0; i-- {
+		sqs[i] = idx % 64
+		idx /= 64
+	}
+	sqs[0] = idx/4*8 + idx%4
+	return turn
+}
+
+// generator builds a table by retrograde analysis.  Every position is
+// first resolved as far as possible from its moves into other tables and
+// its moves within the table are counted.  Mates are then propagated
+// backwards ply by ply: predecessors of lost positions are won and
+// predecessors of won positions are lost once all their moves are.
+type generator struct {
+	tb     *Tablebase
+	key    string
+	pieces []chess.Piece
+	n      int
+	values []byte
+	// remaining counts the moves within the table not yet known to win
+	// for the opponent.
+	remaining []uint8
+	// floor is the longest loss through moves into other tables or
+	// noLoss if the position can't be lost.
+	floor  []uint8
+	wins   [][]int32
+	losses [][]int32
+}
+
+const noLoss = 255
+
+func newGenerator(tb *Tablebase, key string, pieces []chess.Piece) *generator {
+	n := len(pieces)
+	return &generator{
+		tb:        tb,
+		key:       key,
+		pieces:    pieces,
+		n:         n,
+		values:    make([]byte, size(n)),
+		remaining: make([]uint8, size(n)),
+		floor:     make([]uint8, size(n)),
+		wins:      make([][]int32, maxPlies+2),
+		losses:    make([][]int32, maxPlies+2),
+	}
+}
+
+func (g *generator) run() (*Table, error) {
+	if err := g.init(); err != nil {
+		return nil, err
+	}
+	var sqs [MaxPieces]int
+	for ply := 0; ply <= maxPlies; ply++ {
+		for _, idx := range g.losses[ply] {
+			if g.values[idx] != draw {
+				continue
+			}
+			g.values[idx] = loss(ply)
+			turn := decode(int(idx), g.n, sqs[:])
+			g.predecessors(sqs, turn, func(q int) {
+				if g.values[q] == draw {
+					g.schedule(&g.wins, q, ply+1)
+				}
+			})
+		}
+		for _, idx := range g.wins[ply] {
+			if g.values[idx] != draw {
+				continue
+			}
+			g.values[idx] = win(ply)
+			turn := decode(int(idx), g.n, sqs[:])
+			g.predecessors(sqs, turn, func(q int) {
+				if g.values[q] != draw || g.remaining[q] == 0 {
+					return
+				}
+				g.remaining[q]--
+				if g.remaining[q] == 0 && g.floor[q] != noLoss {
+					g.schedule(&g.losses, q, max(ply+1, int(g.floor[q])))
+				}
+			})
+		}
+		g.wins[ply], g.losses[ply] = nil, nil
+	}
+	for _, bucket := range append(g.wins, g.losses...) {
+		if len(bucket) > 0 {
+			return nil, errors.New("tablebase: distance to mate of " + g.key + " is too long")
+		}
+	}
+	return &Table{material: g.key, values: g.values}, nil
+}
+
+func (g *generator) schedule(buckets *[][]int32, idx, ply int) {
+	if ply >= len(*buckets) {
+		ply = len(*buckets) - 1
+	}
+	(*buckets)[ply] = append((*buckets)[ply], int32(idx))
+}
+
+// init marks invalid positions, resolves mates and stalemates and moves
+// into other tables and counts the moves within the table.
+func (g *generator) init() error {
+	var b board
+	for idx := range g.values {
+		turn := decode(idx, g.n, b.sqs[:])
+		if !b.setup(g.pieces, turn) {
+			g.values[idx] = invalid
+			continue
+		}
+		legal, inTable := 0, 0
+		subWin, subLoss, canDraw := -1, 0, false
+		var err error
+		b.moves(func(m move, next board) {
+			legal++
+			if m.capture < 0 && m.promo == chess.NoPieceType {
+				inTable++
+				return
+			}
+			var v byte
+			if v, err = g.convert(&next, m); err != nil {
+				return
+			}
+			switch wdl(v) {
+			case Loss:
+				if subWin < 0 || dtm(v)+1 < subWin {
+					subWin = dtm(v) + 1
+				}
+			case Win:
+				subLoss = max(subLoss, dtm(v)+1)
+			default:
+				canDraw = true
+			}
+		})
+		if err != nil {
+			return err
+		}
+		switch {
+		case legal == 0 && b.inCheck(turn):
+			g.schedule(&g.losses, idx, 0)
+			continue
+		case legal == 0:
+			g.floor[idx] = noLoss
+			continue
+		}
+		g.remaining[idx] = uint8(inTable)
+		g.floor[idx] = uint8(subLoss)
+		if subWin >= 0 {
+			g.schedule(&g.wins, idx, subWin)
+		}
+		if subWin >= 0 || canDraw {
+			g.floor[idx] = noLoss
+		} else if inTable == 0 {
+			g.schedule(&g.losses, idx, subLoss)
+		}
+	}
+	return nil
+}
+
+// convert returns the value of the position after the capture or
+// promotion from the table of the new material.
+func (g *generator) convert(next *board, m move) (byte, error) {
+	pieces := make([]chess.Piece, 0, g.n)
+	squares := make([]int, 0, g.n)
+	for i, p := range g.pieces {
+		if next.sqs[i] < 0 {
+			continue
+		}
+		if i == m.piece && m.promo != chess.NoPieceType {
+			p = chess.NewPiece(m.promo, p.Color())
+		}
+		pieces = append(pieces, p)
+		squares = append(squares, next.sqs[i])
+	}
+	return g.tb.lookup(pieces, squares, next.turn)
+}
+
+// predecessors calls f with the index of every position from which a move
+// within the table leads to the position.
+func (g *generator) predecessors(sqs [MaxPieces]int, turn chess.Color, f func(q int)) {
+	var occ [64]bool
+	for _, sq := range sqs[:g.n] {
+		occ[sq] = true
+	}
+	prev := turn.Other()
+	for i, p := range g.pieces {
+		if p.Color() != prev {
+			continue
+		}
+		to := sqs[i]
+		unmove := func(from int) {
+			q := sqs
+			q[i] = from
+			f(index(q[:g.n], prev))
+		}
+		switch p.Type() {
+		case chess.King:
+			for _, from := range kingSteps[to] {
+				if !occ[from] {
+					unmove(from)
+				}
+			}
+		case chess.Knight:
+			for _, from := range knightSteps[to] {
+				if !occ[from] {
+					unmove(from)
+				}
+			}
+		case chess.Pawn:
+			forward, start := 8, 1
+			if prev == chess.Black {
+				forward, start = -8, 6
+			}
+			from := to - forward
+			if from/8 == 0 || from/8 == 7 || occ[from] {
+				continue
+			}
+			unmove(from)
+			if from/8 == start+forward/8 && !occ[from-forward] {
+				unmove(from - forward)
+			}
+		default:
+			for _, d := range slides(p.Type()) {
+				for from := step(to, d); from >= 0 && !occ[from]; from = step(from, d) {
+					unmove(from)
+				}
+			}
+		}
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,24 @@
+package tablebase
+
+import "testing"
+
+// TestLongestMate checks the longest KBNvK win against the known result
+// of mate in 33 moves, 65 plies with the winning side to move.
+func TestLongestMate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generating a four piece table is slow")
+	}
+	tb := New()
+	if err := tb.Generate("KBNK"); err != nil {
+		t.Fatal(err)
+	}
+	longest := 0
+	for _, v := range tb.Table("KBNvK").values {
+		if wdl(v) == Win && dtm(v) > longest {
+			longest = dtm(v)
+		}
+	}
+	if longest != 65 {
+		t.Fatalf("expected the longest KBNvK mate to take 65 plies but got %d", longest)
+	}
+}
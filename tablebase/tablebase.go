@@ -0,0 +1,380 @@
+// Package tablebase generates and probes distance to mate endgame
+// tablebases for three and four piece endings such as KQvK, KRvK, KPvK,
+// KBNvK and KRvKP.  Tables are built in pure Go by retrograde analysis and
+// stored in a compact compressed file format, so perfect play for the
+// basic endings doesn't need downloaded tables or an engine.
+//
+// Positions with castling rights aren't supported, en passant captures and
+// the fifty move rule are ignored.
+package tablebase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// MaxPieces is the maximum number of pieces, including the kings, of a
+// table.
+const MaxPieces = 4
+
+// WDL is the game theoretical outcome of a position for the side to move.
+type WDL int
+
+const (
+	// Draw indicates that neither side can force mate.
+	Draw WDL = iota
+	// Win indicates that the side to move mates with best play.
+	Win
+	// Loss indicates that the side to move is mated with best play.
+	Loss
+)
+
+// String implements the fmt.Stringer interface.
+func (w WDL) String() string {
+	switch w {
+	case Win:
+		return "Win"
+	case Loss:
+		return "Loss"
+	}
+	return "Draw"
+}
+
+// Result is the result of probing a position.
+type Result struct {
+	WDL WDL
+	// DTM is the number of plies to mate with best play or zero for draws.
+	DTM int
+	// Move is a best move: the fastest mate when winning, the longest
+	// resistance when losing and a drawing move otherwise.  It is nil if
+	// there are no legal moves.
+	Move *chess.Move
+}
+
+// Tablebase is a set of tables.
+type Tablebase struct {
+	tables map[string]*Table
+}
+
+// New returns an empty Tablebase.
+func New() *Tablebase {
+	return &Tablebase{tables: map[string]*Table{}}
+}
+
+// Add adds the table, replacing a table of the same material.
+func (tb *Tablebase) Add(t *Table) {
+	tb.tables[t.material] = t
+}
+
+// Table returns the table of the material, in the same notation as
+// Generate, or nil if it isn't in the Tablebase.
+func (tb *Tablebase) Table(material string) *Table {
+	pieces, err := parseMaterial(material)
+	if err != nil {
+		return nil
+	}
+	key, _ := materialKey(pieces)
+	return tb.tables[key]
+}
+
+// Materials returns the materials of the tables in the Tablebase.
+func (tb *Tablebase) Materials() []string {
+	materials := []string{}
+	for m := range tb.tables {
+		materials = append(materials, m)
+	}
+	sort.Strings(materials)
+	return materials
+}
+
+// Generate generates the table of the material and the tables of the
+// endings it converts to by captures and promotions which aren't in the
+// Tablebase yet.  Material is written as the white pieces followed by the
+// black pieces, each starting with the king and optionally separated by
+// "v": KRKP and KRvKP are the same ending.  Tables are stored with the
+// stronger side as white and probe both colorings.
+func (tb *Tablebase) Generate(material string) error {
+	pieces, err := parseMaterial(material)
+	if err != nil {
+		return err
+	}
+	if len(pieces) < 3 {
+		return errors.New("tablebase: material needs at least three pieces " + material)
+	}
+	return tb.generate(pieces)
+}
+
+func (tb *Tablebase) generate(pieces []chess.Piece) error {
+	key, flipped := materialKey(pieces)
+	if _, ok := tb.tables[key]; ok || len(pieces) < 3 {
+		return nil
+	}
+	if flipped {
+		pieces = flipPieces(pieces)
+	}
+	sortPieces(pieces)
+	for _, sub := range conversions(pieces) {
+		if err := tb.generate(sub); err != nil {
+			return err
+		}
+	}
+	t, err := newGenerator(tb, key, pieces).run()
+	if err != nil {
+		return err
+	}
+	tb.tables[key] = t
+	return nil
+}
+
+// Probe returns the outcome, distance to mate and a best move of the
+// position.
+func (tb *Tablebase) Probe(pos *chess.Position) (Result, error) {
+	if _, ok := pos.Variant().(chess.Standard); !ok {
+		return Result{}, errors.New("tablebase: only standard chess is supported")
+	}
+	if strings.Fields(pos.String())[2] != "-" {
+		return Result{}, errors.New("tablebase: positions with castling rights aren't supported")
+	}
+	v, err := tb.probe(pos)
+	if err != nil {
+		return Result{}, err
+	}
+	r := Result{WDL: wdl(v), DTM: dtm(v)}
+	best := -1
+	for _, m := range pos.ValidMoves() {
+		cv, err := tb.probe(pos.Update(m))
+		if err != nil {
+			return Result{}, err
+		}
+		score := -score(cv)
+		if r.Move == nil || score > best {
+			r.Move, best = m, score
+		}
+	}
+	return r, nil
+}
+
+// probe returns the value of the position from its table.
+func (tb *Tablebase) probe(pos *chess.Position) (byte, error) {
+	pieces := []chess.Piece{}
+	squares := []int{}
+	for sq, p := range pos.Board().SquareMap() {
+		pieces = append(pieces, p)
+		squares = append(squares, int(sq))
+	}
+	if len(pieces) > MaxPieces {
+		return 0, fmt.Errorf("tablebase: %d pieces are more than %d", len(pieces), MaxPieces)
+	}
+	return tb.lookup(pieces, squares, pos.Turn())
+}
+
+// lookup returns the value of the position with the pieces on the squares.
+// Positions with only the kings are drawn.
+func (tb *Tablebase) lookup(pieces []chess.Piece, squares []int, turn chess.Color) (byte, error) {
+	if len(pieces) == 2 {
+		return draw, nil
+	}
+	key, flipped := materialKey(pieces)
+	t, ok := tb.tables[key]
+	if !ok {
+		return 0, errors.New("tablebase: no table for " + key)
+	}
+	n := len(pieces)
+	ps := make([]placed, n)
+	for i := range pieces {
+		ps[i] = placed{pieces[i], squares[i]}
+		if flipped {
+			ps[i] = placed{flipPiece(pieces[i]), squares[i] ^ 56}
+		}
+	}
+	if flipped {
+		turn = turn.Other()
+	}
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].piece < ps[j].piece })
+	var sqs [MaxPieces]int
+	for i, p := range ps {
+		sqs[i] = p.square
+	}
+	return t.values[index(sqs[:n], turn)], nil
+}
+
+type placed struct {
+	piece  chess.Piece
+	square int
+}
+
+// Values are stored in a byte per position: zero for draws, the number of
+// plies for wins and lossBase plus the number of plies for losses.
+const (
+	draw     = 0
+	lossBase = 128
+	invalid  = 255
+	maxPlies = 126
+)
+
+func win(plies int) byte  { return byte(plies) }
+func loss(plies int) byte { return byte(lossBase + plies) }
+
+func wdl(v byte) WDL {
+	switch {
+	case v == draw || v == invalid:
+		return Draw
+	case v < lossBase:
+		return Win
+	}
+	return Loss
+}
+
+func dtm(v byte) int {
+	switch wdl(v) {
+	case Win:
+		return int(v)
+	case Loss:
+		return int(v) - lossBase
+	}
+	return 0
+}
+
+// score orders values from the side to move's point of view: faster wins
+// and slower losses are better.
+func score(v byte) int {
+	switch wdl(v) {
+	case Win:
+		return 1000 - dtm(v)
+	case Loss:
+		return -1000 + dtm(v)
+	}
+	return 0
+}
+
+// parseMaterial parses material such as KRKP or KRvKP.
+func parseMaterial(material string) ([]chess.Piece, error) {
+	s := strings.ToUpper(strings.TrimSpace(material))
+	invalidErr := errors.New("tablebase: invalid material " + material)
+	if !strings.HasPrefix(s, "K") || strings.Count(s, "K") != 2 {
+		return nil, invalidErr
+	}
+	s = strings.Replace(s, "V", "", 1)
+	black := strings.LastIndex(s, "K")
+	pieces := []chess.Piece{}
+	for i, r := range s {
+		c := chess.White
+		if i >= black {
+			c = chess.Black
+		}
+		pt := pieceType(r)
+		if pt == chess.NoPieceType {
+			return nil, invalidErr
+		}
+		pieces = append(pieces, chess.NewPiece(pt, c))
+	}
+	if len(pieces) > MaxPieces {
+		return nil, fmt.Errorf("tablebase: material %s has more than %d pieces", material, MaxPieces)
+	}
+	return pieces, nil
+}
+
+func pieceType(r rune) chess.PieceType {
+	for _, pt := range chess.PieceTypes() {
+		if strings.ToUpper(pt.String()) == string(r) {
+			return pt
+		}
+	}
+	return chess.NoPieceType
+}
+
+// materialKey returns the name of the table of the pieces, such as KRvKP,
+// and whether the colors are swapped in the table because black is the
+// stronger side.
+func materialKey(pieces []chess.Piece) (string, bool) {
+	white, black := []chess.PieceType{}, []chess.PieceType{}
+	for _, p := range pieces {
+		if p.Type() == chess.King {
+			continue
+		}
+		if p.Color() == chess.White {
+			white = append(white, p.Type())
+		} else {
+			black = append(black, p.Type())
+		}
+	}
+	sortTypes(white)
+	sortTypes(black)
+	flipped := stronger(black, white)
+	if flipped {
+		white, black = black, white
+	}
+	return "K" + letters(white) + "vK" + letters(black), flipped
+}
+
+// stronger returns true if a has more pieces than b or, with as many
+// pieces, the first different piece of a is more valuable.
+func stronger(a, b []chess.PieceType) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func sortTypes(types []chess.PieceType) {
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+}
+
+func letters(types []chess.PieceType) string {
+	s := ""
+	for _, pt := range types {
+		s += strings.ToUpper(pt.String())
+	}
+	return s
+}
+
+// sortPieces sorts the pieces in table order: white before black and
+// kings, queens, rooks, bishops, knights and pawns.
+func sortPieces(pieces []chess.Piece) {
+	sort.Slice(pieces, func(i, j int) bool { return pieces[i] < pieces[j] })
+}
+
+func flipPiece(p chess.Piece) chess.Piece {
+	return chess.NewPiece(p.Type(), p.Color().Other())
+}
+
+func flipPieces(pieces []chess.Piece) []chess.Piece {
+	flipped := make([]chess.Piece, len(pieces))
+	for i, p := range pieces {
+		flipped[i] = flipPiece(p)
+	}
+	return flipped
+}
+
+// conversions returns the materials reached from the pieces by captures
+// and promotions.
+func conversions(pieces []chess.Piece) [][]chess.Piece {
+	subs := [][]chess.Piece{}
+	for i, p := range pieces {
+		if p.Type() == chess.King {
+			continue
+		}
+		sub := append(append([]chess.Piece{}, pieces[:i]...), pieces[i+1:]...)
+		subs = append(subs, sub)
+		if p.Type() != chess.Pawn {
+			continue
+		}
+		for _, pt := range promotions {
+			sub := append([]chess.Piece{}, pieces...)
+			sub[i] = chess.NewPiece(pt, p.Color())
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+var promotions = []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight}
@@ -0,0 +1,175 @@
+package tablebase_test
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/tablebase"
+)
+
+var (
+	once sync.Once
+	tb   *tablebase.Tablebase
+)
+
+// tables returns a Tablebase with the three piece tables, generated once.
+func tables(t *testing.T) *tablebase.Tablebase {
+	once.Do(func() {
+		tb = tablebase.New()
+		for _, m := range []string{"KQK", "KRK", "KPK"} {
+			if err := tb.Generate(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	if tb == nil {
+		t.Fatal("tables weren't generated")
+	}
+	return tb
+}
+
+func position(t *testing.T, fen string) *chess.Position {
+	t.Helper()
+	opt, err := chess.FEN(fen, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chess.NewGame(opt).Position()
+}
+
+func TestGenerateDependencies(t *testing.T) {
+	expected := []string{"KBvK", "KNvK", "KPvK", "KQvK", "KRvK"}
+	materials := tables(t).Materials()
+	if len(materials) != len(expected) {
+		t.Fatalf("expected %v but got %v", expected, materials)
+	}
+	for i := range expected {
+		if materials[i] != expected[i] {
+			t.Fatalf("expected %v but got %v", expected, materials)
+		}
+	}
+	if tables(t).Table("KvKP") == nil || tables(t).Table("KRKP") != nil {
+		t.Fatal("expected table lookup by either coloring")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		fen string
+		wdl tablebase.WDL
+		dtm int
+	}{
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", tablebase.Loss, 0},
+		{"k7/8/1K6/8/8/8/8/6Q1 w - - 0 1", tablebase.Win, 1},
+		{"4k3/4P3/4K3/8/8/8/8/8 b - - 0 1", tablebase.Draw, 0},
+		{"k7/8/K7/P7/8/8/8/8 w - - 0 1", tablebase.Draw, 0},
+		{"8/8/8/8/8/8/4P3/4K2k w - - 0 1", tablebase.Win, 0},
+		{"k7/8/8/8/8/8/8/K7 w - - 0 1", tablebase.Draw, 0},
+		{"kq6/8/8/8/3K4/8/8/8 w - - 0 1", tablebase.Loss, 0},
+	}
+	for _, test := range tests {
+		r, err := tables(t).Probe(position(t, test.fen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.WDL != test.wdl || test.dtm > 0 && r.DTM != test.dtm {
+			t.Fatalf("%s: expected %s in %d but got %s in %d", test.fen, test.wdl, test.dtm, r.WDL, r.DTM)
+		}
+	}
+}
+
+// TestProbePlay plays the best moves of both sides and checks that the
+// game ends in mate after the distance to mate.
+func TestProbePlay(t *testing.T) {
+	fens := []string{
+		"8/8/8/4k3/8/8/8/KQ6 w - - 0 1",
+		"8/8/3k4/8/8/8/8/R6K w - - 0 1",
+		"8/8/8/8/8/8/4P3/4K2k w - - 0 1",
+		"kq6/8/8/8/3K4/8/8/8 b - - 0 1",
+	}
+	for _, fen := range fens {
+		opt, err := chess.FEN(fen, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := chess.NewGame(opt)
+		r, err := tables(t).Probe(g.Position())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.WDL != tablebase.Win {
+			t.Fatalf("%s: expected win but got %s", fen, r.WDL)
+		}
+		dtm, plies := r.DTM, 0
+		for r.Move != nil {
+			if err := g.Move(r.Move); err != nil {
+				t.Fatal(err)
+			}
+			plies++
+			if r, err = tables(t).Probe(g.Position()); err != nil {
+				t.Fatal(err)
+			}
+			if r.DTM != dtm-plies {
+				t.Fatalf("%s: expected %d plies to mate after %s but got %d", fen, dtm-plies, g, r.DTM)
+			}
+		}
+		if g.Method() != chess.Checkmate || plies != dtm {
+			t.Fatalf("%s: expected mate in %d plies but got %s after %d", fen, dtm, g.Method(), plies)
+		}
+	}
+}
+
+func TestSaveOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tablebase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := tables(t).Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := tablebase.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := position(t, "8/8/8/4k3/8/8/8/KQ6 w - - 0 1")
+	expected, err := tables(t).Probe(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := opened.Probe(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.WDL != expected.WDL || r.DTM != expected.DTM || r.Move.String() != expected.Move.String() {
+		t.Fatalf("expected %+v but got %+v", expected, r)
+	}
+	fi, err := os.Stat(dir + "/KQvK" + tablebase.Ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > 64*1024 {
+		t.Fatalf("expected compact table but got %d bytes", fi.Size())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, m := range []string{"KQ", "QKK", "KXK", "KQQKQ", "KK"} {
+		if err := tablebase.New().Generate(m); err == nil {
+			t.Fatalf("expected error for material %s", m)
+		}
+	}
+	fens := []string{
+		"8/8/8/4k3/8/8/8/KQR5 w - - 0 1",
+		"r3k3/8/8/8/8/8/8/4K3 w q - 0 1",
+		"r3k3/8/8/8/8/8/8/3QK3 w - - 0 1",
+	}
+	for _, fen := range fens {
+		if _, err := tables(t).Probe(position(t, fen)); err == nil {
+			t.Fatalf("expected error for %s", fen)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package tactics
+
+import (
+	"sort"
+
+	"github.com/notnil/chess"
+)
+
+// value returns the material value of the piece in pawns.
+func value(p chess.Piece) int {
+	switch p.Type() {
+	case chess.Pawn:
+		return 1
+	case chess.Knight, chess.Bishop:
+		return 3
+	case chess.Rook:
+		return 5
+	case chess.Queen:
+		return 9
+	case chess.King:
+		return 100
+	}
+	return 0
+}
+
+func isSlider(p chess.Piece) bool {
+	t := p.Type()
+	return t == chess.Queen || t == chess.Rook || t == chess.Bishop
+}
+
+func defended(b *chess.Board, sq chess.Square) bool {
+	return b.IsAttacked(sq, b.Piece(sq).Color())
+}
+
+// valuable returns true if the target of the attacker is worth attacking: a
+// king, a more valuable piece or an undefended piece other than a pawn.
+func valuable(b *chess.Board, attacker, target chess.Piece, sq chess.Square) bool {
+	switch {
+	case target.Type() == chess.King, value(target) > value(attacker):
+		return true
+	case target.Type() == chess.Pawn:
+		return false
+	}
+	return !defended(b, sq)
+}
+
+// fork returns the fork of the piece on the square.
+func fork(b *chess.Board, sq chess.Square) (Motif, bool) {
+	p := b.Piece(sq)
+	if p == chess.NoPiece {
+		return Motif{}, false
+	}
+	sqs := []chess.Square{sq}
+	for _, target := range b.Attacks(sq) {
+		t := b.Piece(target)
+		if t != chess.NoPiece && t.Color() != p.Color() && valuable(b, p, t, target) {
+			sqs = append(sqs, target)
+		}
+	}
+	if len(sqs) < 3 {
+		return Motif{}, false
+	}
+	return newMotif(b, Fork, sqs...), true
+}
+
+// lines returns the pins and skewers of the sliding piece on the square.
+func lines(b *chess.Board, sq chess.Square, p chess.Piece) []Motif {
+	if !isSlider(p) {
+		return nil
+	}
+	motifs := []Motif{}
+	for _, front := range b.Attacks(sq) {
+		f := b.Piece(front)
+		if f == chess.NoPiece || f.Color() == p.Color() {
+			continue
+		}
+		back := behind(b, sq, front)
+		if back == chess.NoSquare {
+			continue
+		}
+		bk := b.Piece(back)
+		if bk.Color() == p.Color() {
+			continue
+		}
+		worth := value(bk) > value(p) || !defended(b, back)
+		switch {
+		case bk.Type() == chess.King:
+			motifs = append(motifs, newMotif(b, AbsolutePin, sq, front, back))
+		case f.Type() == chess.King:
+			motifs = append(motifs, newMotif(b, Skewer, sq, front, back))
+		case value(bk) > value(f) && worth:
+			motifs = append(motifs, newMotif(b, RelativePin, sq, front, back))
+		case value(f) > value(bk) && worth:
+			motifs = append(motifs, newMotif(b, Skewer, sq, front, back))
+		}
+	}
+	return motifs
+}
+
+// behind returns the first occupied square behind front on the line from
+// sq or NoSquare.
+func behind(b *chess.Board, sq, front chess.Square) chess.Square {
+	df := sign(int(front.File()) - int(sq.File()))
+	dr := sign(int(front.Rank()) - int(sq.Rank()))
+	f, r := int(front.File())+df, int(front.Rank())+dr
+	for f >= 0 && f < 8 && r >= 0 && r < 8 {
+		s := chess.NewSquare(chess.File(f), chess.Rank(r))
+		if b.Piece(s) != chess.NoPiece {
+			return s
+		}
+		f, r = f+df, r+dr
+	}
+	return chess.NoSquare
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// discovered returns the attacks and checks uncovered by the move.
+func discovered(pre, b *chess.Board, m *chess.Move, c chess.Color) []Motif {
+	if m.HasTag(chess.KingSideCastle) || m.HasTag(chess.QueenSideCastle) {
+		return nil
+	}
+	motifs := []Motif{}
+	for sq, p := range b.SquareMap() {
+		if p.Color() != c || sq == m.S2() || !isSlider(p) || pre.Piece(sq) != p {
+			continue
+		}
+		before := map[chess.Square]bool{}
+		for _, target := range pre.Attacks(sq) {
+			before[target] = true
+		}
+		for _, target := range b.Attacks(sq) {
+			t := b.Piece(target)
+			if before[target] || t == chess.NoPiece || t.Color() == c {
+				continue
+			}
+			switch {
+			case t.Type() == chess.King:
+				motifs = append(motifs, newMotif(b, DiscoveredCheck, m.S2(), sq, target))
+			case valuable(b, p, t, target):
+				motifs = append(motifs, newMotif(b, DiscoveredAttack, m.S2(), sq, target))
+			}
+		}
+	}
+	return motifs
+}
+
+// doubleCheck returns the double check of the color's king.
+func doubleCheck(b *chess.Board, c chess.Color) []Motif {
+	king := kingSquare(b, c)
+	if king == chess.NoSquare {
+		return nil
+	}
+	checkers := b.Attackers(king, c.Other())
+	if len(checkers) < 2 {
+		return nil
+	}
+	return []Motif{newMotif(b, DoubleCheck, append([]chess.Square{king}, checkers...)...)}
+}
+
+// overloaded returns the color's pieces which are the only defender of at
+// least two attacked pieces.
+func overloaded(b *chess.Board, c chess.Color) []Motif {
+	motifs := []Motif{}
+	for sq, p := range b.SquareMap() {
+		if p.Color() != c {
+			continue
+		}
+		sqs := []chess.Square{sq}
+		for _, target := range b.Attacks(sq) {
+			t := b.Piece(target)
+			if t == chess.NoPiece || t.Color() != c || t.Type() == chess.King {
+				continue
+			}
+			if b.IsAttacked(target, c.Other()) && len(b.Attackers(target, c)) == 1 {
+				sqs = append(sqs, target)
+			}
+		}
+		if len(sqs) >= 3 {
+			motifs = append(motifs, newMotif(b, OverloadedDefender, sqs...))
+		}
+	}
+	return motifs
+}
+
+// trapped returns the color's knights, bishops, rooks and queens which are
+// attacked without being safe and can't move to a safe square.  Moves are
+// made on the board without checking that they are legal.
+func trapped(b *chess.Board, c chess.Color) []Motif {
+	motifs := []Motif{}
+	squares := b.SquareMap()
+	for sq, p := range squares {
+		if p.Color() != c || p.Type() == chess.King || p.Type() == chess.Pawn || safe(b, sq) {
+			continue
+		}
+		escape := false
+		for _, to := range b.Attacks(sq) {
+			t := b.Piece(to)
+			if t != chess.NoPiece && (t.Color() == c || t.Type() == chess.King) {
+				continue
+			}
+			if value(t) >= value(p) {
+				escape = true
+				break
+			}
+			moved := map[chess.Square]chess.Piece{}
+			for s, piece := range squares {
+				moved[s] = piece
+			}
+			delete(moved, sq)
+			moved[to] = p
+			if safe(chess.NewBoard(moved), to) {
+				escape = true
+				break
+			}
+		}
+		if !escape {
+			sqs := append([]chess.Square{sq}, b.Attackers(sq, c.Other())...)
+			motifs = append(motifs, newMotif(b, TrappedPiece, sqs...))
+		}
+	}
+	return motifs
+}
+
+// safe returns true if the piece on the square isn't attacked by a less
+// valuable piece and is defended if attacked.
+func safe(b *chess.Board, sq chess.Square) bool {
+	p := b.Piece(sq)
+	attackers := b.Attackers(sq, p.Color().Other())
+	if len(attackers) == 0 {
+		return true
+	}
+	for _, a := range attackers {
+		if value(b.Piece(a)) < value(p) {
+			return false
+		}
+	}
+	return defended(b, sq)
+}
+
+// backRank returns the back rank weakness of the color's king.
+func backRank(b *chess.Board, c chess.Color) []Motif {
+	king := kingSquare(b, c)
+	rank, forward := chess.Rank1, 1
+	if c == chess.Black {
+		rank, forward = chess.Rank8, -1
+	}
+	if king == chess.NoSquare || king.Rank() != rank {
+		return nil
+	}
+	heavy := false
+	for _, p := range b.SquareMap() {
+		if p.Color() != c && (p.Type() == chess.Rook || p.Type() == chess.Queen) {
+			heavy = true
+		}
+	}
+	if !heavy {
+		return nil
+	}
+	sqs := []chess.Square{king}
+	r := chess.Rank(int(rank) + forward)
+	for f := int(king.File()) - 1; f <= int(king.File())+1; f++ {
+		if f < 0 || f > 7 {
+			continue
+		}
+		sq := chess.NewSquare(chess.File(f), r)
+		p := b.Piece(sq)
+		if p != chess.NoPiece && p.Color() == c || b.IsAttacked(sq, c.Other()) {
+			sqs = append(sqs, sq)
+			continue
+		}
+		return nil
+	}
+	return []Motif{newMotif(b, BackRankWeakness, sqs...)}
+}
+
+func kingSquare(b *chess.Board, c chess.Color) chess.Square {
+	for sq, p := range b.SquareMap() {
+		if p.Type() == chess.King && p.Color() == c {
+			return sq
+		}
+	}
+	return chess.NoSquare
+}
+
+// sortMotifs sorts the motifs by kind and squares so results don't depend
+// on map iteration order.
+func sortMotifs(motifs []Motif) {
+	sort.Slice(motifs, func(i, j int) bool {
+		a, b := motifs[i], motifs[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		for k := 0; k < len(a.Squares) && k < len(b.Squares); k++ {
+			if a.Squares[k] != b.Squares[k] {
+				return a.Squares[k] < b.Squares[k]
+			}
+		}
+		return len(a.Squares) < len(b.Squares)
+	})
+}
@@ -0,0 +1,200 @@
+// Package tactics detects tactical motifs such as forks, pins and skewers
+// on positions and moves without an engine.  Motifs are reported with the
+// squares and pieces involved and can be described in words.
+package tactics
+
+import (
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Kind is the kind of a motif.
+type Kind int
+
+const (
+	// Fork indicates that a piece attacks several valuable targets.
+	// Squares are the forking piece and the targets.
+	Fork Kind = iota
+	// AbsolutePin indicates that a piece can't move without exposing its
+	// king.  Squares are the pinning piece, the pinned piece and the king.
+	AbsolutePin
+	// RelativePin indicates that a piece can't move without exposing a
+	// more valuable piece.  Squares are the pinning piece, the pinned piece
+	// and the piece behind it.
+	RelativePin
+	// Skewer indicates that a valuable piece is attacked with a less
+	// valuable piece behind it.  Squares are the attacker, the attacked
+	// piece and the piece behind it.
+	Skewer
+	// DiscoveredAttack indicates that a move uncovers an attack by another
+	// piece.  Squares are the moved piece, the uncovered attacker and the
+	// target.
+	DiscoveredAttack
+	// DiscoveredCheck indicates that a move uncovers a check by another
+	// piece.  Squares are the moved piece, the checking piece and the king.
+	DiscoveredCheck
+	// DoubleCheck indicates that the king is attacked by two pieces.
+	// Squares are the king and the checking pieces.
+	DoubleCheck
+	// OverloadedDefender indicates that a piece is the only defender of
+	// several attacked pieces.  Squares are the defender and the defended
+	// pieces.
+	OverloadedDefender
+	// TrappedPiece indicates that an attacked piece has no safe square.
+	// Squares are the trapped piece and its attackers.
+	TrappedPiece
+	// BackRankWeakness indicates that a king on its first rank has no
+	// escape squares while the opponent has a rook or queen.  Squares are
+	// the king and the squares in front of it.
+	BackRankWeakness
+)
+
+// String implements the fmt.Stringer interface.
+func (k Kind) String() string {
+	switch k {
+	case Fork:
+		return "Fork"
+	case AbsolutePin:
+		return "AbsolutePin"
+	case RelativePin:
+		return "RelativePin"
+	case Skewer:
+		return "Skewer"
+	case DiscoveredAttack:
+		return "DiscoveredAttack"
+	case DiscoveredCheck:
+		return "DiscoveredCheck"
+	case DoubleCheck:
+		return "DoubleCheck"
+	case OverloadedDefender:
+		return "OverloadedDefender"
+	case TrappedPiece:
+		return "TrappedPiece"
+	case BackRankWeakness:
+		return "BackRankWeakness"
+	}
+	return ""
+}
+
+// Motif is a tactical motif.  Pieces are the pieces on the squares, or
+// NoPiece for empty squares, when the motif was found.
+type Motif struct {
+	Kind    Kind
+	Squares []chess.Square
+	Pieces  []chess.Piece
+}
+
+// String describes the motif in words such as "knight on c7 forks king on
+// e8 and rook on a8".
+func (m Motif) String() string {
+	n := func(i int) string {
+		return name(m.Pieces[i]) + " on " + m.Squares[i].String()
+	}
+	list := func(from int) string {
+		a := []string{}
+		for i := from; i < len(m.Squares); i++ {
+			a = append(a, n(i))
+		}
+		if len(a) < 2 {
+			return strings.Join(a, "")
+		}
+		return strings.Join(a[:len(a)-1], ", ") + " and " + a[len(a)-1]
+	}
+	switch m.Kind {
+	case Fork:
+		return n(0) + " forks " + list(1)
+	case AbsolutePin, RelativePin:
+		return n(0) + " pins " + n(1) + " to " + n(2)
+	case Skewer:
+		return n(0) + " skewers " + n(1) + " and " + n(2)
+	case DiscoveredAttack:
+		return n(0) + " uncovers an attack by " + n(1) + " on " + n(2)
+	case DiscoveredCheck:
+		return n(0) + " uncovers check by " + n(1)
+	case DoubleCheck:
+		return n(0) + " is in double check from " + list(1)
+	case OverloadedDefender:
+		return n(0) + " is overloaded defending " + list(1)
+	case TrappedPiece:
+		return n(0) + " is trapped"
+	case BackRankWeakness:
+		return n(0) + " has a back rank weakness"
+	}
+	return ""
+}
+
+func name(p chess.Piece) string {
+	switch p.Type() {
+	case chess.King:
+		return "king"
+	case chess.Queen:
+		return "queen"
+	case chess.Rook:
+		return "rook"
+	case chess.Bishop:
+		return "bishop"
+	case chess.Knight:
+		return "knight"
+	case chess.Pawn:
+		return "pawn"
+	}
+	return "square"
+}
+
+// newMotif returns the motif with the pieces on the squares of the board.
+func newMotif(b *chess.Board, k Kind, sqs ...chess.Square) Motif {
+	m := Motif{Kind: k, Squares: sqs}
+	for _, sq := range sqs {
+		m.Pieces = append(m.Pieces, b.Piece(sq))
+	}
+	return m
+}
+
+// Position returns the motifs present in the position: forks, pins and
+// skewers, overloaded defenders, trapped pieces and back rank weaknesses of
+// both colors and double check.
+func Position(pos *chess.Position) []Motif {
+	b := pos.Board()
+	motifs := []Motif{}
+	for sq, p := range b.SquareMap() {
+		if f, ok := fork(b, sq); ok {
+			motifs = append(motifs, f)
+		}
+		motifs = append(motifs, lines(b, sq, p)...)
+	}
+	motifs = append(motifs, doubleCheck(b, pos.Turn())...)
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		motifs = append(motifs, overloaded(b, c)...)
+		motifs = append(motifs, trapped(b, c)...)
+		motifs = append(motifs, backRank(b, c)...)
+	}
+	sortMotifs(motifs)
+	return motifs
+}
+
+// Move returns the motifs created by the move: forks, pins and skewers by
+// the moved piece, discovered attacks and checks, double check and pieces
+// of the opponent trapped by the move.
+func Move(pos *chess.Position, m *chess.Move) []Motif {
+	pre, b := pos.Board(), pos.Update(m).Board()
+	mover := pos.Turn()
+	motifs := []Motif{}
+	if f, ok := fork(b, m.S2()); ok {
+		motifs = append(motifs, f)
+	}
+	motifs = append(motifs, lines(b, m.S2(), b.Piece(m.S2()))...)
+	motifs = append(motifs, discovered(pre, b, m, mover)...)
+	motifs = append(motifs, doubleCheck(b, mover.Other())...)
+	before := map[chess.Square]bool{}
+	for _, t := range trapped(pre, mover.Other()) {
+		before[t.Squares[0]] = true
+	}
+	for _, t := range trapped(b, mover.Other()) {
+		if !before[t.Squares[0]] {
+			motifs = append(motifs, t)
+		}
+	}
+	sortMotifs(motifs)
+	return motifs
+}
@@ -0,0 +1,120 @@
+package tactics_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/tactics"
+)
+
+func position(t *testing.T, fen string) *chess.Position {
+	t.Helper()
+	opt, err := chess.FEN(fen, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chess.NewGame(opt).Position()
+}
+
+func move(t *testing.T, pos *chess.Position, s string) *chess.Move {
+	t.Helper()
+	m, err := chess.AlgebraicNotation{}.Decode(pos, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func find(motifs []tactics.Motif, k tactics.Kind) (tactics.Motif, bool) {
+	for _, m := range motifs {
+		if m.Kind == k {
+			return m, true
+		}
+	}
+	return tactics.Motif{}, false
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		kind tactics.Kind
+		want string
+	}{
+		{"r3k3/8/8/1N6/8/8/8/6K1 w - - 0 1", "Nc7+", tactics.Fork, "knight on c7 forks rook on a8 and king on e8"},
+		{"4k3/8/2n5/8/8/8/8/4KB2 w - - 0 1", "Bb5", tactics.AbsolutePin, "bishop on b5 pins knight on c6 to king on e8"},
+		{"4k3/3q4/2n5/8/8/8/8/4KB2 w - - 0 1", "Bb5", tactics.RelativePin, "bishop on b5 pins knight on c6 to queen on d7"},
+		{"8/8/8/q3k3/8/8/7R/1K6 w - - 0 1", "Rh5+", tactics.Skewer, "rook on h5 skewers king on e5 and queen on a5"},
+		{"4q1k1/8/6p1/8/4B3/8/8/K3R3 w - - 0 1", "Bd5+", tactics.DiscoveredAttack, "bishop on d5 uncovers an attack by rook on e1 on queen on e8"},
+		{"4k3/8/8/8/8/8/4N3/K3R3 w - - 0 1", "Nc3+", tactics.DiscoveredCheck, "knight on c3 uncovers check by rook on e1"},
+		{"4k3/8/8/8/4N3/8/8/K3R3 w - - 0 1", "Nf6+", tactics.DoubleCheck, "king on e8 is in double check from rook on e1 and knight on f6"},
+		{"r3k3/Bpp5/8/8/8/8/8/4K3 b - - 0 1", "b6", tactics.TrappedPiece, "bishop on a7 is trapped"},
+	}
+	for _, test := range tests {
+		pos := position(t, test.fen)
+		motifs := tactics.Move(pos, move(t, pos, test.move))
+		m, ok := find(motifs, test.kind)
+		if !ok {
+			t.Errorf("%s %s: expected %s in %v", test.fen, test.move, test.kind, motifs)
+			continue
+		}
+		if m.String() != test.want {
+			t.Errorf("%s %s: expected %q but got %q", test.fen, test.move, test.want, m.String())
+		}
+	}
+}
+
+func TestMoveTrappedBefore(t *testing.T) {
+	// the bishop is already trapped so the move doesn't trap it
+	pos := position(t, "r3k3/B1p5/1p6/8/8/8/8/4K3 b - - 0 1")
+	if m, ok := find(tactics.Move(pos, move(t, pos, "Kd8")), tactics.TrappedPiece); ok {
+		t.Errorf("expected no trapped piece but got %s", m)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		fen  string
+		kind tactics.Kind
+		want string
+	}{
+		{"r3k3/2N5/8/8/8/8/8/6K1 b - - 0 1", tactics.Fork, "knight on c7 forks rook on a8 and king on e8"},
+		{"4k3/8/2n5/1B6/8/8/8/4K3 b - - 0 1", tactics.AbsolutePin, "bishop on b5 pins knight on c6 to king on e8"},
+		{"b2r4/8/7k/8/3nB3/8/8/K2R4 w - - 0 1", tactics.OverloadedDefender, "rook on d8 is overloaded defending knight on d4 and bishop on a8"},
+		{"r3k3/B1p5/1p6/8/8/8/8/4K3 w - - 0 1", tactics.TrappedPiece, "bishop on a7 is trapped"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", tactics.BackRankWeakness, "king on g8 has a back rank weakness"},
+		{"4k3/8/5N2/8/8/8/8/K3R3 b - - 0 1", tactics.DoubleCheck, "king on e8 is in double check from rook on e1 and knight on f6"},
+	}
+	for _, test := range tests {
+		motifs := tactics.Position(position(t, test.fen))
+		m, ok := find(motifs, test.kind)
+		if !ok {
+			t.Errorf("%s: expected %s in %v", test.fen, test.kind, motifs)
+			continue
+		}
+		if m.String() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.fen, test.want, m.String())
+		}
+	}
+}
+
+func TestPositionBackRankEscape(t *testing.T) {
+	pos := position(t, "6k1/5pp1/7p/8/8/8/8/R5K1 w - - 0 1")
+	if m, ok := find(tactics.Position(pos), tactics.BackRankWeakness); ok {
+		t.Errorf("expected no back rank weakness but got %s", m)
+	}
+}
+
+func TestMotifPieces(t *testing.T) {
+	pos := position(t, "r3k3/8/8/1N6/8/8/8/6K1 w - - 0 1")
+	m, ok := find(tactics.Move(pos, move(t, pos, "Nc7+")), tactics.Fork)
+	if !ok {
+		t.Fatal("expected fork")
+	}
+	sqs := []chess.Square{chess.C7, chess.A8, chess.E8}
+	pieces := []chess.Piece{chess.WhiteKnight, chess.BlackRook, chess.BlackKing}
+	if !reflect.DeepEqual(m.Squares, sqs) || !reflect.DeepEqual(m.Pieces, pieces) {
+		t.Fatalf("expected %v %v but got %v %v", sqs, pieces, m.Squares, m.Pieces)
+	}
+}
@@ -0,0 +1,135 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ThreeCheck is standard chess in which a player also wins by giving
+// check three times.  Positions are written with the remaining checks of
+// both players after the en passant square:
+//
+//	rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 3+2 0 3
+//
+// The check counter appended to the end of the FEN as checks given
+// (+1+0) is also decoded.
+type ThreeCheck struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (ThreeCheck) Name() string {
+	return "Three-check"
+}
+
+// StartingPosition implements the Variant interface.
+func (ThreeCheck) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(startFEN, ThreeCheck{})
+	return pos
+}
+
+// Status implements the Variant interface.  In addition to the standard
+// methods it returns ThirdCheck if the player who just moved gave their
+// third check.
+func (ThreeCheck) Status(pos *Position) (Outcome, Method) {
+	if pos.checks != nil && pos.checks[pos.turn.Other()] <= 0 {
+		if pos.turn == White {
+			return BlackWon, ThirdCheck
+		}
+		return WhiteWon, ThirdCheck
+	}
+	return Standard{}.Status(pos)
+}
+
+// InsufficientMaterial implements the Variant interface and returns true
+// only if both players have a bare king since any piece can give check.
+func (ThreeCheck) InsufficientMaterial(pos *Position) bool {
+	b := pos.board
+	return b.whiteSqs == b.bbWhiteKing && b.blackSqs == b.bbBlackKing
+}
+
+func (ThreeCheck) checkLimit() int {
+	return 3
+}
+
+func (ThreeCheck) afterMove(pos *Position, m *Move, next *Position) {
+	checks := checkCount{}
+	if pos.checks != nil {
+		checks = *pos.checks
+	}
+	if m.HasTag(Check) && checks[pos.turn] > 0 {
+		checks[pos.turn]--
+	}
+	next.checks = &checks
+}
+
+// checkCount holds the number of checks each color has left to give
+// indexed by color.
+type checkCount [3]int8
+
+func (cc *checkCount) equal(o *checkCount) bool {
+	if cc == nil || o == nil {
+		return cc == o
+	}
+	return *cc == *o
+}
+
+// RemainingChecks returns the number of checks the color has to give to
+// win the game.  It returns zero for variants which don't count checks.
+func (pos *Position) RemainingChecks(c Color) int {
+	if pos.checks == nil || c == NoColor {
+		return 0
+	}
+	return int(pos.checks[c])
+}
+
+// checksFEN returns the remaining checks FEN field with a leading space
+// or an empty string if checks aren't counted.
+func (pos *Position) checksFEN() string {
+	if pos.checks == nil {
+		return ""
+	}
+	return fmt.Sprintf(" %d+%d", pos.checks[White], pos.checks[Black])
+}
+
+// decodeChecksFEN removes the check counter from the FEN.  The counter can
+// be the remaining checks after the en passant square (3+3) or the checks
+// given at the end of the FEN (+0+0).  Positions without a counter start
+// with the limit.
+func decodeChecksFEN(fen string, limit int) (string, *checkCount, error) {
+	parts := strings.Fields(fen)
+	checks := &checkCount{NoColor: 0, White: int8(limit), Black: int8(limit)}
+	parse := func(s string) (int, int, error) {
+		counts := strings.Split(strings.TrimPrefix(s, "+"), "+")
+		if len(counts) != 2 {
+			return 0, 0, fmt.Errorf("chess: fen invalid check counter %s", s)
+		}
+		w, err := strconv.Atoi(counts[0])
+		if err != nil || w < 0 || w > limit {
+			return 0, 0, fmt.Errorf("chess: fen invalid check counter %s", s)
+		}
+		b, err := strconv.Atoi(counts[1])
+		if err != nil || b < 0 || b > limit {
+			return 0, 0, fmt.Errorf("chess: fen invalid check counter %s", s)
+		}
+		return w, b, nil
+	}
+	switch {
+	case len(parts) == 7 && strings.HasPrefix(parts[6], "+"):
+		w, b, err := parse(parts[6])
+		if err != nil {
+			return "", nil, err
+		}
+		checks[White], checks[Black] = int8(limit-w), int8(limit-b)
+		parts = parts[:6]
+	case len(parts) == 7 && strings.Contains(parts[4], "+"):
+		w, b, err := parse(parts[4])
+		if err != nil {
+			return "", nil, err
+		}
+		checks[White], checks[Black] = int8(w), int8(b)
+		parts = append(parts[:4], parts[5:]...)
+	}
+	return strings.Join(parts, " "), checks, nil
+}
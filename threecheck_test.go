@@ -0,0 +1,57 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThreeCheck(t *testing.T) {
+	g := NewGame(UseVariant(ThreeCheck{}))
+	if s := g.Position().String(); s != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1" {
+		t.Fatalf("unexpected starting position %s", s)
+	}
+	for _, m := range []string{"e4", "e5", "Bc4", "Nc6", "Bxf7+", "Kxf7", "Qh5+", "Kf6", "Qf5+"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.Outcome() != WhiteWon || g.Method() != ThirdCheck {
+		t.Fatalf("expected white to win by third check but got %s %s", g.Outcome(), g.Method())
+	}
+	if n := g.Position().RemainingChecks(White); n != 0 {
+		t.Fatalf("expected no remaining checks but got %d", n)
+	}
+	opt, err := PGN(strings.NewReader(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2 := NewGame(opt); g2.Variant() != (ThreeCheck{}) || g2.Position().String() != g.Position().String() {
+		t.Fatalf("expected three-check game after decoding %s", g.String())
+	}
+}
+
+func TestThreeCheckFEN(t *testing.T) {
+	for fen, expected := range map[string]string{
+		"rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 3+2 0 3":  "rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 3+2 0 3",
+		"rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 0 3 +2+0": "rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 1+3 0 3",
+		"rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 0 3":      "rnbqkbnr/ppp2ppp/8/3pp3/4P3/5Q2/PPPP1PPP/RNB1KBNR w KQkq - 3+3 0 3",
+	} {
+		opt, err := VariantFEN(ThreeCheck{}, fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := NewGame(opt).Position().String(); s != expected {
+			t.Fatalf("expected %s but got %s", expected, s)
+		}
+	}
+	if _, err := VariantFEN(ThreeCheck{}, "8/8/8/8/8/8/8/K6k w - - 4+0 0 1"); err == nil {
+		t.Fatal("expected invalid check counter error")
+	}
+	opt, err := VariantFEN(ThreeCheck{}, "8/8/8/8/8/8/8/K6k w - - 1+1 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := NewGame(opt); g.Method() != InsufficientMaterial {
+		t.Fatalf("expected insufficient material with bare kings but got %s", g.Method())
+	}
+}
@@ -1,9 +1,9 @@
 package uci
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,9 +41,12 @@ var (
 	CmdUCI = cmdNoOptions{Name: "uci", F: func(e *Engine) error {
 		e.id = map[string]string{}
 		e.options = map[string]Option{}
-		scanner := bufio.NewScanner(e.out)
-		for scanner.Scan() {
-			text := e.readLine(scanner)
+		e.uciSent = true
+		for {
+			text, err := e.readLine()
+			if err != nil {
+				return err
+			}
 			k, v, err := parseIDLine(text)
 			if err == nil {
 				e.id[k] = v
@@ -73,9 +76,11 @@ var (
 	// This command must always be answered with "readyok" and can be sent also when the engine is calculating
 	// in which case the engine should also immediately answer with "readyok" without stopping the search.
 	CmdIsReady = cmdNoOptions{Name: "isready", F: func(e *Engine) error {
-		scanner := bufio.NewScanner(e.out)
-		for scanner.Scan() {
-			text := e.readLine(scanner)
+		for {
+			text, err := e.readLine()
+			if err != nil {
+				return err
+			}
 			if text == "readyok" {
 				break
 			}
@@ -99,6 +104,7 @@ var (
 	// CmdPonderHit corresponds to the "ponderhit" command:
 	// the user has played the expected move. This will be sent if the engine was told to ponder on the same move
 	// the user has played. The engine should continue searching but switch from pondering to normal search.
+	// Like CmdStop it is sent while a search started with the ponder option is running (see Engine.Ponder).
 	CmdPonderHit = cmdNoOptions{Name: "ponderhit", F: func(e *Engine) error {
 		return nil
 	}}
@@ -139,8 +145,43 @@ func (cmd CmdSetOption) String() string {
 	return fmt.Sprintf("setoption name %s value %s", cmd.Name, cmd.Value)
 }
 
+// UnmarshalText implements the encoding.TextUnmarshaler interface and parses
+// the command as sent by the GUI.
+func (cmd *CmdSetOption) UnmarshalText(text []byte) error {
+	parts := strings.Fields(string(text))
+	if len(parts) < 3 || parts[0] != "setoption" || parts[1] != "name" {
+		return errors.New("uci: invalid setoption command " + string(text))
+	}
+	i := len(parts)
+	for j, s := range parts {
+		if s == "value" {
+			i = j
+			break
+		}
+	}
+	cmd.Name = strings.Join(parts[2:i], " ")
+	cmd.Value = ""
+	if i < len(parts) {
+		cmd.Value = strings.Join(parts[i+1:], " ")
+	}
+	if cmd.Name == "" {
+		return errors.New("uci: invalid setoption command " + string(text))
+	}
+	return nil
+}
+
 // ProcessResponse implements the Cmd interface
 func (cmd CmdSetOption) ProcessResponse(e *Engine) error {
+	if strings.EqualFold(cmd.Name, "UCI_Chess960") {
+		e.chess960 = strings.EqualFold(cmd.Value, "true")
+	}
+	for i, opt := range e.setOptions {
+		if strings.EqualFold(opt.Name, cmd.Name) {
+			e.setOptions[i] = cmd
+			return nil
+		}
+	}
+	e.setOptions = append(e.setOptions, cmd)
 	return nil
 }
 
@@ -150,28 +191,95 @@ func (cmd CmdSetOption) ProcessResponse(e *Engine) error {
 // if the game was played  from the start position the string "startpos" will be sent
 // Note: no "new" command is needed. However, if this position is from a different game than
 // the last position sent to the engine, the GUI should have sent a "ucinewgame" inbetween.
+//
+// Chess960 positions are written in X-FEN and castling as the king capturing
+// its own rook.  The Engine sets Chess960 if UCI_Chess960 was enabled with
+// CmdSetOption so castling in standard positions is written the same way.
 type CmdPosition struct {
 	Position *chess.Position
 	Moves    []*chess.Move
+	Chess960 bool
 }
 
 func (cmd CmdPosition) String() string {
 	if cmd.Position == nil {
 		cmd.Position = chess.StartingPosition()
 	}
+	fen := cmd.Position.String()
+	if _, ok := cmd.Position.Variant().(chess.Chess960); ok {
+		fen = cmd.Position.XFENString()
+	}
 	if len(cmd.Moves) == 0 {
-		return "position fen " + cmd.Position.String()
+		return "position fen " + fen
 	}
+	n := chess.UCINotation{Chess960: cmd.Chess960}
 	moveStrs := []string{}
 	for _, m := range cmd.Moves {
-		mStr := chess.UCINotation{}.Encode(nil, m)
+		mStr := n.Encode(nil, m)
 		moveStrs = append(moveStrs, mStr)
 	}
-	return fmt.Sprintf("position fen %s moves %s", cmd.Position, strings.Join(moveStrs, " "))
+	return fmt.Sprintf("position fen %s moves %s", fen, strings.Join(moveStrs, " "))
 }
 
-// ProcessResponse implements the Cmd interface
-func (CmdPosition) ProcessResponse(e *Engine) error {
+// UnmarshalText implements the encoding.TextUnmarshaler interface and parses
+// the command as sent by the GUI.  The FEN is decoded as a Chess960 position
+// if Chess960 is set.  Moves are decoded as legal moves of the position.
+func (cmd *CmdPosition) UnmarshalText(text []byte) error {
+	parts := strings.Fields(string(text))
+	if len(parts) < 2 || parts[0] != "position" {
+		return errors.New("uci: invalid position command " + string(text))
+	}
+	i := 2
+	var pos *chess.Position
+	switch parts[1] {
+	case "startpos":
+		pos = chess.StartingPosition()
+	case "fen":
+		for i < len(parts) && parts[i] != "moves" {
+			i++
+		}
+		opt, err := chess.FEN(strings.Join(parts[2:i], " "), cmd.Chess960)
+		if err != nil {
+			return err
+		}
+		pos = chess.NewGame(opt).Position()
+	default:
+		return errors.New("uci: invalid position command " + string(text))
+	}
+	cmd.Position = pos
+	cmd.Moves = nil
+	if i < len(parts) && parts[i] == "moves" {
+		for _, s := range parts[i+1:] {
+			m, err := chess.UCINotation{}.Decode(pos, s)
+			if err != nil {
+				return err
+			}
+			cmd.Moves = append(cmd.Moves, m)
+			pos = pos.Update(m)
+		}
+	}
+	return nil
+}
+
+// ProcessResponse implements the Cmd interface and keeps track of the
+// position to translate the moves sent by the engine.
+func (cmd CmdPosition) ProcessResponse(e *Engine) error {
+	pos := cmd.Position
+	if pos == nil {
+		pos = chess.StartingPosition()
+	}
+	for _, m := range cmd.Moves {
+		if pos == nil {
+			break
+		}
+		legal, err := chess.UCINotation{}.Decode(pos, m.String())
+		if err != nil {
+			pos = nil
+			break
+		}
+		pos = pos.Update(legal)
+	}
+	e.position = pos
 	return nil
 }
 
@@ -257,7 +365,7 @@ func (cmd CmdGo) String() string {
 		a = append(a, "nodes", fmt.Sprint(cmd.Nodes))
 	}
 	if cmd.Mate > 0 {
-		a = append(a, "mate", fmt.Sprint(cmd.Nodes))
+		a = append(a, "mate", fmt.Sprint(cmd.Mate))
 	}
 	if cmd.MoveTime > 0 {
 		a = append(a, "movetime", msecStr(cmd.MoveTime))
@@ -275,37 +383,73 @@ func (cmd CmdGo) String() string {
 	return strings.Join(a, " ")
 }
 
-// ProcessResponse implements the Cmd interface
-func (CmdGo) ProcessResponse(e *Engine) error {
-	scanner := bufio.NewScanner(e.out)
-	results := SearchResults{}
-	for scanner.Scan() {
-		text := e.readLine(scanner)
-		if strings.HasPrefix(text, "bestmove") {
-			parts := strings.Split(text, " ")
-			if len(parts) <= 1 {
-				return errors.New("best move not found " + text)
-			}
-			bestMove, err := chess.UCINotation{}.Decode(nil, parts[1])
-			if err != nil {
-				return err
-			}
-			results.BestMove = bestMove
-			if len(parts) >= 4 {
-				ponderMove, err := chess.UCINotation{}.Decode(nil, parts[3])
+// UnmarshalText implements the encoding.TextUnmarshaler interface and parses
+// the command as sent by the GUI.  Search moves are decoded without a
+// position.
+func (cmd *CmdGo) UnmarshalText(text []byte) error {
+	parts := strings.Fields(string(text))
+	if len(parts) == 0 || parts[0] != "go" {
+		return errors.New("uci: invalid go command " + string(text))
+	}
+	*cmd = CmdGo{}
+	for i := 1; i < len(parts); i++ {
+		switch parts[i] {
+		case "ponder":
+			cmd.Ponder = true
+			continue
+		case "infinite":
+			cmd.Infinite = true
+			continue
+		case "searchmoves":
+			for i+1 < len(parts) {
+				m, err := chess.UCINotation{}.Decode(nil, parts[i+1])
 				if err != nil {
-					return err
+					break
 				}
-				results.Ponder = ponderMove
+				cmd.SearchMoves = append(cmd.SearchMoves, m)
+				i++
 			}
-			break
+			continue
 		}
-
-		info := &Info{}
-		err := info.UnmarshalText([]byte(text))
-		if err == nil {
-			results.Info = *info
+		if i+1 >= len(parts) {
+			return errors.New("uci: invalid go command " + string(text))
+		}
+		v, err := strconv.Atoi(parts[i+1])
+		if err != nil {
+			return fmt.Errorf("uci: invalid go command %s %w", text, err)
 		}
+		switch parts[i] {
+		case "wtime":
+			cmd.WhiteTime = time.Duration(v) * time.Millisecond
+		case "btime":
+			cmd.BlackTime = time.Duration(v) * time.Millisecond
+		case "winc":
+			cmd.WhiteIncrement = time.Duration(v) * time.Millisecond
+		case "binc":
+			cmd.BlackIncrement = time.Duration(v) * time.Millisecond
+		case "movestogo":
+			cmd.MovesToGo = v
+		case "depth":
+			cmd.Depth = v
+		case "nodes":
+			cmd.Nodes = v
+		case "mate":
+			cmd.Mate = v
+		case "movetime":
+			cmd.MoveTime = time.Duration(v) * time.Millisecond
+		default:
+			return errors.New("uci: invalid go command " + string(text))
+		}
+		i++
+	}
+	return nil
+}
+
+// ProcessResponse implements the Cmd interface
+func (CmdGo) ProcessResponse(e *Engine) error {
+	results, err := e.readSearch(nil)
+	if err != nil {
+		return err
 	}
 	e.results = results
 	return nil
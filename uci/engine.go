@@ -1,27 +1,48 @@
 package uci
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/process"
 )
 
 // Engine represents a UCI compliant chess engine (e.g. Stockfish, Shredder, etc.).
 // Engine is safe for concurrent use.
 type Engine struct {
-	cmd     *exec.Cmd
-	in      *io.PipeWriter
-	out     *io.PipeReader
+	path    string
+	proc    *process.Process
+	procMu  *sync.Mutex
 	debug   bool
 	logger  *log.Logger
 	id      map[string]string
 	options map[string]Option
 	results SearchResults
 	mu      *sync.RWMutex
+	// chess960 is true if UCI_Chess960 was enabled with CmdSetOption
+	chess960 bool
+	// position is the position set by the last CmdPosition or nil if it
+	// couldn't be followed
+	position *chess.Position
+	// ctx is the context of the command being run
+	ctx context.Context
+	// stale is the last line of the responses still to be read for
+	// commands whose context was done
+	stale       []string
+	quitTimeout time.Duration
+	autoRestart bool
+	// uciSent and setOptions are replayed when the engine is restarted
+	uciSent    bool
+	setOptions []CmdSetOption
+	closed     bool
 }
 
 // Debug is an option for the New function to add logging for debugging.  This will
@@ -38,6 +59,22 @@ func Logger(logger *log.Logger) func(e *Engine) {
 	}
 }
 
+// QuitTimeout is an option for the New function to set how long Close waits
+// for the engine to exit after the quit command before killing the process.
+// The default is five seconds.
+func QuitTimeout(d time.Duration) func(e *Engine) {
+	return func(e *Engine) {
+		e.quitTimeout = d
+	}
+}
+
+// AutoRestart is an option for the New function to restart the engine if the
+// process has exited when the next command is run.  The command that was
+// running when the engine exited still returns an *ExitError.
+func AutoRestart(e *Engine) {
+	e.autoRestart = true
+}
+
 // New constructs an engine from the executable path (found using exec.LookPath).
 // New also starts running the executable process in the background.  Once created
 // the Engine can be controlled via the Run method.
@@ -46,16 +83,21 @@ func New(path string, opts ...func(e *Engine)) (*Engine, error) {
 	if err != nil {
 		return nil, fmt.Errorf("uci: executable not found at path %s %w", path, err)
 	}
-	rIn, wIn := io.Pipe()
-	rOut, wOut := io.Pipe()
-	cmd := exec.Command(path)
-	cmd.Stdin = rIn
-	cmd.Stdout = wOut
-	e := &Engine{cmd: cmd, in: wIn, out: rOut, mu: &sync.RWMutex{}, logger: log.New(os.Stdout, "uci", log.LstdFlags)}
+	e := &Engine{
+		path:        path,
+		procMu:      &sync.Mutex{},
+		mu:          &sync.RWMutex{},
+		logger:      log.New(os.Stdout, "uci", log.LstdFlags),
+		quitTimeout: 5 * time.Second,
+	}
 	for _, opt := range opts {
 		opt(e)
 	}
-	go e.cmd.Run()
+	p, err := process.Start("uci", path)
+	if err != nil {
+		return nil, err
+	}
+	e.proc = p
 	return e, nil
 }
 
@@ -121,16 +163,25 @@ func (e *Engine) SearchResults() SearchResults {
 }
 
 // Run runs the set of Cmds in the order given and returns an error if
-// any of the commands fails.  Except for CmdStop (usually paired with
-// CmdGo's infinite option) all commands block via mutux until completed.
+// any of the commands fails.  Except for CmdStop and CmdPonderHit (usually
+// paired with CmdGo's infinite and ponder options) all commands block via
+// mutux until completed.
 func (e *Engine) Run(cmds ...Cmd) error {
+	return e.RunContext(context.Background(), cmds...)
+}
+
+// RunContext is like Run but returns the context's error if the context is
+// done before a command completes.  A search that is cancelled is stopped
+// and the responses of cancelled commands are skipped by later commands.
+// An *ExitError is returned if the engine exits before a command completes.
+func (e *Engine) RunContext(ctx context.Context, cmds ...Cmd) error {
 	for _, cmd := range cmds {
-		if cmd.String() == CmdStop.Name {
+		if name := cmd.String(); name == CmdStop.Name || name == CmdPonderHit.Name {
 			if err := e.processCommand(cmd); err != nil {
 				return err
 			}
 		} else {
-			if err := e.processCommandLocked(cmd); err != nil {
+			if err := e.processCommandLocked(ctx, cmd); err != nil {
 				return err
 			}
 		}
@@ -138,32 +189,59 @@ func (e *Engine) Run(cmds ...Cmd) error {
 	return nil
 }
 
+// Restart kills the engine process and starts it again.  CmdUCI and the
+// options set with CmdSetOption are sent again to the new process followed
+// by CmdIsReady.
+func (e *Engine) Restart() error {
+	return e.RestartContext(context.Background())
+}
+
+// RestartContext is like Restart but returns the context's error if the
+// context is done before the engine is ready.
+func (e *Engine) RestartContext(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ctx = ctx
+	return e.restart()
+}
+
 // Close releases readers, writers, and processes associated with the
-// Engine.  It also invokes the CmdQuit to signal the engine to terminate.
+// Engine.  It also invokes the CmdQuit to signal the engine to terminate
+// and kills the process if it hasn't exited after the QuitTimeout.  Close
+// doesn't wait for a running search which completes with an *ExitError.
 func (e *Engine) Close() error {
-	if err := e.Run(CmdQuit); err != nil {
-		return err
+	e.procMu.Lock()
+	defer e.procMu.Unlock()
+	if e.closed {
+		return nil
 	}
-	if err := e.in.Close(); err != nil {
-		return err
-	}
-	if err := e.out.Close(); err != nil {
-		return err
+	e.closed = true
+	if e.debug {
+		e.logger.Println(CmdQuit.String())
 	}
-	return e.cmd.Process.Kill()
+	return e.proc.Quit(CmdQuit.String(), e.quitTimeout)
 }
 
-func (e *Engine) processCommandLocked(cmd Cmd) error {
+func (e *Engine) processCommandLocked(ctx context.Context, cmd Cmd) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	return e.processCommand(cmd)
+	e.ctx = ctx
+	if err := e.restartIfExited(); err != nil {
+		return err
+	}
+	err := e.processCommand(cmd)
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		e.cancel(cmd)
+	}
+	return err
 }
 
 func (e *Engine) processCommand(cmd Cmd) error {
-	if e.debug {
-		e.logger.Println(cmd.String())
+	if p, ok := cmd.(CmdPosition); ok && e.chess960 {
+		p.Chess960 = true
+		cmd = p
 	}
-	if _, err := fmt.Fprintln(e.in, cmd.String()); err != nil {
+	if err := e.send(cmd.String()); err != nil {
 		return err
 	}
 	if err := cmd.ProcessResponse(e); err != nil {
@@ -172,10 +250,160 @@ func (e *Engine) processCommand(cmd Cmd) error {
 	return nil
 }
 
-func (e *Engine) readLine(scanner *bufio.Scanner) string {
-	s := scanner.Text()
+// send writes the line to the engine.  An *ExitError is returned if the
+// engine has exited.
+func (e *Engine) send(s string) error {
+	e.procMu.Lock()
+	defer e.procMu.Unlock()
+	if e.closed {
+		return errEngineClosed
+	}
 	if e.debug {
 		e.logger.Println(s)
 	}
-	return s
+	return e.proc.Send(s)
+}
+
+// readLine returns the next line sent by the engine skipping the responses
+// of cancelled commands.  The context's error is returned if the context of
+// the command is done and an *ExitError if the engine has exited.
+func (e *Engine) readLine() (string, error) {
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p := e.process()
+	for {
+		var s string
+		select {
+		case line, ok := <-p.Lines():
+			if !ok {
+				return "", p.ExitError()
+			}
+			s = line
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if e.debug {
+			e.logger.Println(s)
+		}
+		if len(e.stale) == 0 {
+			return s, nil
+		}
+		if fields := strings.Fields(s); len(fields) > 0 && fields[0] == e.stale[0] {
+			e.stale = e.stale[1:]
+		}
+	}
+}
+
+// cancel marks the response of the cancelled command as stale and stops
+// the search if the command was CmdGo.
+func (e *Engine) cancel(cmd Cmd) {
+	switch cmd.String() {
+	case CmdUCI.Name:
+		e.stale = append(e.stale, "uciok")
+	case CmdIsReady.Name:
+		e.stale = append(e.stale, "readyok")
+	default:
+		if _, ok := cmd.(CmdGo); ok {
+			e.stopSearch()
+		}
+	}
+}
+
+// stopSearch stops the search whose context was done and skips its best
+// move.
+func (e *Engine) stopSearch() {
+	if err := e.send(CmdStop.String()); err == nil {
+		e.stale = append(e.stale, "bestmove")
+	}
+}
+
+// process returns the running engine process.
+func (e *Engine) process() *process.Process {
+	e.procMu.Lock()
+	defer e.procMu.Unlock()
+	return e.proc
+}
+
+// restartIfExited restarts the engine with the AutoRestart option if the
+// process has exited.
+func (e *Engine) restartIfExited() error {
+	if !e.autoRestart || !e.process().HasExited() {
+		return nil
+	}
+	return e.restart()
+}
+
+// restart replaces the engine process and replays the setup commands.
+func (e *Engine) restart() error {
+	p, err := process.Start("uci", e.path)
+	if err != nil {
+		return err
+	}
+	e.procMu.Lock()
+	if e.closed {
+		e.procMu.Unlock()
+		p.Kill()
+		return errEngineClosed
+	}
+	old := e.proc
+	e.proc = p
+	e.procMu.Unlock()
+	old.Kill()
+	e.stale = nil
+	cmds := []Cmd{}
+	if e.uciSent {
+		cmds = append(cmds, CmdUCI)
+	}
+	for _, opt := range e.setOptions {
+		cmds = append(cmds, opt)
+	}
+	cmds = append(cmds, CmdIsReady)
+	for _, cmd := range cmds {
+		if err := e.processCommand(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notation returns the UCI notation matching the engine's UCI_Chess960
+// setting.
+func (e *Engine) notation() chess.UCINotation {
+	return chess.UCINotation{Chess960: e.chess960}
+}
+
+// decodeMove decodes the move sent by the engine in the position and
+// returns true if it is a legal move of the position.  Castling is
+// translated to the move of the position if the position is known.
+func (e *Engine) decodeMove(pos *chess.Position, s string) (*chess.Move, bool, error) {
+	if pos != nil {
+		if m, err := e.notation().Decode(pos, s); err == nil {
+			return m, true, nil
+		}
+	}
+	m, err := e.notation().Decode(nil, s)
+	return m, false, err
+}
+
+// translateInfo replaces the moves of the info sent by the engine with the
+// moves of the position.
+func (e *Engine) translateInfo(pos *chess.Position, info *Info) {
+	if pos == nil {
+		return
+	}
+	if info.CurrentMove != nil {
+		if m, err := e.notation().Decode(pos, info.CurrentMove.String()); err == nil {
+			info.CurrentMove = m
+		}
+	}
+	for i, m := range info.PV {
+		m, err := e.notation().Decode(pos, m.String())
+		if err != nil {
+			return
+		}
+		info.PV[i] = m
+		pos = pos.Update(m)
+	}
 }
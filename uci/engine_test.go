@@ -178,3 +178,50 @@ info depth 11 seldepth 14 multipv 1 score cp 50 nodes 34551 nps 575850 tbhits 0
 info depth 12 seldepth 14 multipv 1 score cp 50 nodes 55039 nps 534359 tbhits 0 time 103 pv e2e4 e7e5 g1f3 b8c6 d2d4 e5d4 f3d4 g8f6 b1c3 f8b4
 bestmove e2e4 ponder c7c5`
 )
+
+func TestChess960Castling(t *testing.T) {
+	b := bytes.NewBuffer([]byte{})
+	eng := newFakeEngine(t, uci.Debug, uci.Logger(log.New(b, "", 0)))
+	game := chess.NewGame()
+	for _, s := range []string{"e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5"} {
+		if err := game.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setOpt := uci.CmdSetOption{Name: "UCI_Chess960", Value: "true"}
+	setPos := uci.CmdPosition{Position: chess.StartingPosition(), Moves: game.Moves()}
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, setOpt, setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	results := eng.SearchResults()
+	if !results.BestMove.HasTag(chess.KingSideCastle) || results.BestMove.String() != "e1g1" {
+		t.Fatalf("expected castling e1h1 to be translated to e1g1 but got %s", results.BestMove)
+	}
+	if err := game.Move(results.BestMove); err != nil {
+		t.Fatal(err)
+	}
+	if pv := results.Info.PV[0]; !pv.HasTag(chess.KingSideCastle) || pv.String() != "e1g1" {
+		t.Fatalf("expected pv to be translated but got %s", results.Info.PV)
+	}
+	setPos.Moves = game.Moves()
+	if err := eng.Run(setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "f1c4 f8c5 e1h1\n") {
+		t.Fatalf("expected castling to be sent as e1h1 but got %s", b.String())
+	}
+	if err := game.Move(eng.SearchResults().BestMove); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChess960Position(t *testing.T) {
+	opt, err := chess.FEN("bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w GEge - 0 1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := uci.CmdPosition{Position: chess.NewGame(opt).Position()}
+	if s := cmd.String(); s != "position fen bqnbrkrn/pppppppp/8/8/8/8/PPPPPPPP/BQNBRKRN w KQkq - 0 1" {
+		t.Fatalf("expected x-fen but got %s", s)
+	}
+}
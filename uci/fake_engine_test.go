@@ -0,0 +1,184 @@
+package uci_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/enginetest"
+	"github.com/notnil/chess/uci"
+)
+
+// Searches to these depths make the fake engine exit with status 3 or stop
+// responding.
+const (
+	crashDepth = 99
+	hangDepth  = 98
+)
+
+// TestMain makes the test binary act as a minimal UCI engine so the Engine
+// can be tested without an external executable.  The "server" engine is
+// firstMoveSearcher served by uci.Server.
+func TestMain(m *testing.M) {
+	enginetest.Main(m, map[string]enginetest.Engine{
+		"fake":   runFakeEngine,
+		"server": enginetest.Server(&firstMoveSearcher{skill: 10}, uci.ServerID("First", "notnil")),
+	})
+}
+
+// newFakeEngine starts the test binary as a fake engine.
+func newFakeEngine(t *testing.T, opts ...func(e *uci.Engine)) *uci.Engine {
+	t.Helper()
+	return startEngine(t, "fake", opts...)
+}
+
+// startEngine starts the test binary as the named engine.
+func startEngine(t *testing.T, name string, opts ...func(e *uci.Engine)) *uci.Engine {
+	t.Helper()
+	eng, err := uci.New(enginetest.Path(name), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { eng.Close() })
+	return eng
+}
+
+// runFakeEngine plays the first legal move in alphabetical order preferring
+// castling and sends the following moves as additional lines with MultiPV.
+// Searches with "go infinite" send the move being searched and last until
+// "stop" as do searches with "go ponder" until "ponderhit", they are stopped
+// after the first line of the second iteration.  Searches to crashDepth and
+// hangDepth simulate a crash and a hang.
+func runFakeEngine(r io.Reader, w io.Writer) {
+	chess960 := false
+	multiPV := 1
+	var pos *chess.Position
+	infinite := false
+	scanner := bufio.NewScanner(r)
+	bestMove := func(stopped bool) {
+		n := chess.UCINotation{Chess960: chess960}
+		moves := pos.ValidMoves()
+		if len(moves) == 0 {
+			fmt.Fprintln(w, "bestmove (none)")
+			return
+		}
+		sort.Slice(moves, func(i, j int) bool {
+			ci := moves[i].HasTag(chess.KingSideCastle) || moves[i].HasTag(chess.QueenSideCastle)
+			cj := moves[j].HasTag(chess.KingSideCastle) || moves[j].HasTag(chess.QueenSideCastle)
+			if ci != cj {
+				return ci
+			}
+			return moves[i].String() < moves[j].String()
+		})
+		best := moves[0]
+		ponder := ""
+		pvs := []string{}
+		for i := 0; i < multiPV && i < len(moves); i++ {
+			next := pos.Update(moves[i])
+			pv := n.Encode(pos, moves[i])
+			if replies := next.ValidMoves(); len(replies) > 0 {
+				if i == 0 {
+					ponder = n.Encode(next, replies[0])
+				}
+				pv += " " + n.Encode(next, replies[0])
+			}
+			pvs = append(pvs, pv)
+			fmt.Fprintf(w, "info depth 1 seldepth 1 multipv %d score cp %d nodes 20 nps 20000 time 1 pv %s\n", i+1, 13-i, pv)
+		}
+		// like Stockfish, a search stopped during an iteration reports the
+		// lines it didn't search again at the previous depth
+		if stopped && multiPV > 1 {
+			fmt.Fprintf(w, "info depth 2 seldepth 2 multipv 1 score cp 20 nodes 40 nps 20000 time 2 pv %s\n", n.Encode(pos, best))
+			for i := 1; i < len(pvs); i++ {
+				fmt.Fprintf(w, "info depth 1 seldepth 1 multipv %d score cp %d nodes 40 nps 20000 time 2 pv %s\n", i+1, 13-i, pvs[i])
+			}
+		}
+		if ponder == "" {
+			fmt.Fprintf(w, "bestmove %s\n", n.Encode(pos, best))
+			return
+		}
+		fmt.Fprintf(w, "bestmove %s ponder %s\n", n.Encode(pos, best), ponder)
+	}
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+		switch parts[0] {
+		case "uci":
+			fmt.Fprintln(w, "id name Fake")
+			fmt.Fprintln(w, "id author notnil")
+			fmt.Fprintln(w, "option name UCI_Chess960 type check default false")
+			fmt.Fprintln(w, "option name MultiPV type spin default 1 min 1 max 500")
+			fmt.Fprintln(w, "option name Skill Level type spin default 20 min 0 max 20")
+			fmt.Fprintln(w, "option name Style type combo default Normal var Solid var Normal var Risky")
+			fmt.Fprintln(w, "option name Debug Log File type string default <empty>")
+			fmt.Fprintln(w, "option name Clear Hash type button")
+			fmt.Fprintln(w, "uciok")
+		case "isready":
+			fmt.Fprintln(w, "readyok")
+		case "setoption":
+			if len(parts) == 5 && parts[2] == "UCI_Chess960" {
+				chess960 = parts[4] == "true"
+			}
+			if len(parts) == 5 && parts[2] == "MultiPV" {
+				multiPV, _ = strconv.Atoi(parts[4])
+			}
+		case "position":
+			pos = parseFakePosition(parts[1:], chess960)
+		case "go":
+			if len(parts) == 3 && parts[1] == "depth" {
+				switch parts[2] {
+				case strconv.Itoa(crashDepth):
+					fmt.Fprintln(os.Stderr, "segmentation fault")
+					os.Exit(3)
+				case strconv.Itoa(hangDepth):
+					time.Sleep(time.Hour)
+				}
+			}
+			infinite = len(parts) > 1 && (parts[1] == "infinite" || parts[1] == "ponder")
+			if !infinite {
+				bestMove(false)
+			} else if moves := pos.ValidMoves(); len(moves) > 0 {
+				fmt.Fprintf(w, "info depth 1 currmove %s currmovenumber 1\n", moves[0])
+			}
+		case "stop", "ponderhit":
+			if infinite {
+				infinite = false
+				bestMove(true)
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+func parseFakePosition(parts []string, chess960 bool) *chess.Position {
+	pos := chess.StartingPosition()
+	i := 1
+	if parts[0] == "fen" {
+		i = 7
+		opt, err := chess.FEN(strings.Join(parts[1:7], " "), chess960)
+		if err != nil {
+			panic(err)
+		}
+		pos = chess.NewGame(opt).Position()
+	}
+	if i < len(parts) && parts[i] == "moves" {
+		for _, s := range parts[i+1:] {
+			m, err := chess.UCINotation{}.Decode(pos, s)
+			if err != nil {
+				panic(err)
+			}
+			pos = pos.Update(m)
+		}
+	}
+	return pos
+}
@@ -13,10 +13,60 @@ import (
 // data such as the following:
 // info depth 21 seldepth 31 multipv 1 score cp 39 nodes 862438 nps 860716 hashfull 409 tbhits 0 time 1002 pv e2e4
 // bestmove e2e4 ponder c7c5
+//
+// With the MultiPV option the latest line of every multipv index is kept
+// in MultiPV ordered from best to worst and Info is the best line.  When the
+// search is stopped during an iteration the lines which weren't searched
+// again are kept at the previous depth.
 type SearchResults struct {
 	BestMove *chess.Move
 	Ponder   *chess.Move
 	Info     Info
+	MultiPV  []Info
+	// Position is the position searched or nil if it is unknown.
+	Position *chess.Position
+
+	depth int
+}
+
+// SAN returns the PV in algebraic notation starting from the position.  The
+// moves are returned until the first move which isn't legal.
+func (info Info) SAN(pos *chess.Position) []string {
+	a := []string{}
+	for _, m := range info.PV {
+		if pos == nil {
+			break
+		}
+		legal, err := chess.UCINotation{}.Decode(pos, m.String())
+		if err != nil {
+			break
+		}
+		a = append(a, chess.AlgebraicNotation{}.Encode(pos, legal))
+		pos = pos.Update(legal)
+	}
+	return a
+}
+
+// addLine keeps the info as the latest line of its multipv index.  The
+// lines are reset when the best line arrives at a new depth.  Lines one
+// depth behind are kept since engines report the lines they didn't search
+// again at the previous depth when stopped, older lines are dropped.
+func (r *SearchResults) addLine(info Info) {
+	if len(info.PV) == 0 || info.Depth < r.depth-1 {
+		return
+	}
+	i := info.Multipv
+	if i < 1 {
+		i = 1
+	}
+	if i == 1 && info.Depth > r.depth {
+		r.depth = info.Depth
+		r.MultiPV = r.MultiPV[:0]
+	}
+	for len(r.MultiPV) < i {
+		r.MultiPV = append(r.MultiPV, Info{})
+	}
+	r.MultiPV[i-1] = info
 }
 
 // Info corresponds to the "info" engine output:
@@ -117,6 +167,56 @@ type Score struct {
 	UpperBound bool
 }
 
+// MarshalText implements the encoding.TextMarshaler interface and writes
+// the info line sent by engines.  Fields with zero values are left out
+// except for the score of lines with a PV.
+func (info Info) MarshalText() (text []byte, err error) {
+	return []byte(info.marshal(chess.UCINotation{})), nil
+}
+
+func (info Info) marshal(n chess.UCINotation) string {
+	a := []string{"info"}
+	add := func(name string, v int) {
+		if v != 0 {
+			a = append(a, name, strconv.Itoa(v))
+		}
+	}
+	add("depth", info.Depth)
+	add("seldepth", info.Seldepth)
+	add("multipv", info.Multipv)
+	if len(info.PV) > 0 || info.Score != (Score{}) {
+		a = append(a, "score")
+		if info.Score.Mate != 0 {
+			a = append(a, "mate", strconv.Itoa(info.Score.Mate))
+		} else {
+			a = append(a, "cp", strconv.Itoa(info.Score.CP))
+		}
+		if info.Score.LowerBound {
+			a = append(a, "lowerbound")
+		}
+		if info.Score.UpperBound {
+			a = append(a, "upperbound")
+		}
+	}
+	add("nodes", info.Nodes)
+	add("nps", info.NPS)
+	add("hashfull", info.Hashfull)
+	add("tbhits", info.TBHits)
+	add("cpuload", info.CPULoad)
+	add("time", int(info.Time/time.Millisecond))
+	if info.CurrentMove != nil {
+		a = append(a, "currmove", n.Encode(nil, info.CurrentMove))
+	}
+	add("currmovenumber", info.CurrentMoveNumber)
+	if len(info.PV) > 0 {
+		a = append(a, "pv")
+		for _, m := range info.PV {
+			a = append(a, n.Encode(nil, m))
+		}
+	}
+	return strings.Join(a, " ")
+}
+
 // UnmarshalText implements the encoding.TextUnmarshaler interface and parses
 // data like the following:
 // info depth 24 seldepth 32 multipv 1 score cp 29 nodes 5130101 nps 819897 hashfull 967 tbhits 0 time 6257 pv d2d4
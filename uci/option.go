@@ -2,6 +2,9 @@ package uci
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -116,9 +119,11 @@ type Option struct {
 // UnmarshalText implements the encoding.TextUnmarshaler interface and parses
 // data like the following:
 // option name EvalFile type string default nn-82215d0fd0df.nnue
+// Names and values may contain spaces and the default <empty> is parsed as an
+// empty string.
 func (o *Option) UnmarshalText(text []byte) error {
 	o.Type = OptionNoType
-	parts := strings.Split(string(text), " ")
+	parts := strings.Fields(string(text))
 	if len(parts) == 0 {
 		return errors.New("uci: invalid option line")
 	}
@@ -126,12 +131,10 @@ func (o *Option) UnmarshalText(text []byte) error {
 		return errors.New("uci: invalid option line")
 	}
 	ref := ""
-	for i := 1; i < len(parts); i++ {
-		s := parts[i]
-		if ref == "" {
-			ref = s
-			continue
-		}
+	values := []string{}
+	set := func() error {
+		s := strings.Join(values, " ")
+		values = values[:0]
 		switch ref {
 		case "name":
 			o.Name = s
@@ -142,6 +145,9 @@ func (o *Option) UnmarshalText(text []byte) error {
 			}
 			o.Type = ot
 		case "default":
+			if s == "<empty>" {
+				s = ""
+			}
 			o.Default = s
 		case "min":
 			o.Min = s
@@ -150,7 +156,21 @@ func (o *Option) UnmarshalText(text []byte) error {
 		case "var":
 			o.Vars = append(o.Vars, s)
 		}
-		ref = ""
+		return nil
+	}
+	for _, s := range parts[1:] {
+		switch s {
+		case "name", "type", "default", "min", "max", "var":
+			if err := set(); err != nil {
+				return err
+			}
+			ref = s
+		default:
+			values = append(values, s)
+		}
+	}
+	if err := set(); err != nil {
+		return err
 	}
 	if o.Name == "" || o.Type == OptionNoType {
 		return errors.New("uci: invalid option line")
@@ -158,6 +178,72 @@ func (o *Option) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// MarshalText implements the encoding.TextMarshaler interface and writes
+// the option line sent by engines.
+func (o Option) MarshalText() (text []byte, err error) {
+	a := []string{"option", "name", o.Name, "type", string(o.Type)}
+	switch o.Type {
+	case OptionButton:
+	case OptionString:
+		def := o.Default
+		if def == "" {
+			def = "<empty>"
+		}
+		a = append(a, "default", def)
+	default:
+		a = append(a, "default", o.Default)
+	}
+	if o.Min != "" {
+		a = append(a, "min", o.Min)
+	}
+	if o.Max != "" {
+		a = append(a, "max", o.Max)
+	}
+	for _, v := range o.Vars {
+		a = append(a, "var", v)
+	}
+	return []byte(strings.Join(a, " ")), nil
+}
+
+// Validate returns an error describing why the value can't be set for the
+// option: check options take true or false, spin options an integer between
+// Min and Max, combo options one of Vars and buttons no value.
+func (o Option) Validate(value string) error {
+	switch o.Type {
+	case OptionCheck:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("uci: option %s takes true or false not %q", o.Name, value)
+		}
+	case OptionSpin:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("uci: option %s takes an integer not %q", o.Name, value)
+		}
+		if min, err := strconv.Atoi(o.Min); err == nil && v < min {
+			return fmt.Errorf("uci: option %s value %d is below the minimum %d", o.Name, v, min)
+		}
+		if max, err := strconv.Atoi(o.Max); err == nil && v > max {
+			return fmt.Errorf("uci: option %s value %d is above the maximum %d", o.Name, v, max)
+		}
+	case OptionCombo:
+		for _, s := range o.Vars {
+			if strings.EqualFold(s, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("uci: option %s takes one of %s not %q", o.Name, strings.Join(o.Vars, ", "), value)
+	case OptionButton:
+		if value != "" {
+			return fmt.Errorf("uci: button %s takes no value", o.Name)
+		}
+	case OptionString:
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("uci: option %s value contains a line break", o.Name)
+		}
+	}
+	return nil
+}
+
 // OptionType corresponds to the "option"'s type engine output:
 // * type
 // The option has type t.
@@ -198,3 +284,119 @@ func optionTypeFromString(s string) (OptionType, error) {
 	}
 	return OptionNoType, errors.New("uci: invalid option type " + s)
 }
+
+// SetSpin sets the spin option after checking it is in range.
+func (e *Engine) SetSpin(name string, value int) error {
+	return e.setOption(name, OptionSpin, strconv.Itoa(value))
+}
+
+// SetCheck sets the check option.
+func (e *Engine) SetCheck(name string, value bool) error {
+	return e.setOption(name, OptionCheck, strconv.FormatBool(value))
+}
+
+// SetCombo sets the combo option after checking it is one of its vars.
+func (e *Engine) SetCombo(name, value string) error {
+	return e.setOption(name, OptionCombo, value)
+}
+
+// SetString sets the string option.
+func (e *Engine) SetString(name, value string) error {
+	return e.setOption(name, OptionString, value)
+}
+
+// PressButton sends the button option.
+func (e *Engine) PressButton(name string) error {
+	return e.setOption(name, OptionButton, "")
+}
+
+// setOption validates the option against the options returned by CmdUCI and
+// sends it to the engine.
+func (e *Engine) setOption(name string, ot OptionType, value string) error {
+	e.mu.RLock()
+	cmd, err := e.validateSetOption(name, ot, value)
+	e.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return e.Run(cmd)
+}
+
+// validateSetOption returns the command setting the option with the name
+// sent by the engine.  Options of any type are accepted if ot is
+// OptionNoType.
+func (e *Engine) validateSetOption(name string, ot OptionType, value string) (CmdSetOption, error) {
+	if e.options == nil {
+		return CmdSetOption{}, fmt.Errorf("uci: option %s can't be validated before CmdUCI", name)
+	}
+	o, ok := e.option(name)
+	if !ok {
+		return CmdSetOption{}, fmt.Errorf("uci: engine has no option %s", name)
+	}
+	if ot != OptionNoType && o.Type != ot {
+		return CmdSetOption{}, fmt.Errorf("uci: option %s is a %s option not %s", o.Name, o.Type, ot)
+	}
+	if err := o.Validate(value); err != nil {
+		return CmdSetOption{}, err
+	}
+	return CmdSetOption{Name: o.Name, Value: value}, nil
+}
+
+// option returns the option with the name ignoring case.
+func (e *Engine) option(name string) (Option, bool) {
+	if o, ok := e.options[name]; ok {
+		return o, true
+	}
+	for _, o := range e.options {
+		if strings.EqualFold(o.Name, name) {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
+
+// OptionSnapshot is the value of every option of an engine by name.
+type OptionSnapshot map[string]string
+
+// SnapshotOptions returns the current value of every option returned by
+// CmdUCI except buttons.  Options not set with CmdSetOption have their
+// default values.
+func (e *Engine) SnapshotOptions() OptionSnapshot {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.snapshotOptions()
+}
+
+func (e *Engine) snapshotOptions() OptionSnapshot {
+	snapshot := OptionSnapshot{}
+	for name, o := range e.options {
+		if o.Type != OptionButton {
+			snapshot[name] = o.Default
+		}
+	}
+	for _, cmd := range e.setOptions {
+		if o, ok := e.option(cmd.Name); ok && o.Type != OptionButton {
+			snapshot[o.Name] = cmd.Value
+		}
+	}
+	return snapshot
+}
+
+// RestoreOptions sets the options whose values differ from the snapshot.
+func (e *Engine) RestoreOptions(snapshot OptionSnapshot) error {
+	e.mu.RLock()
+	current := e.snapshotOptions()
+	e.mu.RUnlock()
+	names := []string{}
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cmds := []Cmd{}
+	for _, name := range names {
+		if v, ok := current[name]; !ok || v != snapshot[name] {
+			cmds = append(cmds, CmdSetOption{Name: name, Value: snapshot[name]})
+		}
+	}
+	return e.Run(cmds...)
+}
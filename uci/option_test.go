@@ -0,0 +1,120 @@
+package uci_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+func TestOptionUnmarshalText(t *testing.T) {
+	tests := []struct {
+		line   string
+		option uci.Option
+	}{
+		{"option name Skill Level type spin default 20 min 0 max 20", uci.Option{Name: "Skill Level", Type: uci.OptionSpin, Default: "20", Min: "0", Max: "20"}},
+		{"option name Analysis Contempt type combo default Both var Off var White var Black var Both", uci.Option{Name: "Analysis Contempt", Type: uci.OptionCombo, Default: "Both", Vars: []string{"Off", "White", "Black", "Both"}}},
+		{"option name Debug Log File type string default <empty>", uci.Option{Name: "Debug Log File", Type: uci.OptionString}},
+		{"option name Clear Hash type button", uci.Option{Name: "Clear Hash", Type: uci.OptionButton}},
+	}
+	for _, test := range tests {
+		o := uci.Option{}
+		if err := o.UnmarshalText([]byte(test.line)); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(o, test.option) {
+			t.Fatalf("expected %+v but got %+v", test.option, o)
+		}
+	}
+}
+
+func TestOptionValidate(t *testing.T) {
+	spin := uci.Option{Name: "Hash", Type: uci.OptionSpin, Min: "1", Max: "1024"}
+	combo := uci.Option{Name: "Style", Type: uci.OptionCombo, Vars: []string{"Solid", "Risky"}}
+	check := uci.Option{Name: "Ponder", Type: uci.OptionCheck}
+	tests := []struct {
+		option uci.Option
+		value  string
+		err    string
+	}{
+		{spin, "16", ""},
+		{spin, "0", "below the minimum 1"},
+		{spin, "2048", "above the maximum 1024"},
+		{spin, "16MB", "takes an integer"},
+		{combo, "risky", ""},
+		{combo, "Normal", "one of Solid, Risky"},
+		{check, "true", ""},
+		{check, "yes", "true or false"},
+	}
+	for _, test := range tests {
+		err := test.option.Validate(test.value)
+		if test.err == "" && err != nil {
+			t.Fatalf("expected %s to be valid for %s but got %v", test.value, test.option.Name, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Fatalf("expected error %q for %s but got %v", test.err, test.value, err)
+		}
+	}
+}
+
+func TestTypedSetters(t *testing.T) {
+	eng := newFakeEngine(t)
+	if err := eng.SetSpin("MultiPV", 2); err == nil {
+		t.Fatal("expected error before CmdUCI")
+	}
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.SetSpin("Hsh", 16); err == nil || !strings.Contains(err.Error(), "no option Hsh") {
+		t.Fatalf("expected unknown option error but got %v", err)
+	}
+	if err := eng.SetCheck("MultiPV", true); err == nil || !strings.Contains(err.Error(), "spin option") {
+		t.Fatalf("expected type error but got %v", err)
+	}
+	if err := eng.SetSpin("Skill Level", 21); err == nil {
+		t.Fatal("expected range error")
+	}
+	if err := eng.SetSpin("Skill Level", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.SetCombo("style", "Risky"); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.SetString("Debug Log File", "log.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.PressButton("Clear Hash"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := eng.SnapshotOptions()
+	want := uci.OptionSnapshot{"UCI_Chess960": "false", "MultiPV": "1", "Skill Level": "5", "Style": "Risky", "Debug Log File": "log.txt"}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Fatalf("expected snapshot %v but got %v", want, snapshot)
+	}
+}
+
+func TestRestoreOptions(t *testing.T) {
+	eng := newFakeEngine(t)
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := eng.SnapshotOptions()
+	if err := eng.SetSpin("MultiPV", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.RestoreOptions(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Run(setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(eng.SearchResults().MultiPV); n != 1 {
+		t.Fatalf("expected MultiPV to be restored but got %d lines", n)
+	}
+	if got := eng.SnapshotOptions(); !reflect.DeepEqual(got, snapshot) {
+		t.Fatalf("expected snapshot %v but got %v", snapshot, got)
+	}
+}
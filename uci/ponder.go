@@ -0,0 +1,74 @@
+package uci
+
+import (
+	"context"
+
+	"github.com/notnil/chess"
+)
+
+// Ponder is a search on the opponent's time started by Engine.Ponder.  The
+// engine searches the position after the expected move until the opponent
+// moves and Play is called.
+type Ponder struct {
+	e        *Engine
+	ctx      context.Context
+	pos      *chess.Position
+	expected *chess.Move
+	search   *Search
+	onInfo   func(Info)
+}
+
+// Ponder starts pondering in the position, with the opponent to move, on
+// the expected move which is usually SearchResults.Ponder.  The search is
+// sent with the ponder option and the clocks of cmd.  Every info line is
+// passed to onInfo (which may be nil) as with Search.
+func (e *Engine) Ponder(pos *chess.Position, expected *chess.Move, cmd CmdGo, onInfo func(Info)) (*Ponder, error) {
+	return e.PonderContext(context.Background(), pos, expected, cmd, onInfo)
+}
+
+// PonderContext is like Ponder but the pondering and the search started by
+// Play are stopped once the context is done.
+func (e *Engine) PonderContext(ctx context.Context, pos *chess.Position, expected *chess.Move, cmd CmdGo, onInfo func(Info)) (*Ponder, error) {
+	cmd.Ponder = true
+	cmdPos := CmdPosition{Position: pos, Moves: []*chess.Move{expected}}
+	s, err := e.searchPosition(ctx, cmdPos, cmd, onInfo)
+	if err != nil {
+		return nil, err
+	}
+	return &Ponder{e: e, ctx: ctx, pos: pos, expected: expected, search: s, onInfo: onInfo}, nil
+}
+
+// Expected returns the move the engine is pondering on.
+func (p *Ponder) Expected() *chess.Move {
+	return p.expected
+}
+
+// Play tells the engine the move the opponent played and returns the search
+// for the engine's reply.  If the move is the expected move ponderhit is
+// sent and the engine continues its search with the clocks sent to Ponder.
+// Otherwise the pondering is stopped and a new search is started with cmd
+// in the position after the move.
+func (p *Ponder) Play(m *chess.Move, cmd CmdGo) (*Search, error) {
+	if m.S1() == p.expected.S1() && m.S2() == p.expected.S2() && m.Promo() == p.expected.Promo() {
+		if err := p.e.processCommand(CmdPonderHit); err != nil {
+			return nil, err
+		}
+		return p.search, nil
+	}
+	if err := p.Stop(); err != nil {
+		return nil, err
+	}
+	cmd.Ponder = false
+	cmdPos := CmdPosition{Position: p.pos, Moves: []*chess.Move{m}}
+	return p.e.searchPosition(p.ctx, cmdPos, cmd, p.onInfo)
+}
+
+// Stop stops pondering, e.g. if the game ended, and waits for the engine
+// to send its best move.
+func (p *Ponder) Stop() error {
+	if err := p.search.Stop(); err != nil {
+		return err
+	}
+	_, err := p.search.Wait()
+	return err
+}
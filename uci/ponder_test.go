@@ -0,0 +1,69 @@
+package uci_test
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+func TestPonderHit(t *testing.T) {
+	eng := newFakeEngine(t)
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	pos := chess.StartingPosition()
+	e4, _ := chess.UCINotation{}.Decode(pos, "e2e4")
+	infos := make(chan uci.Info, 10)
+	ponder, err := eng.Ponder(pos, e4, uci.CmdGo{WhiteTime: 60000, BlackTime: 60000}, func(info uci.Info) {
+		infos <- info
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := <-infos; info.CurrentMove == nil {
+		t.Fatalf("expected pondering info but got %+v", info)
+	}
+	search, err := ponder.Play(e4, uci.CmdGo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := search.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.BestMove == nil || results.BestMove.String() != "a7a5" {
+		t.Fatalf("expected best move a7a5 but got %s", results.BestMove)
+	}
+}
+
+func TestPonderMiss(t *testing.T) {
+	eng := newFakeEngine(t)
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	pos := chess.StartingPosition()
+	e4, _ := chess.UCINotation{}.Decode(pos, "e2e4")
+	d4, _ := chess.UCINotation{}.Decode(pos, "d2d4")
+	ponder, err := eng.Ponder(pos, e4, uci.CmdGo{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	search, err := ponder.Play(d4, uci.CmdGo{Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := search.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pos.Update(d4).String(); results.Position.String() != want {
+		t.Fatalf("expected search after d4 %s but got %s", want, results.Position)
+	}
+	if results.BestMove == nil || results.BestMove.String() != "a7a5" {
+		t.Fatalf("expected best move a7a5 but got %s", results.BestMove)
+	}
+	if err := eng.Run(uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+}
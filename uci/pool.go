@@ -0,0 +1,193 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errPoolClosed is returned from Get after the pool is closed.
+var errPoolClosed = errors.New("uci: pool closed")
+
+// Pool is a fixed size set of engine processes started from the same
+// executable and options.  Engines are handed out to concurrent jobs with
+// Get and returned with Put.  Pool is safe for concurrent use.
+type Pool struct {
+	path          string
+	engineOpts    []func(e *Engine)
+	setOptions    []CmdSetOption
+	healthTimeout time.Duration
+	snapshot      OptionSnapshot
+	idle          chan *Engine
+	mu            *sync.Mutex
+	engines       []*Engine
+	closed        bool
+}
+
+// PoolEngine is an option for the NewPool function to pass options to the
+// New function for every engine in the pool.
+func PoolEngine(opts ...func(e *Engine)) func(p *Pool) {
+	return func(p *Pool) {
+		p.engineOpts = append(p.engineOpts, opts...)
+	}
+}
+
+// PoolSetOption is an option for the NewPool function to set the engine
+// option on every engine in the pool.  The value is validated against the
+// options returned by CmdUCI.
+func PoolSetOption(name, value string) func(p *Pool) {
+	return func(p *Pool) {
+		p.setOptions = append(p.setOptions, CmdSetOption{Name: name, Value: value})
+	}
+}
+
+// PoolHealthTimeout is an option for the NewPool function to set how long
+// Get waits for an engine to answer isready before restarting it.  The
+// default is five seconds.
+func PoolHealthTimeout(d time.Duration) func(p *Pool) {
+	return func(p *Pool) {
+		p.healthTimeout = d
+	}
+}
+
+// NewPool starts size engines from the executable path and initializes
+// them with CmdUCI and the options set with PoolSetOption.
+func NewPool(path string, size int, opts ...func(p *Pool)) (*Pool, error) {
+	if size < 1 {
+		return nil, errors.New("uci: pool size must be at least one")
+	}
+	p := &Pool{
+		path:          path,
+		healthTimeout: 5 * time.Second,
+		idle:          make(chan *Engine, size),
+		mu:            &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for i := 0; i < size; i++ {
+		e, err := p.startEngine()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		if p.snapshot == nil {
+			p.snapshot = e.SnapshotOptions()
+		}
+		p.engines = append(p.engines, e)
+		p.idle <- e
+	}
+	return p, nil
+}
+
+// Size returns the number of engines in the pool.
+func (p *Pool) Size() int {
+	return cap(p.idle)
+}
+
+// Get waits for an idle engine and returns it once it answers isready.
+// Engines that have exited or don't answer within the health timeout are
+// restarted.  The engine must be returned with Put and must not be closed.
+func (p *Pool) Get(ctx context.Context) (*Engine, error) {
+	var e *Engine
+	select {
+	case e = <-p.idle:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.isClosed() {
+		p.idle <- e
+		return nil, errPoolClosed
+	}
+	if err := p.check(ctx, e); err != nil {
+		p.idle <- e
+		return nil, err
+	}
+	return e, nil
+}
+
+// Put returns the engine to the pool.  Options changed by the job are set
+// back to the options of the pool.
+func (p *Pool) Put(e *Engine) {
+	// errors are found by the health check of the next Get
+	e.RestoreOptions(p.snapshot)
+	p.idle <- e
+}
+
+// Do runs f with an engine from the pool and returns the engine to the pool
+// once f returns.
+func (p *Pool) Do(ctx context.Context, f func(e *Engine) error) error {
+	e, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Put(e)
+	return f(e)
+}
+
+// Close closes every engine in the pool.  Engines in use are closed as
+// well so their commands return errors.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	var first error
+	for _, e := range p.engines {
+		if err := e.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (p *Pool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// startEngine starts a new engine and sets the options of the pool.
+func (p *Pool) startEngine() (*Engine, error) {
+	e, err := New(p.path, p.engineOpts...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.healthTimeout)
+	defer cancel()
+	if err := e.RunContext(ctx, CmdUCI); err != nil {
+		e.Close()
+		return nil, err
+	}
+	cmds := []Cmd{}
+	for _, opt := range p.setOptions {
+		cmd, err := e.validateSetOption(opt.Name, OptionNoType, opt.Value)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	cmds = append(cmds, CmdIsReady)
+	if err := e.RunContext(ctx, cmds...); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// check sends isready to the engine and restarts it if it doesn't answer.
+func (p *Pool) check(ctx context.Context, e *Engine) error {
+	checkCtx, cancel := context.WithTimeout(ctx, p.healthTimeout)
+	defer cancel()
+	err := e.RunContext(checkCtx, CmdIsReady)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	restartCtx, cancel := context.WithTimeout(ctx, p.healthTimeout)
+	defer cancel()
+	return e.RestartContext(restartCtx)
+}
@@ -0,0 +1,114 @@
+package uci_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/enginetest"
+	"github.com/notnil/chess/uci"
+)
+
+func newFakePool(t *testing.T, size int, opts ...func(p *uci.Pool)) *uci.Pool {
+	t.Helper()
+	pool, err := uci.NewPool(enginetest.Path("fake"), size, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestPool(t *testing.T) {
+	pool := newFakePool(t, 2, uci.PoolSetOption("MultiPV", "2"))
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- pool.Do(context.Background(), func(e *uci.Engine) error {
+				// positions cache their moves so every job uses its own
+				setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+				if err := e.Run(setPos, uci.CmdGo{Depth: 1}); err != nil {
+					return err
+				}
+				if n := len(e.SearchResults().MultiPV); n != 2 {
+					t.Errorf("expected pool options to be set but got %d lines", n)
+				}
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPoolResetsOptions(t *testing.T) {
+	pool := newFakePool(t, 1, uci.PoolSetOption("MultiPV", "2"))
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	e, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Run(uci.CmdSetOption{Name: "MultiPV", Value: "3"}); err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(e)
+	e, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Put(e)
+	if err := e.Run(setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(e.SearchResults().MultiPV); n != 2 {
+		t.Fatalf("expected pool options to be set back but got %d lines", n)
+	}
+}
+
+func TestPoolReplacesDeadEngine(t *testing.T) {
+	pool := newFakePool(t, 1)
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	err := pool.Do(context.Background(), func(e *uci.Engine) error {
+		return e.Run(setPos, uci.CmdGo{Depth: crashDepth})
+	})
+	if err == nil {
+		t.Fatal("expected the crash to be returned")
+	}
+	err = pool.Do(context.Background(), func(e *uci.Engine) error {
+		return e.Run(setPos, uci.CmdGo{Depth: 1})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolGetContext(t *testing.T) {
+	pool := newFakePool(t, 1)
+	e, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Put(e)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Get(ctx); err != context.Canceled {
+		t.Fatalf("expected canceled while the engine is in use but got %v", err)
+	}
+}
+
+func TestPoolValidatesOptions(t *testing.T) {
+	_, err := uci.NewPool(enginetest.Path("fake"), 1, uci.PoolSetOption("MultiPV", "0"))
+	if err == nil || !strings.Contains(err.Error(), "below the minimum") {
+		t.Fatalf("expected validation error but got %v", err)
+	}
+}
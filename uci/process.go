@@ -0,0 +1,14 @@
+package uci
+
+import (
+	"errors"
+
+	"github.com/notnil/chess/internal/process"
+)
+
+// ExitError is returned when the engine process exits while a command is
+// waiting for its response.
+type ExitError = process.ExitError
+
+// errEngineClosed is returned for commands run after Close.
+var errEngineClosed = errors.New("uci: engine closed")
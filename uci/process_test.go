@@ -0,0 +1,94 @@
+package uci_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+func TestExitError(t *testing.T) {
+	eng := newFakeEngine(t)
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	err := eng.Run(uci.CmdUCI, uci.CmdIsReady, setPos, uci.CmdGo{Depth: crashDepth})
+	var exitErr *uci.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected exit error but got %v", err)
+	}
+	if exitErr.ExitCode != 3 || !strings.Contains(exitErr.Stderr, "segmentation fault") {
+		t.Fatalf("expected exit status 3 and stderr but got %d %q", exitErr.ExitCode, exitErr.Stderr)
+	}
+	if err := eng.Run(uci.CmdIsReady); !errors.As(err, &exitErr) {
+		t.Fatalf("expected exit error after the engine exited but got %v", err)
+	}
+}
+
+func TestRunContextTimeout(t *testing.T) {
+	eng := newFakeEngine(t, uci.QuitTimeout(10*time.Millisecond))
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, setPos); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := eng.RunContext(ctx, uci.CmdGo{Depth: hangDepth})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded but got %v", err)
+	}
+	start := time.Now()
+	if err := eng.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("expected hanging engine to be killed but close took %s", d)
+	}
+}
+
+func TestSearchContextSkipsStaleResults(t *testing.T) {
+	eng := newFakeEngine(t)
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, setPos); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	search, err := eng.SearchContext(ctx, uci.CmdGo{Infinite: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := search.Wait(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded but got %v", err)
+	}
+	setPos = uci.CmdPosition{Position: chess.StartingPosition(), Moves: []*chess.Move{}}
+	e4, _ := chess.UCINotation{}.Decode(chess.StartingPosition(), "e2e4")
+	setPos.Moves = append(setPos.Moves, e4)
+	if err := eng.Run(setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if m := eng.SearchResults().BestMove; m == nil || m.String() != "a7a5" {
+		t.Fatalf("expected best move a7a5 after e4 but got %s", m)
+	}
+}
+
+func TestAutoRestart(t *testing.T) {
+	eng := newFakeEngine(t, uci.AutoRestart)
+	setOpt := uci.CmdSetOption{Name: "MultiPV", Value: "2"}
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := eng.Run(uci.CmdUCI, setOpt, uci.CmdIsReady, setPos, uci.CmdGo{Depth: crashDepth}); err == nil {
+		t.Fatal("expected the crash to be returned")
+	}
+	if err := eng.Run(setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	results := eng.SearchResults()
+	if len(results.MultiPV) != 2 {
+		t.Fatalf("expected options to be replayed but got %d lines", len(results.MultiPV))
+	}
+	if eng.ID()["name"] != "Fake" {
+		t.Fatalf("expected uci to be replayed but got %v", eng.ID())
+	}
+}
@@ -0,0 +1,148 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Search is a search running in the background started by Engine.Search.
+type Search struct {
+	e       *Engine
+	done    chan struct{}
+	results SearchResults
+	err     error
+}
+
+// Search starts the search in the background and returns immediately.
+// Every info line sent by the engine is parsed and passed to onInfo (which
+// may be nil) as the engine thinks.  The Engine is locked until the search
+// completes so other commands wait for the search except for CmdStop.
+// Searches with the infinite option run until Stop is called.
+func (e *Engine) Search(cmd CmdGo, onInfo func(Info)) (*Search, error) {
+	return e.SearchContext(context.Background(), cmd, onInfo)
+}
+
+// SearchContext is like Search but the search is stopped once the context
+// is done and completes with the context's error.
+func (e *Engine) SearchContext(ctx context.Context, cmd CmdGo, onInfo func(Info)) (*Search, error) {
+	e.mu.Lock()
+	e.ctx = ctx
+	if err := e.restartIfExited(); err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+	return e.search(ctx, cmd, onInfo)
+}
+
+// search sends the go command and reads the search in the background.  The
+// Engine must be locked and is unlocked once the search completes.
+func (e *Engine) search(ctx context.Context, cmd CmdGo, onInfo func(Info)) (*Search, error) {
+	if err := e.send(cmd.String()); err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+	s := &Search{e: e, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		defer e.mu.Unlock()
+		s.results, s.err = e.readSearch(onInfo)
+		if s.err == nil {
+			e.results = s.results
+		} else if ctx.Err() != nil && errors.Is(s.err, ctx.Err()) {
+			e.stopSearch()
+		}
+	}()
+	return s, nil
+}
+
+// searchPosition sets the position and starts the search.
+func (e *Engine) searchPosition(ctx context.Context, pos CmdPosition, cmd CmdGo, onInfo func(Info)) (*Search, error) {
+	e.mu.Lock()
+	e.ctx = ctx
+	if err := e.restartIfExited(); err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+	if err := e.processCommand(pos); err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+	return e.search(ctx, cmd, onInfo)
+}
+
+// Stop sends CmdStop to the engine.  The search completes once the engine
+// sends its best move.
+func (s *Search) Stop() error {
+	return s.e.processCommand(CmdStop)
+}
+
+// Done returns a channel which is closed when the search completes.
+func (s *Search) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait blocks until the search completes and returns its results.
+func (s *Search) Wait() (SearchResults, error) {
+	<-s.done
+	return s.results, s.err
+}
+
+// readSearch reads the engine output until the best move is sent and
+// passes every info line to onInfo.
+func (e *Engine) readSearch(onInfo func(Info)) (SearchResults, error) {
+	results := SearchResults{Position: e.position}
+	for {
+		text, err := e.readLine()
+		if err != nil {
+			return results, err
+		}
+		if strings.HasPrefix(text, "bestmove") {
+			parts := strings.Split(text, " ")
+			if len(parts) <= 1 {
+				return results, errors.New("best move not found " + text)
+			}
+			bestMove, legal, err := e.decodeMove(e.position, parts[1])
+			if err != nil {
+				return results, err
+			}
+			results.BestMove = bestMove
+			if len(parts) >= 4 {
+				var pos *chess.Position
+				if legal {
+					pos = e.position.Update(bestMove)
+				}
+				ponderMove, _, err := e.decodeMove(pos, parts[3])
+				if err != nil {
+					return results, err
+				}
+				results.Ponder = ponderMove
+			}
+			break
+		}
+
+		info := &Info{}
+		err = info.UnmarshalText([]byte(text))
+		if err == nil {
+			e.translateInfo(e.position, info)
+			results.Info = *info
+			results.addLine(*info)
+			if onInfo != nil {
+				onInfo(*info)
+			}
+		}
+	}
+	lines := results.MultiPV[:0]
+	for _, info := range results.MultiPV {
+		if len(info.PV) > 0 {
+			lines = append(lines, info)
+		}
+	}
+	results.MultiPV = lines
+	if len(lines) > 0 {
+		results.Info = lines[0]
+	}
+	return results, nil
+}
@@ -0,0 +1,112 @@
+package uci_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+func TestSearchInfinite(t *testing.T) {
+	eng := newFakeEngine(t)
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady, uci.CmdPosition{Position: chess.StartingPosition()}); err != nil {
+		t.Fatal(err)
+	}
+	infos := make(chan uci.Info, 10)
+	search, err := eng.Search(uci.CmdGo{Infinite: true}, func(info uci.Info) {
+		infos <- info
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := <-infos
+	if info.CurrentMove == nil || info.CurrentMoveNumber != 1 {
+		t.Fatalf("expected current move info but got %+v", info)
+	}
+	select {
+	case <-search.Done():
+		t.Fatal("expected infinite search to run until stopped")
+	default:
+	}
+	if err := search.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	results, err := search.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.BestMove == nil || results.BestMove.String() != "a2a3" {
+		t.Fatalf("expected best move a2a3 but got %s", results.BestMove)
+	}
+	info = <-infos
+	if info.Depth != 1 || info.Score.CP != 13 || len(info.PV) != 2 {
+		t.Fatalf("expected pv info but got %+v", info)
+	}
+	if eng.SearchResults().BestMove != results.BestMove {
+		t.Fatal("expected engine search results to be updated")
+	}
+	// the engine can be used again after the search completes
+	if err := eng.Run(uci.CmdIsReady, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiPV(t *testing.T) {
+	eng := newFakeEngine(t)
+	setOpt := uci.CmdSetOption{Name: "MultiPV", Value: "3"}
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := eng.Run(uci.CmdUCI, setOpt, uci.CmdIsReady, setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	results := eng.SearchResults()
+	if len(results.MultiPV) != 3 {
+		t.Fatalf("expected 3 lines but got %d", len(results.MultiPV))
+	}
+	expected := [][]string{{"a3", "Na6"}, {"a4", "Na6"}, {"Na3", "Na6"}}
+	for i, info := range results.MultiPV {
+		if info.Multipv != i+1 || info.Score.CP != 13-i {
+			t.Fatalf("expected line %d to be ordered but got multipv %d score %d", i+1, info.Multipv, info.Score.CP)
+		}
+		san := info.SAN(results.Position)
+		if strings.Join(san, " ") != strings.Join(expected[i], " ") {
+			t.Fatalf("expected line %d to be %s but got %s", i+1, expected[i], san)
+		}
+	}
+	if results.Info.Multipv != 1 || results.Info.PV[0] != results.BestMove {
+		t.Fatalf("expected info to be the best line but got multipv %d", results.Info.Multipv)
+	}
+}
+
+func TestMultiPVStopped(t *testing.T) {
+	eng := newFakeEngine(t)
+	setOpt := uci.CmdSetOption{Name: "MultiPV", Value: "3"}
+	setPos := uci.CmdPosition{Position: chess.StartingPosition()}
+	if err := eng.Run(uci.CmdUCI, setOpt, uci.CmdIsReady, setPos); err != nil {
+		t.Fatal(err)
+	}
+	search, err := eng.Search(uci.CmdGo{Infinite: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := search.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	results, err := search.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the search stopped after the first line of depth 2 and reported the
+	// other lines at depth 1
+	if len(results.MultiPV) != 3 || results.MultiPV[0].Depth != 2 || results.MultiPV[0].Score.CP != 20 {
+		t.Fatalf("expected the depth 2 line first but got %+v", results.MultiPV)
+	}
+	for i, info := range results.MultiPV[1:] {
+		if info.Multipv != i+2 || info.Depth != 1 || info.Score.CP != 12-i {
+			t.Fatalf("expected line %d at depth 1 but got %+v", i+2, info)
+		}
+	}
+	if results.Info.Depth != 2 {
+		t.Fatalf("expected info at depth 2 but got %d", results.Info.Depth)
+	}
+}
@@ -0,0 +1,267 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+// Searcher is implemented by chess engines served with Server.  Search
+// searches the position within the limits of cmd and returns the best move
+// and optionally the move to ponder on.  The search must return once the
+// context is done (after "stop" or "quit").  Info lines are sent to the GUI
+// with the send function.
+type Searcher interface {
+	Search(ctx context.Context, pos *chess.Position, cmd CmdGo, send func(Info)) (best, ponder *chess.Move)
+}
+
+// OptionSetter is implemented by Searchers with options.  The options are
+// sent in response to "uci" and values received with "setoption" are
+// validated before SetOption is called.
+type OptionSetter interface {
+	Options() []Option
+	SetOption(name, value string) error
+}
+
+// PonderHitter is implemented by Searchers which change their search once
+// the GUI sends "ponderhit" while pondering.  Searchers which don't
+// implement it should search as if cmd.Ponder was false; the best move is
+// held back until "ponderhit" or "stop" is received.
+type PonderHitter interface {
+	PonderHit()
+}
+
+// Server runs the engine side of the UCI protocol for a Searcher so it can
+// be used from any UCI compliant GUI.
+type Server struct {
+	searcher Searcher
+	id       map[string]string
+}
+
+// ServerID is an option for the NewServer function to set the id values
+// sent in response to "uci" such as name and author.
+func ServerID(name, author string) func(srv *Server) {
+	return func(srv *Server) {
+		srv.id["name"] = name
+		srv.id["author"] = author
+	}
+}
+
+// NewServer returns a Server for the Searcher.
+func NewServer(searcher Searcher, opts ...func(srv *Server)) *Server {
+	srv := &Server{searcher: searcher, id: map[string]string{"name": "Go Engine", "author": "unknown"}}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
+
+// Serve reads commands from r and writes responses to w until "quit" is
+// received or r is closed.  Errors in commands are reported to the GUI with
+// "info string".
+func (srv *Server) Serve(r io.Reader, w io.Writer) error {
+	s := &session{srv: srv, w: w, pos: chess.StartingPosition()}
+	defer s.stopSearch()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			s.uci()
+		case "isready":
+			s.println("readyok")
+		case "setoption":
+			s.setOption(text)
+		case "ucinewgame":
+			s.stopSearch()
+		case "position":
+			s.stopSearch()
+			s.position(text)
+		case "go":
+			s.stopSearch()
+			s.goSearch(text)
+		case "stop":
+			s.stopSearch()
+		case "ponderhit":
+			s.ponderHit()
+		case "quit":
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// session is the state of a Serve call.
+type session struct {
+	srv      *Server
+	w        io.Writer
+	wmu      sync.Mutex
+	chess960 bool
+	pos      *chess.Position
+	search   *serverSearch
+}
+
+// serverSearch is a running search.  Bestmove is only sent after release
+// is closed for infinite and ponder searches.
+type serverSearch struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	release  chan struct{}
+	released bool
+	ponder   bool
+}
+
+func (s *session) println(a ...interface{}) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	fmt.Fprintln(s.w, a...)
+}
+
+func (s *session) infoString(err error) {
+	s.println("info string", err.Error())
+}
+
+func (s *session) notation() chess.UCINotation {
+	return chess.UCINotation{Chess960: s.chess960}
+}
+
+func (s *session) uci() {
+	for _, k := range []string{"name", "author"} {
+		s.println("id", k, s.srv.id[k])
+	}
+	s.println(`option name UCI_Chess960 type check default false`)
+	if setter, ok := s.srv.searcher.(OptionSetter); ok {
+		for _, o := range setter.Options() {
+			b, _ := o.MarshalText()
+			s.println(string(b))
+		}
+	}
+	s.println("uciok")
+}
+
+func (s *session) setOption(text string) {
+	cmd := &CmdSetOption{}
+	if err := cmd.UnmarshalText([]byte(text)); err != nil {
+		s.infoString(err)
+		return
+	}
+	if strings.EqualFold(cmd.Name, "UCI_Chess960") {
+		s.chess960 = strings.EqualFold(cmd.Value, "true")
+		return
+	}
+	setter, ok := s.srv.searcher.(OptionSetter)
+	if !ok {
+		s.infoString(fmt.Errorf("uci: engine has no option %s", cmd.Name))
+		return
+	}
+	for _, o := range setter.Options() {
+		if !strings.EqualFold(o.Name, cmd.Name) {
+			continue
+		}
+		if err := o.Validate(cmd.Value); err != nil {
+			s.infoString(err)
+			return
+		}
+		if err := setter.SetOption(o.Name, cmd.Value); err != nil {
+			s.infoString(err)
+		}
+		return
+	}
+	s.infoString(fmt.Errorf("uci: engine has no option %s", cmd.Name))
+}
+
+func (s *session) position(text string) {
+	cmd := &CmdPosition{Chess960: s.chess960}
+	if err := cmd.UnmarshalText([]byte(text)); err != nil {
+		s.infoString(err)
+		return
+	}
+	pos := cmd.Position
+	for _, m := range cmd.Moves {
+		pos = pos.Update(m)
+	}
+	s.pos = pos
+}
+
+func (s *session) goSearch(text string) {
+	cmd := &CmdGo{}
+	if err := cmd.UnmarshalText([]byte(text)); err != nil {
+		s.infoString(err)
+		return
+	}
+	// search moves are translated to the legal moves of the position
+	moves := cmd.SearchMoves[:0]
+	for _, m := range cmd.SearchMoves {
+		if legal, err := s.notation().Decode(s.pos, m.String()); err == nil {
+			moves = append(moves, legal)
+		}
+	}
+	cmd.SearchMoves = moves
+	ctx, cancel := context.WithCancel(context.Background())
+	search := &serverSearch{
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		release: make(chan struct{}),
+		ponder:  cmd.Ponder,
+	}
+	if !cmd.Infinite && !cmd.Ponder {
+		search.released = true
+		close(search.release)
+	}
+	s.search = search
+	pos := s.pos
+	n := s.notation()
+	go func() {
+		defer close(search.done)
+		best, ponder := s.srv.searcher.Search(ctx, pos, *cmd, func(info Info) {
+			s.println(info.marshal(n))
+		})
+		<-search.release
+		switch {
+		case best == nil:
+			s.println("bestmove (none)")
+		case ponder == nil:
+			s.println("bestmove", n.Encode(pos, best))
+		default:
+			s.println("bestmove", n.Encode(pos, best), "ponder", n.Encode(pos.Update(best), ponder))
+		}
+	}()
+}
+
+// stopSearch stops the running search and waits for its best move.
+func (s *session) stopSearch() {
+	search := s.search
+	if search == nil {
+		return
+	}
+	search.cancel()
+	if !search.released {
+		search.released = true
+		close(search.release)
+	}
+	<-search.done
+	s.search = nil
+}
+
+// ponderHit switches the ponder search to a normal search.
+func (s *session) ponderHit() {
+	search := s.search
+	if search == nil || !search.ponder || search.released {
+		return
+	}
+	search.ponder = false
+	if ph, ok := s.srv.searcher.(PonderHitter); ok {
+		ph.PonderHit()
+	}
+	search.released = true
+	close(search.release)
+}
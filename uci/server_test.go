@@ -0,0 +1,193 @@
+package uci_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// firstMoveSearcher plays the first legal move in alphabetical order and
+// reports its skill option as the score.
+type firstMoveSearcher struct {
+	skill int
+}
+
+func (s *firstMoveSearcher) Search(ctx context.Context, pos *chess.Position, cmd uci.CmdGo, send func(uci.Info)) (*chess.Move, *chess.Move) {
+	moves := cmd.SearchMoves
+	if len(moves) == 0 {
+		moves = pos.ValidMoves()
+	}
+	if len(moves) == 0 {
+		return nil, nil
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].String() < moves[j].String() })
+	best := moves[0]
+	send(uci.Info{Depth: 1, Score: uci.Score{CP: s.skill}, Nodes: len(moves), PV: []*chess.Move{best}})
+	if cmd.Infinite {
+		<-ctx.Done()
+	}
+	replies := pos.Update(best).ValidMoves()
+	if len(replies) == 0 {
+		return best, nil
+	}
+	return best, replies[0]
+}
+
+func (s *firstMoveSearcher) Options() []uci.Option {
+	return []uci.Option{{Name: "Skill Level", Type: uci.OptionSpin, Default: "10", Min: "0", Max: "20"}}
+}
+
+func (s *firstMoveSearcher) SetOption(name, value string) error {
+	v, err := strconv.Atoi(value)
+	s.skill = v
+	return err
+}
+
+func newServerEngine(t *testing.T) *uci.Engine {
+	t.Helper()
+	eng := startEngine(t, "server")
+	if err := eng.Run(uci.CmdUCI, uci.CmdIsReady); err != nil {
+		t.Fatal(err)
+	}
+	return eng
+}
+
+func TestServer(t *testing.T) {
+	eng := newServerEngine(t)
+	if name := eng.ID()["name"]; name != "First" {
+		t.Fatalf("expected name First but got %s", name)
+	}
+	if err := eng.SetSpin("Skill Level", 7); err != nil {
+		t.Fatal(err)
+	}
+	pos := chess.StartingPosition()
+	e4, _ := chess.UCINotation{}.Decode(pos, "e2e4")
+	setPos := uci.CmdPosition{Position: pos, Moves: []*chess.Move{e4}}
+	if err := eng.Run(setPos, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	results := eng.SearchResults()
+	if results.BestMove.String() != "a7a5" || results.Ponder == nil {
+		t.Fatalf("expected best move a7a5 with ponder but got %s %s", results.BestMove, results.Ponder)
+	}
+	if results.Info.Score.CP != 7 || results.Info.Depth != 1 {
+		t.Fatalf("expected info with the skill as score but got %+v", results.Info)
+	}
+	d2d4, _ := chess.UCINotation{}.Decode(nil, "d2d4")
+	if err := eng.Run(uci.CmdPosition{Position: pos}, uci.CmdGo{SearchMoves: []*chess.Move{d2d4}}); err != nil {
+		t.Fatal(err)
+	}
+	if m := eng.SearchResults().BestMove; m.String() != "d2d4" {
+		t.Fatalf("expected search moves to be searched but got %s", m)
+	}
+}
+
+func TestServerInfiniteAndPonder(t *testing.T) {
+	eng := newServerEngine(t)
+	if err := eng.Run(uci.CmdPosition{Position: chess.StartingPosition()}); err != nil {
+		t.Fatal(err)
+	}
+	search, err := eng.Search(uci.CmdGo{Infinite: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-search.Done():
+		t.Fatal("expected infinite search to run until stopped")
+	case <-time.After(20 * time.Millisecond):
+	}
+	search.Stop()
+	if results, err := search.Wait(); err != nil || results.BestMove.String() != "a2a3" {
+		t.Fatalf("expected a2a3 but got %v %v", results.BestMove, err)
+	}
+	pos := chess.StartingPosition()
+	e4, _ := chess.UCINotation{}.Decode(pos, "e2e4")
+	ponder, err := eng.Ponder(pos, e4, uci.CmdGo{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	search, err = ponder.Play(e4, uci.CmdGo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results, err := search.Wait(); err != nil || results.BestMove.String() != "a7a5" {
+		t.Fatalf("expected a7a5 after ponderhit but got %v %v", results.BestMove, err)
+	}
+}
+
+func TestServerChess960(t *testing.T) {
+	eng := newServerEngine(t)
+	pos, err := chess.Chess960Position(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setOpt := uci.CmdSetOption{Name: "UCI_Chess960", Value: "true"}
+	if err := eng.Run(setOpt, uci.CmdPosition{Position: pos}, uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if m := eng.SearchResults().BestMove; m == nil || !contains(pos.ValidMoves(), m) {
+		t.Fatalf("expected a legal move but got %s", m)
+	}
+}
+
+func contains(moves []*chess.Move, m *chess.Move) bool {
+	for _, legal := range moves {
+		if legal == m || legal.String() == m.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInfoMarshalText(t *testing.T) {
+	line := "info depth 24 seldepth 32 multipv 1 score cp 29 nodes 5130101 nps 819897 hashfull 967 tbhits 3 time 6257 pv d2d4 d7d5"
+	info := uci.Info{}
+	if err := info.UnmarshalText([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := info.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != line {
+		t.Fatalf("expected %s but got %s", line, b)
+	}
+	info = uci.Info{Score: uci.Score{Mate: -3, LowerBound: true}}
+	if b, _ := info.MarshalText(); string(b) != "info score mate -3 lowerbound" {
+		t.Fatalf("unexpected mate info %s", b)
+	}
+}
+
+func TestCmdUnmarshalText(t *testing.T) {
+	cmdGo := uci.CmdGo{}
+	line := "go ponder wtime 60000 btime 50000 winc 1000 binc 1000 movestogo 20 depth 10 nodes 5000 mate 3 movetime 100 searchmoves e2e4 d2d4"
+	if err := cmdGo.UnmarshalText([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	if cmdGo.String() != line {
+		t.Fatalf("expected %s but got %s", line, cmdGo.String())
+	}
+	setOpt := uci.CmdSetOption{}
+	if err := setOpt.UnmarshalText([]byte("setoption name Skill Level value 5")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(setOpt, uci.CmdSetOption{Name: "Skill Level", Value: "5"}) {
+		t.Fatalf("unexpected setoption %+v", setOpt)
+	}
+	cmdPos := uci.CmdPosition{}
+	if err := cmdPos.UnmarshalText([]byte("position startpos moves e2e4 e7e5 g1f3")); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmdPos.Moves) != 3 || cmdPos.Moves[2].String() != "g1f3" {
+		t.Fatalf("unexpected moves %v", cmdPos.Moves)
+	}
+	if err := cmdPos.UnmarshalText([]byte("position startpos moves e2e5")); err == nil {
+		t.Fatal("expected illegal move error")
+	}
+}
@@ -0,0 +1,244 @@
+package chess
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// A Variant defines the rules of a game: its starting position, move
+// generation and legality, game termination and automatic draws.  A
+// position carries its variant so ValidMoves, Status and Update follow
+// the variant's rules.
+//
+// Custom variants are created by embedding one of the variants of this
+// package and overriding the methods that differ:
+//
+//	// NoCastling is standard chess without castling.
+//	type NoCastling struct{ chess.Standard }
+//
+//	func (NoCastling) Name() string { return "No Castling" }
+//
+//	func (NoCastling) Moves(pos *chess.Position) []*chess.Move {
+//		moves := []*chess.Move{}
+//		for _, m := range (chess.Standard{}).Moves(pos) {
+//			if !m.HasTag(chess.KingSideCastle) && !m.HasTag(chess.QueenSideCastle) {
+//				moves = append(moves, m)
+//			}
+//		}
+//		return moves
+//	}
+type Variant interface {
+	// Name returns the name of the variant as written in the PGN Variant tag.
+	Name() string
+	// StartingPosition returns the initial position of the variant.
+	StartingPosition() *Position
+	// Moves returns the legal moves of the position.
+	Moves(pos *Position) []*Move
+	// Status returns the outcome of the game and the method by which it
+	// ended if the position is terminal, otherwise NoOutcome and NoMethod.
+	Status(pos *Position) (Outcome, Method)
+	// InsufficientMaterial returns true if neither side can win the game
+	// which results in an automatic draw.
+	InsufficientMaterial(pos *Position) bool
+
+	// nineSixty returns true if castling follows Chess960 rules and castle
+	// rights are written in Shredder-FEN.
+	nineSixty() bool
+	// drops returns true if positions have pockets and pieces in hand can
+	// be dropped on the board.
+	drops() bool
+	// checkLimit returns the number of checks that wins the game or zero
+	// if checks aren't counted.
+	checkLimit() int
+	// validate returns an error if the decoded position is illegal.
+	validate(pos *Position) error
+	// afterMove updates the variant specific state of next, the position
+	// resulting from playing m in pos.
+	afterMove(pos *Position, m *Move, next *Position)
+}
+
+// Standard is the variant implementing the FIDE laws of chess.
+type Standard struct{}
+
+// Name implements the Variant interface.
+func (Standard) Name() string {
+	return "Standard"
+}
+
+// StartingPosition implements the Variant interface.
+func (Standard) StartingPosition() *Position {
+	pos, _ := decodeFEN(startFEN, false)
+	return pos
+}
+
+// Moves implements the Variant interface.
+func (Standard) Moves(pos *Position) []*Move {
+	return engine{}.CalcMoves(pos, false)
+}
+
+// Status implements the Variant interface.  Possible methods are
+// Checkmate, Stalemate and NoMethod.
+func (Standard) Status(pos *Position) (Outcome, Method) {
+	switch (engine{}).Status(pos) {
+	case Checkmate:
+		if pos.Turn() == White {
+			return BlackWon, Checkmate
+		}
+		return WhiteWon, Checkmate
+	case Stalemate:
+		return Draw, Stalemate
+	}
+	return NoOutcome, NoMethod
+}
+
+// InsufficientMaterial implements the Variant interface.
+func (Standard) InsufficientMaterial(pos *Position) bool {
+	return !pos.board.hasSufficientMaterial()
+}
+
+func (Standard) nineSixty() bool {
+	return false
+}
+
+func (Standard) drops() bool {
+	return false
+}
+
+func (Standard) validate(pos *Position) error {
+	if (pos.board.bbWhitePawn|pos.board.bbBlackPawn)&(bbRank1|bbRank8) != 0 {
+		return errPawnsOnBackRank
+	}
+	if !pos.board.bbWhiteKing.single() || !pos.board.bbBlackKing.single() {
+		return errors.New("both black and white should have one king each")
+	}
+	// make sure the player in next turn cannot capture opponent's king
+	cp := pos.copy()
+	cp.turn = cp.turn.Other()
+	if isInCheck(cp) {
+		return errors.New("king can be captured in next move")
+	}
+	return nil
+}
+
+func (Standard) checkLimit() int {
+	return 0
+}
+
+func (Standard) afterMove(pos *Position, m *Move, next *Position) {}
+
+// Chess960 (or Fischer Random Chess) is standard chess played from one of
+// 960 starting positions with modified castling rules.  Positions are
+// written in Shredder-FEN and decoded from both Shredder-FEN and X-FEN.
+type Chess960 struct {
+	Standard
+}
+
+// Name implements the Variant interface.
+func (Chess960) Name() string {
+	return "Chess960"
+}
+
+// StartingPosition implements the Variant interface and returns the
+// standard starting position (Chess960 position number 518).
+func (Chess960) StartingPosition() *Position {
+	pos, _ := decodeFEN(startFEN, true)
+	return pos
+}
+
+func (Chess960) nineSixty() bool {
+	return true
+}
+
+func (Chess960) validate(pos *Position) error {
+	if err := (Standard{}).validate(pos); err != nil {
+		return err
+	}
+	cr := pos.castleRights
+	whiteCastles := cr.whiteKingSideCastle || cr.whiteQueenSideCastle
+	blackCastles := cr.blackKingSideCastle || cr.blackQueenSideCastle
+	if whiteCastles && blackCastles && pos.board.whiteKingSq.File() != pos.board.blackKingSq.File() {
+		return errors.New("white and black kings must be on same file for both to have castle rights")
+	}
+	if cr.hSideRookStartingFile[White] != "" && cr.hSideRookStartingFile[Black] != "" &&
+		cr.hSideRookStartingFile[White] != cr.hSideRookStartingFile[Black] {
+		return errors.New("rook starting king side file missmatch")
+	}
+	if cr.aSideRookStartingFile[White] != "" && cr.aSideRookStartingFile[Black] != "" &&
+		cr.aSideRookStartingFile[White] != cr.aSideRookStartingFile[Black] {
+		return errors.New("rook starting queen side file missmatch")
+	}
+	return nil
+}
+
+// DoubleChess960 is Chess960 in which white and black are set up
+// independently so the kings and rooks of the two colors may start on
+// different files.
+type DoubleChess960 struct {
+	Chess960
+}
+
+// Name implements the Variant interface.
+func (DoubleChess960) Name() string {
+	return "Double Chess960"
+}
+
+// StartingPosition implements the Variant interface and returns the
+// standard starting position.
+func (DoubleChess960) StartingPosition() *Position {
+	pos, _ := decodeVariantFEN(startFEN, DoubleChess960{})
+	return pos
+}
+
+func (DoubleChess960) validate(pos *Position) error {
+	return Standard{}.validate(pos)
+}
+
+var errPawnsOnBackRank = errors.New("pawns cannot be on first or last rank")
+
+var (
+	variantsMu sync.RWMutex
+	variants   = map[string]Variant{}
+)
+
+func init() {
+	RegisterVariant(Standard{}, "From Position")
+	RegisterVariant(Chess960{}, "Fischerandom", "Fischer Random")
+	RegisterVariant(DoubleChess960{})
+	RegisterVariant(Crazyhouse{}, "ZH")
+	RegisterVariant(Bughouse{}, "Bug")
+	RegisterVariant(ThreeCheck{}, "3check")
+	RegisterVariant(KingOfTheHill{}, "KOTH")
+	RegisterVariant(RacingKings{})
+	RegisterVariant(Atomic{})
+	RegisterVariant(Antichess{}, "Losing Chess")
+	RegisterVariant(Horde{})
+}
+
+// RegisterVariant makes the variant available for decoding PGN Variant
+// tags under its name and the given aliases.  Names are compared case
+// insensitively ignoring spaces, dashes and underscores.
+func RegisterVariant(v Variant, aliases ...string) {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	for _, name := range append([]string{v.Name()}, aliases...) {
+		variants[normalizeVariantName(name)] = v
+	}
+}
+
+// VariantFromName returns the registered variant matching the name of a
+// PGN Variant tag.  The boolean is false if the variant is unknown.
+func VariantFromName(name string) (Variant, bool) {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+	v, ok := variants[normalizeVariantName(name)]
+	return v, ok
+}
+
+func normalizeVariantName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+func isStandard(v Variant) bool {
+	return v.Name() == Standard{}.Name()
+}
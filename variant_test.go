@@ -0,0 +1,78 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+// noCastling is standard chess without castling.
+type noCastling struct{ Standard }
+
+func (noCastling) Name() string { return "No Castling" }
+
+func (noCastling) Moves(pos *Position) []*Move {
+	moves := []*Move{}
+	for _, m := range (Standard{}).Moves(pos) {
+		if !m.HasTag(KingSideCastle) && !m.HasTag(QueenSideCastle) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+func TestCustomVariant(t *testing.T) {
+	RegisterVariant(noCastling{}, "nocastle")
+	g := NewGame(UseVariant(noCastling{}))
+	for _, m := range []string{"e4", "e5", "Nf3", "Nf6", "Bc4", "Bc5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.MoveStr("O-O"); err == nil {
+		t.Fatal("expected castling to be illegal")
+	}
+	if err := g.MoveStr("Kf1"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Position().Variant() != (noCastling{}) {
+		t.Fatalf("expected variant to be kept after a move but got %s", g.Position().Variant().Name())
+	}
+	pgn := g.String()
+	if !strings.Contains(pgn, `[Variant "No Castling"]`) {
+		t.Fatalf("expected Variant tag in %s", pgn)
+	}
+	opt, err := PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := NewGame(opt).Variant(); v != (noCastling{}) {
+		t.Fatalf("expected No Castling variant but got %s", v.Name())
+	}
+}
+
+func TestVariantFromName(t *testing.T) {
+	for _, name := range []string{"Standard", "From Position", "chess960", "Chess 960", "fischerandom", "Fischer Random"} {
+		if _, ok := VariantFromName(name); !ok {
+			t.Fatalf("expected variant %s to be registered", name)
+		}
+	}
+	if _, ok := VariantFromName("Bughouse Deluxe"); ok {
+		t.Fatal("expected unknown variant")
+	}
+}
+
+func TestUseVariant(t *testing.T) {
+	g := NewGame(UseVariant(Chess960{}))
+	if g.Variant() != (Chess960{}) {
+		t.Fatalf("expected Chess960 but got %s", g.Variant().Name())
+	}
+	if g.Position().String() != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1" {
+		t.Fatalf("expected Shredder-FEN starting position but got %s", g.Position())
+	}
+	if !strings.Contains(g.String(), `[Variant "Chess960"]`) {
+		t.Fatalf("expected Variant tag in %s", g.String())
+	}
+	if v := NewGame().Variant(); v != (Standard{}) {
+		t.Fatalf("expected Standard but got %s", v.Name())
+	}
+}
@@ -0,0 +1,480 @@
+package xboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/process"
+	"github.com/notnil/chess/uci"
+)
+
+// Engine represents a chess engine speaking the Chess Engine Communication
+// Protocol (CECP) used by XBoard and WinBoard (e.g. Sjeng, Fairy-Max, GNU
+// Chess).  The engine is kept in force mode between searches so it only
+// thinks when Go is called.  Engine is safe for concurrent use.
+type Engine struct {
+	path        string
+	proc        *process.Process
+	debug       bool
+	logger      *log.Logger
+	quitTimeout time.Duration
+	mu          *sync.Mutex
+	// ctx is the context of the method being run
+	ctx      context.Context
+	features map[string]string
+	pos      *chess.Position
+	pings    int
+	closed   bool
+}
+
+// Debug is an option for the New function to add logging for debugging.  This will
+// log all output to and from the chess engine.
+func Debug(e *Engine) {
+	e.debug = true
+}
+
+// Logger is an option for the New function to customize the logger.  The logger is
+// only used if the Debug option is also used.
+func Logger(logger *log.Logger) func(e *Engine) {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// QuitTimeout is an option for the New function to set how long Close waits
+// for the engine to exit after the quit command before killing the process.
+// The default is five seconds.
+func QuitTimeout(d time.Duration) func(e *Engine) {
+	return func(e *Engine) {
+		e.quitTimeout = d
+	}
+}
+
+// New constructs an engine from the executable path (found using exec.LookPath)
+// and starts running the executable process in the background.  Init must be
+// called before the engine is used.
+func New(path string, opts ...func(e *Engine)) (*Engine, error) {
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("xboard: executable not found at path %s %w", path, err)
+	}
+	e := &Engine{
+		path:        path,
+		mu:          &sync.Mutex{},
+		logger:      log.New(os.Stdout, "xboard", log.LstdFlags),
+		quitTimeout: 5 * time.Second,
+		features:    map[string]string{},
+		pos:         chess.StartingPosition(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	p, err := process.Start("xboard", path)
+	if err != nil {
+		return nil, err
+	}
+	e.proc = p
+	return e, nil
+}
+
+// featureTimeout is how long engines that don't send "feature done=0" have
+// to send their features.
+const featureTimeout = 2 * time.Second
+
+// Init switches the engine to xboard mode with "protover 2", accepts the
+// features sent by the engine and turns on thinking output with "post".
+func (e *Engine) Init(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ctx = ctx
+	if err := e.send("xboard"); err != nil {
+		return err
+	}
+	if err := e.send("protover 2"); err != nil {
+		return err
+	}
+	timeout, cancel := context.WithTimeout(ctx, featureTimeout)
+	defer cancel()
+	e.ctx = timeout
+	for {
+		text, err := e.readLine()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			// engines without features or done=1
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(text, "feature ") {
+			continue
+		}
+		features, err := parseFeatures(text)
+		if err != nil {
+			return err
+		}
+		done := ""
+		for _, f := range features {
+			if f.name == "done" {
+				done = f.value
+				continue
+			}
+			e.features[f.name] = f.value
+			if err := e.send("accepted " + f.name); err != nil {
+				return err
+			}
+		}
+		if done == "0" {
+			// the engine needs more time and sends done=1 when ready
+			e.ctx = ctx
+		}
+		if done == "1" {
+			break
+		}
+	}
+	e.ctx = ctx
+	return e.send("post")
+}
+
+// Features returns the features sent by the engine during Init such as
+// myname, ping, setboard, usermove, san and variants.
+func (e *Engine) Features() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cp := map[string]string{}
+	for k, v := range e.features {
+		cp[k] = v
+	}
+	return cp
+}
+
+// Name returns the name sent with the myname feature.
+func (e *Engine) Name() string {
+	return e.Features()["myname"]
+}
+
+// Variants returns the variants supported by the engine.
+func (e *Engine) Variants() []chess.Variant {
+	a := []chess.Variant{}
+	for _, name := range strings.Split(e.Features()["variants"], ",") {
+		if v, ok := variantFromName(strings.TrimSpace(name)); ok {
+			a = append(a, v)
+		}
+	}
+	return a
+}
+
+// Position returns the position of the game played with the engine.
+func (e *Engine) Position() *chess.Position {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pos
+}
+
+// NewGame starts a new game of the variant with "new" and "variant".  An
+// error is returned if the engine doesn't support the variant.
+func (e *Engine) NewGame(v chess.Variant) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	name, ok := variantName(v)
+	if !ok {
+		return fmt.Errorf("xboard: variant %s has no xboard name", v.Name())
+	}
+	if name != "normal" && !e.supports("variants", name) {
+		return fmt.Errorf("xboard: engine doesn't support variant %s", name)
+	}
+	if err := e.send("new"); err != nil {
+		return err
+	}
+	if err := e.send("force"); err != nil {
+		return err
+	}
+	if name != "normal" {
+		if err := e.send("variant " + name); err != nil {
+			return err
+		}
+	}
+	e.pos = chess.NewGame(chess.UseVariant(v)).Position()
+	return nil
+}
+
+// SetBoard sets up the position with "setboard".  NewGame with the
+// position's variant must be called first.
+func (e *Engine) SetBoard(pos *chess.Position) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.features["setboard"] != "1" {
+		return errors.New("xboard: engine doesn't support setboard")
+	}
+	fen := pos.String()
+	if _, ok := pos.Variant().(chess.Chess960); ok {
+		fen = pos.XFENString()
+	}
+	if err := e.send("setboard " + fen); err != nil {
+		return err
+	}
+	e.pos = pos
+	return nil
+}
+
+// UserMove plays the move in the engine's position.  Moves are sent in SAN
+// or coordinate notation as requested by the san feature.
+func (e *Engine) UserMove(m *chess.Move) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	legal, err := chess.UCINotation{}.Decode(e.pos, chess.UCINotation{}.Encode(e.pos, m))
+	if err != nil {
+		return fmt.Errorf("xboard: move %s is not legal %w", m, err)
+	}
+	s := e.encodeMove(legal)
+	if e.features["usermove"] == "1" {
+		s = "usermove " + s
+	}
+	if err := e.send(s); err != nil {
+		return err
+	}
+	e.pos = e.pos.Update(legal)
+	return nil
+}
+
+// Level sets a conventional time control with "level": movesPerSession
+// moves (0 for the whole game) in base time with the increment added after
+// every move.
+func (e *Engine) Level(movesPerSession int, base, increment time.Duration) error {
+	minutes := int(base / time.Minute)
+	seconds := int((base % time.Minute) / time.Second)
+	b := strconv.Itoa(minutes)
+	if seconds > 0 {
+		b += fmt.Sprintf(":%02d", seconds)
+	}
+	inc := strconv.FormatFloat(increment.Seconds(), 'f', -1, 64)
+	return e.run(fmt.Sprintf("level %d %s %s", movesPerSession, b, inc))
+}
+
+// Time sets the time left on the clocks of the engine and its opponent
+// with "time" and "otim".  It is sent before Go.
+func (e *Engine) Time(engine, opponent time.Duration) error {
+	return e.run(
+		fmt.Sprintf("time %d", engine/(10*time.Millisecond)),
+		fmt.Sprintf("otim %d", opponent/(10*time.Millisecond)),
+	)
+}
+
+// MoveTime sets a fixed time per move with "st".  The duration is rounded
+// up to whole seconds since "st" doesn't take fractions.
+func (e *Engine) MoveTime(d time.Duration) error {
+	st := (d + time.Second - 1) / time.Second
+	if st < 1 {
+		st = 1
+	}
+	return e.run(fmt.Sprintf("st %d", st))
+}
+
+// Depth limits the search depth with "sd".
+func (e *Engine) Depth(plies int) error {
+	return e.run(fmt.Sprintf("sd %d", plies))
+}
+
+// Ping sends "ping" and waits for "pong" to synchronize with the engine.
+// Ping returns immediately if the engine doesn't support the ping feature.
+func (e *Engine) Ping(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ctx = ctx
+	return e.ping()
+}
+
+// Go makes the engine play the side to move and returns its move.  Every
+// thinking line is parsed and passed to onThinking (which may be nil).  The
+// engine is put back in force mode after its move.  A *ResultError is
+// returned if the engine resigns or claims a result instead of moving.  If
+// the context is done the search is interrupted with "force" and the
+// context's error is returned.
+func (e *Engine) Go(ctx context.Context, onThinking func(uci.Info)) (*chess.Move, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ctx = ctx
+	if err := e.send("go"); err != nil {
+		return nil, err
+	}
+	for {
+		text, err := e.readLine()
+		if err != nil {
+			if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+				e.interrupt()
+			}
+			return nil, err
+		}
+		if m, ok, err := e.parseMove(text); ok {
+			if err != nil {
+				return nil, err
+			}
+			e.pos = e.pos.Update(m)
+			return m, e.send("force")
+		}
+		if r, ok := parseResult(text); ok {
+			e.send("force")
+			return nil, r
+		}
+		if strings.HasPrefix(text, "Illegal move") || strings.HasPrefix(text, "Error") {
+			return nil, fmt.Errorf("xboard: %s", text)
+		}
+		if info, ok := parseThinking(e.pos, text); ok && onThinking != nil {
+			onThinking(info)
+		}
+	}
+}
+
+// Close sends "quit" and kills the engine if it hasn't exited after the
+// QuitTimeout.
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.proc.Quit("quit", e.quitTimeout)
+}
+
+// ResultError is returned from Go when the engine resigns or claims a
+// result instead of moving.
+type ResultError struct {
+	Outcome chess.Outcome
+	// Reason is the comment sent with the result or "resign".
+	Reason string
+}
+
+// Error implements the error interface.
+func (r *ResultError) Error() string {
+	return fmt.Sprintf("xboard: engine claimed %s {%s}", r.Outcome, r.Reason)
+}
+
+// run sends the commands which have no response.
+func (e *Engine) run(cmds ...string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range cmds {
+		if err := e.send(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interrupt stops the search with "force" and synchronizes with ping so
+// the move of the interrupted search isn't read by the next search.  A move
+// sent before the engine received "force" is still played.
+func (e *Engine) interrupt() {
+	if err := e.send("force"); err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), featureTimeout)
+	defer cancel()
+	e.ctx = ctx
+	e.ping()
+}
+
+// ping sends "ping" and reads until the "pong", playing moves sent before.
+func (e *Engine) ping() error {
+	if e.features["ping"] != "1" {
+		return nil
+	}
+	e.pings++
+	n := strconv.Itoa(e.pings)
+	if err := e.send("ping " + n); err != nil {
+		return err
+	}
+	for {
+		text, err := e.readLine()
+		if err != nil {
+			return err
+		}
+		if text == "pong "+n {
+			return nil
+		}
+		if m, ok, err := e.parseMove(text); ok && err == nil {
+			e.pos = e.pos.Update(m)
+		}
+	}
+}
+
+// supports returns true if the comma separated feature contains the value.
+func (e *Engine) supports(feature, value string) bool {
+	for _, s := range strings.Split(e.features[feature], ",") {
+		if strings.TrimSpace(s) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeMove writes the move in the notation requested by the engine.
+func (e *Engine) encodeMove(m *chess.Move) string {
+	if e.features["san"] == "1" {
+		return chess.AlgebraicNotation{}.Encode(e.pos, m)
+	}
+	if m.HasTag(chess.NineSixtyCastle) {
+		if m.HasTag(chess.KingSideCastle) {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+	return chess.UCINotation{}.Encode(e.pos, m)
+}
+
+// parseMove parses "move e2e4" lines.  The boolean is false for other
+// lines.
+func (e *Engine) parseMove(text string) (*chess.Move, bool, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 || fields[0] != "move" {
+		return nil, false, nil
+	}
+	m, err := decodeMove(e.pos, fields[1])
+	if err != nil {
+		return nil, true, fmt.Errorf("xboard: engine sent illegal move %s %w", fields[1], err)
+	}
+	return m, true, nil
+}
+
+// send writes the line to the engine.  An *ExitError is returned if the
+// engine has exited.
+func (e *Engine) send(s string) error {
+	if e.closed {
+		return errEngineClosed
+	}
+	if e.debug {
+		e.logger.Println(s)
+	}
+	return e.proc.Send(s)
+}
+
+// readLine returns the next line sent by the engine.  The context's error
+// is returned if the context is done and an *ExitError if the engine has
+// exited.
+func (e *Engine) readLine() (string, error) {
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case s, ok := <-e.proc.Lines():
+		if !ok {
+			return "", e.proc.ExitError()
+		}
+		if e.debug {
+			e.logger.Println(s)
+		}
+		return s, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
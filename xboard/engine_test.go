@@ -0,0 +1,164 @@
+package xboard_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+	"github.com/notnil/chess/xboard"
+)
+
+func TestInit(t *testing.T) {
+	eng := newFakeEngine(t)
+	if err := eng.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if name := eng.Name(); name != "Fake Board 1.0" {
+		t.Fatalf("expected name Fake Board 1.0 but got %s", name)
+	}
+	if f := eng.Features(); f["ping"] != "1" || f["setboard"] != "1" {
+		t.Fatalf("expected features to be parsed but got %v", f)
+	}
+	if n := len(eng.Variants()); n != 3 {
+		t.Fatalf("expected 3 variants but got %d", n)
+	}
+}
+
+func TestGo(t *testing.T) {
+	eng := newFakeEngine(t)
+	ctx := context.Background()
+	if err := eng.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.NewGame(chess.Standard{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Level(40, 5*time.Minute, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Time(5*time.Minute, 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	e4, _ := chess.UCINotation{}.Decode(chess.StartingPosition(), "e2e4")
+	if err := eng.UserMove(e4); err != nil {
+		t.Fatal(err)
+	}
+	infos := []uci.Info{}
+	m, err := eng.Go(ctx, func(info uci.Info) {
+		infos = append(infos, info)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.String() != "a7a5" {
+		t.Fatalf("expected a7a5 but got %s", m)
+	}
+	if len(infos) != 4 {
+		t.Fatalf("expected 4 thinking lines but got %d", len(infos))
+	}
+	if info := infos[0]; info.Depth != 1 || info.Score.CP != 13 || info.Time != 20*time.Millisecond || info.Nodes != 20 || len(info.PV) != 2 {
+		t.Fatalf("unexpected thinking %+v", info)
+	}
+	if info := infos[1]; info.Score.Mate != 3 || len(info.PV) != 2 {
+		t.Fatalf("expected mate score and pv after move numbers but got %+v", info)
+	}
+	if info := infos[2]; info.Score.Mate != 0 || info.Score.CP != 99500 {
+		t.Fatalf("expected centipawn score below the mate score but got %+v", info.Score)
+	}
+	if info := infos[3]; info.Score.Mate != 0 || info.Score.CP != -100000 {
+		t.Fatalf("expected centipawn score for the mate score itself but got %+v", info.Score)
+	}
+	// the engine is in force mode after its move
+	d4, _ := chess.UCINotation{}.Decode(eng.Position(), "d2d4")
+	if err := eng.UserMove(d4); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := eng.Go(ctx, nil); err != nil || m.String() != "a5a4" {
+		t.Fatalf("expected a5a4 but got %s %v", m, err)
+	}
+}
+
+func TestSetBoardChess960(t *testing.T) {
+	eng := newFakeEngine(t)
+	ctx := context.Background()
+	if err := eng.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.NewGame(chess.Crazyhouse{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.NewGame(chess.Atomic{}); err == nil {
+		t.Fatal("expected unsupported variant error")
+	}
+	pos, _ := chess.Chess960Position(959)
+	if err := eng.NewGame(chess.Chess960{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.SetBoard(pos); err != nil {
+		t.Fatal(err)
+	}
+	m, err := eng.Go(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eng.Position().Turn() != chess.Black || m == nil {
+		t.Fatalf("expected white move but got %s", m)
+	}
+}
+
+func TestResign(t *testing.T) {
+	eng := newFakeEngine(t)
+	ctx := context.Background()
+	if err := eng.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Depth(99); err != nil {
+		t.Fatal(err)
+	}
+	_, err := eng.Go(ctx, nil)
+	var r *xboard.ResultError
+	if !errors.As(err, &r) || r.Reason != "resign" {
+		t.Fatalf("expected resignation but got %v", err)
+	}
+}
+
+func TestGoContext(t *testing.T) {
+	eng := newFakeEngine(t)
+	if err := eng.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Depth(98); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := eng.Go(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded but got %v", err)
+	}
+	if err := eng.Depth(1); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := eng.Go(context.Background(), nil); err != nil || m.String() != "a2a3" {
+		t.Fatalf("expected a2a3 after the interrupted search but got %s %v", m, err)
+	}
+}
+
+func TestMoveTime(t *testing.T) {
+	eng := newFakeEngine(t)
+	if err := eng.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// rounded up to "st 1" instead of "st 0" which the engine rejects
+	if err := eng.MoveTime(300 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := eng.Go(context.Background(), nil); err != nil || m.String() != "a2a3" {
+		t.Fatalf("expected a2a3 but got %s %v", m, err)
+	}
+}
@@ -0,0 +1,143 @@
+package xboard_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/internal/enginetest"
+	"github.com/notnil/chess/xboard"
+)
+
+// Searches to these depths make the fake engine resign or think until
+// interrupted.
+const (
+	resignDepth = 99
+	hangDepth   = 98
+)
+
+// TestMain makes the test binary act as a minimal CECP engine so the
+// Engine can be tested without an external executable.
+func TestMain(m *testing.M) {
+	enginetest.Main(m, map[string]enginetest.Engine{"fake": runFakeEngine})
+}
+
+// newFakeEngine starts the test binary as a fake engine and initializes it.
+func newFakeEngine(t *testing.T, opts ...func(e *xboard.Engine)) *xboard.Engine {
+	t.Helper()
+	eng, err := xboard.New(enginetest.Path("fake"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { eng.Close() })
+	return eng
+}
+
+// runFakeEngine plays the first legal move in alphabetical order and sends
+// its thinking in SAN.  Like real engines it keeps playing its side after
+// "go" until "force".
+func runFakeEngine(r io.Reader, w io.Writer) {
+	pos := chess.StartingPosition()
+	playing := false
+	depth := 0
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	think := func() {
+		moves := pos.ValidMoves()
+		if depth == resignDepth || len(moves) == 0 {
+			fmt.Fprintln(w, "resign")
+			return
+		}
+		sort.Slice(moves, func(i, j int) bool { return moves[i].String() < moves[j].String() })
+		best := moves[0]
+		next := pos.Update(best)
+		pv := chess.AlgebraicNotation{}.Encode(pos, best)
+		if replies := next.ValidMoves(); len(replies) > 0 {
+			pv += " " + chess.AlgebraicNotation{}.Encode(next, replies[0])
+		}
+		fmt.Fprintf(w, "1 13 2 20 %s\n", pv)
+		fmt.Fprintln(w, "2. 100003 5 40 1. "+pv)
+		fmt.Fprintln(w, "3 99500 8 60 "+pv)
+		fmt.Fprintln(w, "4 -100000 9 80 "+pv)
+		if depth == hangDepth {
+			// thinks until force
+			for text := range lines {
+				if text == "force" {
+					playing = false
+					return
+				}
+			}
+		}
+		fmt.Fprintf(w, "move %s\n", best)
+		pos = next
+	}
+	for text := range lines {
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "protover":
+			fmt.Fprintln(w, `feature done=0`)
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprintln(w, `feature ping=1 setboard=1 usermove=1 san=0 myname="Fake Board 1.0"`)
+			fmt.Fprintln(w, `feature variants="normal,fischerandom,crazyhouse" done=1`)
+		case "new":
+			pos = chess.StartingPosition()
+			playing = false
+		case "variant":
+			v, _ := chess.VariantFromName(fields[1])
+			if fields[1] == "fischerandom" {
+				v = chess.Chess960{}
+			}
+			pos = chess.NewGame(chess.UseVariant(v)).Position()
+		case "setboard":
+			opt, err := chess.FEN(strings.Join(fields[1:], " "), pos.Variant().Name() == "Chess960")
+			if err != nil {
+				fmt.Fprintln(w, "Error (bad fen):", text)
+				continue
+			}
+			pos = chess.NewGame(opt).Position()
+		case "usermove":
+			m, err := chess.UCINotation{}.Decode(pos, fields[1])
+			if err != nil {
+				m, err = chess.AlgebraicNotation{}.Decode(pos, fields[1])
+			}
+			if err != nil {
+				fmt.Fprintln(w, "Illegal move:", fields[1])
+				continue
+			}
+			pos = pos.Update(m)
+			if playing {
+				think()
+			}
+		case "sd":
+			fmt.Sscan(fields[1], &depth)
+		case "st":
+			if st, err := strconv.Atoi(fields[1]); err != nil || st < 1 {
+				fmt.Fprintln(w, "Error (bad time):", text)
+			}
+		case "force":
+			playing = false
+		case "go":
+			playing = true
+			think()
+		case "ping":
+			fmt.Fprintln(w, "pong", fields[1])
+		case "quit":
+			return
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package xboard
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// feature is a name value pair of a "feature" command.
+type feature struct {
+	name  string
+	value string
+}
+
+// parseFeatures parses lines like the following:
+// feature ping=1 setboard=1 myname="Fairy-Max 4.8V" variants="normal,fischerandom" done=1
+func parseFeatures(text string) ([]feature, error) {
+	s := strings.TrimPrefix(text, "feature")
+	features := []feature{}
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return features, nil
+		}
+		i := strings.Index(s, "=")
+		if i <= 0 {
+			return nil, errors.New("xboard: invalid feature line " + text)
+		}
+		f := feature{name: s[:i]}
+		s = s[i+1:]
+		if strings.HasPrefix(s, `"`) {
+			j := strings.Index(s[1:], `"`)
+			if j < 0 {
+				return nil, errors.New("xboard: invalid feature line " + text)
+			}
+			f.value = s[1 : j+1]
+			s = s[j+2:]
+		} else {
+			j := strings.IndexByte(s, ' ')
+			if j < 0 {
+				j = len(s)
+			}
+			f.value = s[:j]
+			s = s[j:]
+		}
+		features = append(features, f)
+	}
+}
+
+var resultRe = regexp.MustCompile(`^(1-0|0-1|1/2-1/2)\s*(?:\{(.*)\})?`)
+
+// parseResult parses "resign" and result claims like "1-0 {White mates}".
+func parseResult(text string) (*ResultError, bool) {
+	if text == "resign" {
+		return &ResultError{Outcome: chess.NoOutcome, Reason: "resign"}, true
+	}
+	match := resultRe.FindStringSubmatch(text)
+	if match == nil {
+		return nil, false
+	}
+	return &ResultError{Outcome: chess.Outcome(match[1]), Reason: match[2]}, true
+}
+
+// mateScore is the offset of mate scores in thinking output.
+const mateScore = 100000
+
+var thinkingRe = regexp.MustCompile(`^\s*(\d+)[.&?!]?\s+(-?\d+)\s+(\d+)\s+(\d+)\s*(.*)$`)
+
+var moveNumberRe = regexp.MustCompile(`^\d+\.+$`)
+
+// parseThinking parses thinking output of the form "ply score time nodes pv"
+// where time is in centiseconds and mate scores are 100000 + N for mate in
+// N moves.  A score of exactly 100000 has no mate distance and is kept in
+// centipawns since a mate of zero would read as a score of zero.  The PV is decoded from the position in SAN or coordinate
+// notation until the first move which isn't legal.
+func parseThinking(pos *chess.Position, text string) (uci.Info, bool) {
+	match := thinkingRe.FindStringSubmatch(text)
+	if match == nil {
+		return uci.Info{}, false
+	}
+	info := uci.Info{}
+	info.Depth, _ = strconv.Atoi(match[1])
+	score, _ := strconv.Atoi(match[2])
+	switch {
+	case score > mateScore:
+		info.Score.Mate = score - mateScore
+	case score < -mateScore:
+		info.Score.Mate = score + mateScore
+	default:
+		info.Score.CP = score
+	}
+	cs, _ := strconv.Atoi(match[3])
+	info.Time = time.Duration(cs) * 10 * time.Millisecond
+	info.Nodes, _ = strconv.Atoi(match[4])
+	for _, s := range strings.Fields(match[5]) {
+		if moveNumberRe.MatchString(s) {
+			continue
+		}
+		if pos == nil {
+			break
+		}
+		m, err := decodeMove(pos, strings.TrimRight(s, "+#!?"))
+		if err != nil {
+			break
+		}
+		info.PV = append(info.PV, m)
+		pos = pos.Update(m)
+	}
+	return info, true
+}
+
+// decodeMove decodes a move in coordinate notation or SAN.
+func decodeMove(pos *chess.Position, s string) (*chess.Move, error) {
+	if m, err := (chess.UCINotation{}).Decode(pos, s); err == nil {
+		return m, nil
+	}
+	return chess.AlgebraicNotation{}.Decode(pos, s)
+}
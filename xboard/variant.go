@@ -0,0 +1,33 @@
+package xboard
+
+import "github.com/notnil/chess"
+
+// variantNames are the xboard names of the variants in the chess package.
+var variantNames = map[string]string{
+	chess.Standard{}.Name():      "normal",
+	chess.Chess960{}.Name():      "fischerandom",
+	chess.Crazyhouse{}.Name():    "crazyhouse",
+	chess.Bughouse{}.Name():      "bughouse",
+	chess.ThreeCheck{}.Name():    "3check",
+	chess.KingOfTheHill{}.Name(): "kingofthehill",
+	chess.RacingKings{}.Name():   "racingkings",
+	chess.Atomic{}.Name():        "atomic",
+	chess.Antichess{}.Name():     "giveaway",
+	chess.Horde{}.Name():         "horde",
+}
+
+// variantName returns the xboard name of the variant.
+func variantName(v chess.Variant) (string, bool) {
+	name, ok := variantNames[v.Name()]
+	return name, ok
+}
+
+// variantFromName returns the variant with the xboard name.
+func variantFromName(name string) (chess.Variant, bool) {
+	for v, n := range variantNames {
+		if n == name {
+			return chess.VariantFromName(v)
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,141 @@
+package chess
+
+import "hash/fnv"
+
+// Zobrist keys are generated from a fixed seed so that hashes are stable
+// between runs and can be persisted (for example in a game database index).
+var (
+	zobristPieces    [13][numOfSquaresInBoard]uint64
+	zobristTurn      uint64
+	zobristCastle    [4]uint64
+	zobristEnPassant [numOfSquaresInRow]uint64
+	zobristPockets   [13][17]uint64
+	zobristChecks    [3][8]uint64
+)
+
+func init() {
+	seed := uint64(0x9E3779B97F4A7C15)
+	next := func() uint64 {
+		// splitmix64
+		seed += 0x9E3779B97F4A7C15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+	for _, p := range allPieces {
+		for sq := 0; sq < numOfSquaresInBoard; sq++ {
+			zobristPieces[p][sq] = next()
+		}
+	}
+	zobristTurn = next()
+	for i := range zobristCastle {
+		zobristCastle[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+	for _, p := range allPieces {
+		for n := 1; n < len(zobristPockets[p]); n++ {
+			zobristPockets[p][n] = next()
+		}
+	}
+	for _, c := range []Color{White, Black} {
+		for n := range zobristChecks[c] {
+			zobristChecks[c][n] = next()
+		}
+	}
+}
+
+// ZobristHash returns a 64 bit Zobrist hash of the position.  Unlike Hash,
+// the half move clock and move count are not part of the key so transpositions
+// of the same position share a hash.  The en passant square is only included
+// if the side to move has a pawn that can capture on it.  Pieces in hand and
+// remaining checks are included for variants which track them and the
+// variant is included for variants other than Standard, so games of
+// different rules reaching the same board don't share a hash.
+func (pos *Position) ZobristHash() uint64 {
+	h := zobristVariant(pos.rules())
+	for _, p := range allPieces {
+		bb := pos.board.bbForPiece(p)
+		if bb == 0 {
+			continue
+		}
+		for sq := 0; sq < numOfSquaresInBoard; sq++ {
+			if bb&bbForSquare(Square(sq)) != 0 {
+				h ^= zobristPieces[p][sq]
+			}
+		}
+	}
+	if pos.turn == Black {
+		h ^= zobristTurn
+	}
+	for i, c := range []Color{White, White, Black, Black} {
+		side := KingSide
+		if i%2 == 1 {
+			side = QueenSide
+		}
+		if pos.castleRights.CanCastle(c, side) {
+			h ^= zobristCastle[i]
+		}
+	}
+	if pos.hasEnPassantCapture() {
+		h ^= zobristEnPassant[pos.enPassantSquare.File()]
+	}
+	if pos.pockets != nil {
+		for _, p := range allPieces {
+			n := int(pos.pockets[p])
+			if n >= len(zobristPockets[p]) {
+				n = len(zobristPockets[p]) - 1
+			}
+			if n > 0 {
+				h ^= zobristPockets[p][n]
+			}
+		}
+	}
+	if pos.checks != nil {
+		for _, c := range []Color{White, Black} {
+			n := int(pos.checks[c])
+			if n >= len(zobristChecks[c]) {
+				n = len(zobristChecks[c]) - 1
+			}
+			h ^= zobristChecks[c][n]
+		}
+	}
+	return h
+}
+
+// zobristVariant returns the key of the variant, zero for Standard so the
+// hashes of standard positions don't depend on the registered variants.
+// Keys are derived from the variant name since variants can be registered.
+func zobristVariant(v Variant) uint64 {
+	if isStandard(v) {
+		return 0
+	}
+	f := fnv.New64a()
+	f.Write([]byte(normalizeVariantName(v.Name())))
+	// splitmix64 finalizer
+	z := f.Sum64()
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// hasEnPassantCapture returns true if a pawn of the side to move stands
+// next to the pawn that can be captured en passant.
+func (pos *Position) hasEnPassantCapture() bool {
+	if pos.enPassantSquare == NoSquare {
+		return false
+	}
+	bbPawn := pos.board.bbWhitePawn
+	captured := pos.enPassantSquare - 8
+	if pos.turn == Black {
+		bbPawn = pos.board.bbBlackPawn
+		captured = pos.enPassantSquare + 8
+	}
+	f := captured.File()
+	if f > FileA && bbPawn&bbForSquare(captured-1) != 0 {
+		return true
+	}
+	return f < FileH && bbPawn&bbForSquare(captured+1) != 0
+}